@@ -0,0 +1,90 @@
+// Package alerting implements periodic evaluation of subscribers' rate alerts.
+package alerting
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+type Options struct {
+	Services service.Services
+	Logger   logging.Logger
+	// Interval is how often rates are polled and alerts are evaluated.
+	Interval time.Duration
+}
+
+// Evaluator calls AlertService.Evaluate every interval.
+type Evaluator struct {
+	services service.Services
+	logger   logging.Logger
+	interval time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New(opts *Options) (*Evaluator, error) {
+	if opts.Interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+
+	return &Evaluator{
+		services: opts.Services,
+		logger:   opts.Logger.Named("AlertEvaluator"),
+		interval: opts.Interval,
+	}, nil
+}
+
+// Start runs evaluator in background until Shutdown is called.
+func (e *Evaluator) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.loop(ctx)
+	}()
+}
+
+// Shutdown stops evaluator, cancels evaluation in progress and waits for it to return.
+func (e *Evaluator) Shutdown() {
+	if e.cancel != nil {
+		e.cancel()
+	}
+	e.wg.Wait()
+}
+
+func (e *Evaluator) loop(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		// evaluations run one after another, so that slow one delays the next instead of overlapping it
+		e.run(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Evaluator) run(ctx context.Context) {
+	logger := e.logger.Named("run")
+
+	err := e.services.Alert.Evaluate(ctx)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			logger.Info("evaluation canceled")
+			return
+		}
+		logger.Error("failed to evaluate alerts", "err", err)
+	}
+}
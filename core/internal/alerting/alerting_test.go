@@ -0,0 +1,73 @@
+package alerting
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+type testAlertService struct {
+	service.AlertService
+
+	calls chan struct{}
+}
+
+func (s *testAlertService) Evaluate(ctx context.Context) error {
+	select {
+	case s.calls <- struct{}{}:
+	default:
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestNew_InvalidInterval(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(&Options{
+		Logger: logging.NewZapLogger("debug"),
+	})
+	assert.Error(t, err)
+}
+
+func TestEvaluator_EvaluatesOnStartAndStopsOnShutdown(t *testing.T) {
+	t.Parallel()
+
+	alertService := &testAlertService{
+		calls: make(chan struct{}, 1),
+	}
+
+	e, err := New(&Options{
+		Services: service.Services{
+			Alert: alertService,
+		},
+		Logger:   logging.NewZapLogger("debug"),
+		Interval: time.Hour,
+	})
+	require.NoError(t, err)
+
+	e.Start()
+
+	select {
+	case <-alertService.calls:
+	case <-time.After(time.Second):
+		t.Fatal("alerts were not evaluated on start")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		e.Shutdown()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("shutdown didn't interrupt evaluation")
+	}
+}
@@ -0,0 +1,120 @@
+// Package captcha verifies solutions of hosted CAPTCHA widgets with siteverify API, which is shared by
+// hCaptcha, reCAPTCHA and Cloudflare Turnstile.
+package captcha
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/vadimpk/gses-2023/core/pkg/challenge"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+// VerifyURLs are siteverify endpoints of supported providers.
+var VerifyURLs = map[string]string{
+	"hcaptcha":  "https://api.hcaptcha.com/siteverify",
+	"recaptcha": "https://www.google.com/recaptcha/api/siteverify",
+	"turnstile": "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+}
+
+type captchaAPI struct {
+	client   *resty.Client
+	logger   logging.Logger
+	provider string
+	siteKey  string
+	secret   string
+}
+
+type Options struct {
+	Logger logging.Logger
+
+	// Provider is key of VerifyURLs.
+	Provider string
+	// VerifyURL overrides siteverify endpoint of Provider.
+	VerifyURL string
+	SiteKey   string
+	Secret    string
+	Timeout   time.Duration
+}
+
+func New(options *Options) (*captchaAPI, error) {
+	verifyURL := options.VerifyURL
+	if verifyURL == "" {
+		verifyURL = VerifyURLs[options.Provider]
+	}
+	if verifyURL == "" {
+		return nil, fmt.Errorf("unknown captcha provider %q", options.Provider)
+	}
+
+	return &captchaAPI{
+		client: resty.New().
+			SetBaseURL(verifyURL).
+			SetTimeout(options.Timeout),
+		logger:   options.Logger.Named("CaptchaAPI"),
+		provider: options.Provider,
+		siteKey:  options.SiteKey,
+		secret:   options.Secret,
+	}, nil
+}
+
+// Challenge returns parameters of widget, solution is produced by widget in browser.
+func (c *captchaAPI) Challenge(ctx context.Context) (*challenge.Challenge, error) {
+	return &challenge.Challenge{
+		Type:     challenge.TypeCaptcha,
+		Provider: c.provider,
+		SiteKey:  c.siteKey,
+	}, nil
+}
+
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+func (c *captchaAPI) Verify(ctx context.Context, solution, remoteIP string) error {
+	logger := c.logger.Named("Verify").
+		WithContext(ctx).
+		With("remoteIP", remoteIP)
+
+	if solution == "" {
+		return challenge.ErrMissingSolution
+	}
+
+	form := map[string]string{
+		"secret":   c.secret,
+		"response": solution,
+	}
+	if remoteIP != "" {
+		form["remoteip"] = remoteIP
+	}
+
+	var respBody siteVerifyResponse
+	res, err := c.client.R().
+		SetContext(ctx).
+		SetFormData(form).
+		SetResult(&respBody).
+		Post("")
+	if err != nil {
+		logger.Error("failed to verify captcha", "err", err)
+		return fmt.Errorf("failed to verify captcha: %w", err)
+	}
+	logger = logger.With("status", res.Status()).
+		With("respBody", respBody)
+
+	if res.StatusCode() != http.StatusOK {
+		logger.Error("failed to verify captcha")
+		return fmt.Errorf("failed to verify captcha: unexpected status %s", res.Status())
+	}
+
+	if !respBody.Success {
+		logger.Info("captcha is not solved")
+		return fmt.Errorf("%w: %s", challenge.ErrInvalidSolution, strings.Join(respBody.ErrorCodes, ", "))
+	}
+
+	logger.Info("captcha is solved")
+	return nil
+}
@@ -0,0 +1,58 @@
+package captcha
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/pkg/challenge"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+func TestCaptchaAPI_Verify(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.PostFormValue("secret"))
+		assert.Equal(t, "127.0.0.1", r.PostFormValue("remoteip"))
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.PostFormValue("response") {
+		case "valid":
+			_, _ = w.Write([]byte(`{"success": true}`))
+		case "broken":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			_, _ = w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	api, err := New(&Options{
+		Logger:    logging.NewZapLogger("debug"),
+		Provider:  "hcaptcha",
+		VerifyURL: server.URL,
+		SiteKey:   "site-key",
+		Secret:    "secret",
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	c, err := api.Challenge(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &challenge.Challenge{Type: challenge.TypeCaptcha, Provider: "hcaptcha", SiteKey: "site-key"}, c)
+
+	assert.NoError(t, api.Verify(ctx, "valid", "127.0.0.1"))
+	assert.ErrorIs(t, api.Verify(ctx, "invalid", "127.0.0.1"), challenge.ErrInvalidSolution)
+	assert.ErrorIs(t, api.Verify(ctx, "", "127.0.0.1"), challenge.ErrMissingSolution)
+
+	err = api.Verify(ctx, "broken", "127.0.0.1")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, challenge.ErrInvalidSolution)
+
+	_, err = New(&Options{Logger: logging.NewZapLogger("debug"), Provider: "unknown"})
+	assert.Error(t, err)
+}
@@ -2,8 +2,47 @@ package crypto
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// rateResponseBody is response of "/api/v2/rate" of crypto service. Older routes and versions of service
+// respond with naked number, e.g. 1234.5, which is decoded into Rate.
+type rateResponseBody struct {
+	Pair      string    `json:"pair"`
+	Rate      string    `json:"rate"`
+	Provider  string    `json:"provider"`
+	FetchedAt time.Time `json:"fetched_at"`
+	CacheAge  int64     `json:"cache_age"`
+	Stale     bool      `json:"stale"`
+}
+
+func (b *rateResponseBody) UnmarshalJSON(data []byte) error {
+	var legacy float64
+	if err := json.Unmarshal(data, &legacy); err == nil {
+		*b = rateResponseBody{Rate: strconv.FormatFloat(legacy, 'f', -1, 64)}
+		return nil
+	}
+
+	// alias type doesn't have UnmarshalJSON method, so that it's decoded as regular struct
+	type body rateResponseBody
+	if err := json.Unmarshal(data, (*body)(b)); err != nil {
+		return fmt.Errorf("failed to decode rate response: %w", err)
+	}
+
+	return nil
+}
+
+const (
+	ratePath = "/api/v2/rate"
+	// legacyRatePath is served by every version of crypto service, including older ones that don't have
+	// versioned routes, and responds with naked number.
+	legacyRatePath = "/api/rate"
 )
 
 func (c *cryptoAPI) GetRate(ctx context.Context, fromCurrency, toCurrency string) (float64, error) {
@@ -12,27 +51,46 @@ func (c *cryptoAPI) GetRate(ctx context.Context, fromCurrency, toCurrency string
 		With("fromCurrency", fromCurrency).
 		With("toCurrency", toCurrency)
 
-	var respBody float64
-	res, err := c.client.R().
-		SetQueryParams(map[string]string{
-			"crypto_currency": fromCurrency,
-			"fiat_currency":   toCurrency,
-		}).
-		SetResult(&respBody).
-		Get("/api/rate")
-	logger = logger.With("response", res.String()).
-		With("status", res.Status()).
-		With("respBody", respBody)
-
+	respBody, res, err := c.getRate(ctx, ratePath, fromCurrency, toCurrency)
+	if err == nil && res.StatusCode() == http.StatusNotFound {
+		logger.Warn("crypto service doesn't serve v2 rate route, falling back to legacy one")
+		respBody, res, err = c.getRate(ctx, legacyRatePath, fromCurrency, toCurrency)
+	}
 	if err != nil {
 		logger.Error("failed to get rate", "err", err)
 		return 0, fmt.Errorf("failed to get rate: %w", err)
 	}
+	logger = logger.With("response", res.String()).
+		With("status", res.Status()).
+		With("respBody", respBody)
 
 	if res.StatusCode() != http.StatusOK {
-		logger.Error("failed to get rate", "err", err)
-		return 0, fmt.Errorf("failed to get rate: %w", err)
+		logger.Error("failed to get rate")
+		return 0, fmt.Errorf("failed to get rate: unexpected status %s", res.Status())
 	}
 
-	return respBody, err
+	rate, err := strconv.ParseFloat(respBody.Rate, 64)
+	if err != nil {
+		logger.Error("failed to parse rate", "err", err)
+		return 0, fmt.Errorf("failed to parse rate: %w", err)
+	}
+	if respBody.Stale {
+		logger.Warn("crypto service returned stale rate")
+	}
+
+	logger.Info("successfully got rate")
+	return rate, nil
+}
+
+func (c *cryptoAPI) getRate(ctx context.Context, path, fromCurrency, toCurrency string) (*rateResponseBody, *resty.Response, error) {
+	var respBody rateResponseBody
+	res, err := c.client.R().
+		SetContext(ctx).
+		SetQueryParams(map[string]string{
+			"crypto_currency": fromCurrency,
+			"fiat_currency":   toCurrency,
+		}).
+		SetResult(&respBody).
+		Get(path)
+	return &respBody, res, err
 }
@@ -0,0 +1,111 @@
+package crypto
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/config"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+func TestCryptoAPI_GetRate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		status       int
+		body         string
+		legacy       bool
+		expectedRate float64
+		expectedErr  bool
+	}{
+		{
+			name:         "positive: structured response",
+			status:       http.StatusOK,
+			body:         `{"pair": "BTC-UAH", "rate": "1234567.891", "provider": "coinbase", "fetched_at": "2023-07-01T12:00:00Z", "cache_age": 12, "stale": false}`,
+			expectedRate: 1234567.891,
+		},
+		{
+			name:         "positive: stale structured response",
+			status:       http.StatusOK,
+			body:         `{"pair": "BTC-UAH", "rate": "1000", "provider": "coinapi", "fetched_at": "2023-07-01T12:00:00Z", "cache_age": 600, "stale": true}`,
+			expectedRate: 1000,
+		},
+		{
+			name:         "positive: legacy response",
+			status:       http.StatusOK,
+			body:         `1234567.891`,
+			expectedRate: 1234567.891,
+		},
+		{
+			name:         "positive: legacy service",
+			status:       http.StatusOK,
+			body:         `1234567.891`,
+			legacy:       true,
+			expectedRate: 1234567.891,
+		},
+		{
+			name:        "negative: legacy service error status",
+			status:      http.StatusBadRequest,
+			body:        `{"message": "invalid currency"}`,
+			legacy:      true,
+			expectedErr: true,
+		},
+		{
+			name:        "negative: invalid rate",
+			status:      http.StatusOK,
+			body:        `{"pair": "BTC-UAH", "rate": "many"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "negative: error status",
+			status:      http.StatusInternalServerError,
+			body:        `{"message": "failed to get rate", "code": 500}`,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path := "/api/v2/rate"
+				if tc.legacy {
+					path = "/api/rate"
+				}
+				if r.URL.Path != path {
+					http.NotFound(w, r)
+					return
+				}
+				assert.Equal(t, "BTC", r.URL.Query().Get("crypto_currency"))
+				assert.Equal(t, "UAH", r.URL.Query().Get("fiat_currency"))
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			t.Cleanup(server.Close)
+
+			cfg := &config.Config{}
+			cfg.CryptoService.BaseURL = server.URL
+			api := New(&Options{
+				Logger: logging.NewZapLogger("debug"),
+				Config: cfg,
+			})
+
+			rate, err := api.GetRate(context.Background(), "BTC", "UAH")
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedRate, rate)
+		})
+	}
+}
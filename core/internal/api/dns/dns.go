@@ -0,0 +1,89 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+// Resolver looks up DNS records, it's implemented by *net.Resolver.
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+type domainAPI struct {
+	logger   logging.Logger
+	resolver Resolver
+	timeout  time.Duration
+}
+
+type Options struct {
+	Logger logging.Logger
+	// Resolver defaults to net.DefaultResolver if nil.
+	Resolver Resolver
+	// Timeout limits time of checking single domain, there is no limit if it's zero.
+	Timeout time.Duration
+}
+
+func New(options *Options) *domainAPI {
+	resolver := options.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	return &domainAPI{
+		logger:   options.Logger.Named("DomainAPI"),
+		resolver: resolver,
+		timeout:  options.Timeout,
+	}
+}
+
+// AcceptsMail reports whether domain has MX records. Domain without MX records accepts mail if it has
+// address records (implicit MX of RFC 5321), and domain with null MX of RFC 7505 doesn't accept mail.
+func (d *domainAPI) AcceptsMail(ctx context.Context, domain string) (bool, error) {
+	logger := d.logger.Named("AcceptsMail").
+		WithContext(ctx).
+		With("domain", domain)
+
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	records, err := d.resolver.LookupMX(ctx, domain)
+	if err != nil && !isNotFound(err) {
+		logger.Error("failed to look up mx records", "err", err)
+		return false, fmt.Errorf("failed to look up mx records: %w", err)
+	}
+	if len(records) > 0 {
+		if len(records) == 1 && (records[0].Host == "." || records[0].Host == "") {
+			logger.Info("domain has null mx record")
+			return false, nil
+		}
+		return true, nil
+	}
+
+	hosts, err := d.resolver.LookupHost(ctx, domain)
+	if err != nil && !isNotFound(err) {
+		logger.Error("failed to look up address records", "err", err)
+		return false, fmt.Errorf("failed to look up address records: %w", err)
+	}
+	if len(hosts) == 0 {
+		logger.Info("domain has neither mx nor address records")
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// isNotFound reports whether err means that domain or its records don't exist.
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,90 @@
+package dns_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vadimpk/gses-2023/core/internal/api/dns"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+// fakeResolver returns records of domains it knows, other domains don't exist.
+type fakeResolver struct {
+	mx    map[string][]*net.MX
+	hosts map[string][]string
+	err   error
+}
+
+func (r *fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if records, ok := r.mx[name]; ok {
+		return records, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if records, ok := r.hosts[host]; ok {
+		return records, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestDomainAPI_AcceptsMail(t *testing.T) {
+	t.Parallel()
+
+	resolver := &fakeResolver{
+		mx: map[string][]*net.MX{
+			"mail.com":   {{Host: "mx.mail.com.", Pref: 10}},
+			"nomail.com": {{Host: ".", Pref: 0}},
+		},
+		hosts: map[string][]string{
+			"implicit.com": {"192.0.2.1"},
+		},
+	}
+	api := dns.New(&dns.Options{
+		Logger:   logging.NewZapLogger("debug"),
+		Resolver: resolver,
+	})
+
+	testCases := []struct {
+		domain   string
+		expected bool
+	}{
+		{domain: "mail.com", expected: true},
+		{domain: "implicit.com", expected: true},
+		{domain: "nomail.com", expected: false},
+		{domain: "missing.com", expected: false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.domain, func(t *testing.T) {
+			t.Parallel()
+
+			accepts, err := api.AcceptsMail(context.Background(), tc.domain)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, accepts)
+		})
+	}
+}
+
+func TestDomainAPI_AcceptsMail_ResolverError(t *testing.T) {
+	t.Parallel()
+
+	testErr := &net.DNSError{Err: "server misbehaving", Name: "mail.com", IsTemporary: true}
+	api := dns.New(&dns.Options{
+		Logger:   logging.NewZapLogger("debug"),
+		Resolver: &fakeResolver{err: testErr},
+	})
+
+	_, err := api.AcceptsMail(context.Background(), "mail.com")
+	assert.ErrorIs(t, err, testErr)
+}
@@ -3,7 +3,9 @@ package mailgun
 import (
 	"context"
 	"fmt"
+	"net/http"
 
+	"github.com/mailgun/mailgun-go/v4"
 	"github.com/vadimpk/gses-2023/core/internal/service"
 )
 
@@ -12,9 +14,21 @@ func (m *mailgunAPI) Send(ctx context.Context, opts *service.SendOptions) error
 		WithContext(ctx).
 		With("opts", opts)
 
-	_, id, err := m.client.Send(ctx, m.client.NewMessage(m.from, opts.Subject, opts.Body, opts.To))
+	message := m.client.NewMessage(m.from, opts.Subject, opts.Body, opts.To)
+	if opts.HTML != "" {
+		message.SetHtml(opts.HTML)
+	}
+
+	_, id, err := m.client.Send(ctx, message)
 	if err != nil {
 		logger.Error("failed to send email", "err", err)
+		status := mailgun.GetStatusFromErr(err)
+		if status == http.StatusUnauthorized || status == http.StatusForbidden {
+			return fmt.Errorf("%w: %v", service.ErrEmailUnauthorized, err)
+		}
+		if isPermanentStatus(status) {
+			return fmt.Errorf("%w: %v", service.ErrEmailRejected, err)
+		}
 		return fmt.Errorf("failed to send email: %w", err)
 	}
 	logger = logger.With("id", id)
@@ -22,3 +36,11 @@ func (m *mailgunAPI) Send(ctx context.Context, opts *service.SendOptions) error
 	logger.Info("successfully sent email")
 	return err
 }
+
+// isPermanentStatus checks if mailgun rejected request in a way that won't change on retry.
+// Rate limiting and timeouts are 4xx too, but they are transient. Rejected credentials (401 and 403)
+// are checked before it.
+func isPermanentStatus(status int) bool {
+	return status >= http.StatusBadRequest && status < http.StatusInternalServerError &&
+		status != http.StatusTooManyRequests && status != http.StatusRequestTimeout
+}
@@ -0,0 +1,103 @@
+// Package retry implements retrying of failed email sends with exponential backoff.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+type emailAPI struct {
+	api    service.EmailAPI
+	logger logging.Logger
+
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+type Options struct {
+	Logger logging.Logger
+
+	// MaxAttempts is max number of attempts to send email including the first one.
+	MaxAttempts int
+	// BaseDelay is a delay before the first retry, it doubles with each next retry.
+	BaseDelay time.Duration
+	// MaxDelay limits delay between retries.
+	MaxDelay time.Duration
+}
+
+// NewEmailAPI wraps api, so that transient errors are retried. Errors wrapping
+// service.ErrEmailRejected or service.ErrEmailUnauthorized are permanent and are returned right away.
+func NewEmailAPI(api service.EmailAPI, opts *Options) *emailAPI {
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &emailAPI{
+		api:         api,
+		logger:      opts.Logger.Named("RetryEmailAPI"),
+		maxAttempts: maxAttempts,
+		baseDelay:   opts.BaseDelay,
+		maxDelay:    opts.MaxDelay,
+	}
+}
+
+func (r *emailAPI) Send(ctx context.Context, opts *service.SendOptions) error {
+	logger := r.logger.Named("Send").
+		WithContext(ctx).
+		With("to", opts.To)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = r.api.Send(ctx, opts)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, service.ErrEmailRejected) {
+			logger.Info("email rejected, not retrying", "err", err)
+			return err
+		}
+		if errors.Is(err, service.ErrEmailUnauthorized) {
+			logger.Error("email api unauthorized, not retrying", "err", err)
+			return err
+		}
+		if attempt >= r.maxAttempts {
+			break
+		}
+
+		delay := r.backoff(attempt)
+		logger.Info("failed to send email, retrying", "err", err, "attempt", attempt, "delay", delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %v", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+
+	logger.Error("failed to send email after all attempts", "err", err, "attempts", r.maxAttempts)
+	return fmt.Errorf("failed after %d attempts: %w", r.maxAttempts, err)
+}
+
+// backoff returns delay before retry that follows given attempt. It uses "full jitter":
+// random delay up to exponentially growing limit, so that retries of many emails are spread in time.
+func (r *emailAPI) backoff(attempt int) time.Duration {
+	limit := r.baseDelay << (attempt - 1)
+	if limit <= 0 || (r.maxDelay > 0 && limit > r.maxDelay) {
+		limit = r.maxDelay
+	}
+	if limit <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(limit) + 1))
+}
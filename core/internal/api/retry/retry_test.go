@@ -0,0 +1,115 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vadimpk/gses-2023/core/internal/api/retry"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/core/internal/service/mocks"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+func TestEmailAPI_Send(t *testing.T) {
+	t.Parallel()
+
+	testOptions := &service.SendOptions{
+		To:      "email@test.com",
+		Subject: "subject",
+		Body:    "body",
+	}
+	testErr := errors.New("some err")
+	testRejectedErr := fmt.Errorf("%w: bad address", service.ErrEmailRejected)
+	testUnauthorizedErr := fmt.Errorf("%w: bad password", service.ErrEmailUnauthorized)
+
+	testCases := []struct {
+		name        string
+		mock        func(m *mocks.EmailAPI)
+		expectedErr error
+	}{
+		{
+			name: "positive: sent on first attempt",
+			mock: func(m *mocks.EmailAPI) {
+				m.On("Send", mock.Anything, testOptions).Return(nil).Once()
+			},
+		},
+		{
+			name: "positive: sent after transient errors",
+			mock: func(m *mocks.EmailAPI) {
+				m.On("Send", mock.Anything, testOptions).Return(testErr).Twice()
+				m.On("Send", mock.Anything, testOptions).Return(nil).Once()
+			},
+		},
+		{
+			name: "negative: failed after all attempts",
+			mock: func(m *mocks.EmailAPI) {
+				m.On("Send", mock.Anything, testOptions).Return(testErr).Times(3)
+			},
+			expectedErr: testErr,
+		},
+		{
+			name: "negative: rejected email is not retried",
+			mock: func(m *mocks.EmailAPI) {
+				m.On("Send", mock.Anything, testOptions).Return(testRejectedErr).Once()
+			},
+			expectedErr: service.ErrEmailRejected,
+		},
+		{
+			name: "negative: unauthorized api is not retried",
+			mock: func(m *mocks.EmailAPI) {
+				m.On("Send", mock.Anything, testOptions).Return(testUnauthorizedErr).Once()
+			},
+			expectedErr: service.ErrEmailUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			emailAPI := mocks.NewEmailAPI(t)
+			tc.mock(emailAPI)
+
+			api := retry.NewEmailAPI(emailAPI, &retry.Options{
+				Logger:      logging.NewZapLogger("debug"),
+				MaxAttempts: 3,
+				BaseDelay:   time.Millisecond,
+				MaxDelay:    time.Millisecond * 5,
+			})
+
+			err := api.Send(context.Background(), testOptions)
+			if tc.expectedErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestEmailAPI_Send_Canceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	emailAPI := mocks.NewEmailAPI(t)
+	emailAPI.On("Send", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		cancel()
+	}).Return(errors.New("some err")).Once()
+
+	api := retry.NewEmailAPI(emailAPI, &retry.Options{
+		Logger:      logging.NewZapLogger("debug"),
+		MaxAttempts: 3,
+		BaseDelay:   time.Hour,
+		MaxDelay:    time.Hour,
+	})
+
+	err := api.Send(ctx, &service.SendOptions{To: "email@test.com"})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,54 @@
+package smtp
+
+import (
+	"errors"
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+// loginAuth implements LOGIN mechanism, which is not provided by net/smtp but is still
+// the only one supported by some relays.
+type loginAuth struct {
+	username string
+	password string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch prompt := strings.ToLower(strings.TrimSpace(string(fromServer))); {
+	case strings.HasPrefix(prompt, "username"):
+		return []byte(a.username), nil
+	case strings.HasPrefix(prompt, "password"):
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge: %s", fromServer)
+	}
+}
+
+func (s *smtpAPI) newAuth() smtp.Auth {
+	switch s.auth {
+	case AuthPlain:
+		return smtp.PlainAuth("", s.username, s.password, s.host)
+	case AuthLogin:
+		return &loginAuth{username: s.username, password: s.password}
+	default:
+		return nil
+	}
+}
+
+// isLocalhost checks if credentials can be sent to server without encryption,
+// the same way net/smtp does for PLAIN mechanism.
+func isLocalhost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
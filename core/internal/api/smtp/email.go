@@ -0,0 +1,274 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/internal/service"
+)
+
+func (s *smtpAPI) Send(ctx context.Context, opts *service.SendOptions) error {
+	logger := s.logger.Named("Send").
+		WithContext(ctx).
+		With("opts", opts)
+
+	from, err := mail.ParseAddress(s.from)
+	if err != nil {
+		logger.Error("failed to parse from address", "err", err)
+		return fmt.Errorf("failed to parse from address: %w", err)
+	}
+
+	to, err := mail.ParseAddress(opts.To)
+	if err != nil {
+		logger.Info("failed to parse recipient address", "err", err)
+		return fmt.Errorf("%w: %v", service.ErrEmailRejected, err)
+	}
+
+	message, err := buildMessage(from, to, opts)
+	if err != nil {
+		logger.Error("failed to build message", "err", err)
+		return fmt.Errorf("failed to build message: %w", err)
+	}
+
+	err = s.send(ctx, from.Address, to.Address, message)
+	if err != nil {
+		logger.Error("failed to send email", "err", err)
+		if isAuthError(err) {
+			return fmt.Errorf("%w: %v", service.ErrEmailUnauthorized, err)
+		}
+		if isPermanentError(err) {
+			return fmt.Errorf("%w: %v", service.ErrEmailRejected, err)
+		}
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	logger.Info("successfully sent email")
+	return nil
+}
+
+// send delivers message in a single SMTP session. Session is aborted when ctx is done.
+func (s *smtpAPI) send(ctx context.Context, from, to string, message []byte) error {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	// net/smtp isn't context aware, so closing connection is the only way to interrupt it
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	err = s.session(conn, from, to, message)
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("%w: %v", ctx.Err(), err)
+	}
+	return err
+}
+
+func (s *smtpAPI) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	if s.encryption == EncryptionTLS {
+		tlsDialer := &tls.Dialer{
+			NetDialer: dialer,
+			Config:    s.tlsConfig(),
+		}
+		return tlsDialer.DialContext(ctx, "tcp", s.addr)
+	}
+	return dialer.DialContext(ctx, "tcp", s.addr)
+}
+
+func (s *smtpAPI) session(conn net.Conn, from, to string, message []byte) error {
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer client.Close()
+
+	if s.encryption == EncryptionSTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("server doesn't support STARTTLS")
+		}
+		if err = client.StartTLS(s.tlsConfig()); err != nil {
+			return fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+
+	if auth := s.newAuth(); auth != nil {
+		if err = client.Auth(auth); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err = client.Mail(from); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	if err = client.Rcpt(to); err != nil {
+		return fmt.Errorf("failed to set recipient: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start data: %w", err)
+	}
+	if _, err = w.Write(message); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("failed to finish data: %w", err)
+	}
+
+	return client.Quit()
+}
+
+func (s *smtpAPI) tlsConfig() *tls.Config {
+	return &tls.Config{
+		ServerName: s.host,
+		MinVersion: tls.VersionTLS12,
+	}
+}
+
+// buildMessage formats email according to RFC 5322 with quoted-printable UTF-8 parts. Emails with
+// HTML part are sent as multipart/alternative with plain text part first.
+func buildMessage(from, to *mail.Address, opts *service.SendOptions) ([]byte, error) {
+	messageID, err := newMessageID(from.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	contentType := plainTextContentType
+	if opts.HTML == "" {
+		err = writeQuotedPrintable(&body, opts.Body)
+	} else {
+		contentType, err = writeAlternative(&body, opts.Body, opts.HTML)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode body: %w", err)
+	}
+
+	var buf bytes.Buffer
+	headers := [][2]string{
+		{"From", from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", opts.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", messageID},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", contentType},
+	}
+	if opts.HTML == "" {
+		headers = append(headers, [2]string{"Content-Transfer-Encoding", "quoted-printable"})
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", header[0], header[1])
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+const (
+	plainTextContentType = "text/plain; charset=UTF-8"
+	htmlContentType      = "text/html; charset=UTF-8"
+)
+
+// writeAlternative writes text and html as parts of multipart/alternative body and returns its content type.
+func writeAlternative(w io.Writer, text, html string) (string, error) {
+	mw := multipart.NewWriter(w)
+	for _, part := range [][2]string{{plainTextContentType, text}, {htmlContentType, html}} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part[0]},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return "", err
+		}
+		if err = writeQuotedPrintable(pw, part[1]); err != nil {
+			return "", err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+
+	return "multipart/alternative; boundary=" + mw.Boundary(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qw := quotedprintable.NewWriter(w)
+	if _, err := qw.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+func newMessageID(from string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate message id: %w", err)
+	}
+
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = from[i+1:]
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain), nil
+}
+
+// authErrorCodes are replies about authentication (RFC 4954), they don't depend on recipient.
+var authErrorCodes = map[int]struct{}{
+	530: {}, // authentication required
+	534: {}, // authentication mechanism is too weak
+	535: {}, // authentication credentials invalid
+	538: {}, // encryption required for requested authentication mechanism
+}
+
+// isAuthError checks if server rejected credentials or requires authentication.
+func isAuthError(err error) bool {
+	var protoErr *textproto.Error
+	if !errors.As(err, &protoErr) {
+		return false
+	}
+	_, ok := authErrorCodes[protoErr.Code]
+	return ok
+}
+
+// isPermanentError checks if server replied with 5xx code, which means that retrying won't help.
+// Replies about authentication are checked before it.
+func isPermanentError(err error) bool {
+	var protoErr *textproto.Error
+	return errors.As(err, &protoErr) && protoErr.Code >= 500
+}
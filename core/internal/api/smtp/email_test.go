@@ -0,0 +1,256 @@
+package smtp_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/internal/api/smtp"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+// fakeServer is minimal SMTP server that accepts single email per connection.
+type fakeServer struct {
+	listener net.Listener
+	// rcptReply is reply to RCPT command.
+	rcptReply string
+
+	mu       sync.Mutex
+	auth     []string
+	from     string
+	to       string
+	messages []string
+}
+
+func newFakeServer(t *testing.T, rcptReply string) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &fakeServer{
+		listener:  listener,
+		rcptReply: rcptReply,
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+	read := func() string {
+		line, _ := r.ReadString('\n')
+		return strings.TrimRight(line, "\r\n")
+	}
+	decode := func(s string) string {
+		b, _ := base64.StdEncoding.DecodeString(s)
+		return string(b)
+	}
+
+	reply("220 fake ESMTP")
+	for {
+		line := read()
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case command == "EHLO":
+			reply("250-fake")
+			reply("250 AUTH PLAIN LOGIN")
+		case line == "AUTH LOGIN":
+			reply("334 " + base64.StdEncoding.EncodeToString([]byte("Username:")))
+			username := decode(read())
+			reply("334 " + base64.StdEncoding.EncodeToString([]byte("Password:")))
+			password := decode(read())
+			s.record(func() { s.auth = append(s.auth, "LOGIN", username, password) })
+			reply("235 ok")
+		case strings.HasPrefix(line, "AUTH PLAIN "):
+			parts := strings.Split(decode(strings.TrimPrefix(line, "AUTH PLAIN ")), "\x00")
+			s.record(func() { s.auth = append(s.auth, "PLAIN", parts[1], parts[2]) })
+			if parts[2] != "pass" {
+				reply("535 5.7.8 authentication credentials invalid")
+				continue
+			}
+			reply("235 ok")
+		case command == "MAIL":
+			s.record(func() { s.from = line })
+			reply("250 ok")
+		case command == "RCPT":
+			s.record(func() { s.to = line })
+			reply(s.rcptReply)
+		case command == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for l := read(); l != "."; l = read() {
+				data.WriteString(l + "\r\n")
+			}
+			s.record(func() { s.messages = append(s.messages, data.String()) })
+			reply("250 queued")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		case command == "":
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func (s *fakeServer) record(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f()
+}
+
+func TestSMTPAPI_Send(t *testing.T) {
+	t.Parallel()
+
+	testOptions := &service.SendOptions{
+		To:      "email@test.com",
+		Subject: "Rate info",
+		Body:    "Current rates:\nBTC-USD: 100.000000\n",
+	}
+
+	testCases := []struct {
+		name         string
+		auth         smtp.AuthMechanism
+		password     string
+		html         string
+		rcptReply    string
+		expectedAuth []string
+		expectedErr  error
+	}{
+		{
+			name:      "positive: send without auth",
+			auth:      smtp.AuthNone,
+			rcptReply: "250 ok",
+		},
+		{
+			name:         "positive: send with PLAIN auth",
+			auth:         smtp.AuthPlain,
+			rcptReply:    "250 ok",
+			expectedAuth: []string{"PLAIN", "user", "pass"},
+		},
+		{
+			name:         "positive: send with LOGIN auth",
+			auth:         smtp.AuthLogin,
+			rcptReply:    "250 ok",
+			expectedAuth: []string{"LOGIN", "user", "pass"},
+		},
+		{
+			name:      "positive: send with HTML part",
+			auth:      smtp.AuthNone,
+			html:      "<p>BTC-USD: $100.00</p>",
+			rcptReply: "250 ok",
+		},
+		{
+			name:        "negative: recipient rejected",
+			auth:        smtp.AuthNone,
+			rcptReply:   "550 no such user",
+			expectedErr: service.ErrEmailRejected,
+		},
+		{
+			name:        "negative: credentials rejected",
+			auth:        smtp.AuthPlain,
+			password:    "wrong",
+			rcptReply:   "250 ok",
+			expectedErr: service.ErrEmailUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := newFakeServer(t, tc.rcptReply)
+			password := "pass"
+			if tc.password != "" {
+				password = tc.password
+			}
+			api, err := smtp.New(&smtp.Options{
+				Logger:   logging.NewZapLogger("debug"),
+				Host:     "127.0.0.1",
+				Port:     server.port(),
+				Username: "user",
+				Password: password,
+				From:     "GSES <gses@test.com>",
+				Auth:     tc.auth,
+				Timeout:  time.Second * 5,
+			})
+			require.NoError(t, err)
+
+			options := *testOptions
+			options.HTML = tc.html
+
+			err = api.Send(context.Background(), &options)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				// credentials are checked before recipient, so it's not rejected
+				if tc.expectedErr == service.ErrEmailUnauthorized {
+					assert.NotErrorIs(t, err, service.ErrEmailRejected)
+				}
+				return
+			}
+			require.NoError(t, err)
+
+			server.mu.Lock()
+			defer server.mu.Unlock()
+			assert.Equal(t, tc.expectedAuth, server.auth)
+			assert.Equal(t, "MAIL FROM:<gses@test.com>", server.from)
+			assert.Equal(t, "RCPT TO:<email@test.com>", server.to)
+			require.Len(t, server.messages, 1)
+			assert.Contains(t, server.messages[0], "Subject: Rate info\r\n")
+			assert.Contains(t, server.messages[0], "To: <email@test.com>\r\n")
+			assert.Contains(t, server.messages[0], "BTC-USD: 100.000000\r\n")
+			if tc.html != "" {
+				assert.Contains(t, server.messages[0], "Content-Type: multipart/alternative; boundary=")
+				assert.Contains(t, server.messages[0], "Content-Type: text/html; charset=UTF-8\r\n")
+				assert.Contains(t, server.messages[0], "<p>BTC-USD: $100.00</p>")
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	_, err := smtp.New(&smtp.Options{
+		Logger:     logging.NewZapLogger("debug"),
+		Host:       "localhost",
+		Port:       25,
+		Encryption: "ssl",
+	})
+	assert.Error(t, err)
+
+	_, err = smtp.New(&smtp.Options{
+		Logger: logging.NewZapLogger("debug"),
+		Host:   "localhost",
+		Port:   25,
+		Auth:   "cram-md5",
+	})
+	assert.Error(t, err)
+}
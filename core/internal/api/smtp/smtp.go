@@ -0,0 +1,92 @@
+package smtp
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+// Encryption is a way connection to SMTP server is secured.
+type Encryption string
+
+const (
+	// EncryptionNone sends emails over plain connection, it's meant for local catchers like MailHog.
+	EncryptionNone Encryption = "none"
+	// EncryptionSTARTTLS upgrades plain connection to TLS before authenticating, usually on port 587.
+	EncryptionSTARTTLS Encryption = "starttls"
+	// EncryptionTLS uses implicit TLS from the very start of connection, usually on port 465.
+	EncryptionTLS Encryption = "tls"
+)
+
+// AuthMechanism is SASL mechanism used to authenticate at SMTP server.
+type AuthMechanism string
+
+const (
+	AuthNone  AuthMechanism = "none"
+	AuthPlain AuthMechanism = "plain"
+	AuthLogin AuthMechanism = "login"
+)
+
+type smtpAPI struct {
+	logger logging.Logger
+
+	addr       string
+	host       string
+	username   string
+	password   string
+	from       string
+	auth       AuthMechanism
+	encryption Encryption
+	timeout    time.Duration
+}
+
+type Options struct {
+	Logger logging.Logger
+
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	// Auth is mechanism used to authenticate, no authentication is done if it's empty or AuthNone.
+	Auth AuthMechanism
+	// Encryption defaults to EncryptionNone if empty.
+	Encryption Encryption
+	// Timeout limits time of sending single email including connecting to server.
+	Timeout time.Duration
+}
+
+func New(options *Options) (*smtpAPI, error) {
+	auth := options.Auth
+	switch auth {
+	case "":
+		auth = AuthNone
+	case AuthNone, AuthPlain, AuthLogin:
+	default:
+		return nil, fmt.Errorf("unknown auth mechanism: %s", auth)
+	}
+
+	encryption := options.Encryption
+	switch encryption {
+	case "":
+		encryption = EncryptionNone
+	case EncryptionNone, EncryptionSTARTTLS, EncryptionTLS:
+	default:
+		return nil, fmt.Errorf("unknown encryption: %s", encryption)
+	}
+
+	return &smtpAPI{
+		logger:     options.Logger.Named("SMTPAPI"),
+		addr:       net.JoinHostPort(options.Host, strconv.Itoa(options.Port)),
+		host:       options.Host,
+		username:   options.Username,
+		password:   options.Password,
+		from:       options.From,
+		auth:       auth,
+		encryption: encryption,
+		timeout:    options.Timeout,
+	}, nil
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
 	"os/signal"
@@ -10,17 +11,39 @@ import (
 
 	"github.com/streadway/amqp"
 	"github.com/vadimpk/gses-2023/core/config"
+	"github.com/vadimpk/gses-2023/core/internal/alerting"
+	"github.com/vadimpk/gses-2023/core/internal/api/captcha"
 	"github.com/vadimpk/gses-2023/core/internal/api/crypto"
+	"github.com/vadimpk/gses-2023/core/internal/api/dns"
 	"github.com/vadimpk/gses-2023/core/internal/api/mailgun"
+	"github.com/vadimpk/gses-2023/core/internal/api/retry"
+	"github.com/vadimpk/gses-2023/core/internal/api/smtp"
 	"github.com/vadimpk/gses-2023/core/internal/controller"
+	"github.com/vadimpk/gses-2023/core/internal/scheduler"
 	"github.com/vadimpk/gses-2023/core/internal/service"
 	"github.com/vadimpk/gses-2023/core/internal/storage/localstorage"
+	"github.com/vadimpk/gses-2023/core/internal/storage/sqlstorage"
+	"github.com/vadimpk/gses-2023/core/pkg/challenge"
 	"github.com/vadimpk/gses-2023/core/pkg/database"
+	"github.com/vadimpk/gses-2023/core/pkg/ratelimit"
+	"github.com/vadimpk/gses-2023/pkg/apiversion"
 	"github.com/vadimpk/gses-2023/pkg/httpserver"
 	"github.com/vadimpk/gses-2023/pkg/logging"
 )
 
 func Run(cfg *config.Config) {
+	if err := validateSecret(cfg.Token.Secret); err != nil {
+		log.Fatal("invalid GSES_TOKEN_SECRET", "err", err)
+	}
+	if cfg.Challenge.Type == challenge.TypeProofOfWork {
+		if err := validateSecret(cfg.Challenge.Secret); err != nil {
+			log.Fatal("invalid GSES_CHALLENGE_SECRET", "err", err)
+		}
+		if cfg.Challenge.Secret == cfg.Token.Secret {
+			log.Fatal("invalid GSES_CHALLENGE_SECRET: secret is the same as GSES_TOKEN_SECRET")
+		}
+	}
+
 	rabbitmqConn, err := amqp.Dial(cfg.RabbitMQ.URL)
 	if err != nil {
 		log.Fatal("failed to init rabbitmq connection", "err", err)
@@ -43,14 +66,113 @@ func Run(cfg *config.Config) {
 		log.Fatal("failed to init rabbitmq logger", "err", err)
 	}
 
-	fileStorage := database.NewFileDB(cfg.FileStorage.BaseDirectory)
+	fileStorage := database.NewFileDB(cfg.FileStorage.BaseDirectory,
+		database.SyncWrites(cfg.FileStorage.SyncWrites),
+	)
 	err = fileStorage.Ping(context.TODO())
 	if err != nil {
 		log.Fatal("failed to init file storage", "err", err)
 	}
 
+	sendJobStorage := localstorage.NewSendJobStorage(fileStorage, "send_jobs.txt")
+	deadLetterStorage := localstorage.NewDeadLetterStorage(fileStorage, "dead_letters.txt")
+	rateStorage := localstorage.NewRateStorage(fileStorage, "rates.txt")
+	alertStorage := localstorage.NewAlertStorage(fileStorage, "alerts.txt")
+	fileStorages := map[string]fileCompacter{
+		"send jobs":    sendJobStorage,
+		"dead letters": deadLetterStorage,
+		"rates":        rateStorage,
+		"alerts":       alertStorage,
+	}
+
+	normalizeEmail := func(email string) (string, bool) {
+		normalized, err := service.NormalizeEmail(email, cfg.EmailValidation)
+		return normalized, err == nil
+	}
+
+	var emailStorage service.EmailStorage
+	switch cfg.EmailStorage.Driver {
+	case "file":
+		// emails stored before normalization was introduced are normalized on compaction
+		normalizeEmails := localstorage.NormalizeEmails(normalizeEmail)
+		if cfg.EmailStorage.InMemoryIndex {
+			indexedEmailStorage := localstorage.NewIndexedEmailStorage(fileStorage, "emails.txt", normalizeEmails)
+			fileStorages["emails"] = indexedEmailStorage
+			emailStorage = indexedEmailStorage
+		} else {
+			localEmailStorage := localstorage.NewEmailStorage(fileStorage, "emails.txt", normalizeEmails)
+			fileStorages["emails"] = localEmailStorage
+			emailStorage = localEmailStorage
+		}
+	case string(database.SQLDriverSQLite), string(database.SQLDriverPostgres):
+		sqlDB, err := database.NewSQLDB(database.SQLDriver(cfg.EmailStorage.Driver), cfg.EmailStorage.DSN)
+		if err != nil {
+			log.Fatal("failed to init sql database", "err", err)
+		}
+		defer sqlDB.Close()
+
+		err = sqlDB.Ping(context.TODO())
+		if err != nil {
+			log.Fatal("failed to connect to sql database", "err", err)
+		}
+
+		err = sqlstorage.Migrate(context.TODO(), sqlDB)
+		if err != nil {
+			log.Fatal("failed to migrate sql database", "err", err)
+		}
+
+		sqlEmailStorage := sqlstorage.NewEmailStorage(sqlDB)
+		// emails stored before normalization was introduced are normalized on start
+		changed, err := sqlEmailStorage.NormalizeEmails(context.TODO(), normalizeEmail)
+		if err != nil {
+			logger.Error("app - Run - NormalizeEmails: failed to normalize emails", "err", err)
+		} else if changed > 0 {
+			logger.Warn("app - Run - NormalizeEmails: normalized emails", "changed", changed)
+		}
+		emailStorage = sqlEmailStorage
+	default:
+		log.Fatal("unknown email storage driver", "driver", cfg.EmailStorage.Driver)
+	}
+
+	// clean up files after interrupted writes before they are read
+	if cfg.FileStorage.CompactOnStart {
+		compactFileStorages(logger, fileStorages)
+	}
+
 	storages := service.Storages{
-		Email: localstorage.NewEmailStorage(fileStorage, "emails.txt"),
+		Email:      emailStorage,
+		SendJob:    sendJobStorage,
+		DeadLetter: deadLetterStorage,
+		Rate:       rateStorage,
+		Alert:      alertStorage,
+	}
+
+	var emailAPI service.EmailAPI
+	switch cfg.Email.Provider {
+	case "mailgun":
+		emailAPI = mailgun.New(&mailgun.Options{
+			Domain: cfg.MailGun.Domain,
+			APIKey: cfg.MailGun.Key,
+			From:   cfg.MailGun.From,
+			Logger: logger,
+		})
+	case "smtp":
+		emailAPI, err = smtp.New(&smtp.Options{
+			Host:       cfg.SMTP.Host,
+			Port:       cfg.SMTP.Port,
+			Username:   cfg.SMTP.Username,
+			Password:   cfg.SMTP.Password,
+			From:       cfg.SMTP.From,
+			Auth:       smtp.AuthMechanism(cfg.SMTP.Auth),
+			Encryption: smtp.Encryption(cfg.SMTP.Encryption),
+			Timeout:    time.Second * time.Duration(cfg.SMTP.Timeout),
+			Logger:     logger,
+		})
+		if err != nil {
+			log.Fatal("failed to init smtp email api", "err", err)
+		}
+	default:
+		log.Fatal("unknown email provider", "provider", cfg.Email.Provider)
 	}
 
 	apis := service.APIs{
@@ -58,29 +180,204 @@ func Run(cfg *config.Config) {
 			Logger: logger,
 			Config: cfg,
 		}),
-		Email: mailgun.New(&mailgun.Options{
-			Domain: cfg.MailGun.Domain,
-			APIKey: cfg.MailGun.Key,
-			From:   cfg.MailGun.From,
-			Logger: logger,
+		Email: retry.NewEmailAPI(emailAPI, &retry.Options{
+			Logger:      logger,
+			MaxAttempts: cfg.Retry.MaxAttempts,
+			BaseDelay:   time.Millisecond * time.Duration(cfg.Retry.BaseDelay),
+			MaxDelay:    time.Millisecond * time.Duration(cfg.Retry.MaxDelay),
 		}),
 	}
 
+	switch cfg.EmailValidation.Resolver {
+	case "dns":
+		apis.Domain = dns.New(&dns.Options{
+			Logger:  logger,
+			Timeout: time.Second * time.Duration(cfg.EmailValidation.ResolverTimeout),
+		})
+	case "none":
+	default:
+		log.Fatal("unknown email validation resolver", "resolver", cfg.EmailValidation.Resolver)
+	}
+
+	templates, err := service.LoadTemplates(cfg.Templates.Directory)
+	if err != nil {
+		log.Fatal("failed to load email templates", "err", err)
+	}
+
+	var disposableDomains *service.DomainList
+	if cfg.EmailValidation.DisposableDomainsFile != "" {
+		disposableDomains, err = service.LoadDomainList(cfg.EmailValidation.DisposableDomainsFile)
+		if err != nil {
+			log.Fatal("failed to load disposable email domains", "err", err)
+		}
+		logger.Info("loaded disposable email domains", "count", disposableDomains.Len())
+	}
+
 	serviceOptions := service.Options{
-		Storages: storages,
-		APIs:     apis,
-		Logger:   logger,
-		Cfg:      cfg,
+		Storages:          storages,
+		APIs:              apis,
+		Logger:            logger,
+		Cfg:               cfg,
+		Templates:         templates,
+		DisposableDomains: disposableDomains,
 	}
 
+	emailService := service.NewEmailService(&serviceOptions)
 	services := service.Services{
-		Email: service.NewEmailService(&serviceOptions),
+		Email:      emailService,
+		SendJob:    service.NewSendJobService(&serviceOptions, emailService),
+		DeadLetter: service.NewDeadLetterService(&serviceOptions),
+		Alert:      service.NewAlertService(&serviceOptions, emailService),
+		Subscriber: service.NewSubscriberService(&serviceOptions),
+	}
+
+	// resume send jobs interrupted by previous shutdown
+	err = services.SendJob.Resume(context.Background())
+	if err != nil {
+		logger.Error("app - Run - services.SendJob.Resume", "err", err)
+	}
+
+	// init and run scheduler
+	var rateScheduler *scheduler.Scheduler
+	if cfg.Scheduler.Enabled {
+		rateScheduler, err = scheduler.New(&scheduler.Options{
+			Services: services,
+			Storage:  localstorage.NewSchedulerStorage(fileStorage, "scheduler.txt"),
+			Logger:   logger,
+			Schedule: cfg.Scheduler.Cron,
+		})
+		if err != nil {
+			log.Fatal("failed to init scheduler", "err", err)
+		}
+		rateScheduler.Start()
+	}
+
+	// init and run alert evaluator
+	var alertEvaluator *alerting.Evaluator
+	if cfg.Alerts.Enabled {
+		alertEvaluator, err = alerting.New(&alerting.Options{
+			Services: services,
+			Logger:   logger,
+			Interval: time.Second * time.Duration(cfg.Alerts.Interval),
+		})
+		if err != nil {
+			log.Fatal("failed to init alert evaluator", "err", err)
+		}
+		alertEvaluator.Start()
+	}
+
+	apiKeyAuthenticator, err := controller.NewAPIKeyAuthenticator(cfg.Auth.APIKeys)
+	if err != nil {
+		log.Fatal("failed to init api key authenticator", "err", err)
+	}
+	authenticators := []controller.Authenticator{apiKeyAuthenticator}
+	if cfg.Auth.JWTSecret != "" {
+		jwtAuthenticator, err := controller.NewJWTAuthenticator(&controller.JWTOptions{
+			Secret:   cfg.Auth.JWTSecret,
+			Issuer:   cfg.Auth.JWTIssuer,
+			Audience: cfg.Auth.JWTAudience,
+		})
+		if err != nil {
+			log.Fatal("failed to init jwt authenticator", "err", err)
+		}
+		authenticators = append(authenticators, jwtAuthenticator)
+	}
+	if cfg.Auth.Disabled {
+		logger.Warn("authentication is disabled, admin and sendEmails endpoints are available to everyone")
+	}
+
+	// database of rate limit backend also keeps nonces of solved challenges, so that replicas share them
+	var rateLimitDB *database.SQLDB
+	switch cfg.RateLimit.Backend {
+	case string(database.SQLDriverSQLite), string(database.SQLDriverPostgres):
+		if !cfg.RateLimit.Enabled && cfg.Challenge.Type != challenge.TypeProofOfWork {
+			break
+		}
+
+		rateLimitDB, err = database.NewSQLDB(database.SQLDriver(cfg.RateLimit.Backend), cfg.RateLimit.DSN)
+		if err != nil {
+			log.Fatal("failed to init rate limit database", "err", err)
+		}
+		defer rateLimitDB.Close()
+
+		err = rateLimitDB.Ping(context.TODO())
+		if err != nil {
+			log.Fatal("failed to connect to rate limit database", "err", err)
+		}
+
+		err = sqlstorage.MigrateRateLimits(context.TODO(), rateLimitDB)
+		if err != nil {
+			log.Fatal("failed to migrate rate limit database", "err", err)
+		}
+	}
+
+	var (
+		rateLimits     *controller.RateLimits
+		rateLimitStore ratelimit.Store
+	)
+	if cfg.RateLimit.Enabled {
+		rateLimits, err = controller.ParseRateLimits(cfg.RateLimit.Default, cfg.RateLimit.Routes)
+		if err != nil {
+			log.Fatal("failed to parse rate limits", "err", err)
+		}
+
+		switch cfg.RateLimit.Backend {
+		case "memory":
+			rateLimitStore = ratelimit.NewMemoryStore()
+		case string(database.SQLDriverSQLite), string(database.SQLDriverPostgres):
+			rateLimitStore = sqlstorage.NewRateLimitStore(rateLimitDB)
+		default:
+			log.Fatal("unknown rate limit backend", "backend", cfg.RateLimit.Backend)
+		}
+	}
+
+	var challengeVerifier challenge.Verifier
+	switch cfg.Challenge.Type {
+	case "none":
+	case challenge.TypeProofOfWork:
+		// without shared database each replica accepts solution once
+		var nonces challenge.NonceStore
+		if rateLimitDB != nil {
+			nonces = sqlstorage.NewNonceStore(rateLimitDB)
+		}
+		challengeVerifier = challenge.NewProofOfWork(&challenge.ProofOfWorkOptions{
+			Secret:     cfg.Challenge.Secret,
+			Difficulty: cfg.Challenge.Difficulty,
+			TTL:        time.Second * time.Duration(cfg.Challenge.TTL),
+			Nonces:     nonces,
+		})
+	case challenge.TypeCaptcha:
+		challengeVerifier, err = captcha.New(&captcha.Options{
+			Logger:    logger,
+			Provider:  cfg.Challenge.CaptchaProvider,
+			VerifyURL: cfg.Challenge.CaptchaVerifyURL,
+			SiteKey:   cfg.Challenge.CaptchaSiteKey,
+			Secret:    cfg.Challenge.CaptchaSecret,
+			Timeout:   time.Second * time.Duration(cfg.Challenge.CaptchaTimeout),
+		})
+		if err != nil {
+			log.Fatal("failed to init captcha api", "err", err)
+		}
+	default:
+		log.Fatal("unknown challenge type", "type", cfg.Challenge.Type)
+	}
+
+	unversionedDeprecation, err := apiversion.ParseDeprecation(cfg.API.UnversionedDeprecatedSince,
+		cfg.API.UnversionedSunset, "/api/v1")
+	if err != nil {
+		log.Fatal("failed to parse deprecation of unversioned api", "err", err)
 	}
 
 	handler := controller.New(&controller.Options{
-		Config:   cfg,
-		Logger:   logger,
-		Services: services,
+		Config:         cfg,
+		Logger:         logger,
+		Services:       services,
+		Authenticators: authenticators,
+		RateLimitStore: rateLimitStore,
+		RateLimits:     rateLimits,
+
+		ChallengeVerifier:      challengeVerifier,
+		UnversionedDeprecation: unversionedDeprecation,
 	})
 
 	// init and run http server
@@ -109,4 +406,54 @@ func Run(cfg *config.Config) {
 	if err != nil {
 		logger.Error("app - Run - httpServer.Shutdown", "err", err)
 	}
+
+	// shutdown scheduler
+	if rateScheduler != nil {
+		rateScheduler.Shutdown()
+	}
+
+	// shutdown alert evaluator
+	if alertEvaluator != nil {
+		alertEvaluator.Shutdown()
+	}
+
+	// interrupt send jobs, they are resumed on next start
+	services.SendJob.Shutdown()
+}
+
+// exampleSecrets are publicly known secrets from .env-example and from older default configuration.
+var exampleSecrets = map[string]bool{
+	"your-token-secret":           true,
+	"<your_token_secret>":         true,
+	"<your_proof_of_work_secret>": true,
+}
+
+// validateSecret checks that secret used to sign tokens is set and isn't publicly known, so that tokens can't
+// be forged.
+func validateSecret(secret string) error {
+	if secret == "" {
+		return errors.New("secret is empty")
+	}
+	if exampleSecrets[secret] {
+		return errors.New("secret is publicly known example value")
+	}
+	return nil
+}
+
+// fileCompacter is implemented by file storages that can remove duplicate and malformed records.
+type fileCompacter interface {
+	Compact(ctx context.Context) (int, error)
+}
+
+func compactFileStorages(logger logging.Logger, storages map[string]fileCompacter) {
+	for name, storage := range storages {
+		removed, err := storage.Compact(context.TODO())
+		if err != nil {
+			logger.Error("app - Run - compactFileStorages", "storage", name, "err", err)
+			continue
+		}
+		if removed > 0 {
+			logger.Warn("app - Run - compactFileStorages: removed records", "storage", name, "removed", removed)
+		}
+	}
 }
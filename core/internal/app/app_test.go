@@ -0,0 +1,28 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSecret(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]bool{
+		"":                            false,
+		"your-token-secret":           false,
+		"<your_token_secret>":         false,
+		"<your_proof_of_work_secret>": false,
+		"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08": true,
+	}
+
+	for secret, valid := range testCases {
+		err := validateSecret(secret)
+		if valid {
+			assert.NoError(t, err, secret)
+		} else {
+			assert.Error(t, err, secret)
+		}
+	}
+}
@@ -12,6 +12,16 @@ func TestArchitecture(t *testing.T) {
 		ShouldNotDependOn("github.com/vadimpk/gses-2023/core/internal/api",
 			"github.com/vadimpk/gses-2023/core/internal/storage/...")
 
+	// Scheduler should not depend on anything except service layer
+	archtest.Package(t, "github.com/vadimpk/gses-2023/core/internal/scheduler").
+		ShouldNotDependOn("github.com/vadimpk/gses-2023/core/internal/api",
+			"github.com/vadimpk/gses-2023/core/internal/storage/...")
+
+	// Alert evaluator should not depend on anything except service layer
+	archtest.Package(t, "github.com/vadimpk/gses-2023/core/internal/alerting").
+		ShouldNotDependOn("github.com/vadimpk/gses-2023/core/internal/api",
+			"github.com/vadimpk/gses-2023/core/internal/storage/...")
+
 	// Service layer should not depend on API or Storage layers
 	archtest.Package(t, "github.com/vadimpk/gses-2023/core/internal/service").
 		ShouldNotDependOn("github.com/vadimpk/gses-2023/core/internal/api",
@@ -0,0 +1,405 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+)
+
+type adminRoutes struct {
+	routerContext
+}
+
+func setupAdminRoutes(opts *routerOptions) {
+	adminRoutes := adminRoutes{
+		routerContext: routerContext{
+			services: opts.services,
+			cfg:      opts.cfg,
+			logger:   opts.logger.Named("Admin"),
+		},
+	}
+
+	router := opts.router.Group("/admin", authMiddleware(opts, ScopeAdmin))
+	router.GET("/deadLetters", wrapHandler(opts, adminRoutes.listDeadLetters))
+	router.POST("/deadLetters/redrive", wrapHandler(opts, adminRoutes.redriveDeadLetters))
+	router.GET("/subscribers", wrapHandler(opts, adminRoutes.listSubscribers))
+	router.POST("/subscribers/import", wrapHandler(opts, adminRoutes.importSubscribers))
+	router.GET("/subscribers/:email", wrapHandler(opts, adminRoutes.getSubscriber))
+	router.DELETE("/subscribers/:email", wrapHandler(opts, adminRoutes.deleteSubscriber))
+}
+
+type deadLetterResponseBody struct {
+	ID       string    `json:"id"`
+	To       string    `json:"to"`
+	Subject  string    `json:"subject"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+type listDeadLettersResponseBody struct {
+	DeadLetters []deadLetterResponseBody `json:"dead_letters"`
+}
+
+func (r *adminRoutes) listDeadLetters(c *gin.Context) (interface{}, *httpResponseError) {
+	logger := r.logger.Named("listDeadLetters")
+
+	letters, err := r.services.DeadLetter.List(c.Request.Context())
+	if err != nil {
+		logger.Error("failed to list dead letters", "err", err)
+		return nil, &httpResponseError{
+			Type:    ErrorTypeServer,
+			Message: "failed to list dead letters",
+			Details: err.Error(),
+		}
+	}
+
+	response := listDeadLettersResponseBody{
+		DeadLetters: make([]deadLetterResponseBody, 0, len(letters)),
+	}
+	for _, letter := range letters {
+		response.DeadLetters = append(response.DeadLetters, deadLetterResponseBody{
+			ID:       letter.ID,
+			To:       letter.To,
+			Subject:  letter.Subject,
+			Error:    letter.Error,
+			FailedAt: letter.FailedAt,
+		})
+	}
+
+	logger.Info("successfully listed dead letters")
+	return response, nil
+}
+
+type redriveDeadLettersRequestQuery struct {
+	// IDs is comma separated list of dead letters to redrive, it's required unless All is set.
+	IDs string `form:"ids" json:"ids"`
+	// All redrives all dead letters.
+	All bool `form:"all" json:"all"`
+}
+
+type redriveDeadLettersResponseBody struct {
+	Sent    []string `json:"sent"`
+	Failed  []string `json:"failed"`
+	Dropped []string `json:"dropped"`
+}
+
+func (r *adminRoutes) redriveDeadLetters(c *gin.Context) (interface{}, *httpResponseError) {
+	logger := r.logger.Named("redriveDeadLetters")
+
+	var query redriveDeadLettersRequestQuery
+	if errResp := bindRequest(c, &query); errResp != nil {
+		logger.Info("failed to bind request", "err", errResp)
+		return nil, errResp
+	}
+	logger = logger.With("query", query)
+
+	var ids []string
+	if query.IDs != "" {
+		ids = strings.Split(query.IDs, ",")
+	}
+
+	output, err := r.services.DeadLetter.Redrive(c.Request.Context(), &service.RedriveOptions{
+		IDs: ids,
+		All: query.All,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrRedriveNoLetters) {
+			logger.Info("failed to redrive dead letters", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusBadRequest,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		}
+		logger.Error("failed to redrive dead letters", "err", err)
+		return nil, &httpResponseError{
+			Type:    ErrorTypeServer,
+			Message: "failed to redrive dead letters",
+			Details: err.Error(),
+		}
+	}
+
+	logger.Info("successfully redrove dead letters")
+	return redriveDeadLettersResponseBody{
+		Sent:    output.Sent,
+		Failed:  output.Failed,
+		Dropped: output.Dropped,
+	}, nil
+}
+
+type subscriberResponseBody struct {
+	Email     string    `json:"email"`
+	Status    string    `json:"status"`
+	Pairs     []string  `json:"pairs"`
+	Locale    string    `json:"locale"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newSubscriberResponseBody(subscriber *entity.Subscriber) subscriberResponseBody {
+	return subscriberResponseBody{
+		Email:     subscriber.Email,
+		Status:    subscriber.Status.String(),
+		Pairs:     formatCurrencyPairs(subscriber.Pairs),
+		Locale:    subscriber.Locale.String(),
+		CreatedAt: subscriber.CreatedAt,
+	}
+}
+
+type listSubscribersRequestQuery struct {
+	// Query is part of email to search for.
+	Query string `form:"query" json:"query"`
+	// Status is "pending" or "confirmed", subscribers with any status are listed if it's empty.
+	Status string `form:"status" json:"status" binding:"omitempty,oneof=pending confirmed"`
+	Offset int    `form:"offset" json:"offset" binding:"min=0"`
+	Limit  int    `form:"limit" json:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+type listSubscribersResponseBody struct {
+	Subscribers []subscriberResponseBody `json:"subscribers"`
+	Total       int                      `json:"total"`
+	Offset      int                      `json:"offset"`
+	Limit       int                      `json:"limit"`
+}
+
+func (r *adminRoutes) listSubscribers(c *gin.Context) (interface{}, *httpResponseError) {
+	logger := r.logger.Named("listSubscribers")
+
+	var query listSubscribersRequestQuery
+	if errResp := bindRequest(c, &query); errResp != nil {
+		logger.Info("failed to bind request", "err", errResp)
+		return nil, errResp
+	}
+	logger = logger.With("query", query)
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = service.DefaultSubscribersPageSize
+	}
+
+	output, err := r.services.Subscriber.List(c.Request.Context(), &service.ListSubscribersOptions{
+		Query:  query.Query,
+		Status: entity.SubscriberStatus(query.Status),
+		Offset: query.Offset,
+		Limit:  limit,
+	})
+	if err != nil {
+		logger.Error("failed to list subscribers", "err", err)
+		return nil, &httpResponseError{
+			Type:    ErrorTypeServer,
+			Message: "failed to list subscribers",
+			Details: err.Error(),
+		}
+	}
+
+	response := listSubscribersResponseBody{
+		Subscribers: make([]subscriberResponseBody, 0, len(output.Subscribers)),
+		Total:       output.Total,
+		Offset:      query.Offset,
+		Limit:       limit,
+	}
+	for _, subscriber := range output.Subscribers {
+		response.Subscribers = append(response.Subscribers, newSubscriberResponseBody(subscriber))
+	}
+
+	logger.Info("successfully listed subscribers")
+	return response, nil
+}
+
+func (r *adminRoutes) getSubscriber(c *gin.Context) (interface{}, *httpResponseError) {
+	logger := r.logger.Named("getSubscriber").
+		With("email", c.Param("email"))
+
+	subscriber, err := r.services.Subscriber.Get(c.Request.Context(), c.Param("email"))
+	if err != nil {
+		if errors.Is(err, service.ErrSubscriberNotFound) {
+			logger.Info("failed to get subscriber", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusNotFound,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		}
+
+		logger.Error("failed to get subscriber", "err", err)
+		return nil, &httpResponseError{
+			Type:    ErrorTypeServer,
+			Message: "failed to get subscriber",
+			Details: err.Error(),
+		}
+	}
+
+	logger.Info("successfully got subscriber")
+	return newSubscriberResponseBody(subscriber), nil
+}
+
+type deleteSubscriberResponseBody struct {
+	Email string `json:"email"`
+}
+
+func (r *adminRoutes) deleteSubscriber(c *gin.Context) (interface{}, *httpResponseError) {
+	logger := r.logger.Named("deleteSubscriber").
+		With("email", c.Param("email"))
+
+	err := r.services.Subscriber.Delete(c.Request.Context(), c.Param("email"))
+	if err != nil {
+		if errors.Is(err, service.ErrSubscriberNotFound) {
+			logger.Info("failed to delete subscriber", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusNotFound,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		}
+
+		logger.Error("failed to delete subscriber", "err", err)
+		return nil, &httpResponseError{
+			Type:    ErrorTypeServer,
+			Message: "failed to delete subscriber",
+			Details: err.Error(),
+		}
+	}
+
+	logger.Info("successfully deleted subscriber")
+	return deleteSubscriberResponseBody{
+		Email: c.Param("email"),
+	}, nil
+}
+
+type importSubscribersRequestBody struct {
+	Subscribers []importSubscriberRequestBody `json:"subscribers" binding:"required"`
+}
+
+type importSubscriberRequestBody struct {
+	Email string `json:"email"`
+	// Status is "pending" or "confirmed", imported subscribers are confirmed if it's empty.
+	Status string `json:"status"`
+	// Pairs are currency pairs, e.g. ["BTC-USD", "ETH-UAH"].
+	Pairs     []string  `json:"pairs"`
+	Locale    string    `json:"locale"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type importSubscribersResponseBody struct {
+	Imported []string                        `json:"imported"`
+	Existing []string                        `json:"existing"`
+	Invalid  []importSubscriberErrorResponse `json:"invalid"`
+}
+
+type importSubscriberErrorResponse struct {
+	Email string `json:"email"`
+	Error string `json:"error"`
+}
+
+func (r *adminRoutes) importSubscribers(c *gin.Context) (interface{}, *httpResponseError) {
+	logger := r.logger.Named("importSubscribers")
+
+	var body importSubscribersRequestBody
+	if errResp := bindRequest(c, &body); errResp != nil {
+		logger.Info("failed to bind request", "err", errResp)
+		return nil, errResp
+	}
+	logger = logger.With("count", len(body.Subscribers))
+
+	// limit applies to the whole request, including subscribers that can't be parsed
+	if len(body.Subscribers) > service.MaxImportSubscribers {
+		logger.Info("too many subscribers to import")
+		return nil, &httpResponseError{
+			Code:    http.StatusBadRequest,
+			Type:    ErrorTypeClient,
+			Message: service.ErrImportSubscribersTooMany.Error(),
+		}
+	}
+
+	// malformed subscribers are reported the same way as invalid emails, so that they don't fail the whole import
+	response := importSubscribersResponseBody{
+		Imported: []string{},
+		Existing: []string{},
+		Invalid:  []importSubscriberErrorResponse{},
+	}
+	subscribers := make([]*service.ImportSubscriberOptions, 0, len(body.Subscribers))
+	for _, s := range body.Subscribers {
+		subscriber, err := parseImportedSubscriber(s)
+		if err != nil {
+			response.Invalid = append(response.Invalid, importSubscriberErrorResponse{
+				Email: s.Email,
+				Error: err.Error(),
+			})
+			continue
+		}
+		subscribers = append(subscribers, subscriber)
+	}
+	if len(subscribers) == 0 && len(response.Invalid) > 0 {
+		logger.Info("no subscribers can be parsed")
+		return response, nil
+	}
+
+	output, err := r.services.Subscriber.Import(c.Request.Context(), subscribers)
+	if err != nil {
+		if errors.Is(err, service.ErrImportSubscribersEmpty) ||
+			errors.Is(err, service.ErrImportSubscribersTooMany) {
+			logger.Info("failed to import subscribers", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusBadRequest,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		}
+
+		logger.Error("failed to import subscribers", "err", err)
+		return nil, &httpResponseError{
+			Type:    ErrorTypeServer,
+			Message: "failed to import subscribers",
+			Details: err.Error(),
+		}
+	}
+
+	response.Imported = append(response.Imported, output.Imported...)
+	response.Existing = append(response.Existing, output.Existing...)
+	for _, invalid := range output.Invalid {
+		response.Invalid = append(response.Invalid, importSubscriberErrorResponse{
+			Email: invalid.Email,
+			Error: invalid.Err.Error(),
+		})
+	}
+
+	logger.Info("successfully imported subscribers")
+	return response, nil
+}
+
+func parseImportedSubscriber(body importSubscriberRequestBody) (*service.ImportSubscriberOptions, error) {
+	subscriber := &service.ImportSubscriberOptions{
+		Email:     body.Email,
+		CreatedAt: body.CreatedAt,
+	}
+
+	if body.Status != "" {
+		status, err := entity.ParseSubscriberStatus(body.Status)
+		if err != nil {
+			return nil, err
+		}
+		subscriber.Status = status
+	}
+
+	for _, p := range body.Pairs {
+		pair, err := entity.ParseCurrencyPair(p)
+		if err != nil {
+			return nil, err
+		}
+		subscriber.Pairs = append(subscriber.Pairs, pair)
+	}
+
+	if body.Locale != "" {
+		locale, err := entity.ParseLocale(body.Locale)
+		if err != nil {
+			return nil, err
+		}
+		subscriber.Locale = locale
+	}
+
+	return subscriber, nil
+}
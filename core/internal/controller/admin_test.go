@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/config"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+// importSubscriberService implements only Import of service.SubscriberService.
+type importSubscriberService struct {
+	service.SubscriberService
+	imported []*service.ImportSubscriberOptions
+}
+
+func (s *importSubscriberService) Import(ctx context.Context, subscribers []*service.ImportSubscriberOptions) (*service.ImportSubscribersOutput, error) {
+	s.imported = append(s.imported, subscribers...)
+
+	output := &service.ImportSubscribersOutput{}
+	for _, subscriber := range subscribers {
+		output.Imported = append(output.Imported, subscriber.Email)
+	}
+	return output, nil
+}
+
+func TestImportSubscribers(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		body             string
+		expectedImported []string
+		expectedInvalid  []importSubscriberErrorResponse
+	}{
+		{
+			name: "malformed subscribers don't fail the rest",
+			body: `{"subscribers": [
+				{"email": "a@test.com", "pairs": ["BTC-USD"], "locale": "uk"},
+				{"email": "b@test.com", "pairs": ["BTC-XYZ"]},
+				{"email": "c@test.com", "locale": "xx"},
+				{"email": "d@test.com", "status": "unknown"},
+				{"email": "e@test.com"}
+			]}`,
+			expectedImported: []string{"a@test.com", "e@test.com"},
+			expectedInvalid: []importSubscriberErrorResponse{
+				{Email: "b@test.com"},
+				{Email: "c@test.com"},
+				{Email: "d@test.com"},
+			},
+		},
+		{
+			name:             "all subscribers are malformed",
+			body:             `{"subscribers": [{"email": "a@test.com", "locale": "xx"}]}`,
+			expectedImported: []string{},
+			expectedInvalid:  []importSubscriberErrorResponse{{Email: "a@test.com"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			subscriberService := &importSubscriberService{}
+			cfg := &config.Config{}
+			cfg.Auth.Disabled = true
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			setupAdminRoutes(&routerOptions{
+				router:   r.Group("/api"),
+				services: service.Services{Subscriber: subscriberService},
+				cfg:      cfg,
+				logger:   logging.NewZapLogger("debug"),
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/subscribers/import", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+			var response importSubscribersResponseBody
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Equal(t, tc.expectedImported, response.Imported)
+			require.Len(t, response.Invalid, len(tc.expectedInvalid))
+			for i, invalid := range tc.expectedInvalid {
+				assert.Equal(t, invalid.Email, response.Invalid[i].Email)
+				assert.NotEmpty(t, response.Invalid[i].Error)
+			}
+			assert.Len(t, subscriberService.imported, len(tc.expectedImported))
+		})
+	}
+}
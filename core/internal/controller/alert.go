@@ -0,0 +1,257 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+)
+
+type alertRoutes struct {
+	routerContext
+}
+
+func setupAlertRoutes(opts *routerOptions) {
+	alertRoutes := alertRoutes{
+		routerContext: routerContext{
+			services: opts.services,
+			cfg:      opts.cfg,
+			logger:   opts.logger.Named("Alert"),
+		},
+	}
+
+	opts.router.POST("/alerts", wrapHandler(opts, alertRoutes.createAlert))
+	opts.router.GET("/alerts", wrapHandler(opts, alertRoutes.listAlerts))
+	opts.router.DELETE("/alerts/:id", wrapHandler(opts, alertRoutes.deleteAlert))
+}
+
+type alertResponseBody struct {
+	ID        string  `json:"id"`
+	Pair      string  `json:"pair"`
+	Condition string  `json:"condition"`
+	Threshold float64 `json:"threshold"`
+	// WindowHours is set only for change alerts.
+	WindowHours int        `json:"window_hours,omitempty"`
+	Triggered   bool       `json:"triggered"`
+	TriggeredAt *time.Time `json:"triggered_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func newAlertResponseBody(alert *entity.Alert) alertResponseBody {
+	body := alertResponseBody{
+		ID:          alert.ID,
+		Pair:        alert.Pair.String(),
+		Condition:   alert.Condition.String(),
+		Threshold:   alert.Threshold,
+		WindowHours: int(alert.Window / time.Hour),
+		Triggered:   alert.Triggered,
+		CreatedAt:   alert.CreatedAt,
+	}
+	if !alert.TriggeredAt.IsZero() {
+		body.TriggeredAt = &alert.TriggeredAt
+	}
+	return body
+}
+
+type createAlertRequestQuery struct {
+	Email string `form:"email" json:"email" binding:"required,email"`
+	Token string `form:"token" json:"token" binding:"required"`
+	// Pair is currency pair, e.g. "BTC-UAH".
+	Pair string `form:"pair" json:"pair" binding:"required"`
+	// Condition is one of "above", "below" or "change".
+	Condition string `form:"condition" json:"condition" binding:"required"`
+	// Threshold is rate for "above" and "below" conditions and change in percents for "change" condition.
+	Threshold float64 `form:"threshold" json:"threshold" binding:"required"`
+	// Window is period in hours change is measured in, 24 hours are used if it's empty.
+	Window int `form:"window" json:"window"`
+}
+
+func (r *alertRoutes) createAlert(c *gin.Context) (interface{}, *httpResponseError) {
+	logger := r.logger.Named("createAlert")
+
+	var query createAlertRequestQuery
+	if errResp := bindRequest(c, &query); errResp != nil {
+		logger.Info("failed to bind request", "err", errResp)
+		return nil, errResp
+	}
+	logger = logger.With("email", query.Email).
+		With("pair", query.Pair).
+		With("condition", query.Condition).
+		With("threshold", query.Threshold)
+
+	pair, err := entity.ParseCurrencyPair(query.Pair)
+	if err != nil {
+		logger.Info("failed to parse pair", "err", err)
+		return nil, &httpResponseError{
+			Code:    http.StatusBadRequest,
+			Type:    ErrorTypeClient,
+			Message: "failed to parse pair",
+			Details: err.Error(),
+		}
+	}
+
+	condition, err := entity.ParseAlertCondition(strings.ToLower(query.Condition))
+	if err != nil {
+		logger.Info("failed to parse condition", "err", err)
+		return nil, &httpResponseError{
+			Code:    http.StatusBadRequest,
+			Type:    ErrorTypeClient,
+			Message: "failed to parse condition",
+			Details: err.Error(),
+		}
+	}
+
+	alert, err := r.services.Alert.Create(c.Request.Context(), &service.CreateAlertOptions{
+		Email:     query.Email,
+		Token:     query.Token,
+		Pair:      pair,
+		Condition: condition,
+		Threshold: query.Threshold,
+		Window:    time.Duration(query.Window) * time.Hour,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrAlertInvalidToken):
+			logger.Info("failed to create alert", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusForbidden,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		case errors.Is(err, service.ErrAlertNotSubscribed):
+			logger.Info("failed to create alert", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusNotFound,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		case errors.Is(err, service.ErrAlertInvalidThreshold),
+			errors.Is(err, service.ErrAlertInvalidWindow):
+			logger.Info("failed to create alert", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusBadRequest,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		case errors.Is(err, service.ErrAlertLimitExceeded):
+			logger.Info("failed to create alert", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusConflict,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		}
+
+		logger.Error("failed to create alert", "err", err)
+		return nil, &httpResponseError{
+			Type:    ErrorTypeServer,
+			Message: "failed to create alert",
+			Details: err.Error(),
+		}
+	}
+
+	logger.Info("successfully created alert", "id", alert.ID)
+	c.Status(http.StatusCreated)
+	return newAlertResponseBody(alert), nil
+}
+
+type alertsRequestQuery struct {
+	Email string `form:"email" json:"email" binding:"required,email"`
+	Token string `form:"token" json:"token" binding:"required"`
+}
+
+type listAlertsResponseBody struct {
+	Alerts []alertResponseBody `json:"alerts"`
+}
+
+func (r *alertRoutes) listAlerts(c *gin.Context) (interface{}, *httpResponseError) {
+	logger := r.logger.Named("listAlerts")
+
+	var query alertsRequestQuery
+	if errResp := bindRequest(c, &query); errResp != nil {
+		logger.Info("failed to bind request", "err", errResp)
+		return nil, errResp
+	}
+	logger = logger.With("email", query.Email)
+
+	alerts, err := r.services.Alert.List(c.Request.Context(), query.Email, query.Token)
+	if err != nil {
+		if errors.Is(err, service.ErrAlertInvalidToken) {
+			logger.Info("failed to list alerts", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusForbidden,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		}
+
+		logger.Error("failed to list alerts", "err", err)
+		return nil, &httpResponseError{
+			Type:    ErrorTypeServer,
+			Message: "failed to list alerts",
+			Details: err.Error(),
+		}
+	}
+
+	response := listAlertsResponseBody{
+		Alerts: make([]alertResponseBody, 0, len(alerts)),
+	}
+	for _, alert := range alerts {
+		response.Alerts = append(response.Alerts, newAlertResponseBody(alert))
+	}
+
+	logger.Info("successfully listed alerts")
+	return response, nil
+}
+
+type deleteAlertResponseBody struct {
+	ID string `json:"id"`
+}
+
+func (r *alertRoutes) deleteAlert(c *gin.Context) (interface{}, *httpResponseError) {
+	logger := r.logger.Named("deleteAlert").
+		With("id", c.Param("id"))
+
+	var query alertsRequestQuery
+	if errResp := bindRequest(c, &query); errResp != nil {
+		logger.Info("failed to bind request", "err", errResp)
+		return nil, errResp
+	}
+	logger = logger.With("email", query.Email)
+
+	err := r.services.Alert.Delete(c.Request.Context(), query.Email, query.Token, c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrAlertInvalidToken):
+			logger.Info("failed to delete alert", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusForbidden,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		case errors.Is(err, service.ErrAlertNotFound):
+			logger.Info("failed to delete alert", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusNotFound,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		}
+
+		logger.Error("failed to delete alert", "err", err)
+		return nil, &httpResponseError{
+			Type:    ErrorTypeServer,
+			Message: "failed to delete alert",
+			Details: err.Error(),
+		}
+	}
+
+	logger.Info("successfully deleted alert")
+	return deleteAlertResponseBody{
+		ID: c.Param("id"),
+	}, nil
+}
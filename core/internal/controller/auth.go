@@ -0,0 +1,255 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// ScopeSendEmails allows to start sending rate info to all subscribers and to track sending jobs.
+	ScopeSendEmails = "emails:send"
+	// ScopeAdmin allows to use admin endpoints.
+	ScopeAdmin = "admin"
+)
+
+const (
+	PrincipalTypeAPIKey = "api-key"
+	PrincipalTypeJWT    = "jwt"
+)
+
+// Principal is authenticated client of API.
+type Principal struct {
+	// Type is kind of credentials, PrincipalTypeAPIKey or PrincipalTypeJWT. Names are unique only within it.
+	Type string
+	// Name identifies client in logs, it's name of API key or subject of token.
+	Name   string
+	Scopes []string
+}
+
+// HasScope checks if principal is allowed to use endpoints that require scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator checks credentials of request. It returns nil principal and nil error if request doesn't
+// have credentials it handles, so that next authenticator can be tried, and errInvalidCredentials if it
+// has invalid ones.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+var errInvalidCredentials = errors.New("invalid credentials")
+
+// principalKey is key of authenticated Principal in gin context.
+const principalKey = "principal"
+
+// authMiddleware allows request to continue if any of authenticators authenticates it with scope.
+func authMiddleware(opts *routerOptions, scope string) gin.HandlerFunc {
+	return wrapHandler(opts, func(c *gin.Context) (interface{}, *httpResponseError) {
+		logger := opts.logger.Named("authMiddleware").
+			With("scope", scope).
+			With("path", c.FullPath())
+
+		if opts.cfg.Auth.Disabled {
+			return nil, nil
+		}
+
+		var principal *Principal
+		for _, authenticator := range opts.authenticators {
+			var err error
+			principal, err = authenticator.Authenticate(c.Request)
+			if err != nil {
+				logger.Info("failed to authenticate", "err", err)
+				c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+				return nil, &httpResponseError{
+					Code:    http.StatusUnauthorized,
+					Type:    ErrorTypeClient,
+					Message: errInvalidCredentials.Error(),
+				}
+			}
+			if principal != nil {
+				break
+			}
+		}
+
+		if principal == nil {
+			logger.Info("missing credentials")
+			c.Header("WWW-Authenticate", "Bearer")
+			return nil, &httpResponseError{
+				Code:    http.StatusUnauthorized,
+				Type:    ErrorTypeClient,
+				Message: "missing credentials",
+			}
+		}
+		logger = logger.With("principal", principal.Name)
+
+		if !principal.HasScope(scope) {
+			logger.Info("insufficient scope")
+			c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, scope))
+			return nil, &httpResponseError{
+				Code:    http.StatusForbidden,
+				Type:    ErrorTypeClient,
+				Message: "insufficient scope",
+				Details: fmt.Sprintf("%q scope is required", scope),
+			}
+		}
+
+		c.Set(principalKey, principal)
+		return nil, nil
+	})
+}
+
+type apiKey struct {
+	name   string
+	hash   []byte
+	scopes []string
+}
+
+type apiKeyAuthenticator struct {
+	keys []apiKey
+}
+
+// NewAPIKeyAuthenticator returns authenticator of keys sent in X-API-Key header. Keys are comma separated
+// list of "<name>:<hex sha256 of key>:<space separated scopes>", so that keys themselves are not stored
+// in configuration.
+func NewAPIKeyAuthenticator(keys string) (Authenticator, error) {
+	authenticator := &apiKeyAuthenticator{}
+	for _, entry := range strings.Split(keys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		// scopes may contain colons themselves, so only the first two separate fields
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid api key entry, expected name:sha256:scopes")
+		}
+		hash, err := hex.DecodeString(parts[1])
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("invalid sha256 of api key %q", parts[0])
+		}
+
+		authenticator.keys = append(authenticator.keys, apiKey{
+			name:   parts[0],
+			hash:   hash,
+			scopes: strings.Fields(parts[2]),
+		})
+	}
+
+	return authenticator, nil
+}
+
+func (a *apiKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		return nil, nil
+	}
+
+	// all keys are compared in constant time, so that timing doesn't reveal which of them matched
+	hash := sha256.Sum256([]byte(key))
+	var matched *apiKey
+	for i := range a.keys {
+		if subtle.ConstantTimeCompare(hash[:], a.keys[i].hash) == 1 {
+			matched = &a.keys[i]
+		}
+	}
+	if matched == nil {
+		return nil, errInvalidCredentials
+	}
+
+	return &Principal{
+		Type:   PrincipalTypeAPIKey,
+		Name:   matched.name,
+		Scopes: matched.scopes,
+	}, nil
+}
+
+type JWTOptions struct {
+	// Secret is HMAC secret tokens are signed with using HS256.
+	Secret string
+	// Issuer is required "iss" claim, it's not checked if empty.
+	Issuer string
+	// Audience is required "aud" claim, it's not checked if empty.
+	Audience string
+}
+
+type jwtAuthenticator struct {
+	secret []byte
+	parser *jwt.Parser
+}
+
+// jwtLeeway is allowed clock skew between token issuer and API.
+const jwtLeeway = 30 * time.Second
+
+// NewJWTAuthenticator returns authenticator of bearer tokens in Authorization header. Tokens have to
+// be signed with HS256, have "exp" claim and list scopes in space separated "scope" claim.
+func NewJWTAuthenticator(opts *JWTOptions) (Authenticator, error) {
+	if opts.Secret == "" {
+		return nil, errors.New("jwt secret is empty")
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithLeeway(jwtLeeway),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+
+	return &jwtAuthenticator{
+		secret: []byte(opts.Secret),
+		parser: jwt.NewParser(parserOpts...),
+	}, nil
+}
+
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	Scope string `json:"scope"`
+}
+
+func (a *jwtAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, nil
+	}
+
+	var claims jwtClaims
+	_, err := a.parser.ParseWithClaims(strings.TrimSpace(token), &claims, func(*jwt.Token) (interface{}, error) {
+		return a.secret, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidCredentials, err)
+	}
+	// tokens without expiration would be valid forever if leaked
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: token has no expiration", errInvalidCredentials)
+	}
+	// clients are told apart by subject, e.g. in rate limits
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", errInvalidCredentials)
+	}
+
+	return &Principal{
+		Type:   PrincipalTypeJWT,
+		Name:   claims.Subject,
+		Scopes: strings.Fields(claims.Scope),
+	}, nil
+}
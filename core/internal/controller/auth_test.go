@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/config"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+const (
+	testAPIKey    = "test-api-key"
+	testJWTSecret = "test-jwt-secret"
+)
+
+func newTestAuthRouter(t *testing.T, cfg *config.Config) *gin.Engine {
+	hash := sha256.Sum256([]byte(testAPIKey))
+	apiKeyAuthenticator, err := NewAPIKeyAuthenticator("ci:" + hex.EncodeToString(hash[:]) + ":" + ScopeSendEmails)
+	require.NoError(t, err)
+	jwtAuthenticator, err := NewJWTAuthenticator(&JWTOptions{
+		Secret:   testJWTSecret,
+		Audience: "gses",
+	})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	opts := &routerOptions{
+		router:         r.Group("/api"),
+		cfg:            cfg,
+		logger:         logging.NewZapLogger("debug"),
+		authenticators: []Authenticator{apiKeyAuthenticator, jwtAuthenticator},
+	}
+	opts.router.GET("/protected", authMiddleware(opts, ScopeSendEmails), wrapHandler(opts, func(c *gin.Context) (interface{}, *httpResponseError) {
+		principal, _ := c.Get(principalKey)
+		if principal == nil {
+			return "anonymous", nil
+		}
+		return principal.(*Principal).Name, nil
+	}))
+
+	return r
+}
+
+func newTestJWT(t *testing.T, secret string, claims jwtClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	t.Parallel()
+
+	validClaims := func(scope string) jwtClaims {
+		return jwtClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "admin@test.com",
+				Audience:  jwt.ClaimStrings{"gses"},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+			Scope: scope,
+		}
+	}
+	expiredClaims := validClaims(ScopeSendEmails)
+	expiredClaims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	noExpirationClaims := validClaims(ScopeSendEmails)
+	noExpirationClaims.ExpiresAt = nil
+	otherAudienceClaims := validClaims(ScopeSendEmails)
+	otherAudienceClaims.Audience = jwt.ClaimStrings{"other"}
+	noSubjectClaims := validClaims(ScopeSendEmails)
+	noSubjectClaims.Subject = ""
+
+	testCases := []struct {
+		name         string
+		header       http.Header
+		disabled     bool
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "positive: valid api key",
+			header:       http.Header{"X-Api-Key": {testAPIKey}},
+			expectedCode: http.StatusOK,
+			expectedBody: `"ci"`,
+		},
+		{
+			name: "positive: valid bearer token",
+			header: http.Header{"Authorization": {
+				"Bearer " + newTestJWT(t, testJWTSecret, validClaims("admin "+ScopeSendEmails)),
+			}},
+			expectedCode: http.StatusOK,
+			expectedBody: `"admin@test.com"`,
+		},
+		{
+			name:         "positive: authentication is disabled",
+			disabled:     true,
+			expectedCode: http.StatusOK,
+			expectedBody: `"anonymous"`,
+		},
+		{
+			name:         "negative: missing credentials",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "negative: invalid api key",
+			header:       http.Header{"X-Api-Key": {"wrong"}},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "negative: token signed with another secret",
+			header: http.Header{"Authorization": {
+				"Bearer " + newTestJWT(t, "wrong", validClaims(ScopeSendEmails)),
+			}},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "negative: expired token",
+			header:       http.Header{"Authorization": {"Bearer " + newTestJWT(t, testJWTSecret, expiredClaims)}},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "negative: token without expiration",
+			header:       http.Header{"Authorization": {"Bearer " + newTestJWT(t, testJWTSecret, noExpirationClaims)}},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "negative: token for another audience",
+			header:       http.Header{"Authorization": {"Bearer " + newTestJWT(t, testJWTSecret, otherAudienceClaims)}},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "negative: token without subject",
+			header:       http.Header{"Authorization": {"Bearer " + newTestJWT(t, testJWTSecret, noSubjectClaims)}},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "negative: token without required scope",
+			header:       http.Header{"Authorization": {"Bearer " + newTestJWT(t, testJWTSecret, validClaims(ScopeAdmin))}},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := newTestAuthRouter(t, &config.Config{Auth: config.Auth{Disabled: tc.disabled}})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/protected", nil)
+			for key, values := range tc.header {
+				req.Header[key] = values
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			if tc.expectedCode == http.StatusOK {
+				assert.Equal(t, tc.expectedBody, rec.Body.String())
+			} else {
+				assert.Contains(t, rec.Body.String(), `"code":`)
+				assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestNewAPIKeyAuthenticator_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, keys := range []string{"ci", "ci:not-hex:admin", ":" + hex.EncodeToString(make([]byte, sha256.Size)) + ":admin"} {
+		_, err := NewAPIKeyAuthenticator(keys)
+		assert.Error(t, err, keys)
+	}
+}
@@ -5,11 +5,17 @@ import (
 	"fmt"
 	"net/http"
 	"runtime/debug"
+	"strings"
 
 	"github.com/DataDog/gostackparse"
 	"github.com/gin-gonic/gin"
 	"github.com/vadimpk/gses-2023/core/config"
 	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/core/pkg/challenge"
+	"github.com/vadimpk/gses-2023/core/pkg/ratelimit"
+	"github.com/vadimpk/gses-2023/pkg/apiversion"
+	"github.com/vadimpk/gses-2023/pkg/httpbind"
+	"github.com/vadimpk/gses-2023/pkg/httprender"
 	"github.com/vadimpk/gses-2023/pkg/logging"
 )
 
@@ -17,6 +23,16 @@ type Options struct {
 	Services service.Services
 	Config   *config.Config
 	Logger   logging.Logger
+	// Authenticators check credentials of requests to protected endpoints in order, requests are
+	// rejected if none of them authenticates request, unless authentication is disabled in Config.
+	Authenticators []Authenticator
+	// RateLimitStore keeps counters of RateLimits, requests are not limited if it's nil.
+	RateLimitStore ratelimit.Store
+	RateLimits     *RateLimits
+	// ChallengeVerifier checks challenge solved by clients before subscribing, it's not required if it's nil.
+	ChallengeVerifier challenge.Verifier
+	// UnversionedDeprecation marks routes under "/api" alias of "/api/v1" as deprecated if it's not nil.
+	UnversionedDeprecation *apiversion.Deprecation
 }
 
 type routerContext struct {
@@ -26,23 +42,67 @@ type routerContext struct {
 }
 
 type routerOptions struct {
-	router   *gin.RouterGroup
-	services service.Services
-	cfg      *config.Config
-	logger   logging.Logger
+	router         *gin.RouterGroup
+	services       service.Services
+	cfg            *config.Config
+	logger         logging.Logger
+	authenticators []Authenticator
+	rateLimitStore ratelimit.Store
+	rateLimits     *RateLimits
+	// challengeVerifier is nil if subscribing doesn't require challenge.
+	challengeVerifier challenge.Verifier
 }
 
 func New(opts *Options) *gin.Engine {
 	r := gin.Default()
 
-	routerOptions := routerOptions{
-		router:   r.Group("/api"),
-		services: opts.Services,
-		cfg:      opts.Config,
-		logger:   opts.Logger.Named("HTTPController"),
+	logger := opts.Logger.Named("HTTPController")
+	// client IP is taken from X-Forwarded-For only if request came through trusted proxy, so that
+	// clients can't spoof it to avoid rate limits
+	var trustedProxies []string
+	if opts.Config.App.TrustedProxies != "" {
+		trustedProxies = strings.Split(opts.Config.App.TrustedProxies, ",")
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		logger.Error("failed to set trusted proxies, no proxies are trusted", "err", err)
+		_ = r.SetTrustedProxies(nil)
 	}
 
-	setupEmailRoutes(&routerOptions)
+	doc := newOpenAPIDocument()
+	// routes are served under "/api/v1" and under "/api", which is alias of v1 kept for clients that
+	// don't use versions
+	for _, version := range []struct {
+		basePath    string
+		deprecation *apiversion.Deprecation
+	}{
+		{basePath: apiV1Path},
+		{basePath: apiBasePath, deprecation: opts.UnversionedDeprecation},
+	} {
+		routerOptions := routerOptions{
+			router:         r.Group(version.basePath),
+			services:       opts.Services,
+			cfg:            opts.Config,
+			logger:         logger,
+			authenticators: opts.Authenticators,
+			rateLimitStore: opts.RateLimitStore,
+			rateLimits:     opts.RateLimits,
+
+			challengeVerifier: opts.ChallengeVerifier,
+		}
+		if version.deprecation != nil {
+			routerOptions.router.Use(version.deprecation.Middleware(logger, routerOptions.router))
+		}
+		if routerOptions.rateLimitStore != nil && routerOptions.rateLimits != nil {
+			routerOptions.router.Use(rateLimitMiddleware(&routerOptions))
+		}
+
+		setupEmailRoutes(&routerOptions)
+		setupAlertRoutes(&routerOptions)
+		setupAdminRoutes(&routerOptions)
+		if err := setupDocsRoutes(&routerOptions, doc); err != nil {
+			logger.Error("failed to setup docs routes", "err", err)
+		}
+	}
 
 	return r
 }
@@ -67,6 +127,19 @@ func (e httpResponseError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Type, e.Message)
 }
 
+// bindRequest binds parameters of request to obj and validates them, see httpbind.Bind.
+func bindRequest(c *gin.Context, obj interface{}) *httpResponseError {
+	if err := httpbind.Bind(c, obj); err != nil {
+		return &httpResponseError{
+			Code:    err.Code,
+			Type:    ErrorTypeClient,
+			Message: err.Message,
+			Details: err.Details,
+		}
+	}
+	return nil
+}
+
 func wrapHandler(options *routerOptions, handler func(c *gin.Context) (interface{}, *httpResponseError)) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		logger := options.logger.Named("wrapHandler")
@@ -103,15 +176,18 @@ func wrapHandler(options *routerOptions, handler func(c *gin.Context) (interface
 		if err != nil {
 			if err.Type == ErrorTypeServer {
 				logger.Error("internal server error")
-				c.AbortWithStatusJSON(http.StatusInternalServerError, err)
+				c.Abort()
+				httprender.Render(c, http.StatusInternalServerError, err)
 			} else {
 				logger.Info("client error")
-				c.AbortWithStatusJSON(err.Code, err)
+				c.Abort()
+				httprender.Render(c, err.Code, err)
 			}
 			return
 		}
 
+		// status is 200 unless handler has set another one with c.Status
 		logger.Info("request handled")
-		c.JSON(http.StatusOK, body)
+		httprender.Render(c, c.Writer.Status(), body)
 	}
 }
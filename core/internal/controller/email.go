@@ -3,13 +3,18 @@ package controller
 import (
 	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vadimpk/gses-2023/core/internal/entity"
 	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/core/pkg/challenge"
 )
 
 type emailRoutes struct {
 	routerContext
+	challengeVerifier challenge.Verifier
 }
 
 func setupEmailRoutes(opts *routerOptions) {
@@ -19,38 +24,87 @@ func setupEmailRoutes(opts *routerOptions) {
 			cfg:      opts.cfg,
 			logger:   opts.logger.Named("Email"),
 		},
+		challengeVerifier: opts.challengeVerifier,
 	}
 
 	opts.router.POST("/subscribe", wrapHandler(opts, emailRoutes.subscribe))
-	opts.router.POST("/sendEmails", wrapHandler(opts, emailRoutes.sendRateInfo)) // TODO: add auth
+	if emailRoutes.challengeVerifier != nil {
+		opts.router.GET("/subscribe/challenge", wrapHandler(opts, emailRoutes.subscribeChallenge))
+	}
+	opts.router.GET("/subscribe/confirm", wrapHandler(opts, emailRoutes.confirmSubscription))
+	opts.router.GET("/subscribe/pairs", wrapHandler(opts, emailRoutes.getPairs))
+	opts.router.POST("/subscribe/pairs", wrapHandler(opts, emailRoutes.updatePairs))
+	opts.router.GET("/unsubscribe", wrapHandler(opts, emailRoutes.confirmUnsubscribe))
+	opts.router.POST("/unsubscribe", wrapHandler(opts, emailRoutes.unsubscribe))
+
+	// sending to all subscribers is available only to authenticated clients
+	sendEmailsRouter := opts.router.Group("/sendEmails", authMiddleware(opts, ScopeSendEmails))
+	sendEmailsRouter.POST("", wrapHandler(opts, emailRoutes.sendRateInfo))
+	sendEmailsRouter.GET("/:id", wrapHandler(opts, emailRoutes.getSendJob))
 }
 
 type subscribeRequestBody struct {
-	Email string `form:"email" binding:"required,email"`
+	// Email is validated and normalized by service.
+	Email string `form:"email" json:"email" binding:"required"`
+	// Pairs is comma separated list of currency pairs, e.g. "BTC-USD,ETH-UAH".
+	Pairs string `form:"pairs" json:"pairs"`
+	// Locale is language of emails, e.g. "uk". Accept-Language header is used if it's empty.
+	Locale string `form:"locale" json:"locale"`
+	// Challenge is solution of challenge from /subscribe/challenge, it's required if challenge is enabled.
+	Challenge string `form:"challenge" json:"challenge"`
 }
 
 type subscribeResponseBody struct {
-	Email string `json:"email"`
+	Email  string   `json:"email"`
+	Pairs  []string `json:"pairs,omitempty"`
+	Locale string   `json:"locale,omitempty"`
 }
 
-// TODO: generate swagger
 func (r *emailRoutes) subscribe(c *gin.Context) (interface{}, *httpResponseError) {
 	logger := r.logger.Named("subscribe")
 
 	var query subscribeRequestBody
-	if err := c.ShouldBindQuery(&query); err != nil {
-		logger.Info("failed to bind query", "err", err)
+	if errResp := bindRequest(c, &query); errResp != nil {
+		logger.Info("failed to bind request", "err", errResp)
+		return nil, errResp
+	}
+	logger = logger.With("query", query)
+
+	pairs, err := parseCurrencyPairs(query.Pairs)
+	if err != nil {
+		logger.Info("failed to parse pairs", "err", err)
 		return nil, &httpResponseError{
+			Code:    http.StatusBadRequest,
 			Type:    ErrorTypeClient,
-			Message: "failed to bind query",
+			Message: "failed to parse pairs",
+			Details: err.Error(),
+		}
+	}
+
+	locale, err := parseLocale(query.Locale, c.GetHeader("Accept-Language"))
+	if err != nil {
+		logger.Info("failed to parse locale", "err", err)
+		return nil, &httpResponseError{
+			Code:    http.StatusBadRequest,
+			Type:    ErrorTypeClient,
+			Message: "failed to parse locale",
 			Details: err.Error(),
 		}
 	}
-	logger = logger.With("query", query)
 
-	err := r.services.Email.Subscribe(c.Request.Context(), query.Email)
+	// challenge is checked after input, so that solution isn't spent on request that is rejected anyway
+	if errResp := r.verifyChallenge(c, query.Challenge); errResp != nil {
+		return nil, errResp
+	}
+
+	subscriber, err := r.services.Email.Subscribe(c.Request.Context(), &service.SubscribeOptions{
+		Email:  query.Email,
+		Pairs:  pairs,
+		Locale: locale,
+	})
 	if err != nil {
-		if errors.Is(err, service.ErrSubscribeAlreadySubscribed) {
+		if errors.Is(err, service.ErrSubscribeAlreadySubscribed) ||
+			errors.Is(err, service.ErrSubscribeConfirmationPending) {
 			logger.Info("failed to subscribe", "err", err)
 			return nil, &httpResponseError{
 				Code:    http.StatusConflict,
@@ -58,6 +112,16 @@ func (r *emailRoutes) subscribe(c *gin.Context) (interface{}, *httpResponseError
 				Message: err.Error(),
 			}
 		}
+		if errors.Is(err, service.ErrSubscribeInvalidEmail) ||
+			errors.Is(err, service.ErrSubscribeUndeliverableEmail) ||
+			errors.Is(err, service.ErrSubscribeDisposableEmail) {
+			logger.Info("failed to subscribe", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusBadRequest,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		}
 
 		logger.Error("failed to subscribe", "err", err)
 		return nil, &httpResponseError{
@@ -69,40 +133,517 @@ func (r *emailRoutes) subscribe(c *gin.Context) (interface{}, *httpResponseError
 
 	logger.Info("successfully subscribed")
 	return subscribeResponseBody{
+		Email:  subscriber.Email,
+		Pairs:  formatCurrencyPairs(subscriber.Pairs),
+		Locale: subscriber.Locale.String(),
+	}, nil
+}
+
+// verifyChallenge checks solution of subscribe challenge, it returns nil if challenge is disabled.
+func (r *emailRoutes) verifyChallenge(c *gin.Context, solution string) *httpResponseError {
+	if r.challengeVerifier == nil {
+		return nil
+	}
+	logger := r.logger.Named("verifyChallenge").
+		With("clientIP", c.ClientIP())
+
+	err := r.challengeVerifier.Verify(c.Request.Context(), solution, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, challenge.ErrMissingSolution) || errors.Is(err, challenge.ErrInvalidSolution) {
+			logger.Info("challenge failed", "err", err)
+			return &httpResponseError{
+				Code:    http.StatusForbidden,
+				Type:    ErrorTypeClient,
+				Message: "challenge failed",
+				Details: err.Error(),
+			}
+		}
+
+		logger.Error("failed to verify challenge", "err", err)
+		return &httpResponseError{
+			Type:    ErrorTypeServer,
+			Message: "failed to verify challenge",
+			Details: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+func (r *emailRoutes) subscribeChallenge(c *gin.Context) (interface{}, *httpResponseError) {
+	logger := r.logger.Named("subscribeChallenge")
+
+	ch, err := r.challengeVerifier.Challenge(c.Request.Context())
+	if err != nil {
+		logger.Error("failed to create challenge", "err", err)
+		return nil, &httpResponseError{
+			Type:    ErrorTypeServer,
+			Message: "failed to create challenge",
+			Details: err.Error(),
+		}
+	}
+
+	logger.Info("successfully created challenge")
+	return ch, nil
+}
+
+type confirmSubscriptionRequestQuery struct {
+	Email string `form:"email" json:"email" binding:"required,email"`
+	Token string `form:"token" json:"token" binding:"required"`
+}
+
+type confirmSubscriptionResponseBody struct {
+	Email string `json:"email"`
+}
+
+func (r *emailRoutes) confirmSubscription(c *gin.Context) (interface{}, *httpResponseError) {
+	logger := r.logger.Named("confirmSubscription")
+
+	var query confirmSubscriptionRequestQuery
+	if errResp := bindRequest(c, &query); errResp != nil {
+		logger.Info("failed to bind request", "err", errResp)
+		return nil, errResp
+	}
+	logger = logger.With("email", query.Email)
+
+	err := r.services.Email.ConfirmSubscription(c.Request.Context(), query.Email, query.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrConfirmSubscriptionInvalidToken):
+			logger.Info("failed to confirm subscription", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusForbidden,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		case errors.Is(err, service.ErrConfirmSubscriptionTokenExpired):
+			logger.Info("failed to confirm subscription", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusGone,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		case errors.Is(err, service.ErrConfirmSubscriptionNotFound):
+			logger.Info("failed to confirm subscription", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusNotFound,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		}
+
+		logger.Error("failed to confirm subscription", "err", err)
+		return nil, &httpResponseError{
+			Type:    ErrorTypeServer,
+			Message: "failed to confirm subscription",
+			Details: err.Error(),
+		}
+	}
+
+	logger.Info("successfully confirmed subscription")
+	return confirmSubscriptionResponseBody{
 		Email: query.Email,
 	}, nil
 }
 
 type sendRateInfoResponseBody struct {
-	FailedEmails []string `json:"failed_emails"`
+	JobID string `json:"job_id"`
+	State string `json:"state"`
 }
 
-// TODO: generate swagger
 func (r *emailRoutes) sendRateInfo(c *gin.Context) (interface{}, *httpResponseError) {
 	logger := r.logger.Named("sendRateInfo")
 
-	output, err := r.services.Email.SendRateInfo(c.Request.Context())
+	job, err := r.services.SendJob.Start(c.Request.Context())
 	if err != nil {
-		if errors.Is(err, service.ErrSendRateInfoFailedToSendToAllEmails) {
-			logger.Info("failed to send rate info", "err", err)
+		if errors.Is(err, service.ErrSendJobInProgress) {
+			logger.Info("failed to start send job", "err", err)
 			return nil, &httpResponseError{
-				Code:    http.StatusServiceUnavailable,
+				Code:    http.StatusConflict,
 				Type:    ErrorTypeClient,
 				Message: err.Error(),
 			}
 		}
-
-		logger.Error("failed to send rate info", "err", err)
+		logger.Error("failed to start send job", "err", err)
 		return nil, &httpResponseError{
 			Type:    ErrorTypeServer,
-			Message: "failed to send rate info",
+			Message: "failed to start send job",
 			Details: err.Error(),
 		}
 	}
-	logger = logger.With("output", output)
+	logger = logger.With("jobID", job.ID)
 
-	logger.Info("successfully sent rate info")
+	logger.Info("successfully started send job")
+	c.Status(http.StatusAccepted)
 	return sendRateInfoResponseBody{
-		FailedEmails: output.FailedEmails,
+		JobID: job.ID,
+		State: job.State.String(),
+	}, nil
+}
+
+type getSendJobResponseBody struct {
+	ID           string    `json:"id"`
+	State        string    `json:"state"`
+	Total        int       `json:"total"`
+	Sent         int       `json:"sent"`
+	Failed       int       `json:"failed"`
+	FailedEmails []string  `json:"failed_emails"`
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (r *emailRoutes) getSendJob(c *gin.Context) (interface{}, *httpResponseError) {
+	logger := r.logger.Named("getSendJob").
+		With("id", c.Param("id"))
+
+	job, err := r.services.SendJob.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, service.ErrSendJobNotFound) {
+			logger.Info("failed to get send job", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusNotFound,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		}
+
+		logger.Error("failed to get send job", "err", err)
+		return nil, &httpResponseError{
+			Type:    ErrorTypeServer,
+			Message: "failed to get send job",
+			Details: err.Error(),
+		}
+	}
+
+	logger.Info("successfully got send job")
+	return getSendJobResponseBody{
+		ID:           job.ID,
+		State:        job.State.String(),
+		Total:        job.Total,
+		Sent:         job.Sent,
+		Failed:       job.Failed,
+		FailedEmails: job.FailedEmails,
+		Error:        job.Error,
+		CreatedAt:    job.CreatedAt,
+		UpdatedAt:    job.UpdatedAt,
+	}, nil
+}
+
+type unsubscribeRequestQuery struct {
+	Email string `form:"email" json:"email" binding:"required,email"`
+	Token string `form:"token" json:"token" binding:"required"`
+}
+
+type unsubscribeResponseBody struct {
+	Email string `json:"email"`
+	// Unsubscribed is false until unsubscribing is confirmed with POST.
+	Unsubscribed bool `json:"unsubscribed"`
+}
+
+// unsubscribePageData is passed to unsubscribe page, which lets subscriber confirm unsubscribing in browser.
+type unsubscribePageData struct {
+	Email string
+	Token string
+	// Action is path form is posted to.
+	Action       string
+	Unsubscribed bool
+}
+
+// confirmUnsubscribe checks link from email without unsubscribing, so that link is safe to be opened by link
+// scanners. Unsubscribing is done with POST to the same link, e.g. by mail client supporting one-click
+// unsubscribe (RFC 8058) or by form of page that browsers get.
+func (r *emailRoutes) confirmUnsubscribe(c *gin.Context) (interface{}, *httpResponseError) {
+	logger := r.logger.Named("confirmUnsubscribe")
+
+	var query unsubscribeRequestQuery
+	if errResp := bindRequest(c, &query); errResp != nil {
+		logger.Info("failed to bind request", "err", errResp)
+		return nil, errResp
+	}
+	logger = logger.With("email", query.Email)
+
+	err := r.services.Email.VerifyUnsubscribeToken(query.Email, query.Token)
+	if err != nil {
+		logger.Info("invalid unsubscribe token", "err", err)
+		return nil, &httpResponseError{
+			Code:    http.StatusForbidden,
+			Type:    ErrorTypeClient,
+			Message: err.Error(),
+		}
+	}
+
+	logger.Info("successfully verified unsubscribe token")
+	if prefersHTML(c) {
+		return renderPage(c, "unsubscribe.html.tmpl", unsubscribePageData{
+			Email:  query.Email,
+			Token:  query.Token,
+			Action: c.Request.URL.Path,
+		})
+	}
+	return unsubscribeResponseBody{
+		Email: query.Email,
+	}, nil
+}
+
+func (r *emailRoutes) unsubscribe(c *gin.Context) (interface{}, *httpResponseError) {
+	logger := r.logger.Named("unsubscribe")
+
+	var query unsubscribeRequestQuery
+	if errResp := bindRequest(c, &query); errResp != nil {
+		logger.Info("failed to bind request", "err", errResp)
+		return nil, errResp
+	}
+	logger = logger.With("email", query.Email)
+
+	err := r.services.Email.VerifyUnsubscribeToken(query.Email, query.Token)
+	if err != nil {
+		logger.Info("invalid unsubscribe token", "err", err)
+		return nil, &httpResponseError{
+			Code:    http.StatusForbidden,
+			Type:    ErrorTypeClient,
+			Message: err.Error(),
+		}
+	}
+
+	err = r.services.Email.Unsubscribe(c.Request.Context(), query.Email)
+	if err != nil {
+		if errors.Is(err, service.ErrUnsubscribeNotSubscribed) {
+			logger.Info("failed to unsubscribe", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusNotFound,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		}
+
+		logger.Error("failed to unsubscribe", "err", err)
+		return nil, &httpResponseError{
+			Type:    ErrorTypeServer,
+			Message: "failed to unsubscribe",
+			Details: err.Error(),
+		}
+	}
+
+	logger.Info("successfully unsubscribed")
+	if prefersHTML(c) {
+		return renderPage(c, "unsubscribe.html.tmpl", unsubscribePageData{
+			Email:        query.Email,
+			Unsubscribed: true,
+		})
+	}
+	return unsubscribeResponseBody{
+		Email:        query.Email,
+		Unsubscribed: true,
 	}, nil
 }
+
+type getPairsRequestQuery struct {
+	Email string `form:"email" json:"email" binding:"required,email"`
+	Token string `form:"token" json:"token" binding:"required"`
+}
+
+type pairsResponseBody struct {
+	Email string   `json:"email"`
+	Pairs []string `json:"pairs"`
+}
+
+// preferencesPageData is passed to preferences page, which lets subscriber change pairs in browser.
+type preferencesPageData struct {
+	Email string
+	Token string
+	// Action is path form is posted to.
+	Action string
+	// Pairs are current pairs separated by commas.
+	Pairs          string
+	SupportedPairs string
+	// Updated is true if page is shown after pairs were changed.
+	Updated bool
+}
+
+func newPreferencesPageData(c *gin.Context, email, token string, pairs []entity.CurrencyPair, updated bool) preferencesPageData {
+	return preferencesPageData{
+		Email:          email,
+		Token:          token,
+		Action:         c.Request.URL.Path,
+		Pairs:          strings.Join(formatCurrencyPairs(pairs), ","),
+		SupportedPairs: strings.Join(formatCurrencyPairs(entity.SupportedCurrencyPairs()), ", "),
+		Updated:        updated,
+	}
+}
+
+// getPairs returns pairs of subscriber without changing them, so that link from email is safe to be opened
+// by link scanners. Browsers get page with form that changes pairs.
+func (r *emailRoutes) getPairs(c *gin.Context) (interface{}, *httpResponseError) {
+	logger := r.logger.Named("getPairs")
+
+	var query getPairsRequestQuery
+	if errResp := bindRequest(c, &query); errResp != nil {
+		logger.Info("failed to bind request", "err", errResp)
+		return nil, errResp
+	}
+	logger = logger.With("email", query.Email)
+
+	pairs, err := r.services.Email.GetPairs(c.Request.Context(), query.Email, query.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUpdatePairsInvalidToken):
+			logger.Info("failed to get pairs", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusForbidden,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		case errors.Is(err, service.ErrUpdatePairsNotSubscribed):
+			logger.Info("failed to get pairs", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusNotFound,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		}
+
+		logger.Error("failed to get pairs", "err", err)
+		return nil, &httpResponseError{
+			Type:    ErrorTypeServer,
+			Message: "failed to get pairs",
+			Details: err.Error(),
+		}
+	}
+
+	logger.Info("successfully got pairs")
+	if prefersHTML(c) {
+		return renderPage(c, "preferences.html.tmpl", newPreferencesPageData(c, query.Email, query.Token, pairs, false))
+	}
+	return pairsResponseBody{
+		Email: query.Email,
+		Pairs: formatCurrencyPairs(pairs),
+	}, nil
+}
+
+type updatePairsRequestQuery struct {
+	Email string `form:"email" json:"email" binding:"required,email"`
+	Token string `form:"token" json:"token" binding:"required"`
+	// Pairs is comma separated list of currency pairs, e.g. "BTC-USD,ETH-UAH".
+	Pairs string `form:"pairs" json:"pairs" binding:"required"`
+}
+
+func (r *emailRoutes) updatePairs(c *gin.Context) (interface{}, *httpResponseError) {
+	logger := r.logger.Named("updatePairs")
+
+	var query updatePairsRequestQuery
+	if errResp := bindRequest(c, &query); errResp != nil {
+		logger.Info("failed to bind request", "err", errResp)
+		return nil, errResp
+	}
+	logger = logger.With("email", query.Email).With("pairs", query.Pairs)
+
+	pairs, err := parseCurrencyPairs(query.Pairs)
+	if err != nil {
+		logger.Info("failed to parse pairs", "err", err)
+		return nil, &httpResponseError{
+			Code:    http.StatusBadRequest,
+			Type:    ErrorTypeClient,
+			Message: "failed to parse pairs",
+			Details: err.Error(),
+		}
+	}
+
+	err = r.services.Email.UpdatePairs(c.Request.Context(), &service.UpdatePairsOptions{
+		Email: query.Email,
+		Token: query.Token,
+		Pairs: pairs,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUpdatePairsInvalidToken):
+			logger.Info("failed to update pairs", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusForbidden,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		case errors.Is(err, service.ErrUpdatePairsNotSubscribed):
+			logger.Info("failed to update pairs", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusNotFound,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		case errors.Is(err, service.ErrUpdatePairsNoPairs):
+			logger.Info("failed to update pairs", "err", err)
+			return nil, &httpResponseError{
+				Code:    http.StatusBadRequest,
+				Type:    ErrorTypeClient,
+				Message: err.Error(),
+			}
+		}
+
+		logger.Error("failed to update pairs", "err", err)
+		return nil, &httpResponseError{
+			Type:    ErrorTypeServer,
+			Message: "failed to update pairs",
+			Details: err.Error(),
+		}
+	}
+
+	logger.Info("successfully updated pairs")
+	if prefersHTML(c) {
+		return renderPage(c, "preferences.html.tmpl", newPreferencesPageData(c, query.Email, query.Token, pairs, true))
+	}
+	return pairsResponseBody{
+		Email: query.Email,
+		Pairs: formatCurrencyPairs(pairs),
+	}, nil
+}
+
+// parseCurrencyPairs parses comma separated list of currency pairs. Empty string results in no pairs,
+// duplicate pairs are returned once.
+func parseCurrencyPairs(s string) ([]entity.CurrencyPair, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(s, ",")
+	pairs := make([]entity.CurrencyPair, 0, len(parts))
+	seen := make(map[entity.CurrencyPair]bool, len(parts))
+	for _, part := range parts {
+		pair, err := entity.ParseCurrencyPair(part)
+		if err != nil {
+			return nil, err
+		}
+		if seen[pair] {
+			continue
+		}
+		seen[pair] = true
+		pairs = append(pairs, pair)
+	}
+
+	return pairs, nil
+}
+
+func formatCurrencyPairs(pairs []entity.CurrencyPair) []string {
+	formatted := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		formatted = append(formatted, pair.String())
+	}
+	return formatted
+}
+
+// parseLocale parses locale chosen explicitly. If it's empty, the first supported language from
+// Accept-Language header is used, and if there is none, entity.DefaultLocale is returned.
+func parseLocale(locale, acceptLanguage string) (entity.Locale, error) {
+	if strings.TrimSpace(locale) != "" {
+		return entity.ParseLocale(locale)
+	}
+
+	// languages are expected to be listed by preference, so quality values are ignored
+	for _, tag := range strings.Split(acceptLanguage, ",") {
+		language, _, _ := strings.Cut(tag, ";")
+		if parsed, err := entity.ParseLocale(language); err == nil {
+			return parsed, nil
+		}
+	}
+
+	return entity.DefaultLocale, nil
+}
@@ -0,0 +1,234 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/config"
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/core/pkg/challenge"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+// subscribeEmailService implements only Subscribe of service.EmailService.
+type subscribeEmailService struct {
+	service.EmailService
+	subscribed []string
+}
+
+func (s *subscribeEmailService) Subscribe(ctx context.Context, opts *service.SubscribeOptions) (*entity.Subscriber, error) {
+	s.subscribed = append(s.subscribed, opts.Email)
+	return &entity.Subscriber{Email: opts.Email, Pairs: opts.Pairs, Locale: opts.Locale}, nil
+}
+
+func TestSubscribeChallenge(t *testing.T) {
+	t.Parallel()
+
+	emailService := &subscribeEmailService{}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	setupEmailRoutes(&routerOptions{
+		router:   r.Group("/api"),
+		services: service.Services{Email: emailService},
+		cfg:      &config.Config{},
+		logger:   logging.NewZapLogger("debug"),
+		challengeVerifier: challenge.NewProofOfWork(&challenge.ProofOfWorkOptions{
+			Secret:     "secret",
+			Difficulty: 8,
+			TTL:        time.Minute,
+		}),
+	})
+
+	subscribe := func(solution string) *httptest.ResponseRecorder {
+		query := url.Values{"email": {"user@example.com"}, "challenge": {solution}}
+		req := httptest.NewRequest(http.MethodPost, "/api/subscribe?"+query.Encode(), nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := subscribe("")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), challenge.ErrMissingSolution.Error())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/subscribe/challenge", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var ch challenge.Challenge
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &ch))
+	assert.Equal(t, challenge.TypeProofOfWork, ch.Type)
+
+	solution := challenge.Solve(&ch)
+	assert.Equal(t, http.StatusOK, subscribe(solution).Code)
+	// solution can't be replayed
+	assert.Equal(t, http.StatusForbidden, subscribe(solution).Code)
+	assert.Equal(t, []string{"user@example.com"}, emailService.subscribed)
+}
+
+// pairsEmailService implements only GetPairs and UpdatePairs of service.EmailService.
+type pairsEmailService struct {
+	service.EmailService
+	pairs   []entity.CurrencyPair
+	updates int
+}
+
+func (s *pairsEmailService) GetPairs(ctx context.Context, email, token string) ([]entity.CurrencyPair, error) {
+	if token != "token" {
+		return nil, service.ErrUpdatePairsInvalidToken
+	}
+	return s.pairs, nil
+}
+
+func (s *pairsEmailService) UpdatePairs(ctx context.Context, opts *service.UpdatePairsOptions) error {
+	if opts.Token != "token" {
+		return service.ErrUpdatePairsInvalidToken
+	}
+	s.pairs = opts.Pairs
+	s.updates++
+	return nil
+}
+
+func TestPairs(t *testing.T) {
+	t.Parallel()
+
+	emailService := &pairsEmailService{
+		pairs: []entity.CurrencyPair{entity.DefaultCurrencyPair},
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	setupEmailRoutes(&routerOptions{
+		router:   r.Group("/api"),
+		services: service.Services{Email: emailService},
+		cfg:      &config.Config{},
+		logger:   logging.NewZapLogger("debug"),
+	})
+
+	serve := func(method, accept string, query, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/subscribe/pairs?"+query.Encode(), strings.NewReader(form.Encode()))
+		if form != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	link := url.Values{"email": {"user@example.com"}, "token": {"token"}}
+	browserAccept := "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+	// link from email doesn't change anything, even without pairs
+	rec := serve(http.MethodGet, "", link, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"email": "user@example.com", "pairs": ["BTC-USD"]}`, rec.Body.String())
+
+	rec = serve(http.MethodGet, browserAccept, link, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, rec.Body.String(), `<form method="post" action="/api/subscribe/pairs">`)
+	assert.Contains(t, rec.Body.String(), `value="BTC-USD"`)
+	assert.NotContains(t, rec.Body.String(), "Currency pairs are updated")
+
+	rec = serve(http.MethodGet, "", url.Values{"email": {"user@example.com"}, "token": {"other"}}, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Zero(t, emailService.updates)
+
+	// duplicate pairs are saved once
+	rec = serve(http.MethodPost, "", link, url.Values{"pairs": {"ETH-UAH,btc-usd,eth-uah"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"email": "user@example.com", "pairs": ["ETH-UAH", "BTC-USD"]}`, rec.Body.String())
+	assert.Equal(t, []entity.CurrencyPair{
+		{Crypto: entity.CryptoCurrencyETH, Fiat: entity.FiatCurrencyUAH},
+		entity.DefaultCurrencyPair,
+	}, emailService.pairs)
+
+	// form of page is posted by browser
+	form := url.Values{"email": {"user@example.com"}, "token": {"token"}, "pairs": {"ETH-USD"}}
+	rec = serve(http.MethodPost, browserAccept, nil, form)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Currency pairs are updated")
+	assert.Contains(t, rec.Body.String(), `value="ETH-USD"`)
+	assert.Equal(t, 2, emailService.updates)
+}
+
+// unsubscribeEmailService implements only VerifyUnsubscribeToken and Unsubscribe of service.EmailService.
+type unsubscribeEmailService struct {
+	service.EmailService
+	unsubscribed []string
+}
+
+func (s *unsubscribeEmailService) VerifyUnsubscribeToken(email, token string) error {
+	if token != "token" {
+		return service.ErrUnsubscribeInvalidToken
+	}
+	return nil
+}
+
+func (s *unsubscribeEmailService) Unsubscribe(ctx context.Context, email string) error {
+	s.unsubscribed = append(s.unsubscribed, email)
+	return nil
+}
+
+func TestUnsubscribe(t *testing.T) {
+	t.Parallel()
+
+	emailService := &unsubscribeEmailService{}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	setupEmailRoutes(&routerOptions{
+		router:   r.Group("/api"),
+		services: service.Services{Email: emailService},
+		cfg:      &config.Config{},
+		logger:   logging.NewZapLogger("debug"),
+	})
+
+	serve := func(method, accept string, query, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/unsubscribe?"+query.Encode(), strings.NewReader(form.Encode()))
+		if form != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	link := url.Values{"email": {"user@example.com"}, "token": {"token"}}
+	browserAccept := "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+	// link from email doesn't unsubscribe, so that link scanners can open it
+	rec := serve(http.MethodGet, "", link, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"email": "user@example.com", "unsubscribed": false}`, rec.Body.String())
+
+	rec = serve(http.MethodGet, browserAccept, link, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, rec.Body.String(), `<form method="post" action="/api/unsubscribe">`)
+
+	rec = serve(http.MethodGet, "", url.Values{"email": {"user@example.com"}, "token": {"other"}}, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Empty(t, emailService.unsubscribed)
+
+	// one-click unsubscribe posts to link from email
+	rec = serve(http.MethodPost, "", link, url.Values{"List-Unsubscribe": {"One-Click"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"email": "user@example.com", "unsubscribed": true}`, rec.Body.String())
+
+	// form of page is posted by browser
+	rec = serve(http.MethodPost, browserAccept, nil, link)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "is unsubscribed")
+	assert.NotContains(t, rec.Body.String(), "<form")
+	assert.Equal(t, []string{"user@example.com", "user@example.com"}, emailService.unsubscribed)
+}
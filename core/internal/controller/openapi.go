@@ -0,0 +1,260 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vadimpk/gses-2023/core/pkg/challenge"
+	"github.com/vadimpk/gses-2023/pkg/openapi"
+)
+
+const (
+	openAPIPath   = "/openapi.json"
+	swaggerUIPath = "/docs"
+	// swaggerUIAssetsPath is where styles and scripts of Swagger UI are served, so that it works offline.
+	swaggerUIAssetsPath = swaggerUIPath + "/assets"
+
+	securityAPIKey = "apiKey"
+	securityBearer = "bearer"
+)
+
+// newOpenAPIDocument describes every route of router. Routes are described here rather than next to
+// handlers, so that TestOpenAPIDocument catches routes that were added without description.
+func newOpenAPIDocument() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "gses-2023 core API",
+		Description: "Subscriptions to currency rate newsletter and alerts. Routes are also served under unversioned \"/api\" prefix, which is alias of \"/api/v1\".",
+		Version:     "1.0.0",
+	}, httpResponseError{})
+
+	doc.Components.SecuritySchemes[securityAPIKey] = &openapi.SecurityScheme{
+		Type:        "apiKey",
+		Description: "API key configured in GSES_AUTH_API_KEYS.",
+		Name:        "X-API-Key",
+		In:          "header",
+	}
+	doc.Components.SecuritySchemes[securityBearer] = &openapi.SecurityScheme{
+		Type:         "http",
+		Description:  "HS256 token with scopes in \"scope\" claim.",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+	}
+	security := []string{securityAPIKey, securityBearer}
+
+	// every route can be rate limited and fail, and routes with parameters reject invalid ones
+	errs := func(codes ...int) []int {
+		return append(codes, http.StatusTooManyRequests, http.StatusInternalServerError)
+	}
+	inputErrs := func(codes ...int) []int {
+		return errs(append(codes, http.StatusBadRequest, http.StatusUnsupportedMediaType)...)
+	}
+	authErrs := func(codes ...int) []int {
+		return append(codes, http.StatusUnauthorized, http.StatusForbidden)
+	}
+
+	// email routes
+	doc.Add(openapi.Route{
+		Method:      http.MethodPost,
+		Path:        "/api/v1/subscribe",
+		Summary:     "Subscribe to newsletter",
+		Description: "Creates pending subscription and sends confirmation email. Email is normalized, invalid, undeliverable and disposable emails are rejected.",
+		Tags:        []string{"subscription"},
+		Request:     subscribeRequestBody{},
+		Response:    subscribeResponseBody{},
+		Errors:      inputErrs(http.StatusForbidden, http.StatusConflict),
+	})
+	doc.Add(openapi.Route{
+		Method:      http.MethodGet,
+		Path:        "/api/v1/subscribe/challenge",
+		Summary:     "Get subscribe challenge",
+		Description: "Returns challenge whose solution has to be sent to subscribe. Available when challenge is enabled.",
+		Tags:        []string{"subscription"},
+		Response:    challenge.Challenge{},
+		Errors:      errs(),
+	})
+	doc.Add(openapi.Route{
+		Method:   http.MethodGet,
+		Path:     "/api/v1/subscribe/confirm",
+		Summary:  "Confirm subscription",
+		Tags:     []string{"subscription"},
+		Request:  confirmSubscriptionRequestQuery{},
+		Response: confirmSubscriptionResponseBody{},
+		Errors:   inputErrs(http.StatusForbidden, http.StatusNotFound, http.StatusGone),
+	})
+	doc.Add(openapi.Route{
+		Method:      http.MethodGet,
+		Path:        "/api/v1/unsubscribe",
+		Summary:     "Check unsubscribe link",
+		Description: "Checks link without unsubscribing, browsers that prefer HTML get page with form that unsubscribes.",
+		Tags:        []string{"subscription"},
+		Request:     unsubscribeRequestQuery{},
+		Response:    unsubscribeResponseBody{},
+		Errors:      inputErrs(http.StatusForbidden),
+	})
+	doc.Add(openapi.Route{
+		Method:      http.MethodPost,
+		Path:        "/api/v1/unsubscribe",
+		Summary:     "Unsubscribe from newsletter",
+		Description: "Supports one-click unsubscribe (RFC 8058) with link from email.",
+		Tags:        []string{"subscription"},
+		Request:     unsubscribeRequestQuery{},
+		Response:    unsubscribeResponseBody{},
+		Errors:      inputErrs(http.StatusForbidden, http.StatusNotFound),
+	})
+	doc.Add(openapi.Route{
+		Method:      http.MethodGet,
+		Path:        "/api/v1/subscribe/pairs",
+		Summary:     "Get currency pairs",
+		Description: "Returns pairs without changing them, browsers that prefer HTML get page with form that changes them.",
+		Tags:        []string{"subscription"},
+		Request:     getPairsRequestQuery{},
+		Response:    pairsResponseBody{},
+		Errors:      inputErrs(http.StatusForbidden, http.StatusNotFound),
+	})
+	doc.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/subscribe/pairs",
+		Summary:  "Change currency pairs",
+		Tags:     []string{"subscription"},
+		Request:  updatePairsRequestQuery{},
+		Response: pairsResponseBody{},
+		Errors:   inputErrs(http.StatusForbidden, http.StatusNotFound),
+	})
+	doc.Add(openapi.Route{
+		Method:      http.MethodPost,
+		Path:        "/api/v1/sendEmails",
+		Summary:     "Send rate to all subscribers",
+		Description: "Starts job sending current rate to all confirmed subscribers in background. Only one job runs at a time. Requires \"emails:send\" scope.",
+		Tags:        []string{"newsletter"},
+		Response:    sendRateInfoResponseBody{},
+		Status:      http.StatusAccepted,
+		Errors:      errs(authErrs(http.StatusConflict)...),
+		Security:    security,
+	})
+	doc.Add(openapi.Route{
+		Method:      http.MethodGet,
+		Path:        "/api/v1/sendEmails/:id",
+		Summary:     "Get progress of sending job",
+		Description: "Requires \"emails:send\" scope.",
+		Tags:        []string{"newsletter"},
+		Response:    getSendJobResponseBody{},
+		Errors:      errs(authErrs(http.StatusNotFound)...),
+		Security:    security,
+	})
+
+	// alert routes
+	doc.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/alerts",
+		Summary:  "Create rate alert",
+		Tags:     []string{"alerts"},
+		Request:  createAlertRequestQuery{},
+		Response: alertResponseBody{},
+		Errors:   inputErrs(http.StatusForbidden, http.StatusNotFound, http.StatusConflict),
+	})
+	doc.Add(openapi.Route{
+		Method:   http.MethodGet,
+		Path:     "/api/v1/alerts",
+		Summary:  "List rate alerts",
+		Tags:     []string{"alerts"},
+		Request:  alertsRequestQuery{},
+		Response: listAlertsResponseBody{},
+		Errors:   inputErrs(http.StatusForbidden),
+	})
+	doc.Add(openapi.Route{
+		Method:   http.MethodDelete,
+		Path:     "/api/v1/alerts/:id",
+		Summary:  "Remove rate alert",
+		Tags:     []string{"alerts"},
+		Request:  alertsRequestQuery{},
+		Response: deleteAlertResponseBody{},
+		Errors:   inputErrs(http.StatusForbidden, http.StatusNotFound),
+	})
+
+	// admin routes
+	doc.Add(openapi.Route{
+		Method:   http.MethodGet,
+		Path:     "/api/v1/admin/deadLetters",
+		Summary:  "List emails that failed to be sent",
+		Tags:     []string{"admin"},
+		Response: listDeadLettersResponseBody{},
+		Errors:   errs(authErrs()...),
+		Security: security,
+	})
+	doc.Add(openapi.Route{
+		Method:      http.MethodPost,
+		Path:        "/api/v1/admin/deadLetters/redrive",
+		Summary:     "Send failed emails again",
+		Description: "Letters of recipients that are no longer confirmed subscribers are dropped without sending, selected ids that are not in the list are reported as dropped.",
+		Tags:        []string{"admin"},
+		Request:     redriveDeadLettersRequestQuery{},
+		Response:    redriveDeadLettersResponseBody{},
+		Errors:      inputErrs(authErrs()...),
+		Security:    security,
+	})
+	doc.Add(openapi.Route{
+		Method:   http.MethodGet,
+		Path:     "/api/v1/admin/subscribers",
+		Summary:  "List subscribers",
+		Tags:     []string{"admin"},
+		Request:  listSubscribersRequestQuery{},
+		Response: listSubscribersResponseBody{},
+		Errors:   inputErrs(authErrs()...),
+		Security: security,
+	})
+	doc.Add(openapi.Route{
+		Method:      http.MethodPost,
+		Path:        "/api/v1/admin/subscribers/import",
+		Summary:     "Import subscribers",
+		Description: "Imports up to 10000 subscribers, existing emails are skipped. Subscribers with invalid email, status, pairs or locale are listed in invalid and don't fail the rest.",
+		Tags:        []string{"admin"},
+		Request:     importSubscribersRequestBody{},
+		Response:    importSubscribersResponseBody{},
+		Errors:      inputErrs(authErrs()...),
+		Security:    security,
+	})
+	doc.Add(openapi.Route{
+		Method:   http.MethodGet,
+		Path:     "/api/v1/admin/subscribers/:email",
+		Summary:  "Get subscriber",
+		Tags:     []string{"admin"},
+		Response: subscriberResponseBody{},
+		Errors:   errs(authErrs(http.StatusNotFound)...),
+		Security: security,
+	})
+	doc.Add(openapi.Route{
+		Method:   http.MethodDelete,
+		Path:     "/api/v1/admin/subscribers/:email",
+		Summary:  "Remove subscriber",
+		Tags:     []string{"admin"},
+		Response: deleteSubscriberResponseBody{},
+		Errors:   errs(authErrs(http.StatusNotFound)...),
+		Security: security,
+	})
+
+	return doc
+}
+
+// setupDocsRoutes serves OpenAPI document of API and Swagger UI that shows it.
+func setupDocsRoutes(opts *routerOptions, doc *openapi.Document) error {
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal openapi document: %w", err)
+	}
+	page, err := openapi.SwaggerUI(doc.Info.Title, opts.router.BasePath()+openAPIPath, opts.router.BasePath()+swaggerUIAssetsPath)
+	if err != nil {
+		return fmt.Errorf("failed to render swagger ui: %w", err)
+	}
+
+	opts.router.GET(openAPIPath, func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+	})
+	opts.router.GET(swaggerUIPath, func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+	})
+	opts.router.StaticFS(swaggerUIAssetsPath, http.FS(openapi.SwaggerUIAssets))
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/config"
+	"github.com/vadimpk/gses-2023/core/pkg/challenge"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+	"github.com/vadimpk/gses-2023/pkg/openapi"
+)
+
+// newTestRouter returns router with every optional route enabled.
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	return New(&Options{
+		Config: &config.Config{},
+		Logger: logging.NewZapLogger("debug"),
+		ChallengeVerifier: challenge.NewProofOfWork(&challenge.ProofOfWorkOptions{
+			Secret:     "secret",
+			Difficulty: 1,
+			TTL:        time.Minute,
+		}),
+	})
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	t.Parallel()
+
+	r := newTestRouter()
+	doc := newOpenAPIDocument()
+
+	routes := make(map[string]bool)
+	for _, route := range r.Routes() {
+		// unversioned routes are aliases of v1 routes
+		path := route.Path
+		if !strings.HasPrefix(path, apiV1Path+"/") {
+			path = apiV1Path + strings.TrimPrefix(path, apiBasePath)
+		}
+		if path == apiV1Path+openAPIPath || path == apiV1Path+swaggerUIPath ||
+			strings.HasPrefix(path, apiV1Path+swaggerUIAssetsPath+"/") {
+			continue
+		}
+
+		routes[route.Method+" "+path] = true
+		assert.True(t, doc.Has(route.Method, path), "route %s %s is not described in openapi document", route.Method, route.Path)
+	}
+
+	// document doesn't describe removed routes either
+	assert.Len(t, doc.Operations(), len(routes))
+}
+
+func TestDocsRoutes(t *testing.T) {
+	t.Parallel()
+
+	r := newTestRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var doc openapi.Document
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+
+	subscribe := doc.Paths["/api/v1/subscribe"]["post"]
+	require.NotNil(t, subscribe)
+	assert.Equal(t, "#/components/schemas/SubscribeRequestBody", subscribe.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Equal(t, []string{"email"}, doc.Components.Schemas["SubscribeRequestBody"].Required)
+	assert.Contains(t, subscribe.Responses, "409")
+
+	listSubscribers := doc.Paths["/api/v1/admin/subscribers"]["get"]
+	require.NotNil(t, listSubscribers)
+	require.Len(t, listSubscribers.Parameters, 4)
+	assert.Equal(t, "status", listSubscribers.Parameters[1].Name)
+	assert.Equal(t, []string{"pending", "confirmed"}, listSubscribers.Parameters[1].Schema.Enum)
+	assert.Equal(t, 1000.0, *listSubscribers.Parameters[3].Schema.Maximum)
+	assert.Len(t, listSubscribers.Security, 2)
+
+	getSubscriber := doc.Paths["/api/v1/admin/subscribers/{email}"]["get"]
+	require.NotNil(t, getSubscriber)
+	assert.Equal(t, "path", getSubscriber.Parameters[0].In)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/docs", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `data-spec-url="/api/openapi.json"`)
+	assert.Contains(t, rec.Body.String(), `src="/api/docs/assets/swagger-ui-bundle.js"`)
+	assert.NotContains(t, rec.Body.String(), "https://")
+
+	// assets are served by service itself
+	for _, asset := range []string{"swagger-ui.css", "swagger-ui-bundle.js", "swagger-initializer.js"} {
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/docs/assets/"+asset, nil)
+		rec = httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code, asset)
+		assert.NotEmpty(t, rec.Body.Bytes(), asset)
+	}
+}
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// pages are shown to subscribers who follow links from emails in browser, API clients get the same data as
+// JSON or XML.
+//
+//go:embed pages/*.html.tmpl
+var pagesFS embed.FS
+
+var pages = template.Must(template.ParseFS(pagesFS, "pages/*.html.tmpl"))
+
+// prefersHTML returns true if client prefers HTML to JSON, e.g. it's browser of subscriber.
+func prefersHTML(c *gin.Context) bool {
+	return c.NegotiateFormat(binding.MIMEJSON, binding.MIMEHTML) == binding.MIMEHTML
+}
+
+// renderPage writes page with data. Handlers return its result, so that wrapHandler doesn't write response
+// after page.
+func renderPage(c *gin.Context, name string, data interface{}) (interface{}, *httpResponseError) {
+	var buf bytes.Buffer
+	if err := pages.ExecuteTemplate(&buf, name, data); err != nil {
+		return nil, &httpResponseError{
+			Type:    ErrorTypeServer,
+			Message: "failed to render page",
+			Details: err.Error(),
+		}
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+	return nil, nil
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vadimpk/gses-2023/core/pkg/ratelimit"
+)
+
+// RateLimits are limits of requests of each client to routes.
+type RateLimits struct {
+	// Default is limit of routes that are not in Routes, such routes are not limited if it's nil.
+	Default *ratelimit.Limit
+	// Routes are keyed by method and path without version, e.g. "POST /api/subscribe" limits both
+	// "/api/subscribe" and "/api/v1/subscribe".
+	Routes map[string]ratelimit.Limit
+}
+
+// ParseRateLimits parses default limit and comma separated list of route limits in
+// "<method> <path>=<limit>" format, e.g. "POST /api/subscribe=5/m,GET /api/alerts=30/m".
+func ParseRateLimits(defaultLimit, routes string) (*RateLimits, error) {
+	limits := &RateLimits{
+		Routes: make(map[string]ratelimit.Limit),
+	}
+
+	if strings.TrimSpace(defaultLimit) != "" {
+		limit, err := ratelimit.ParseLimit(defaultLimit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse default limit: %w", err)
+		}
+		limits.Default = &limit
+	}
+
+	for _, entry := range strings.Split(routes, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		route, l, ok := strings.Cut(entry, "=")
+		method, path, hasPath := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok || !hasPath {
+			return nil, fmt.Errorf("invalid route limit %q, expected \"<method> <path>=<limit>\"", entry)
+		}
+		limit, err := ratelimit.ParseLimit(l)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse limit of %q: %w", route, err)
+		}
+		limits.Routes[strings.ToUpper(method)+" "+strings.TrimSpace(path)] = limit
+	}
+
+	return limits, nil
+}
+
+// get returns limit of route, false is returned if route is not limited.
+func (l *RateLimits) get(route string) (ratelimit.Limit, bool) {
+	if limit, ok := l.Routes[route]; ok {
+		return limit, true
+	}
+	if l.Default != nil {
+		return *l.Default, true
+	}
+	return ratelimit.Limit{}, false
+}
+
+// rateLimitMiddleware rejects requests of clients that exceeded limit of route with 429. Each client has
+// its own bucket per route. Clients with valid credentials are identified by them, so that clients
+// behind the same IP don't share limit, and others are identified by IP.
+func rateLimitMiddleware(opts *routerOptions) gin.HandlerFunc {
+	return wrapHandler(opts, func(c *gin.Context) (interface{}, *httpResponseError) {
+		// route and its alias share limit and bucket
+		route := c.Request.Method + " " + unversionedPath(c.FullPath())
+		limit, ok := opts.rateLimits.get(route)
+		if c.FullPath() == "" || !ok {
+			return nil, nil
+		}
+
+		client := rateLimitClient(opts, c)
+		logger := opts.logger.Named("rateLimitMiddleware").
+			With("route", route).
+			With("client", client)
+
+		result, err := opts.rateLimitStore.Take(c.Request.Context(), route+"|"+client, limit)
+		if err != nil {
+			// limiting is best effort, so requests are not rejected when counters are unavailable
+			logger.Error("failed to take rate limit token", "err", err)
+			return nil, nil
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		if result.Allowed {
+			return nil, nil
+		}
+
+		logger.Info("rate limit exceeded")
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		return nil, &httpResponseError{
+			Code:    http.StatusTooManyRequests,
+			Type:    ErrorTypeClient,
+			Message: "too many requests",
+			Details: fmt.Sprintf("limit of %s is %s", route, limit),
+		}
+	})
+}
+
+// rateLimitClient returns key of client's bucket.
+func rateLimitClient(opts *routerOptions, c *gin.Context) string {
+	for _, authenticator := range opts.authenticators {
+		principal, err := authenticator.Authenticate(c.Request)
+		if err != nil {
+			// invalid credentials are rejected later by auth middleware, limit of IP is applied until then
+			break
+		}
+		if principal != nil {
+			return "principal:" + principal.Type + ":" + principal.Name
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/config"
+	"github.com/vadimpk/gses-2023/core/pkg/ratelimit"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+func newTestRateLimitRouter(t *testing.T) *gin.Engine {
+	hash := sha256.Sum256([]byte(testAPIKey))
+	apiKeyAuthenticator, err := NewAPIKeyAuthenticator("ci:" + hex.EncodeToString(hash[:]) + ":" + ScopeSendEmails)
+	require.NoError(t, err)
+	jwtAuthenticator, err := NewJWTAuthenticator(&JWTOptions{
+		Secret: testJWTSecret,
+	})
+	require.NoError(t, err)
+
+	rateLimits, err := ParseRateLimits("3/m", "POST /api/subscribe=1/h")
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	opts := &routerOptions{
+		router:         r.Group("/api"),
+		cfg:            &config.Config{},
+		logger:         logging.NewZapLogger("debug"),
+		authenticators: []Authenticator{apiKeyAuthenticator, jwtAuthenticator},
+		rateLimitStore: ratelimit.NewMemoryStore(),
+		rateLimits:     rateLimits,
+	}
+	opts.router.Use(rateLimitMiddleware(opts))
+
+	ok := wrapHandler(opts, func(c *gin.Context) (interface{}, *httpResponseError) {
+		return "ok", nil
+	})
+	opts.router.GET("/rate", ok)
+	opts.router.POST("/subscribe", ok)
+
+	return r
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		header     http.Header
+		allowed    int
+		limit      string
+		retryAfter int
+	}{
+		{
+			name:       "default limit",
+			method:     http.MethodGet,
+			path:       "/api/rate",
+			allowed:    3,
+			limit:      "3",
+			retryAfter: 20,
+		},
+		{
+			name:       "route limit",
+			method:     http.MethodPost,
+			path:       "/api/subscribe",
+			allowed:    1,
+			limit:      "1",
+			retryAfter: 3600,
+		},
+		{
+			name:       "invalid api key is limited by ip",
+			method:     http.MethodGet,
+			path:       "/api/rate",
+			header:     http.Header{"X-Api-Key": {"wrong"}},
+			allowed:    3,
+			limit:      "3",
+			retryAfter: 20,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := newTestRateLimitRouter(t)
+			do := func() *httptest.ResponseRecorder {
+				req := httptest.NewRequest(tc.method, tc.path, nil)
+				for key, values := range tc.header {
+					req.Header[key] = values
+				}
+				rec := httptest.NewRecorder()
+				r.ServeHTTP(rec, req)
+				return rec
+			}
+
+			for i := 0; i < tc.allowed; i++ {
+				rec := do()
+				require.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, tc.limit, rec.Header().Get("RateLimit-Limit"))
+				assert.Equal(t, strconv.Itoa(tc.allowed-i-1), rec.Header().Get("RateLimit-Remaining"))
+			}
+
+			rec := do()
+			assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+			assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+			assert.Equal(t, strconv.Itoa(tc.retryAfter), rec.Header().Get("Retry-After"))
+			assert.Contains(t, rec.Body.String(), `"code":429`)
+		})
+	}
+}
+
+func TestRateLimitMiddleware_SeparateBuckets(t *testing.T) {
+	t.Parallel()
+
+	r := newTestRateLimitRouter(t)
+	do := func(header http.Header) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/subscribe", nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do(nil))
+	assert.Equal(t, http.StatusTooManyRequests, do(nil))
+
+	// authenticated client behind the same ip has its own bucket
+	assert.Equal(t, http.StatusOK, do(http.Header{"X-Api-Key": {testAPIKey}}))
+	assert.Equal(t, http.StatusTooManyRequests, do(http.Header{"X-Api-Key": {testAPIKey}}))
+
+	// token with subject equal to name of api key has its own bucket
+	token := "Bearer " + newTestJWT(t, testJWTSecret, jwtClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "ci",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	assert.Equal(t, http.StatusOK, do(http.Header{"Authorization": {token}}))
+	assert.Equal(t, http.StatusTooManyRequests, do(http.Header{"Authorization": {token}}))
+
+	// routes have separate buckets
+	req := httptest.NewRequest(http.MethodGet, "/api/rate", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestParseRateLimits(t *testing.T) {
+	t.Parallel()
+
+	limits, err := ParseRateLimits("", " post /api/subscribe = 5/m , GET /api/rate=1/s")
+	require.NoError(t, err)
+	assert.Nil(t, limits.Default)
+	assert.Equal(t, map[string]ratelimit.Limit{
+		"POST /api/subscribe": {Requests: 5, Period: time.Minute},
+		"GET /api/rate":       {Requests: 1, Period: time.Second},
+	}, limits.Routes)
+
+	_, ok := limits.get("GET /api/other")
+	assert.False(t, ok)
+
+	for _, routes := range []string{"/api/subscribe=5/m", "POST /api/subscribe", "POST /api/subscribe=5"} {
+		_, err = ParseRateLimits("", routes)
+		assert.Error(t, err, routes)
+	}
+	_, err = ParseRateLimits("60", "")
+	assert.ErrorIs(t, err, ratelimit.ErrInvalidLimit)
+}
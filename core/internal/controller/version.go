@@ -0,0 +1,20 @@
+package controller
+
+import (
+	"strings"
+)
+
+const (
+	// apiBasePath is unversioned alias of latest version of API kept for clients that don't use versions.
+	apiBasePath = "/api"
+	apiV1Path   = "/api/v1"
+)
+
+// unversionedPath returns path of route without version, e.g. "/api/subscribe" for "/api/v1/subscribe", so
+// that route and its alias share settings.
+func unversionedPath(path string) string {
+	if path == apiV1Path || strings.HasPrefix(path, apiV1Path+"/") {
+		return apiBasePath + strings.TrimPrefix(path, apiV1Path)
+	}
+	return path
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/config"
+	"github.com/vadimpk/gses-2023/core/pkg/ratelimit"
+	"github.com/vadimpk/gses-2023/pkg/apiversion"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+func TestVersionedRoutes(t *testing.T) {
+	t.Parallel()
+
+	deprecation, err := apiversion.ParseDeprecation("2023-07-01T00:00:00Z", "2024-01-01T00:00:00Z", apiV1Path)
+	require.NoError(t, err)
+	rateLimits, err := ParseRateLimits("", "GET /api/unsubscribe=1/h")
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := New(&Options{
+		Config:                 &config.Config{},
+		Logger:                 logging.NewZapLogger("debug"),
+		RateLimitStore:         ratelimit.NewMemoryStore(),
+		RateLimits:             rateLimits,
+		UnversionedDeprecation: deprecation,
+	})
+	do := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// invalid requests are enough to check routing, they are rejected by handler
+	rec := do("/api/v1/unsubscribe")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+
+	rec = do("/api/unsubscribe?email=user")
+	assert.Equal(t, "@1688169600", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Mon, 01 Jan 2024 00:00:00 GMT", rec.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v1/unsubscribe>; rel="successor-version"`, rec.Header().Get("Link"))
+	// alias shares limit of route
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+}
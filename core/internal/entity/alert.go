@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AlertCondition is a kind of rate movement alert is triggered by.
+type AlertCondition string
+
+const (
+	// AlertConditionAbove triggers alert when rate rises to threshold or above.
+	AlertConditionAbove AlertCondition = "above"
+	// AlertConditionBelow triggers alert when rate falls to threshold or below.
+	AlertConditionBelow AlertCondition = "below"
+	// AlertConditionChange triggers alert when rate moves by threshold percents or more within window.
+	AlertConditionChange AlertCondition = "change"
+)
+
+func (c AlertCondition) String() string {
+	return string(c)
+}
+
+var alertConditions = map[AlertCondition]struct{}{
+	AlertConditionAbove: {}, AlertConditionBelow: {}, AlertConditionChange: {},
+}
+
+func (c AlertCondition) IsValid() bool {
+	_, ok := alertConditions[c]
+	return ok
+}
+
+var ErrInvalidAlertCondition = errors.New("invalid alert condition")
+
+func ParseAlertCondition(s string) (AlertCondition, error) {
+	condition := AlertCondition(s)
+	if !condition.IsValid() {
+		return "", fmt.Errorf("%w: %q", ErrInvalidAlertCondition, s)
+	}
+	return condition, nil
+}
+
+// Alert is a subscriber's request to be notified when rate of currency pair meets condition.
+type Alert struct {
+	ID    string
+	Email string
+	Pair  CurrencyPair
+	// Condition together with Threshold defines when alert is triggered.
+	Condition AlertCondition
+	// Threshold is rate for AlertConditionAbove and AlertConditionBelow and change in percents
+	// for AlertConditionChange.
+	Threshold float64
+	// Window is period rate change is measured in for AlertConditionChange.
+	Window time.Duration
+	// Triggered is set when notification is sent and is reset when rate moves back far enough
+	// from threshold, so that rate oscillating around threshold doesn't trigger alert repeatedly.
+	Triggered   bool
+	TriggeredAt time.Time
+	CreatedAt   time.Time
+}
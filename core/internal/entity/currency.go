@@ -1,5 +1,12 @@
 package entity
 
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
 type CryptoCurrency string
 
 const (
@@ -39,3 +46,72 @@ func (f FiatCurrency) IsValid() bool {
 	_, ok := fiatCurrencies[f]
 	return ok
 }
+
+var fiatCurrencySymbols = map[FiatCurrency]string{
+	FiatCurrencyUSD: "$", FiatCurrencyUAH: "₴",
+}
+
+// Symbol returns sign of currency, e.g. "$" for USD. Code is returned for currencies without sign.
+func (f FiatCurrency) Symbol() string {
+	if symbol, ok := fiatCurrencySymbols[f]; ok {
+		return symbol
+	}
+	return f.String()
+}
+
+// CurrencyPair is a pair of crypto and fiat currencies rate of which is sent to subscribers.
+type CurrencyPair struct {
+	Crypto CryptoCurrency
+	Fiat   FiatCurrency
+}
+
+// DefaultCurrencyPair is used for subscribers that didn't choose any pair.
+var DefaultCurrencyPair = CurrencyPair{
+	Crypto: CryptoCurrencyBTC,
+	Fiat:   FiatCurrencyUSD,
+}
+
+const currencyPairSeparator = "-"
+
+// String returns pair in format "BTC-USD", that is accepted by ParseCurrencyPair.
+func (p CurrencyPair) String() string {
+	return p.Crypto.String() + currencyPairSeparator + p.Fiat.String()
+}
+
+func (p CurrencyPair) IsValid() bool {
+	return p.Crypto.IsValid() && p.Fiat.IsValid()
+}
+
+var ErrInvalidCurrencyPair = errors.New("invalid currency pair")
+
+// ParseCurrencyPair parses pair in format "BTC-USD". Currency codes are case-insensitive.
+func ParseCurrencyPair(s string) (CurrencyPair, error) {
+	crypto, fiat, ok := strings.Cut(strings.TrimSpace(s), currencyPairSeparator)
+	if !ok {
+		return CurrencyPair{}, fmt.Errorf("%w: %q", ErrInvalidCurrencyPair, s)
+	}
+
+	pair := CurrencyPair{
+		Crypto: CryptoCurrency(strings.ToUpper(crypto)),
+		Fiat:   FiatCurrency(strings.ToUpper(fiat)),
+	}
+	if !pair.IsValid() {
+		return CurrencyPair{}, fmt.Errorf("%w: %q", ErrInvalidCurrencyPair, s)
+	}
+
+	return pair, nil
+}
+
+// SupportedCurrencyPairs returns every valid pair, ordered by crypto and then by fiat currency.
+func SupportedCurrencyPairs() []CurrencyPair {
+	pairs := make([]CurrencyPair, 0, len(cryptoCurrencies)*len(fiatCurrencies))
+	for crypto := range cryptoCurrencies {
+		for fiat := range fiatCurrencies {
+			pairs = append(pairs, CurrencyPair{Crypto: crypto, Fiat: fiat})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].String() < pairs[j].String()
+	})
+	return pairs
+}
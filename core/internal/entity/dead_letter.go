@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// DeadLetter is an email that failed to be sent after all retries. It keeps whole message,
+// so that it can be sent again later.
+type DeadLetter struct {
+	ID       string
+	To       string
+	Subject  string
+	Body     string
+	HTML     string
+	Error    string
+	FailedAt time.Time
+}
@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Locale is a language emails are sent in, represented by ISO 639-1 code.
+type Locale string
+
+const (
+	LocaleEnglish   Locale = "en"
+	LocaleUkrainian Locale = "uk"
+)
+
+// DefaultLocale is used for subscribers that didn't choose locale and when there is no
+// content for subscriber's locale.
+const DefaultLocale = LocaleEnglish
+
+func (l Locale) String() string {
+	return string(l)
+}
+
+var locales = map[Locale]struct{}{
+	LocaleEnglish: {}, LocaleUkrainian: {},
+}
+
+func (l Locale) IsValid() bool {
+	_, ok := locales[l]
+	return ok
+}
+
+var ErrInvalidLocale = errors.New("invalid locale")
+
+// ParseLocale parses language code. Region is ignored, so "uk-UA" and "uk_UA" are parsed as "uk".
+func ParseLocale(s string) (Locale, error) {
+	language, _, _ := strings.Cut(strings.TrimSpace(s), "-")
+	language, _, _ = strings.Cut(language, "_")
+
+	locale := Locale(strings.ToLower(language))
+	if !locale.IsValid() {
+		return "", fmt.Errorf("%w: %q", ErrInvalidLocale, s)
+	}
+
+	return locale, nil
+}
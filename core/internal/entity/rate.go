@@ -0,0 +1,10 @@
+package entity
+
+import "time"
+
+// Rate is a rate of currency pair at some moment.
+type Rate struct {
+	Pair      CurrencyPair
+	Value     float64
+	FetchedAt time.Time
+}
@@ -0,0 +1,46 @@
+package entity
+
+import "time"
+
+type SendJobState string
+
+const (
+	// SendJobStateQueued is state of job that was created, but hasn't started sending yet.
+	SendJobStateQueued SendJobState = "queued"
+	// SendJobStateRunning is state of job that is sending emails. Jobs left in this state
+	// by restart are resumed.
+	SendJobStateRunning SendJobState = "running"
+	// SendJobStateCompleted is state of job that processed all subscribers.
+	SendJobStateCompleted SendJobState = "completed"
+	// SendJobStateFailed is state of job that stopped because of error.
+	SendJobStateFailed SendJobState = "failed"
+)
+
+func (s SendJobState) String() string {
+	return string(s)
+}
+
+// IsFinished checks if job won't change anymore.
+func (s SendJobState) IsFinished() bool {
+	return s == SendJobStateCompleted || s == SendJobStateFailed
+}
+
+// SendJob is a background sending of rate info to all subscribers.
+type SendJob struct {
+	ID     string
+	State  SendJobState
+	Total  int
+	Sent   int
+	Failed int
+	// Cursor is the last email of continuous progress, recipients are processed in order of emails and
+	// the ones up to Cursor are skipped when job is resumed.
+	Cursor string
+	// Processed are emails after Cursor that were already sent or failed, they are skipped when job is
+	// resumed. There are only few of them, because concurrent sending doesn't get far ahead of Cursor.
+	Processed    []string
+	FailedEmails []string
+	// Error is a reason why job failed.
+	Error     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type SubscriberStatus string
+
+const (
+	// SubscriberStatusPending is status of subscriber that hasn't confirmed email yet.
+	SubscriberStatusPending SubscriberStatus = "pending"
+	// SubscriberStatusConfirmed is status of subscriber that confirmed email and receives newsletter.
+	SubscriberStatusConfirmed SubscriberStatus = "confirmed"
+)
+
+func (s SubscriberStatus) String() string {
+	return string(s)
+}
+
+func (s SubscriberStatus) IsValid() bool {
+	return s == SubscriberStatusPending || s == SubscriberStatusConfirmed
+}
+
+var ErrInvalidSubscriberStatus = errors.New("invalid subscriber status")
+
+func ParseSubscriberStatus(s string) (SubscriberStatus, error) {
+	status := SubscriberStatus(s)
+	if !status.IsValid() {
+		return "", fmt.Errorf("%w: %q", ErrInvalidSubscriberStatus, s)
+	}
+	return status, nil
+}
+
+type Subscriber struct {
+	Email     string
+	Status    SubscriberStatus
+	CreatedAt time.Time
+	// Pairs are currency pairs subscriber receives rates for.
+	Pairs []CurrencyPair
+	// Locale is language of emails sent to subscriber.
+	Locale Locale
+}
+
+// IsExpired checks if subscriber is still pending after ttl since subscription.
+func (s *Subscriber) IsExpired(now time.Time, ttl time.Duration) bool {
+	return s.Status == SubscriberStatusPending && now.Sub(s.CreatedAt) > ttl
+}
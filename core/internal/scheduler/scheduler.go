@@ -0,0 +1,153 @@
+// Package scheduler implements periodic sending of rate newsletters.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+// Storage persists time of the last run, so that restarts neither repeat nor miss runs.
+type Storage interface {
+	// GetLastRun returns scheduled time of the last run. Returns zero time if there were no runs.
+	GetLastRun(ctx context.Context) (time.Time, error)
+	// SaveLastRun saves scheduled time of the last run.
+	SaveLastRun(ctx context.Context, t time.Time) error
+}
+
+type Options struct {
+	Services service.Services
+	Storage  Storage
+	Logger   logging.Logger
+	// Schedule is cron expression in standard 5-field format, e.g. "0 9 * * *".
+	Schedule string
+}
+
+// Scheduler calls EmailService.SendRateInfo according to cron schedule.
+type Scheduler struct {
+	services service.Services
+	storage  Storage
+	logger   logging.Logger
+	schedule cron.Schedule
+
+	running atomic.Bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func New(opts *Options) (*Scheduler, error) {
+	schedule, err := cron.ParseStandard(opts.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schedule: %w", err)
+	}
+
+	return &Scheduler{
+		services: opts.Services,
+		storage:  opts.Storage,
+		logger:   opts.Logger.Named("Scheduler"),
+		schedule: schedule,
+	}, nil
+}
+
+// Start runs scheduler in background until Shutdown is called.
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.loop(ctx)
+	}()
+}
+
+// Shutdown stops scheduler, cancels run in progress and waits for it to return.
+func (s *Scheduler) Shutdown() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context) {
+	logger := s.logger.Named("loop")
+
+	lastRun, err := s.storage.GetLastRun(ctx)
+	if err != nil {
+		logger.Error("failed to get last run, run that was missed during downtime may be skipped", "err", err)
+	}
+
+	// catch up on run that was missed while service was down
+	if !lastRun.IsZero() {
+		if missed := s.schedule.Next(lastRun); !missed.After(time.Now()) {
+			logger.Info("running missed run", "lastRun", lastRun, "missed", missed)
+			s.trigger(ctx, missed)
+		}
+	}
+
+	for {
+		next := s.schedule.Next(time.Now())
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.trigger(ctx, next)
+		}
+	}
+}
+
+// trigger starts run in background unless previous one is still in progress.
+func (s *Scheduler) trigger(ctx context.Context, scheduledAt time.Time) {
+	logger := s.logger.Named("trigger").
+		With("scheduledAt", scheduledAt)
+
+	if !s.running.CompareAndSwap(false, true) {
+		logger.Warn("previous run is still in progress, skipping")
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.running.Store(false)
+		s.run(ctx, scheduledAt)
+	}()
+}
+
+func (s *Scheduler) run(ctx context.Context, scheduledAt time.Time) {
+	logger := s.logger.Named("run").
+		With("scheduledAt", scheduledAt)
+
+	// run is marked as done before sending, so that crash in the middle of sending doesn't lead to double-send
+	err := s.storage.SaveLastRun(ctx, scheduledAt)
+	if err != nil {
+		logger.Error("failed to save last run, skipping", "err", err)
+		return
+	}
+
+	output, err := s.services.Email.SendRateInfo(ctx)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			logger.Info("run canceled")
+			return
+		}
+		if errors.Is(err, service.ErrSendRateInfoInProgress) {
+			logger.Warn("run skipped, rate info is already being sent")
+			return
+		}
+		logger.Error("failed to send rate info", "err", err)
+		return
+	}
+
+	logger.Info("successfully sent rate info", "failedEmails", output.FailedEmails)
+}
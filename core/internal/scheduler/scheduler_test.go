@@ -0,0 +1,161 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+type testEmailService struct {
+	service.EmailService
+
+	calls   chan struct{}
+	release chan struct{}
+}
+
+func (s *testEmailService) SendRateInfo(ctx context.Context) (*service.SendRateInfoOutput, error) {
+	s.calls <- struct{}{}
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &service.SendRateInfoOutput{}, nil
+}
+
+type testStorage struct {
+	mu      sync.Mutex
+	lastRun time.Time
+}
+
+func (s *testStorage) GetLastRun(ctx context.Context) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastRun, nil
+}
+
+func (s *testStorage) SaveLastRun(ctx context.Context, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRun = t
+	return nil
+}
+
+func newTestScheduler(t *testing.T, storage Storage, emailService service.EmailService) *Scheduler {
+	s, err := New(&Options{
+		Services: service.Services{
+			Email: emailService,
+		},
+		Storage:  storage,
+		Logger:   logging.NewZapLogger("debug"),
+		Schedule: "0 * * * *",
+	})
+	require.NoError(t, err)
+	return s
+}
+
+func TestScheduler_MissedRun(t *testing.T) {
+	t.Parallel()
+
+	emailService := &testEmailService{
+		calls:   make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	close(emailService.release)
+
+	storage := &testStorage{
+		lastRun: time.Now().Add(-2 * time.Hour).Truncate(time.Hour),
+	}
+
+	s := newTestScheduler(t, storage, emailService)
+	s.Start()
+	defer s.Shutdown()
+
+	select {
+	case <-emailService.calls:
+	case <-time.After(time.Second):
+		t.Fatal("missed run was not triggered")
+	}
+}
+
+func TestScheduler_NoMissedRun(t *testing.T) {
+	t.Parallel()
+
+	emailService := &testEmailService{
+		calls:   make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+
+	storage := &testStorage{
+		lastRun: time.Now().Truncate(time.Hour),
+	}
+
+	s := newTestScheduler(t, storage, emailService)
+	s.Start()
+
+	select {
+	case <-emailService.calls:
+		t.Fatal("run was triggered although it has already been done before restart")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	s.Shutdown()
+}
+
+func TestScheduler_SkipOverlappingRun(t *testing.T) {
+	t.Parallel()
+
+	emailService := &testEmailService{
+		calls:   make(chan struct{}, 2),
+		release: make(chan struct{}),
+	}
+	storage := &testStorage{}
+
+	s := newTestScheduler(t, storage, emailService)
+	ctx := context.Background()
+
+	first := time.Now().Truncate(time.Hour)
+	s.trigger(ctx, first)
+	<-emailService.calls
+
+	s.trigger(ctx, first.Add(time.Hour))
+	close(emailService.release)
+	s.wg.Wait()
+
+	assert.Len(t, emailService.calls, 0)
+	assert.Equal(t, first, storage.lastRun)
+}
+
+func TestScheduler_ShutdownCancelsRun(t *testing.T) {
+	t.Parallel()
+
+	emailService := &testEmailService{
+		calls:   make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	storage := &testStorage{
+		lastRun: time.Now().Add(-2 * time.Hour).Truncate(time.Hour),
+	}
+
+	s := newTestScheduler(t, storage, emailService)
+	s.Start()
+	<-emailService.calls
+
+	done := make(chan struct{})
+	go func() {
+		s.Shutdown()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("shutdown didn't cancel run in progress")
+	}
+}
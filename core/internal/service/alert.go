@@ -0,0 +1,423 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+)
+
+const (
+	defaultAlertWindow = 24 * time.Hour
+	minAlertWindow     = time.Hour
+	maxAlertWindow     = 7 * 24 * time.Hour
+)
+
+const alertTemplate = "alert"
+
+type alertService struct {
+	serviceContext
+	email *emailService
+
+	// history keeps rates polled by Evaluate, so that change alerts can be evaluated.
+	history *rateHistory
+}
+
+func NewAlertService(opts *Options, emailService *emailService) *alertService {
+	return &alertService{
+		serviceContext: serviceContext{
+			storages: opts.Storages,
+			apis:     opts.APIs,
+			logger:   opts.Logger.Named("AlertService"),
+			cfg:      opts.Cfg,
+		},
+		email:   emailService,
+		history: newRateHistory(maxAlertWindow),
+	}
+}
+
+func (s *alertService) Create(ctx context.Context, opts *CreateAlertOptions) (*entity.Alert, error) {
+	logger := s.logger.Named("Create").
+		WithContext(ctx).
+		With("email", opts.Email).
+		With("pair", opts.Pair).
+		With("condition", opts.Condition).
+		With("threshold", opts.Threshold)
+
+	if !s.email.token.Verify(opts.Token, preferencesTokenPurpose, opts.Email) {
+		logger.Info("invalid preferences token")
+		return nil, ErrAlertInvalidToken
+	}
+
+	if opts.Threshold <= 0 {
+		logger.Info("invalid threshold")
+		return nil, ErrAlertInvalidThreshold
+	}
+
+	window := opts.Window
+	if opts.Condition == entity.AlertConditionChange {
+		if window == 0 {
+			window = defaultAlertWindow
+		}
+		if window < minAlertWindow || window > maxAlertWindow {
+			logger.Info("invalid window", "window", window)
+			return nil, ErrAlertInvalidWindow
+		}
+	} else {
+		window = 0
+	}
+
+	subscriber, err := s.storages.Email.Get(ctx, opts.Email)
+	if err != nil {
+		logger.Error("failed to get email from storage", "err", err)
+		return nil, fmt.Errorf("failed to get email from storage: %w", err)
+	}
+	if subscriber == nil || subscriber.Status != entity.SubscriberStatusConfirmed {
+		logger.Info("email is not subscribed")
+		return nil, ErrAlertNotSubscribed
+	}
+
+	alerts, err := s.listByEmail(ctx, opts.Email)
+	if err != nil {
+		logger.Error("failed to get alerts from storage", "err", err)
+		return nil, fmt.Errorf("failed to get alerts from storage: %w", err)
+	}
+	if len(alerts) >= s.cfg.Alerts.MaxPerSubscriber {
+		logger.Info("too many alerts", "count", len(alerts))
+		return nil, ErrAlertLimitExceeded
+	}
+
+	id, err := newID()
+	if err != nil {
+		logger.Error("failed to generate alert id", "err", err)
+		return nil, fmt.Errorf("failed to generate alert id: %w", err)
+	}
+
+	alert := &entity.Alert{
+		ID:        id,
+		Email:     opts.Email,
+		Pair:      opts.Pair,
+		Condition: opts.Condition,
+		Threshold: opts.Threshold,
+		Window:    window,
+		CreatedAt: time.Now(),
+	}
+	err = s.storages.Alert.Save(ctx, alert)
+	if err != nil {
+		logger.Error("failed to save alert", "err", err)
+		return nil, fmt.Errorf("failed to save alert to storage: %w", err)
+	}
+
+	logger.Info("successfully created alert", "id", id)
+	return alert, nil
+}
+
+func (s *alertService) List(ctx context.Context, email, token string) ([]*entity.Alert, error) {
+	logger := s.logger.Named("List").
+		WithContext(ctx).
+		With("email", email)
+
+	if !s.email.token.Verify(token, preferencesTokenPurpose, email) {
+		logger.Info("invalid preferences token")
+		return nil, ErrAlertInvalidToken
+	}
+
+	alerts, err := s.listByEmail(ctx, email)
+	if err != nil {
+		logger.Error("failed to get alerts from storage", "err", err)
+		return nil, fmt.Errorf("failed to get alerts from storage: %w", err)
+	}
+
+	return alerts, nil
+}
+
+func (s *alertService) Delete(ctx context.Context, email, token, id string) error {
+	logger := s.logger.Named("Delete").
+		WithContext(ctx).
+		With("email", email).
+		With("id", id)
+
+	if !s.email.token.Verify(token, preferencesTokenPurpose, email) {
+		logger.Info("invalid preferences token")
+		return ErrAlertInvalidToken
+	}
+
+	alerts, err := s.listByEmail(ctx, email)
+	if err != nil {
+		logger.Error("failed to get alerts from storage", "err", err)
+		return fmt.Errorf("failed to get alerts from storage: %w", err)
+	}
+
+	found := false
+	for _, alert := range alerts {
+		if alert.ID == id {
+			found = true
+			break
+		}
+	}
+	if !found {
+		logger.Info("alert doesn't exist")
+		return ErrAlertNotFound
+	}
+
+	err = s.storages.Alert.Delete(ctx, id)
+	if err != nil {
+		logger.Error("failed to delete alert", "err", err)
+		return fmt.Errorf("failed to delete alert from storage: %w", err)
+	}
+
+	logger.Info("successfully deleted alert")
+	return nil
+}
+
+func (s *alertService) listByEmail(ctx context.Context, email string) ([]*entity.Alert, error) {
+	alerts, err := s.storages.Alert.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*entity.Alert
+	for _, alert := range alerts {
+		if alert.Email == email {
+			result = append(result, alert)
+		}
+	}
+	return result, nil
+}
+
+func (s *alertService) Evaluate(ctx context.Context) error {
+	logger := s.logger.Named("Evaluate").
+		WithContext(ctx)
+
+	alerts, err := s.storages.Alert.List(ctx)
+	if err != nil {
+		logger.Error("failed to get alerts from storage", "err", err)
+		return fmt.Errorf("failed to get alerts from storage: %w", err)
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	subscribers, err := s.storages.Email.List(ctx)
+	if err != nil {
+		logger.Error("failed to get emails from storage", "err", err)
+		return fmt.Errorf("failed to get emails from storage: %w", err)
+	}
+	confirmed := make(map[string]*entity.Subscriber, len(subscribers))
+	for _, subscriber := range subscribers {
+		if subscriber.Status == entity.SubscriberStatusConfirmed {
+			confirmed[subscriber.Email] = subscriber
+		}
+	}
+
+	now := time.Now()
+	rates := make(map[entity.CurrencyPair]float64)
+	requested := make(map[entity.CurrencyPair]struct{})
+	for _, alert := range alerts {
+		if _, ok := confirmed[alert.Email]; !ok {
+			continue
+		}
+		if _, ok := requested[alert.Pair]; ok {
+			continue
+		}
+		requested[alert.Pair] = struct{}{}
+
+		rate, err := s.apis.Crypto.GetRate(ctx, alert.Pair.Crypto.String(), alert.Pair.Fiat.String())
+		if err != nil {
+			logger.Error(fmt.Sprintf("failed to get rate for: %s", alert.Pair), "err", err)
+			continue
+		}
+		rates[alert.Pair] = rate
+		s.history.add(alert.Pair, now, rate)
+	}
+
+	for _, alert := range alerts {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		subscriber, ok := confirmed[alert.Email]
+		if !ok {
+			// alerts of unsubscribed emails are not needed anymore
+			if err := s.storages.Alert.Delete(ctx, alert.ID); err != nil {
+				logger.Error(fmt.Sprintf("failed to delete alert of unsubscribed email: %s", alert.ID), "err", err)
+			}
+			continue
+		}
+
+		rate, ok := rates[alert.Pair]
+		if !ok {
+			continue
+		}
+
+		s.evaluateAlert(ctx, alert, subscriber, rate, now)
+	}
+
+	return nil
+}
+
+// evaluateAlert notifies subscriber if alert is met and wasn't triggered yet, or re-arms triggered
+// alert if rate moved back far enough.
+func (s *alertService) evaluateAlert(ctx context.Context, alert *entity.Alert, subscriber *entity.Subscriber, rate float64, now time.Time) {
+	logger := s.logger.Named("evaluateAlert").
+		WithContext(ctx).
+		With("id", alert.ID).
+		With("rate", rate)
+
+	hysteresis := s.cfg.Alerts.Hysteresis / 100
+	var met, rearm bool
+	var change float64
+	switch alert.Condition {
+	case entity.AlertConditionAbove:
+		met = rate >= alert.Threshold
+		rearm = rate < alert.Threshold*(1-hysteresis)
+	case entity.AlertConditionBelow:
+		met = rate <= alert.Threshold
+		rearm = rate > alert.Threshold*(1+hysteresis)
+	case entity.AlertConditionChange:
+		change = s.history.change(alert.Pair, now.Add(-alert.Window), rate)
+		met = math.Abs(change) >= alert.Threshold
+		rearm = math.Abs(change) < alert.Threshold*(1-hysteresis)
+	}
+
+	switch {
+	case alert.Triggered && rearm:
+		alert.Triggered = false
+		logger.Info("alert re-armed")
+	case !alert.Triggered && met:
+		err := s.notify(ctx, alert, subscriber, rate, change, now)
+		if err != nil {
+			// alert stays armed, so that notification is sent on the next evaluation
+			logger.Error("failed to send alert", "err", err)
+			return
+		}
+		alert.Triggered = true
+		alert.TriggeredAt = now
+		logger.Info("alert triggered")
+	default:
+		return
+	}
+
+	err := s.storages.Alert.Update(ctx, alert)
+	if err != nil {
+		logger.Error("failed to update alert", "err", err)
+	}
+}
+
+// alertEmailData is passed to alert templates.
+type alertEmailData struct {
+	Pair      entity.CurrencyPair
+	Rate      float64
+	Condition entity.AlertCondition
+	Threshold float64
+	// Change is change of rate in percents within window, it's set only for change alerts.
+	Change      float64
+	WindowHours int
+	// Timestamp is time when rate was fetched in UTC.
+	Timestamp       time.Time
+	AlertsLink      string
+	UnsubscribeLink string
+}
+
+func (s *alertService) notify(ctx context.Context, alert *entity.Alert, subscriber *entity.Subscriber, rate, change float64, now time.Time) error {
+	message, err := s.email.templates.Render(subscriber.Locale, alertTemplate, alertEmailData{
+		Pair:            alert.Pair,
+		Rate:            rate,
+		Condition:       alert.Condition,
+		Threshold:       alert.Threshold,
+		Change:          change,
+		WindowHours:     int(alert.Window / time.Hour),
+		Timestamp:       now.UTC(),
+		AlertsLink:      s.alertsLink(alert.Email),
+		UnsubscribeLink: s.email.unsubscribeLink(alert.Email),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render alert email: %w", err)
+	}
+
+	return s.apis.Email.Send(ctx, &SendOptions{
+		To:      alert.Email,
+		Subject: message.Subject,
+		Body:    message.Text,
+		HTML:    message.HTML,
+	})
+}
+
+// alertsLink returns link for listing alerts signed for email.
+func (s *alertService) alertsLink(email string) string {
+	query := url.Values{
+		"email": {email},
+		"token": {s.email.token.Sign(preferencesTokenPurpose, email)},
+	}
+	return fmt.Sprintf("%s/api/v1/alerts?%s", s.cfg.App.PublicURL, query.Encode())
+}
+
+type rateSample struct {
+	at   time.Time
+	rate float64
+}
+
+// rateHistory keeps recent rates of pairs in memory. After restart change alerts are evaluated
+// on shorter history until it is filled again.
+type rateHistory struct {
+	mu      sync.Mutex
+	samples map[entity.CurrencyPair][]rateSample
+	// retention is how long samples are kept.
+	retention time.Duration
+}
+
+func newRateHistory(retention time.Duration) *rateHistory {
+	return &rateHistory{
+		samples:   make(map[entity.CurrencyPair][]rateSample),
+		retention: retention,
+	}
+}
+
+func (h *rateHistory) add(pair entity.CurrencyPair, at time.Time, rate float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	samples := h.samples[pair]
+	expired := 0
+	for expired < len(samples) && at.Sub(samples[expired].at) > h.retention {
+		expired++
+	}
+	h.samples[pair] = append(samples[expired:], rateSample{at: at, rate: rate})
+}
+
+// change returns the largest move in percents of current rate from rates since given time,
+// positive if rate rose from the lowest one and negative if it fell from the highest one.
+func (h *rateHistory) change(pair entity.CurrencyPair, since time.Time, current float64) float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	low, high := current, current
+	for _, sample := range h.samples[pair] {
+		if sample.at.Before(since) {
+			continue
+		}
+		if sample.rate < low {
+			low = sample.rate
+		}
+		if sample.rate > high {
+			high = sample.rate
+		}
+	}
+
+	var rise, fall float64
+	if low > 0 {
+		rise = (current - low) / low * 100
+	}
+	if high > 0 {
+		fall = (high - current) / high * 100
+	}
+
+	if rise >= fall {
+		return rise
+	}
+	return -fall
+}
@@ -0,0 +1,494 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/config"
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/core/internal/service/mocks"
+	"github.com/vadimpk/gses-2023/core/pkg/token"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+var alertTestConfig = func() *config.Config {
+	cfg := *testConfig
+	cfg.Alerts = config.Alerts{
+		Hysteresis:       1,
+		MaxPerSubscriber: 2,
+	}
+	return &cfg
+}()
+
+type alertTestMocks struct {
+	emailStorage *mocks.EmailStorage
+	alertStorage *mocks.AlertStorage
+	cryptoAPI    *mocks.CryptoAPI
+	emailAPI     *mocks.EmailAPI
+}
+
+func newAlertTestMocks(t *testing.T) alertTestMocks {
+	return alertTestMocks{
+		emailStorage: mocks.NewEmailStorage(t),
+		alertStorage: mocks.NewAlertStorage(t),
+		cryptoAPI:    mocks.NewCryptoAPI(t),
+		emailAPI:     mocks.NewEmailAPI(t),
+	}
+}
+
+func newAlertTestService(m alertTestMocks) service.AlertService {
+	opts := &service.Options{
+		Storages: service.Storages{
+			Email: m.emailStorage,
+			Alert: m.alertStorage,
+		},
+		APIs: service.APIs{
+			Email:  m.emailAPI,
+			Crypto: m.cryptoAPI,
+		},
+		Logger: logging.NewZapLogger("debug"),
+		Cfg:    alertTestConfig,
+	}
+
+	return service.NewAlertService(opts, service.NewEmailService(opts))
+}
+
+func TestAlertService_Create(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		opts *service.CreateAlertOptions
+	}
+
+	type expected struct {
+		err error
+	}
+
+	ctx := context.Background()
+	testEmail := "email@test.com"
+	testToken := token.NewHMAC(testConfig.Token.Secret).Sign("preferences", testEmail)
+	testPair := entity.CurrencyPair{Crypto: entity.CryptoCurrencyBTC, Fiat: entity.FiatCurrencyUAH}
+	confirmedSubscriber := &entity.Subscriber{
+		Email:  testEmail,
+		Status: entity.SubscriberStatusConfirmed,
+	}
+
+	isAlert := func(condition entity.AlertCondition, window time.Duration) interface{} {
+		return mock.MatchedBy(func(a *entity.Alert) bool {
+			return a.ID != "" && a.Email == testEmail && a.Pair == testPair && a.Condition == condition &&
+				a.Window == window && !a.Triggered && time.Since(a.CreatedAt) < time.Minute
+		})
+	}
+
+	testCases := []struct {
+		name     string
+		mock     func(m alertTestMocks)
+		args     args
+		expected expected
+	}{
+		{
+			name: "positive: created above alert",
+			mock: func(m alertTestMocks) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(confirmedSubscriber, nil)
+				m.alertStorage.On("List", ctx).Return(nil, nil)
+				m.alertStorage.On("Save", ctx, isAlert(entity.AlertConditionAbove, 0)).Return(nil)
+			},
+			args: args{
+				opts: &service.CreateAlertOptions{
+					Email:     testEmail,
+					Token:     testToken,
+					Pair:      testPair,
+					Condition: entity.AlertConditionAbove,
+					Threshold: 2000000,
+					Window:    time.Hour,
+				},
+			},
+			expected: expected{
+				err: nil,
+			},
+		},
+		{
+			name: "positive: created change alert with default window",
+			mock: func(m alertTestMocks) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(confirmedSubscriber, nil)
+				m.alertStorage.On("List", ctx).Return([]*entity.Alert{
+					{ID: "1", Email: "other@test.com"},
+					{ID: "2", Email: "another@test.com"},
+				}, nil)
+				m.alertStorage.On("Save", ctx, isAlert(entity.AlertConditionChange, 24*time.Hour)).Return(nil)
+			},
+			args: args{
+				opts: &service.CreateAlertOptions{
+					Email:     testEmail,
+					Token:     testToken,
+					Pair:      testPair,
+					Condition: entity.AlertConditionChange,
+					Threshold: 5,
+				},
+			},
+			expected: expected{
+				err: nil,
+			},
+		},
+		{
+			name: "negative: invalid token",
+			mock: func(m alertTestMocks) {},
+			args: args{
+				opts: &service.CreateAlertOptions{
+					Email:     testEmail,
+					Token:     token.NewHMAC(testConfig.Token.Secret).Sign("unsubscribe", testEmail),
+					Pair:      testPair,
+					Condition: entity.AlertConditionAbove,
+					Threshold: 2000000,
+				},
+			},
+			expected: expected{
+				err: service.ErrAlertInvalidToken,
+			},
+		},
+		{
+			name: "negative: threshold is not positive",
+			mock: func(m alertTestMocks) {},
+			args: args{
+				opts: &service.CreateAlertOptions{
+					Email:     testEmail,
+					Token:     testToken,
+					Pair:      testPair,
+					Condition: entity.AlertConditionBelow,
+					Threshold: -1,
+				},
+			},
+			expected: expected{
+				err: service.ErrAlertInvalidThreshold,
+			},
+		},
+		{
+			name: "negative: window is too long",
+			mock: func(m alertTestMocks) {},
+			args: args{
+				opts: &service.CreateAlertOptions{
+					Email:     testEmail,
+					Token:     testToken,
+					Pair:      testPair,
+					Condition: entity.AlertConditionChange,
+					Threshold: 5,
+					Window:    30 * 24 * time.Hour,
+				},
+			},
+			expected: expected{
+				err: service.ErrAlertInvalidWindow,
+			},
+		},
+		{
+			name: "negative: subscription is not confirmed",
+			mock: func(m alertTestMocks) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(&entity.Subscriber{
+					Email:  testEmail,
+					Status: entity.SubscriberStatusPending,
+				}, nil)
+			},
+			args: args{
+				opts: &service.CreateAlertOptions{
+					Email:     testEmail,
+					Token:     testToken,
+					Pair:      testPair,
+					Condition: entity.AlertConditionAbove,
+					Threshold: 2000000,
+				},
+			},
+			expected: expected{
+				err: service.ErrAlertNotSubscribed,
+			},
+		},
+		{
+			name: "negative: too many alerts",
+			mock: func(m alertTestMocks) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(confirmedSubscriber, nil)
+				m.alertStorage.On("List", ctx).Return([]*entity.Alert{
+					{ID: "1", Email: testEmail},
+					{ID: "2", Email: testEmail},
+				}, nil)
+			},
+			args: args{
+				opts: &service.CreateAlertOptions{
+					Email:     testEmail,
+					Token:     testToken,
+					Pair:      testPair,
+					Condition: entity.AlertConditionAbove,
+					Threshold: 2000000,
+				},
+			},
+			expected: expected{
+				err: service.ErrAlertLimitExceeded,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			testMocks := newAlertTestMocks(t)
+			tc.mock(testMocks)
+
+			alert, err := newAlertTestService(testMocks).Create(ctx, tc.args.opts)
+			assert.Equal(t, tc.expected.err, err)
+			if tc.expected.err == nil {
+				assert.NotNil(t, alert)
+			}
+		})
+	}
+}
+
+func TestAlertService_Delete(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		id string
+	}
+
+	type expected struct {
+		err error
+	}
+
+	ctx := context.Background()
+	testEmail := "email@test.com"
+	testToken := token.NewHMAC(testConfig.Token.Secret).Sign("preferences", testEmail)
+
+	testCases := []struct {
+		name     string
+		mock     func(m alertTestMocks)
+		args     args
+		expected expected
+	}{
+		{
+			name: "positive: deleted alert",
+			mock: func(m alertTestMocks) {
+				m.alertStorage.On("List", ctx).Return([]*entity.Alert{{ID: "1", Email: testEmail}}, nil)
+				m.alertStorage.On("Delete", ctx, "1").Return(nil)
+			},
+			args: args{
+				id: "1",
+			},
+			expected: expected{
+				err: nil,
+			},
+		},
+		{
+			name: "negative: alert belongs to another subscriber",
+			mock: func(m alertTestMocks) {
+				m.alertStorage.On("List", ctx).Return([]*entity.Alert{{ID: "1", Email: "other@test.com"}}, nil)
+			},
+			args: args{
+				id: "1",
+			},
+			expected: expected{
+				err: service.ErrAlertNotFound,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			testMocks := newAlertTestMocks(t)
+			tc.mock(testMocks)
+
+			err := newAlertTestService(testMocks).Delete(ctx, testEmail, testToken, tc.args.id)
+			assert.Equal(t, tc.expected.err, err)
+		})
+	}
+}
+
+func TestAlertService_Evaluate(t *testing.T) {
+	t.Parallel()
+
+	type expected struct {
+		err error
+	}
+
+	ctx := context.Background()
+	testEmail := "email@test.com"
+	testSendErr := errors.New("some err")
+	testSubscribers := []*entity.Subscriber{
+		{Email: testEmail, Status: entity.SubscriberStatusConfirmed, Locale: entity.LocaleEnglish},
+	}
+
+	newAlert := func(condition entity.AlertCondition, threshold float64, triggered bool) *entity.Alert {
+		return &entity.Alert{
+			ID:        "1",
+			Email:     testEmail,
+			Pair:      entity.CurrencyPair{Crypto: entity.CryptoCurrencyBTC, Fiat: entity.FiatCurrencyUAH},
+			Condition: condition,
+			Threshold: threshold,
+			Triggered: triggered,
+		}
+	}
+	isAlertEmail := mock.MatchedBy(func(opts *service.SendOptions) bool {
+		return opts.To == testEmail && strings.Contains(opts.Subject, "BTC-UAH") &&
+			strings.Contains(opts.Body, testConfig.App.PublicURL+"/api/v1/alerts?") && opts.HTML != ""
+	})
+	isTriggered := func(triggered bool) interface{} {
+		return mock.MatchedBy(func(a *entity.Alert) bool {
+			return a.ID == "1" && a.Triggered == triggered
+		})
+	}
+
+	testCases := []struct {
+		name     string
+		mock     func(m alertTestMocks)
+		expected expected
+	}{
+		{
+			name: "positive: above alert is triggered",
+			mock: func(m alertTestMocks) {
+				m.alertStorage.On("List", ctx).Return([]*entity.Alert{
+					newAlert(entity.AlertConditionAbove, 2000000, false),
+				}, nil)
+				m.emailStorage.On("List", ctx).Return(testSubscribers, nil)
+				m.cryptoAPI.On("GetRate", ctx, "BTC", "UAH").Return(2000001.0, nil)
+				m.emailAPI.On("Send", ctx, isAlertEmail).Return(nil)
+				m.alertStorage.On("Update", ctx, isTriggered(true)).Return(nil)
+			},
+			expected: expected{
+				err: nil,
+			},
+		},
+		{
+			name: "positive: below alert is not met",
+			mock: func(m alertTestMocks) {
+				m.alertStorage.On("List", ctx).Return([]*entity.Alert{
+					newAlert(entity.AlertConditionBelow, 2000000, false),
+				}, nil)
+				m.emailStorage.On("List", ctx).Return(testSubscribers, nil)
+				m.cryptoAPI.On("GetRate", ctx, "BTC", "UAH").Return(2000001.0, nil)
+			},
+			expected: expected{
+				err: nil,
+			},
+		},
+		{
+			name: "positive: triggered alert isn't sent again within hysteresis",
+			mock: func(m alertTestMocks) {
+				m.alertStorage.On("List", ctx).Return([]*entity.Alert{
+					newAlert(entity.AlertConditionAbove, 2000000, true),
+				}, nil)
+				m.emailStorage.On("List", ctx).Return(testSubscribers, nil)
+				m.cryptoAPI.On("GetRate", ctx, "BTC", "UAH").Return(1990000.0, nil)
+			},
+			expected: expected{
+				err: nil,
+			},
+		},
+		{
+			name: "positive: triggered alert is re-armed when rate moves back",
+			mock: func(m alertTestMocks) {
+				m.alertStorage.On("List", ctx).Return([]*entity.Alert{
+					newAlert(entity.AlertConditionAbove, 2000000, true),
+				}, nil)
+				m.emailStorage.On("List", ctx).Return(testSubscribers, nil)
+				m.cryptoAPI.On("GetRate", ctx, "BTC", "UAH").Return(1970000.0, nil)
+				m.alertStorage.On("Update", ctx, isTriggered(false)).Return(nil)
+			},
+			expected: expected{
+				err: nil,
+			},
+		},
+		{
+			name: "positive: alert stays armed if failed to send email",
+			mock: func(m alertTestMocks) {
+				m.alertStorage.On("List", ctx).Return([]*entity.Alert{
+					newAlert(entity.AlertConditionBelow, 2000000, false),
+				}, nil)
+				m.emailStorage.On("List", ctx).Return(testSubscribers, nil)
+				m.cryptoAPI.On("GetRate", ctx, "BTC", "UAH").Return(1999999.0, nil)
+				m.emailAPI.On("Send", ctx, isAlertEmail).Return(testSendErr)
+			},
+			expected: expected{
+				err: nil,
+			},
+		},
+		{
+			name: "positive: alert of unsubscribed email is deleted",
+			mock: func(m alertTestMocks) {
+				m.alertStorage.On("List", ctx).Return([]*entity.Alert{
+					newAlert(entity.AlertConditionAbove, 2000000, false),
+				}, nil)
+				m.emailStorage.On("List", ctx).Return(nil, nil)
+				m.alertStorage.On("Delete", ctx, "1").Return(nil)
+			},
+			expected: expected{
+				err: nil,
+			},
+		},
+		{
+			name: "positive: no alerts",
+			mock: func(m alertTestMocks) {
+				m.alertStorage.On("List", ctx).Return(nil, nil)
+			},
+			expected: expected{
+				err: nil,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			testMocks := newAlertTestMocks(t)
+			tc.mock(testMocks)
+
+			err := newAlertTestService(testMocks).Evaluate(ctx)
+			assert.Equal(t, tc.expected.err, err)
+		})
+	}
+}
+
+func TestAlertService_Evaluate_Change(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	testEmail := "email@test.com"
+	alert := &entity.Alert{
+		ID:        "1",
+		Email:     testEmail,
+		Pair:      entity.CurrencyPair{Crypto: entity.CryptoCurrencyETH, Fiat: entity.FiatCurrencyUSD},
+		Condition: entity.AlertConditionChange,
+		Threshold: 5,
+		Window:    24 * time.Hour,
+	}
+
+	testMocks := newAlertTestMocks(t)
+	testMocks.alertStorage.On("List", ctx).Return([]*entity.Alert{alert}, nil)
+	testMocks.emailStorage.On("List", ctx).Return([]*entity.Subscriber{
+		{Email: testEmail, Status: entity.SubscriberStatusConfirmed, Locale: entity.LocaleUkrainian},
+	}, nil)
+	testMocks.cryptoAPI.On("GetRate", ctx, "ETH", "USD").Return(2000.0, nil).Once()
+	testMocks.cryptoAPI.On("GetRate", ctx, "ETH", "USD").Return(1960.0, nil).Once()
+	testMocks.cryptoAPI.On("GetRate", ctx, "ETH", "USD").Return(1880.0, nil).Once()
+	testMocks.emailAPI.On("Send", ctx, mock.MatchedBy(func(opts *service.SendOptions) bool {
+		return opts.To == testEmail && strings.Contains(opts.Body, "-6,00%")
+	})).Return(nil).Once()
+	testMocks.alertStorage.On("Update", ctx, alert).Return(nil).Once()
+
+	alertService := newAlertTestService(testMocks)
+
+	// rate fell by 2% and 6% from the first one, only the latter exceeds threshold
+	for i := 0; i < 3; i++ {
+		require.NoError(t, alertService.Evaluate(ctx))
+	}
+
+	assert.True(t, alert.Triggered)
+}
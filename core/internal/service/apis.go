@@ -2,11 +2,14 @@ package service
 
 import (
 	"context"
+	"errors"
 )
 
 type APIs struct {
 	Email  EmailAPI
 	Crypto CryptoAPI
+	// Domain checks that domains of subscribed emails can receive emails, only syntax is checked if nil.
+	Domain DomainAPI
 }
 
 // EmailAPI provides methods for sending emails that are used in EmailService and
@@ -17,10 +20,22 @@ type EmailAPI interface {
 	Send(ctx context.Context, opts *SendOptions) error
 }
 
+// ErrEmailRejected is wrapped by errors returned from EmailAPI when email was rejected
+// permanently (e.g. invalid address), so that there is no sense in retrying.
+var ErrEmailRejected = errors.New("email rejected")
+
+// ErrEmailUnauthorized is wrapped by errors returned from EmailAPI when provider rejected its credentials or
+// configuration (e.g. wrong password). It's not a failure of recipient and no email can be sent until it's
+// fixed, so sending is aborted instead of recording dead letters.
+var ErrEmailUnauthorized = errors.New("email api unauthorized")
+
 type SendOptions struct {
 	To      string
 	Subject string
-	Body    string
+	// Body is plain text part of email.
+	Body string
+	// HTML is optional HTML part of email, clients that can't display it show Body instead.
+	HTML string
 }
 
 // CryptoAPI provides methods for getting crypto rates that are used in CryptoService and
@@ -30,3 +45,13 @@ type SendOptions struct {
 type CryptoAPI interface {
 	GetRate(ctx context.Context, fromCurrency, toCurrency string) (float64, error)
 }
+
+// DomainAPI provides methods for checking email domains that are used in EmailService and
+// implemented in external packages.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.27.1 --dir . --name DomainAPI --output ../../internal/service/mocks
+type DomainAPI interface {
+	// AcceptsMail reports whether domain has mail exchanger. Error is returned only when it can't be
+	// determined, e.g. DNS server is unreachable.
+	AcceptsMail(ctx context.Context, domain string) (bool, error)
+}
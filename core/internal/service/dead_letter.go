@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+)
+
+type deadLetterService struct {
+	serviceContext
+}
+
+func NewDeadLetterService(opts *Options) *deadLetterService {
+	return &deadLetterService{
+		serviceContext: serviceContext{
+			storages: opts.Storages,
+			apis:     opts.APIs,
+			logger:   opts.Logger.Named("DeadLetterService"),
+			cfg:      opts.Cfg,
+		},
+	}
+}
+
+func (s *deadLetterService) List(ctx context.Context) ([]*entity.DeadLetter, error) {
+	logger := s.logger.Named("List").
+		WithContext(ctx)
+
+	letters, err := s.storages.DeadLetter.List(ctx)
+	if err != nil {
+		logger.Error("failed to get dead letters from storage", "err", err)
+		return nil, fmt.Errorf("failed to get dead letters from storage: %w", err)
+	}
+
+	return letters, nil
+}
+
+func (s *deadLetterService) Redrive(ctx context.Context, opts *RedriveOptions) (*RedriveOutput, error) {
+	logger := s.logger.Named("Redrive").
+		WithContext(ctx).
+		With("ids", opts.IDs).
+		With("all", opts.All)
+
+	if len(opts.IDs) == 0 && !opts.All {
+		logger.Info("no letters selected")
+		return nil, ErrRedriveNoLetters
+	}
+
+	letters, err := s.storages.DeadLetter.List(ctx)
+	if err != nil {
+		logger.Error("failed to get dead letters from storage", "err", err)
+		return nil, fmt.Errorf("failed to get dead letters from storage: %w", err)
+	}
+
+	selected := make(map[string]struct{}, len(opts.IDs))
+	for _, id := range opts.IDs {
+		selected[id] = struct{}{}
+	}
+
+	output := &RedriveOutput{}
+	for _, letter := range letters {
+		if _, ok := selected[letter.ID]; !opts.All && !ok {
+			continue
+		}
+		delete(selected, letter.ID)
+
+		// letter may have been waiting for a long time, so recipient could unsubscribe or be deleted since then
+		subscriber, err := s.storages.Email.Get(ctx, letter.To)
+		if err != nil {
+			logger.Error(fmt.Sprintf("failed to get subscriber of letter: %s", letter.ID), "err", err)
+			return nil, fmt.Errorf("failed to get subscriber from storage: %w", err)
+		}
+		if subscriber == nil || subscriber.Status != entity.SubscriberStatusConfirmed {
+			err = s.storages.DeadLetter.Delete(ctx, letter.ID)
+			if err != nil {
+				logger.Error(fmt.Sprintf("failed to delete letter: %s", letter.ID), "err", err)
+				return nil, fmt.Errorf("failed to delete dead letter from storage: %w", err)
+			}
+			logger.Info(fmt.Sprintf("dropped letter of not subscribed recipient: %s", letter.ID))
+			output.Dropped = append(output.Dropped, letter.ID)
+			continue
+		}
+
+		err = s.apis.Email.Send(ctx, &SendOptions{
+			To:      letter.To,
+			Subject: letter.Subject,
+			Body:    letter.Body,
+			HTML:    letter.HTML,
+		})
+		if errors.Is(err, ErrEmailUnauthorized) {
+			// other letters would fail the same way
+			logger.Error(fmt.Sprintf("failed to redrive letter: %s", letter.ID), "err", err)
+			return nil, fmt.Errorf("failed to send email: %w", err)
+		}
+		if err != nil {
+			logger.Error(fmt.Sprintf("failed to redrive letter: %s", letter.ID), "err", err)
+			output.Failed = append(output.Failed, letter.ID)
+			continue
+		}
+
+		err = s.storages.DeadLetter.Delete(ctx, letter.ID)
+		if err != nil {
+			logger.Error(fmt.Sprintf("failed to delete letter: %s", letter.ID), "err", err)
+			return nil, fmt.Errorf("failed to delete dead letter from storage: %w", err)
+		}
+		output.Sent = append(output.Sent, letter.ID)
+	}
+
+	// selected letters that aren't in the list were redriven or dropped already
+	for _, id := range opts.IDs {
+		if _, ok := selected[id]; ok {
+			logger.Info(fmt.Sprintf("dropped unknown letter: %s", id))
+			output.Dropped = append(output.Dropped, id)
+			delete(selected, id)
+		}
+	}
+
+	logger.Info("successfully redrove dead letters", "output", output)
+	return output, nil
+}
+
+// saveDeadLetter records message that failed to be sent, so that it can be redriven later.
+func saveDeadLetter(ctx context.Context, storage DeadLetterStorage, message *SendOptions, sendErr error) error {
+	id, err := newID()
+	if err != nil {
+		return fmt.Errorf("failed to generate dead letter id: %w", err)
+	}
+
+	return storage.Save(ctx, &entity.DeadLetter{
+		ID:       id,
+		To:       message.To,
+		Subject:  message.Subject,
+		Body:     message.Body,
+		HTML:     message.HTML,
+		Error:    sendErr.Error(),
+		FailedAt: time.Now(),
+	})
+}
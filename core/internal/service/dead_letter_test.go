@@ -0,0 +1,211 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/core/internal/service/mocks"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+func TestDeadLetterService_Redrive(t *testing.T) {
+	t.Parallel()
+
+	type mocksForExecution struct {
+		deadLetterStorage *mocks.DeadLetterStorage
+		emailStorage      *mocks.EmailStorage
+		emailAPI          *mocks.EmailAPI
+	}
+
+	type args struct {
+		opts *service.RedriveOptions
+	}
+
+	type expected struct {
+		output *service.RedriveOutput
+		err    error
+	}
+
+	ctx := context.Background()
+
+	testLetters := []*entity.DeadLetter{
+		{ID: "1", To: "email1@test.com", Subject: "Rate info", Body: "body1"},
+		{ID: "2", To: "email2@test.com", Subject: "Rate info", Body: "body2"},
+		{ID: "3", To: "email3@test.com", Subject: "Rate info", Body: "body3"},
+		{ID: "4", To: "email4@test.com", Subject: "Rate info", Body: "body4"},
+	}
+	testSubscriber := func(email string, status entity.SubscriberStatus) *entity.Subscriber {
+		return &entity.Subscriber{Email: email, Status: status}
+	}
+	testSendOptions := func(letter *entity.DeadLetter) *service.SendOptions {
+		return &service.SendOptions{
+			To:      letter.To,
+			Subject: letter.Subject,
+			Body:    letter.Body,
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		mock     func(m mocksForExecution)
+		args     args
+		expected expected
+	}{
+		{
+			name: "positive: redrive all letters",
+			mock: func(m mocksForExecution) {
+				m.deadLetterStorage.On("List", ctx).Return(testLetters, nil)
+				m.emailStorage.On("Get", ctx, "email1@test.com").
+					Return(testSubscriber("email1@test.com", entity.SubscriberStatusConfirmed), nil)
+				m.emailStorage.On("Get", ctx, "email2@test.com").
+					Return(testSubscriber("email2@test.com", entity.SubscriberStatusConfirmed), nil)
+				m.emailStorage.On("Get", ctx, "email3@test.com").Return(nil, nil)
+				m.emailStorage.On("Get", ctx, "email4@test.com").
+					Return(testSubscriber("email4@test.com", entity.SubscriberStatusPending), nil)
+				m.emailAPI.On("Send", ctx, testSendOptions(testLetters[0])).Return(nil)
+				m.emailAPI.On("Send", ctx, testSendOptions(testLetters[1])).Return(errors.New("some err"))
+				m.deadLetterStorage.On("Delete", ctx, "1").Return(nil)
+				m.deadLetterStorage.On("Delete", ctx, "3").Return(nil)
+				m.deadLetterStorage.On("Delete", ctx, "4").Return(nil)
+			},
+			args: args{
+				opts: &service.RedriveOptions{All: true},
+			},
+			expected: expected{
+				output: &service.RedriveOutput{
+					Sent:    []string{"1"},
+					Failed:  []string{"2"},
+					Dropped: []string{"3", "4"},
+				},
+			},
+		},
+		{
+			name: "positive: redrive selected letters",
+			mock: func(m mocksForExecution) {
+				m.deadLetterStorage.On("List", ctx).Return(testLetters, nil)
+				m.emailStorage.On("Get", ctx, "email2@test.com").
+					Return(testSubscriber("email2@test.com", entity.SubscriberStatusConfirmed), nil)
+				m.emailAPI.On("Send", ctx, testSendOptions(testLetters[1])).Return(nil)
+				m.deadLetterStorage.On("Delete", ctx, "2").Return(nil)
+			},
+			args: args{
+				opts: &service.RedriveOptions{IDs: []string{"2"}},
+			},
+			expected: expected{
+				output: &service.RedriveOutput{
+					Sent: []string{"2"},
+				},
+			},
+		},
+		{
+			name: "positive: unknown letters are dropped",
+			mock: func(m mocksForExecution) {
+				m.deadLetterStorage.On("List", ctx).Return(testLetters, nil)
+				m.emailStorage.On("Get", ctx, "email2@test.com").
+					Return(testSubscriber("email2@test.com", entity.SubscriberStatusConfirmed), nil)
+				m.emailAPI.On("Send", ctx, testSendOptions(testLetters[1])).Return(nil)
+				m.deadLetterStorage.On("Delete", ctx, "2").Return(nil)
+			},
+			args: args{
+				opts: &service.RedriveOptions{IDs: []string{"5", "2", "5", "6"}},
+			},
+			expected: expected{
+				output: &service.RedriveOutput{
+					Sent:    []string{"2"},
+					Dropped: []string{"5", "6"},
+				},
+			},
+		},
+		{
+			name: "negative: email api unauthorized",
+			mock: func(m mocksForExecution) {
+				m.deadLetterStorage.On("List", ctx).Return(testLetters, nil)
+				m.emailStorage.On("Get", ctx, "email1@test.com").
+					Return(testSubscriber("email1@test.com", entity.SubscriberStatusConfirmed), nil)
+				m.emailAPI.On("Send", ctx, testSendOptions(testLetters[0])).
+					Return(fmt.Errorf("%w: bad password", service.ErrEmailUnauthorized))
+			},
+			args: args{
+				opts: &service.RedriveOptions{IDs: []string{"1", "2"}},
+			},
+			expected: expected{
+				err: errors.New("failed to send email: email api unauthorized: bad password"),
+			},
+		},
+		{
+			name: "negative: no letters selected",
+			mock: func(m mocksForExecution) {},
+			args: args{
+				opts: &service.RedriveOptions{},
+			},
+			expected: expected{
+				err: service.ErrRedriveNoLetters,
+			},
+		},
+		{
+			name: "negative: failed to get subscriber",
+			mock: func(m mocksForExecution) {
+				m.deadLetterStorage.On("List", ctx).Return(testLetters, nil)
+				m.emailStorage.On("Get", ctx, "email1@test.com").Return(nil, errors.New("some err"))
+			},
+			args: args{
+				opts: &service.RedriveOptions{IDs: []string{"1"}},
+			},
+			expected: expected{
+				err: errors.New("failed to get subscriber from storage: some err"),
+			},
+		},
+		{
+			name: "negative: failed to list letters",
+			mock: func(m mocksForExecution) {
+				m.deadLetterStorage.On("List", ctx).Return(nil, errors.New("some err"))
+			},
+			args: args{
+				opts: &service.RedriveOptions{All: true},
+			},
+			expected: expected{
+				err: errors.New("failed to get dead letters from storage: some err"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			testMocks := mocksForExecution{
+				deadLetterStorage: mocks.NewDeadLetterStorage(t),
+				emailStorage:      mocks.NewEmailStorage(t),
+				emailAPI:          mocks.NewEmailAPI(t),
+			}
+
+			tc.mock(testMocks)
+
+			deadLetterService := service.NewDeadLetterService(&service.Options{
+				Storages: service.Storages{
+					DeadLetter: testMocks.deadLetterStorage,
+					Email:      testMocks.emailStorage,
+				},
+				APIs: service.APIs{
+					Email: testMocks.emailAPI,
+				},
+				Logger: logging.NewZapLogger("debug"),
+				Cfg:    testConfig,
+			})
+
+			output, err := deadLetterService.Redrive(ctx, tc.args.opts)
+			if tc.expected.err != nil {
+				assert.EqualError(t, err, tc.expected.err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected.output, output)
+		})
+	}
+}
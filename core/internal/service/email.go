@@ -2,16 +2,55 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/pkg/token"
+	"golang.org/x/time/rate"
 )
 
 type emailService struct {
 	serviceContext
+	token *token.HMAC
+	// sendLimiter limits rate of sending rate info, it is shared between SendRateInfo calls.
+	sendLimiter     *rate.Limiter
+	sendConcurrency int
+	templates       *Templates
+	// disposableDomains are domains of emails that can't be subscribed, it may be nil.
+	disposableDomains *DomainList
+	// sending is held while rate info is sent to all subscribers, so that scheduled sending and send jobs
+	// don't overlap and subscribers don't get the same rate info twice.
+	sending sync.Mutex
 }
 
 func NewEmailService(opts *Options) *emailService {
+	sendLimit := rate.Limit(opts.Cfg.Sender.RateLimit)
+	if opts.Cfg.Sender.RateLimit <= 0 {
+		sendLimit = rate.Inf
+	}
+	sendBurst := opts.Cfg.Sender.Burst
+	if sendBurst < 1 {
+		sendBurst = 1
+	}
+	sendConcurrency := opts.Cfg.Sender.Concurrency
+	if sendConcurrency < 1 {
+		sendConcurrency = 1
+	}
+	templates := opts.Templates
+	if templates == nil {
+		var err error
+		templates, err = LoadTemplates("")
+		if err != nil {
+			// embedded templates are checked by tests, so they can't fail to load
+			panic(err)
+		}
+	}
+
 	return &emailService{
 		serviceContext: serviceContext{
 			storages: opts.Storages,
@@ -19,64 +58,342 @@ func NewEmailService(opts *Options) *emailService {
 			logger:   opts.Logger.Named("EmailService"),
 			cfg:      opts.Cfg,
 		},
+		token:             token.NewHMAC(opts.Cfg.Token.Secret),
+		sendLimiter:       rate.NewLimiter(sendLimit, sendBurst),
+		sendConcurrency:   sendConcurrency,
+		templates:         templates,
+		disposableDomains: opts.DisposableDomains,
 	}
 }
 
-func (s *emailService) Subscribe(ctx context.Context, email string) error {
+func (s *emailService) Subscribe(ctx context.Context, opts *SubscribeOptions) (*entity.Subscriber, error) {
 	logger := s.logger.Named("Subscribe").
+		WithContext(ctx).
+		With("opts", opts)
+
+	email, err := s.validateEmail(ctx, opts.Email)
+	if err != nil {
+		logger.Info("email is rejected", "err", err)
+		return nil, err
+	}
+	logger = logger.With("email", email)
+
+	pairs := opts.Pairs
+	if len(pairs) == 0 {
+		pairs = []entity.CurrencyPair{entity.DefaultCurrencyPair}
+	}
+	locale := opts.Locale
+	if locale == "" {
+		locale = entity.DefaultLocale
+	}
+
+	subscriber, err := s.storages.Email.Get(ctx, email)
+	if err != nil {
+		logger.Error("failed to get email from storage", "err", err)
+		return nil, fmt.Errorf("failed to get email from storage: %w", err)
+	}
+
+	now := time.Now()
+	if subscriber != nil {
+		if subscriber.Status == entity.SubscriberStatusConfirmed {
+			logger.Info("email already exists")
+			return nil, ErrSubscribeAlreadySubscribed
+		}
+		if !subscriber.IsExpired(now, s.pendingTTL()) {
+			logger.Info("email is waiting for confirmation")
+			return nil, ErrSubscribeConfirmationPending
+		}
+
+		// pending subscription has expired, so it is renewed and confirmation email is sent again
+		subscriber.CreatedAt = now
+		subscriber.Pairs = pairs
+		subscriber.Locale = locale
+		err = s.storages.Email.Update(ctx, subscriber)
+	} else {
+		subscriber = &entity.Subscriber{
+			Email:     email,
+			Status:    entity.SubscriberStatusPending,
+			CreatedAt: now,
+			Pairs:     pairs,
+			Locale:    locale,
+		}
+		err = s.storages.Email.Save(ctx, subscriber)
+	}
+	if err != nil {
+		if errors.Is(err, ErrAlreadyExists) {
+			// email was saved by concurrent request after it was checked
+			logger.Info("email already exists")
+			return nil, ErrSubscribeAlreadySubscribed
+		}
+		logger.Error("failed to save email", "err", err)
+		return nil, fmt.Errorf("failed to save email to storage: %w", err)
+	}
+
+	message, err := s.confirmationEmail(subscriber)
+	if err == nil {
+		err = s.apis.Email.Send(ctx, message)
+	}
+	if err != nil {
+		logger.Error("failed to send confirmation email", "err", err)
+
+		// remove pending subscription, so that user doesn't have to wait for it to expire to try again
+		if err := s.storages.Email.Delete(ctx, email); err != nil {
+			logger.Error("failed to delete pending email", "err", err)
+		}
+		return nil, fmt.Errorf("failed to send confirmation email: %w", err)
+	}
+
+	logger.Info("successfully subscribed, waiting for confirmation")
+	return subscriber, nil
+}
+
+// validateEmail normalizes email and checks that it can be subscribed. Returns ErrSubscribeInvalidEmail,
+// ErrSubscribeDisposableEmail or ErrSubscribeUndeliverableEmail if it can't.
+func (s *emailService) validateEmail(ctx context.Context, email string) (string, error) {
+	logger := s.logger.Named("validateEmail").
 		WithContext(ctx).
 		With("email", email)
 
-	exists, err := s.storages.Email.Exist(ctx, email)
+	local, domain, err := normalizeEmail(email, s.cfg.EmailValidation)
+	if err != nil {
+		return "", err
+	}
+
+	if s.disposableDomains != nil && s.disposableDomains.Contains(domain) {
+		return "", ErrSubscribeDisposableEmail
+	}
+
+	if s.apis.Domain != nil {
+		accepts, err := s.apis.Domain.AcceptsMail(ctx, domain)
+		if err != nil {
+			// it's unknown whether domain is valid, so email isn't rejected because of resolver failure
+			logger.Warn("failed to check domain", "domain", domain, "err", err)
+		} else if !accepts {
+			return "", ErrSubscribeUndeliverableEmail
+		}
+	}
+
+	return local + "@" + domain, nil
+}
+
+const (
+	confirmationTokenPurpose = "confirm"
+	confirmationTemplate     = "confirmation"
+)
+
+func (s *emailService) ConfirmSubscription(ctx context.Context, email, confirmationToken string) error {
+	logger := s.logger.Named("ConfirmSubscription").
+		WithContext(ctx).
+		With("email", email)
+
+	err := s.token.VerifyExpiring(confirmationToken, time.Now(), confirmationTokenPurpose, email)
+	if err != nil {
+		logger.Info("invalid confirmation token", "err", err)
+		if errors.Is(err, token.ErrExpired) {
+			return ErrConfirmSubscriptionTokenExpired
+		}
+		return ErrConfirmSubscriptionInvalidToken
+	}
+
+	subscriber, err := s.storages.Email.Get(ctx, email)
 	if err != nil {
 		logger.Error("failed to get email from storage", "err", err)
 		return fmt.Errorf("failed to get email from storage: %w", err)
 	}
-	if exists {
-		logger.Info("email already exists")
-		return ErrSubscribeAlreadySubscribed
+	if subscriber == nil {
+		logger.Info("email doesn't exist")
+		return ErrConfirmSubscriptionNotFound
+	}
+	if subscriber.Status == entity.SubscriberStatusConfirmed {
+		logger.Info("email already confirmed")
+		return nil
 	}
 
-	err = s.storages.Email.Save(ctx, email)
+	subscriber.Status = entity.SubscriberStatusConfirmed
+	err = s.storages.Email.Update(ctx, subscriber)
 	if err != nil {
-		logger.Error("failed to save email", "err", err)
-		return fmt.Errorf("failed to save email to storage: %w", err)
+		logger.Error("failed to update email", "err", err)
+		return fmt.Errorf("failed to update email in storage: %w", err)
 	}
 
-	logger.Info("successfully subscribed")
+	logger.Info("successfully confirmed subscription")
 	return nil
 }
 
 func (s *emailService) SendRateInfo(ctx context.Context) (*SendRateInfoOutput, error) {
+	if !s.sending.TryLock() {
+		s.logger.Named("SendRateInfo").WithContext(ctx).Info("rate info is already being sent")
+		return nil, ErrSendRateInfoInProgress
+	}
+	defer s.sending.Unlock()
+
+	return s.sendRateInfo(ctx, &sendRateInfoOptions{})
+}
+
+// sendRateInfoOptions allow to track progress of sending and to resume interrupted sending.
+type sendRateInfoOptions struct {
+	// after is an email up to which (inclusive) recipients are skipped, recipients are processed in order
+	// of emails, so that interrupted job can be resumed from the last processed one.
+	after string
+	// skip contains emails that shouldn't be sent to, e.g. processed by interrupted job after the last
+	// email of continuous progress.
+	skip map[string]struct{}
+	// onStart is called with sorted emails of recipients before sending.
+	onStart func(emails []string)
+	// onProcessed is called after each recipient is processed, err is nil if email was sent.
+	// It's not called for recipients that weren't processed because ctx was canceled.
+	onProcessed func(email string, err error)
+}
+
+var (
+	errNoRatesAvailable = errors.New("no rates available")
+	// errSendingAborted is error of messages that were not sent, because credentials of email api were rejected.
+	errSendingAborted = errors.New("sending was aborted")
+)
+
+func (s *emailService) sendRateInfo(ctx context.Context, opts *sendRateInfoOptions) (*SendRateInfoOutput, error) {
 	logger := s.logger.Named("SendRateInfo").
 		WithContext(ctx)
 
-	emails, err := s.storages.Email.List(ctx)
+	subscribers, err := s.storages.Email.List(ctx)
 	if err != nil {
 		logger.Error("failed to get emails from storage", "err", err)
 		return nil, fmt.Errorf("failed to get emails from storage: %w", err)
 	}
 
-	rate, err := s.apis.Crypto.GetRate(ctx, entity.CryptoCurrencyBTC.String(), entity.FiatCurrencyUSD.String())
+	now := time.Now()
+	var recipients []*entity.Subscriber
+	for _, subscriber := range subscribers {
+		if subscriber.Status == entity.SubscriberStatusConfirmed {
+			if _, ok := opts.skip[subscriber.Email]; !ok && subscriber.Email > opts.after {
+				recipients = append(recipients, subscriber)
+			}
+			continue
+		}
+
+		// expired pending subscriptions are removed, so that they don't pile up in storage
+		if subscriber.IsExpired(now, s.pendingTTL()) {
+			err = s.storages.Email.Delete(ctx, subscriber.Email)
+			if err != nil {
+				logger.Error(fmt.Sprintf("failed to delete expired email: %s", subscriber.Email), "err", err)
+			}
+		}
+	}
+
+	rates, err := s.getRates(ctx, recipients)
 	if err != nil {
 		logger.Error("failed to get rate", "err", err)
 		return nil, fmt.Errorf("failed to get rate: %w", err)
 	}
+	fetchedAt := time.Now()
+	lastRates := s.getLastRates(ctx)
 
-	var failedEmails []string
-	for _, email := range emails {
-		err = s.apis.Email.Send(ctx, &SendOptions{
-			To:      email,
-			Subject: "Rate info",
-			Body:    fmt.Sprintf("Current rate is %f", rate),
-		})
+	onProcessed := func(email string, err error) {}
+	if opts.onProcessed != nil {
+		onProcessed = opts.onProcessed
+	}
+	sort.Slice(recipients, func(i, j int) bool {
+		return recipients[i].Email < recipients[j].Email
+	})
+	if opts.onStart != nil {
+		emails := make([]string, len(recipients))
+		for i, recipient := range recipients {
+			emails[i] = recipient.Email
+		}
+		opts.onStart(emails)
+	}
+
+	messages := make([]*SendOptions, len(recipients))
+	for i, recipient := range recipients {
+		data := rateInfoEmailData{
+			Timestamp:       fetchedAt.UTC(),
+			PreferencesLink: s.preferencesLink(recipient.Email),
+			UnsubscribeLink: s.unsubscribeLink(recipient.Email),
+		}
+		for _, pair := range recipient.Pairs {
+			if pairRate, ok := rates[pair]; ok {
+				data.Rates = append(data.Rates, rateInfoEmailRate{
+					Pair:   pair,
+					Rate:   pairRate,
+					Change: newRateChange(pairRate, lastRates[pair]),
+				})
+			}
+		}
+
+		// none of subscriber's pairs is available, so there is nothing to send
+		if len(data.Rates) == 0 {
+			onProcessed(recipient.Email, errNoRatesAvailable)
+			continue
+		}
+
+		message, err := s.templates.Render(recipient.Locale, rateInfoTemplate, data)
 		if err != nil {
-			logger.Error(fmt.Sprintf("failed to send email to: %s", email), "err", err)
-			failedEmails = append(failedEmails, email)
+			logger.Error("failed to render rate info email", "err", err)
+			return nil, fmt.Errorf("failed to render rate info email: %w", err)
+		}
+
+		messages[i] = &SendOptions{
+			To:      recipient.Email,
+			Subject: message.Subject,
+			Body:    message.Text,
+			HTML:    message.HTML,
+		}
+	}
+
+	sendErrs := s.sendAll(ctx, messages, func(i int, err error) {
+		onProcessed(recipients[i].Email, err)
+	})
+
+	// errors are aggregated in order of recipients, so that output doesn't depend on order of sending
+	var (
+		failedEmails []string
+		abortErr     error
+	)
+	for i, recipient := range recipients {
+		if abortErr == nil && errors.Is(sendErrs[i], ErrEmailUnauthorized) {
+			abortErr = sendErrs[i]
+		}
+
+		switch {
+		case messages[i] == nil:
+			logger.Error(fmt.Sprintf("no rates available for: %s", recipient.Email), "err", errNoRatesAvailable)
+		case sendErrs[i] != nil:
+			logger.Error(fmt.Sprintf("failed to send email to: %s", recipient.Email), "err", sendErrs[i])
+
+			// emails interrupted by cancellation or abort weren't really attempted and rejected credentials
+			// aren't failure of recipient, so they are not dead letters
+			if !errors.Is(sendErrs[i], context.Canceled) && !errors.Is(sendErrs[i], context.DeadlineExceeded) &&
+				!errors.Is(sendErrs[i], errSendingAborted) && !errors.Is(sendErrs[i], ErrEmailUnauthorized) {
+				if err := saveDeadLetter(ctx, s.storages.DeadLetter, messages[i], sendErrs[i]); err != nil {
+					logger.Error(fmt.Sprintf("failed to save dead letter for: %s", recipient.Email), "err", err)
+				}
+			}
+		default:
+			continue
 		}
+		failedEmails = append(failedEmails, recipient.Email)
 	}
 
-	if len(failedEmails) == len(emails) {
+	if abortErr != nil {
+		logger.Error("sending was aborted", "err", abortErr)
+		return &SendRateInfoOutput{
+			FailedEmails: failedEmails,
+		}, fmt.Errorf("%w: %w", errSendingAborted, abortErr)
+	}
+
+	if ctx.Err() != nil {
+		logger.Error("sending was interrupted", "err", ctx.Err())
+		return &SendRateInfoOutput{
+			FailedEmails: failedEmails,
+		}, fmt.Errorf("sending was interrupted: %w", ctx.Err())
+	}
+
+	// rates are remembered only after sending is finished, so that resumed sending shows the same changes
+	if len(failedEmails) < len(recipients) {
+		s.saveLastRates(ctx, rates, fetchedAt)
+	}
+
+	if len(recipients) > 0 && len(failedEmails) == len(recipients) {
 		return &SendRateInfoOutput{
 			FailedEmails: failedEmails,
 		}, ErrSendRateInfoFailedToSendToAllEmails
@@ -87,3 +404,303 @@ func (s *emailService) SendRateInfo(ctx context.Context) (*SendRateInfoOutput, e
 		FailedEmails: failedEmails,
 	}, nil
 }
+
+// sendAll sends messages using pool of workers limited by sendLimiter. Nil messages are skipped.
+// Returned errors are in the same order as messages. Messages that were not sent because ctx was
+// canceled get ctx error. onSent is called from workers after each attempt to send message,
+// except for ones interrupted by ctx cancellation. Sending stops when email api reports that its
+// credentials are rejected, that message isn't passed to onSent and messages that were not sent
+// get errSendingAborted.
+func (s *emailService) sendAll(ctx context.Context, messages []*SendOptions, onSent func(i int, err error)) []error {
+	errs := make([]error, len(messages))
+
+	aborted := make(chan struct{})
+	var abortOnce sync.Once
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < s.sendConcurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// limiter doesn't check ctx when rate is unlimited
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				if err := s.sendLimiter.Wait(ctx); err != nil {
+					errs[i] = err
+					continue
+				}
+				select {
+				case <-aborted:
+					errs[i] = errSendingAborted
+					continue
+				default:
+				}
+
+				errs[i] = s.apis.Email.Send(ctx, messages[i])
+				if errors.Is(errs[i], ErrEmailUnauthorized) {
+					abortOnce.Do(func() { close(aborted) })
+					continue
+				}
+				if errs[i] == nil || ctx.Err() == nil {
+					onSent(i, errs[i])
+				}
+			}
+		}()
+	}
+
+	next := 0
+feed:
+	for ; next < len(messages); next++ {
+		if messages[next] == nil {
+			continue
+		}
+
+		select {
+		case jobs <- next:
+		case <-ctx.Done():
+			break feed
+		case <-aborted:
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	notSentErr := ctx.Err()
+	select {
+	case <-aborted:
+		notSentErr = errSendingAborted
+	default:
+	}
+	for ; next < len(messages); next++ {
+		if messages[next] != nil {
+			errs[next] = notSentErr
+		}
+	}
+
+	return errs
+}
+
+// getRates fetches rate of each distinct pair of subscribers once. Pairs that failed to be fetched
+// are left out of result, error is returned only if none of the pairs was fetched.
+func (s *emailService) getRates(ctx context.Context, subscribers []*entity.Subscriber) (map[entity.CurrencyPair]float64, error) {
+	logger := s.logger.Named("getRates").
+		WithContext(ctx)
+
+	rates := make(map[entity.CurrencyPair]float64)
+	requested := make(map[entity.CurrencyPair]struct{})
+
+	var lastErr error
+	for _, subscriber := range subscribers {
+		for _, pair := range subscriber.Pairs {
+			if _, ok := requested[pair]; ok {
+				continue
+			}
+			requested[pair] = struct{}{}
+
+			rate, err := s.apis.Crypto.GetRate(ctx, pair.Crypto.String(), pair.Fiat.String())
+			if err != nil {
+				logger.Error(fmt.Sprintf("failed to get rate for: %s", pair), "err", err)
+				lastErr = err
+				continue
+			}
+			rates[pair] = rate
+		}
+	}
+
+	if len(rates) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	return rates, nil
+}
+
+// getLastRates returns rates sent in the last email by pair. Changes of rates are optional part of
+// email, so failure to get them is only logged.
+func (s *emailService) getLastRates(ctx context.Context) map[entity.CurrencyPair]*entity.Rate {
+	logger := s.logger.Named("getLastRates").
+		WithContext(ctx)
+
+	rates, err := s.storages.Rate.List(ctx)
+	if err != nil {
+		logger.Error("failed to get last rates from storage", "err", err)
+		return nil
+	}
+
+	lastRates := make(map[entity.CurrencyPair]*entity.Rate, len(rates))
+	for _, lastRate := range rates {
+		lastRates[lastRate.Pair] = lastRate
+	}
+	return lastRates
+}
+
+func (s *emailService) saveLastRates(ctx context.Context, rates map[entity.CurrencyPair]float64, fetchedAt time.Time) {
+	logger := s.logger.Named("saveLastRates").
+		WithContext(ctx)
+
+	for pair, value := range rates {
+		err := s.storages.Rate.Save(ctx, &entity.Rate{
+			Pair:      pair,
+			Value:     value,
+			FetchedAt: fetchedAt,
+		})
+		if err != nil {
+			logger.Error(fmt.Sprintf("failed to save last rate for: %s", pair), "err", err)
+		}
+	}
+}
+
+func (s *emailService) Unsubscribe(ctx context.Context, email string) error {
+	logger := s.logger.Named("Unsubscribe").
+		WithContext(ctx).
+		With("email", email)
+
+	exists, err := s.storages.Email.Exist(ctx, email)
+	if err != nil {
+		logger.Error("failed to get email from storage", "err", err)
+		return fmt.Errorf("failed to get email from storage: %w", err)
+	}
+	if !exists {
+		logger.Info("email doesn't exist")
+		return ErrUnsubscribeNotSubscribed
+	}
+
+	err = s.storages.Email.Delete(ctx, email)
+	if err != nil {
+		logger.Error("failed to delete email", "err", err)
+		return fmt.Errorf("failed to delete email from storage: %w", err)
+	}
+
+	logger.Info("successfully unsubscribed")
+	return nil
+}
+
+const unsubscribeTokenPurpose = "unsubscribe"
+
+func (s *emailService) VerifyUnsubscribeToken(email, unsubscribeToken string) error {
+	if !s.token.Verify(unsubscribeToken, unsubscribeTokenPurpose, email) {
+		return ErrUnsubscribeInvalidToken
+	}
+	return nil
+}
+
+const preferencesTokenPurpose = "preferences"
+
+func (s *emailService) GetPairs(ctx context.Context, email, token string) ([]entity.CurrencyPair, error) {
+	logger := s.logger.Named("GetPairs").
+		WithContext(ctx).
+		With("email", email)
+
+	if !s.token.Verify(token, preferencesTokenPurpose, email) {
+		logger.Info("invalid preferences token")
+		return nil, ErrUpdatePairsInvalidToken
+	}
+
+	subscriber, err := s.storages.Email.Get(ctx, email)
+	if err != nil {
+		logger.Error("failed to get email from storage", "err", err)
+		return nil, fmt.Errorf("failed to get email from storage: %w", err)
+	}
+	if subscriber == nil {
+		logger.Info("email doesn't exist")
+		return nil, ErrUpdatePairsNotSubscribed
+	}
+
+	logger.Info("successfully got pairs")
+	return subscriber.Pairs, nil
+}
+
+func (s *emailService) UpdatePairs(ctx context.Context, opts *UpdatePairsOptions) error {
+	logger := s.logger.Named("UpdatePairs").
+		WithContext(ctx).
+		With("email", opts.Email).
+		With("pairs", opts.Pairs)
+
+	if !s.token.Verify(opts.Token, preferencesTokenPurpose, opts.Email) {
+		logger.Info("invalid preferences token")
+		return ErrUpdatePairsInvalidToken
+	}
+
+	if len(opts.Pairs) == 0 {
+		logger.Info("no pairs provided")
+		return ErrUpdatePairsNoPairs
+	}
+
+	subscriber, err := s.storages.Email.Get(ctx, opts.Email)
+	if err != nil {
+		logger.Error("failed to get email from storage", "err", err)
+		return fmt.Errorf("failed to get email from storage: %w", err)
+	}
+	if subscriber == nil {
+		logger.Info("email doesn't exist")
+		return ErrUpdatePairsNotSubscribed
+	}
+
+	subscriber.Pairs = opts.Pairs
+	err = s.storages.Email.Update(ctx, subscriber)
+	if err != nil {
+		logger.Error("failed to update email", "err", err)
+		return fmt.Errorf("failed to update email in storage: %w", err)
+	}
+
+	logger.Info("successfully updated pairs")
+	return nil
+}
+
+// unsubscribeLink returns one-click unsubscribe link signed for email.
+func (s *emailService) unsubscribeLink(email string) string {
+	query := url.Values{
+		"email": {email},
+		"token": {s.token.Sign(unsubscribeTokenPurpose, email)},
+	}
+	return fmt.Sprintf("%s/api/v1/unsubscribe?%s", s.cfg.App.PublicURL, query.Encode())
+}
+
+// preferencesLink returns link for changing currency pairs signed for email.
+func (s *emailService) preferencesLink(email string) string {
+	query := url.Values{
+		"email": {email},
+		"token": {s.token.Sign(preferencesTokenPurpose, email)},
+	}
+	return fmt.Sprintf("%s/api/v1/subscribe/pairs?%s", s.cfg.App.PublicURL, query.Encode())
+}
+
+// confirmationEmailData is passed to confirmation templates.
+type confirmationEmailData struct {
+	ConfirmationLink string
+	// ExpiresAt is time when confirmation link expires in UTC.
+	ExpiresAt time.Time
+}
+
+// confirmationEmail renders email in locale of subscriber with confirmation link, which expires together with
+// pending subscription.
+func (s *emailService) confirmationEmail(subscriber *entity.Subscriber) (*SendOptions, error) {
+	expiresAt := subscriber.CreatedAt.Add(s.pendingTTL())
+	query := url.Values{
+		"email": {subscriber.Email},
+		"token": {s.token.SignExpiring(expiresAt, confirmationTokenPurpose, subscriber.Email)},
+	}
+
+	message, err := s.templates.Render(subscriber.Locale, confirmationTemplate, confirmationEmailData{
+		ConfirmationLink: fmt.Sprintf("%s/api/v1/subscribe/confirm?%s", s.cfg.App.PublicURL, query.Encode()),
+		ExpiresAt:        expiresAt.UTC(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render confirmation email: %w", err)
+	}
+
+	return &SendOptions{
+		To:      subscriber.Email,
+		Subject: message.Subject,
+		Body:    message.Text,
+		HTML:    message.HTML,
+	}, nil
+}
+
+func (s *emailService) pendingTTL() time.Duration {
+	return time.Duration(s.cfg.Subscription.PendingTTL) * time.Second
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+
+	"github.com/vadimpk/gses-2023/core/config"
+	"golang.org/x/net/idna"
+)
+
+// maxLocalPartLength is max length of local part of email defined by RFC 5321.
+const maxLocalPartLength = 64
+
+// domainProfile converts domains to ASCII form and checks that they are valid host names.
+var domainProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.VerifyDNSLength(true),
+)
+
+// normalizeEmail trims email, folds case of its domain and converts it to ASCII form, so that
+// different spellings of the same address are subscribed once. Case of local part is folded and
+// plus tag is removed if it's configured. Returns local part and domain of normalized email.
+func normalizeEmail(email string, cfg config.EmailValidation) (string, string, error) {
+	email = strings.TrimSpace(email)
+
+	// display names, comments and other forms accepted by parser are not addresses by themselves
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "", "", ErrSubscribeInvalidEmail
+	}
+
+	at := strings.LastIndexByte(email, '@')
+	local, domain := email[:at], email[at+1:]
+
+	domain, err = domainProfile.ToASCII(domain)
+	if err != nil || !strings.Contains(domain, ".") {
+		return "", "", ErrSubscribeInvalidEmail
+	}
+
+	if cfg.StripPlusTag {
+		if tag := strings.IndexByte(local, '+'); tag > 0 {
+			local = local[:tag]
+		}
+	}
+	if cfg.LowercaseLocalPart {
+		local = strings.ToLower(local)
+	}
+	if len(local) > maxLocalPartLength {
+		return "", "", ErrSubscribeInvalidEmail
+	}
+
+	return local, domain, nil
+}
+
+// NormalizeEmail normalizes email the same way as it's normalized on subscribe, it's used to normalize
+// emails stored before normalization was introduced.
+func NormalizeEmail(email string, cfg config.EmailValidation) (string, error) {
+	local, domain, err := normalizeEmail(email, cfg)
+	if err != nil {
+		return "", err
+	}
+	return local + "@" + domain, nil
+}
+
+// DomainList is a set of domains, domain is in the list if it or any of its parent domains is listed.
+type DomainList struct {
+	domains map[string]struct{}
+}
+
+// NewDomainList creates list of domains, domains are normalized the same way as domains of emails.
+func NewDomainList(domains ...string) (*DomainList, error) {
+	list := &DomainList{
+		domains: make(map[string]struct{}, len(domains)),
+	}
+	for _, domain := range domains {
+		normalized, err := domainProfile.ToASCII(strings.TrimSuffix(domain, "."))
+		if err != nil {
+			return nil, fmt.Errorf("invalid domain %q: %w", domain, err)
+		}
+		list.domains[normalized] = struct{}{}
+	}
+
+	return list, nil
+}
+
+// LoadDomainList loads list of domains from file with one domain per line. Empty lines and lines
+// starting with "#" are skipped.
+func LoadDomainList(path string) (*DomainList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open domain list: %w", err)
+	}
+	defer file.Close()
+
+	var domains []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, line)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read domain list: %w", err)
+	}
+
+	return NewDomainList(domains...)
+}
+
+// Contains reports whether normalized domain or any of its parent domains is in the list.
+func (l *DomainList) Contains(domain string) bool {
+	for {
+		if _, ok := l.domains[domain]; ok {
+			return true
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+// Len returns number of domains in the list.
+func (l *DomainList) Len() int {
+	return len(l.domains)
+}
@@ -0,0 +1,232 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/config"
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/core/internal/service/mocks"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+var validationTestConfig = func() *config.Config {
+	cfg := *testConfig
+	cfg.EmailValidation = config.EmailValidation{
+		StripPlusTag:       true,
+		LowercaseLocalPart: true,
+	}
+	return &cfg
+}()
+
+func TestEmailService_Subscribe_Validation(t *testing.T) {
+	t.Parallel()
+
+	type mocksForExecution struct {
+		emailStorage *mocks.EmailStorage
+		emailAPI     *mocks.EmailAPI
+		domainAPI    *mocks.DomainAPI
+	}
+
+	type args struct {
+		email string
+	}
+
+	type expected struct {
+		email string
+		err   error
+	}
+
+	ctx := context.Background()
+
+	// subscribed expects email to be subscribed as normalized email.
+	subscribed := func(m mocksForExecution, email string) {
+		m.emailStorage.On("Get", ctx, email).Return(nil, nil)
+		m.emailStorage.On("Save", ctx, mock.MatchedBy(func(s *entity.Subscriber) bool {
+			return s.Email == email
+		})).Return(nil)
+		m.emailAPI.On("Send", ctx, mock.MatchedBy(func(opts *service.SendOptions) bool {
+			return opts.To == email
+		})).Return(nil)
+	}
+
+	disposableDomains, err := service.NewDomainList("mailinator.com")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		mock     func(m mocksForExecution)
+		args     args
+		expected expected
+	}{
+		{
+			name: "positive: email is normalized",
+			mock: func(m mocksForExecution) {
+				m.domainAPI.On("AcceptsMail", ctx, "example.com").Return(true, nil)
+				subscribed(m, "user@example.com")
+			},
+			args: args{
+				email: "  User+News@Example.COM ",
+			},
+			expected: expected{
+				email: "user@example.com",
+			},
+		},
+		{
+			name: "positive: internationalized domain is converted to ascii",
+			mock: func(m mocksForExecution) {
+				m.domainAPI.On("AcceptsMail", ctx, "xn--e1afmkfd.xn--j1amh").Return(true, nil)
+				subscribed(m, "user@xn--e1afmkfd.xn--j1amh")
+			},
+			args: args{
+				email: "user@Пример.укр",
+			},
+			expected: expected{
+				email: "user@xn--e1afmkfd.xn--j1amh",
+			},
+		},
+		{
+			name: "positive: domain is not checked when resolver fails",
+			mock: func(m mocksForExecution) {
+				m.domainAPI.On("AcceptsMail", ctx, "example.com").Return(false, errors.New("some err"))
+				subscribed(m, "user@example.com")
+			},
+			args: args{
+				email: "user@example.com",
+			},
+			expected: expected{
+				email: "user@example.com",
+			},
+		},
+		{
+			name: "negative: email without domain",
+			mock: func(m mocksForExecution) {},
+			args: args{
+				email: "user@",
+			},
+			expected: expected{
+				err: service.ErrSubscribeInvalidEmail,
+			},
+		},
+		{
+			name: "negative: email with display name",
+			mock: func(m mocksForExecution) {},
+			args: args{
+				email: "User <user@example.com>",
+			},
+			expected: expected{
+				err: service.ErrSubscribeInvalidEmail,
+			},
+		},
+		{
+			name: "negative: domain without top level domain",
+			mock: func(m mocksForExecution) {},
+			args: args{
+				email: "user@localhost",
+			},
+			expected: expected{
+				err: service.ErrSubscribeInvalidEmail,
+			},
+		},
+		{
+			name: "negative: invalid domain",
+			mock: func(m mocksForExecution) {},
+			args: args{
+				email: "user@-example.com",
+			},
+			expected: expected{
+				err: service.ErrSubscribeInvalidEmail,
+			},
+		},
+		{
+			name: "negative: disposable domain",
+			mock: func(m mocksForExecution) {},
+			args: args{
+				email: "user@Inbox.Mailinator.com",
+			},
+			expected: expected{
+				err: service.ErrSubscribeDisposableEmail,
+			},
+		},
+		{
+			name: "negative: domain doesn't accept mail",
+			mock: func(m mocksForExecution) {
+				m.domainAPI.On("AcceptsMail", ctx, "gmial.com").Return(false, nil)
+			},
+			args: args{
+				email: "user@gmial.com",
+			},
+			expected: expected{
+				err: service.ErrSubscribeUndeliverableEmail,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			testMocks := mocksForExecution{
+				emailStorage: mocks.NewEmailStorage(t),
+				emailAPI:     mocks.NewEmailAPI(t),
+				domainAPI:    mocks.NewDomainAPI(t),
+			}
+
+			tc.mock(testMocks)
+
+			emailService := service.NewEmailService(&service.Options{
+				Storages: service.Storages{
+					Email: testMocks.emailStorage,
+				},
+				APIs: service.APIs{
+					Email:  testMocks.emailAPI,
+					Domain: testMocks.domainAPI,
+				},
+				Logger:            logging.NewZapLogger("debug"),
+				Cfg:               validationTestConfig,
+				DisposableDomains: disposableDomains,
+			})
+
+			subscriber, err := emailService.Subscribe(ctx, &service.SubscribeOptions{
+				Email: tc.args.email,
+			})
+			assert.ErrorIs(t, err, tc.expected.err)
+			if tc.expected.err == nil {
+				assert.Equal(t, tc.expected.email, subscriber.Email)
+			}
+		})
+	}
+}
+
+func TestLoadDomainList(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "domains.txt")
+	err := os.WriteFile(path, []byte("# disposable domains\nmailinator.com\n\n  Temp-Mail.org  \n"), 0600)
+	require.NoError(t, err)
+
+	list, err := service.LoadDomainList(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, list.Len())
+	assert.True(t, list.Contains("mailinator.com"))
+	assert.True(t, list.Contains("temp-mail.org"))
+	assert.True(t, list.Contains("inbox.mailinator.com"))
+	assert.False(t, list.Contains("notmailinator.com"))
+	assert.False(t, list.Contains("com"))
+}
+
+func TestLoadDomainList_NotExist(t *testing.T) {
+	t.Parallel()
+
+	_, err := service.LoadDomainList(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"github.com/vadimpk/gses-2023/core/config"
 	"github.com/vadimpk/gses-2023/core/internal/api/mailgun"
@@ -43,12 +44,18 @@ func TestSuite(t *testing.T) {
 }
 
 func (suite *EmailServiceTestSuite) TestEmailSubscribe() {
+	emailAPI := mocks.NewEmailAPI(suite.T())
+	emailAPI.On("Send", context.Background(), mock.Anything).Return(nil)
 
 	testOptions := &service.Options{
+		APIs: service.APIs{
+			Email: emailAPI,
+		},
 		Storages: service.Storages{
 			Email: localstorage.NewEmailStorage(suite.db, "EmailServiceTest_TestEmailSubscribe.txt"),
 		},
 		Logger: logging.NewZapLogger("debug"),
+		Cfg:    config.Get("../../.env"),
 	}
 	emailSrv := service.NewEmailService(testOptions)
 
@@ -78,7 +85,10 @@ func (suite *EmailServiceTestSuite) TestEmailSubscribe() {
 		{
 			name: "negative: such email already exists",
 			setup: func(s *service.Options) {
-				err := s.Storages.Email.Save(context.Background(), "existing_email@email.com")
+				err := s.Storages.Email.Save(context.Background(), &entity.Subscriber{
+					Email:  "existing_email@email.com",
+					Status: entity.SubscriberStatusConfirmed,
+				})
 				assert.NoError(suite.T(), err)
 			},
 			args: args{
@@ -96,7 +106,9 @@ func (suite *EmailServiceTestSuite) TestEmailSubscribe() {
 			suite.T().Parallel()
 
 			tc.setup(testOptions)
-			err := emailSrv.Subscribe(context.Background(), tc.args.email)
+			_, err := emailSrv.Subscribe(context.Background(), &service.SubscribeOptions{
+				Email: tc.args.email,
+			})
 			assert.Equal(suite.T(), tc.expected.err, err)
 		})
 	}
@@ -119,9 +131,12 @@ func (suite *EmailServiceTestSuite) TestEmailSendRateInfo() {
 			Crypto: cryptoAPI,
 		},
 		Storages: service.Storages{
-			Email: localstorage.NewEmailStorage(suite.db, "EmailServiceTest_TestEmailSendRate.txt"),
+			Email:      localstorage.NewEmailStorage(suite.db, "EmailServiceTest_TestEmailSendRate.txt"),
+			DeadLetter: localstorage.NewDeadLetterStorage(suite.db, "EmailServiceTest_TestEmailSendRate_DeadLetters.txt"),
+			Rate:       localstorage.NewRateStorage(suite.db, "EmailServiceTest_TestEmailSendRate_Rates.txt"),
 		},
 		Logger: logging.NewZapLogger("debug"),
+		Cfg:    cfg,
 	}
 
 	emailSrv := service.NewEmailService(testOptions)
@@ -139,9 +154,15 @@ func (suite *EmailServiceTestSuite) TestEmailSendRateInfo() {
 		{
 			name: "positive: send rate info",
 			setup: func(s *service.Options) {
-				err := s.Storages.Email.Save(context.Background(), "vadyman.pk@gmail.com")
+				err := s.Storages.Email.Save(context.Background(), &entity.Subscriber{
+					Email:  "vadyman.pk@gmail.com",
+					Status: entity.SubscriberStatusConfirmed,
+				})
 				assert.NoError(suite.T(), err)
-				err = s.Storages.Email.Save(context.Background(), "vd.polishchuk4@gmail.com")
+				err = s.Storages.Email.Save(context.Background(), &entity.Subscriber{
+					Email:  "vd.polishchuk4@gmail.com",
+					Status: entity.SubscriberStatusConfirmed,
+				})
 				assert.NoError(suite.T(), err)
 			},
 			expected: expected{
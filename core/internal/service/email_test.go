@@ -4,24 +4,48 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"html"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vadimpk/gses-2023/core/config"
 	"github.com/vadimpk/gses-2023/core/internal/entity"
 	"github.com/vadimpk/gses-2023/core/internal/service"
 	"github.com/vadimpk/gses-2023/core/internal/service/mocks"
+	"github.com/vadimpk/gses-2023/core/pkg/token"
 	"github.com/vadimpk/gses-2023/pkg/logging"
 )
 
+var testConfig = &config.Config{
+	App: config.App{
+		PublicURL: "http://localhost:8080",
+	},
+	Token: config.Token{
+		Secret: "test-secret",
+	},
+	Subscription: config.Subscription{
+		PendingTTL: 86400,
+	},
+	Sender: config.Sender{
+		Concurrency: 3,
+	},
+}
+
 func TestEmailService_Subscribe(t *testing.T) {
 	t.Parallel()
 
 	type mocksForExecution struct {
 		emailStorage *mocks.EmailStorage
+		emailAPI     *mocks.EmailAPI
 	}
 
 	type args struct {
-		email string
+		email  string
+		locale entity.Locale
 	}
 
 	type expected struct {
@@ -30,6 +54,18 @@ func TestEmailService_Subscribe(t *testing.T) {
 
 	ctx := context.Background()
 	testEmail := "email@test.com"
+	testSendErr := errors.New("some err")
+	confirmationLink := testConfig.App.PublicURL + "/api/v1/subscribe/confirm?"
+
+	isPendingSubscriber := mock.MatchedBy(func(s *entity.Subscriber) bool {
+		return s.Email == testEmail && s.Status == entity.SubscriberStatusPending && time.Since(s.CreatedAt) < time.Minute &&
+			assert.ObjectsAreEqual([]entity.CurrencyPair{entity.DefaultCurrencyPair}, s.Pairs) &&
+			s.Locale == entity.DefaultLocale
+	})
+	isConfirmationEmail := mock.MatchedBy(func(opts *service.SendOptions) bool {
+		return opts.To == testEmail && opts.Subject == "Confirm your subscription to crypto rates" &&
+			strings.Contains(opts.Body, confirmationLink) && strings.Contains(opts.HTML, confirmationLink)
+	})
 
 	testCases := []struct {
 		name     string
@@ -40,8 +76,27 @@ func TestEmailService_Subscribe(t *testing.T) {
 		{
 			name: "positive: subscribed email",
 			mock: func(m mocksForExecution) {
-				m.emailStorage.On("Exist", ctx, testEmail).Return(false, nil)
-				m.emailStorage.On("Save", ctx, testEmail).Return(nil)
+				m.emailStorage.On("Get", ctx, testEmail).Return(nil, nil)
+				m.emailStorage.On("Save", ctx, isPendingSubscriber).Return(nil)
+				m.emailAPI.On("Send", ctx, isConfirmationEmail).Return(nil)
+			},
+			args: args{
+				email: testEmail,
+			},
+			expected: expected{
+				err: nil,
+			},
+		},
+		{
+			name: "positive: renewed expired pending subscription",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(&entity.Subscriber{
+					Email:     testEmail,
+					Status:    entity.SubscriberStatusPending,
+					CreatedAt: time.Now().Add(-48 * time.Hour),
+				}, nil)
+				m.emailStorage.On("Update", ctx, isPendingSubscriber).Return(nil)
+				m.emailAPI.On("Send", ctx, isConfirmationEmail).Return(nil)
 			},
 			args: args{
 				email: testEmail,
@@ -50,10 +105,64 @@ func TestEmailService_Subscribe(t *testing.T) {
 				err: nil,
 			},
 		},
+		{
+			name: "positive: confirmation email in locale of subscriber",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(nil, nil)
+				m.emailStorage.On("Save", ctx, mock.MatchedBy(func(s *entity.Subscriber) bool {
+					return s.Locale == entity.LocaleUkrainian
+				})).Return(nil)
+				m.emailAPI.On("Send", ctx, mock.MatchedBy(func(opts *service.SendOptions) bool {
+					return opts.Subject == "Підтвердьте підписку на курси криптовалют" &&
+						strings.HasPrefix(opts.Body, "Щоб отримувати курси криптовалют") &&
+						strings.Contains(opts.Body, confirmationLink) && strings.Contains(opts.HTML, `lang="uk"`)
+				})).Return(nil)
+			},
+			args: args{
+				email:  testEmail,
+				locale: entity.LocaleUkrainian,
+			},
+			expected: expected{
+				err: nil,
+			},
+		},
 		{
 			name: "negative: such email already exists",
 			mock: func(m mocksForExecution) {
-				m.emailStorage.On("Exist", ctx, testEmail).Return(true, nil)
+				m.emailStorage.On("Get", ctx, testEmail).Return(&entity.Subscriber{
+					Email:  testEmail,
+					Status: entity.SubscriberStatusConfirmed,
+				}, nil)
+			},
+			args: args{
+				email: testEmail,
+			},
+			expected: expected{
+				err: service.ErrSubscribeAlreadySubscribed,
+			},
+		},
+		{
+			name: "negative: such email is waiting for confirmation",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(&entity.Subscriber{
+					Email:     testEmail,
+					Status:    entity.SubscriberStatusPending,
+					CreatedAt: time.Now(),
+				}, nil)
+			},
+			args: args{
+				email: testEmail,
+			},
+			expected: expected{
+				err: service.ErrSubscribeConfirmationPending,
+			},
+		},
+		{
+			name: "negative: such email was saved by concurrent request",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(nil, nil)
+				m.emailStorage.On("Save", ctx, isPendingSubscriber).
+					Return(fmt.Errorf("%w: unique constraint failed", service.ErrAlreadyExists))
 			},
 			args: args{
 				email: testEmail,
@@ -62,6 +171,21 @@ func TestEmailService_Subscribe(t *testing.T) {
 				err: service.ErrSubscribeAlreadySubscribed,
 			},
 		},
+		{
+			name: "negative: failed to send confirmation email",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(nil, nil)
+				m.emailStorage.On("Save", ctx, isPendingSubscriber).Return(nil)
+				m.emailAPI.On("Send", ctx, isConfirmationEmail).Return(testSendErr)
+				m.emailStorage.On("Delete", ctx, testEmail).Return(nil)
+			},
+			args: args{
+				email: testEmail,
+			},
+			expected: expected{
+				err: testSendErr,
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -71,6 +195,7 @@ func TestEmailService_Subscribe(t *testing.T) {
 
 			testMocks := mocksForExecution{
 				emailStorage: mocks.NewEmailStorage(t),
+				emailAPI:     mocks.NewEmailAPI(t),
 			}
 
 			tc.mock(testMocks)
@@ -79,10 +204,142 @@ func TestEmailService_Subscribe(t *testing.T) {
 				Storages: service.Storages{
 					Email: testMocks.emailStorage,
 				},
+				APIs: service.APIs{
+					Email: testMocks.emailAPI,
+				},
 				Logger: logging.NewZapLogger("debug"),
+				Cfg:    testConfig,
+			})
+
+			_, err := emailService.Subscribe(ctx, &service.SubscribeOptions{
+				Email:  tc.args.email,
+				Locale: tc.args.locale,
 			})
+			assert.ErrorIs(t, err, tc.expected.err)
+		})
+	}
+}
+
+func TestEmailService_ConfirmSubscription(t *testing.T) {
+	t.Parallel()
 
-			err := emailService.Subscribe(ctx, tc.args.email)
+	type mocksForExecution struct {
+		emailStorage *mocks.EmailStorage
+	}
+
+	type args struct {
+		email string
+		token string
+	}
+
+	type expected struct {
+		err error
+	}
+
+	ctx := context.Background()
+	testEmail := "email@test.com"
+	testSigner := token.NewHMAC(testConfig.Token.Secret)
+	testToken := testSigner.SignExpiring(time.Now().Add(time.Hour), "confirm", testEmail)
+
+	testCases := []struct {
+		name     string
+		mock     func(m mocksForExecution)
+		args     args
+		expected expected
+	}{
+		{
+			name: "positive: confirmed subscription",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(&entity.Subscriber{
+					Email:  testEmail,
+					Status: entity.SubscriberStatusPending,
+				}, nil)
+				m.emailStorage.On("Update", ctx, &entity.Subscriber{
+					Email:  testEmail,
+					Status: entity.SubscriberStatusConfirmed,
+				}).Return(nil)
+			},
+			args: args{
+				email: testEmail,
+				token: testToken,
+			},
+			expected: expected{
+				err: nil,
+			},
+		},
+		{
+			name: "positive: subscription already confirmed",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(&entity.Subscriber{
+					Email:  testEmail,
+					Status: entity.SubscriberStatusConfirmed,
+				}, nil)
+			},
+			args: args{
+				email: testEmail,
+				token: testToken,
+			},
+			expected: expected{
+				err: nil,
+			},
+		},
+		{
+			name: "negative: subscription doesn't exist",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(nil, nil)
+			},
+			args: args{
+				email: testEmail,
+				token: testToken,
+			},
+			expected: expected{
+				err: service.ErrConfirmSubscriptionNotFound,
+			},
+		},
+		{
+			name: "negative: token issued for another email",
+			mock: func(m mocksForExecution) {},
+			args: args{
+				email: "another@test.com",
+				token: testToken,
+			},
+			expected: expected{
+				err: service.ErrConfirmSubscriptionInvalidToken,
+			},
+		},
+		{
+			name: "negative: token expired",
+			mock: func(m mocksForExecution) {},
+			args: args{
+				email: testEmail,
+				token: testSigner.SignExpiring(time.Now().Add(-time.Hour), "confirm", testEmail),
+			},
+			expected: expected{
+				err: service.ErrConfirmSubscriptionTokenExpired,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			testMocks := mocksForExecution{
+				emailStorage: mocks.NewEmailStorage(t),
+			}
+
+			tc.mock(testMocks)
+
+			emailService := service.NewEmailService(&service.Options{
+				Storages: service.Storages{
+					Email: testMocks.emailStorage,
+				},
+				Logger: logging.NewZapLogger("debug"),
+				Cfg:    testConfig,
+			})
+
+			err := emailService.ConfirmSubscription(ctx, tc.args.email, tc.args.token)
 			assert.Equal(t, tc.expected.err, err)
 		})
 	}
@@ -92,9 +349,11 @@ func TestEmailService_SendRateInfo(t *testing.T) {
 	t.Parallel()
 
 	type mocksForExecution struct {
-		emailStorage *mocks.EmailStorage
-		cryptoAPI    *mocks.CryptoAPI
-		emailAPI     *mocks.EmailAPI
+		emailStorage      *mocks.EmailStorage
+		deadLetterStorage *mocks.DeadLetterStorage
+		rateStorage       *mocks.RateStorage
+		cryptoAPI         *mocks.CryptoAPI
+		emailAPI          *mocks.EmailAPI
 	}
 
 	type args struct{}
@@ -112,14 +371,53 @@ func TestEmailService_SendRateInfo(t *testing.T) {
 		"email3@test.com",
 	}
 
+	testSubscribers := make([]*entity.Subscriber, 0, len(testEmails))
+	for _, testEmail := range testEmails {
+		testSubscribers = append(testSubscribers, &entity.Subscriber{
+			Email:  testEmail,
+			Status: entity.SubscriberStatusConfirmed,
+			Pairs:  []entity.CurrencyPair{entity.DefaultCurrencyPair},
+		})
+	}
+
 	testRate := float64(100)
 
 	testGetRateFromCurrency := entity.CryptoCurrencyBTC.String()
 	testGetRateToCurrency := entity.FiatCurrencyUSD.String()
 
-	testSendEmailOptions := service.SendOptions{
-		Subject: "Rate info",
-		Body:    fmt.Sprintf("Current rate is %f", testRate),
+	// isRateEmail matches rate email sent to email with given lines of rates in plain text part
+	isRateEmail := func(email string, rates string) interface{} {
+		signer := token.NewHMAC(testConfig.Token.Secret)
+		link := func(path, purpose string) string {
+			return fmt.Sprintf("%s%s?%s", testConfig.App.PublicURL, path, url.Values{
+				"email": {email},
+				"token": {signer.Sign(purpose, email)},
+			}.Encode())
+		}
+		preferencesLink := link("/api/v1/subscribe/pairs", "preferences")
+		unsubscribeLink := link("/api/v1/unsubscribe", "unsubscribe")
+
+		return mock.MatchedBy(func(opts *service.SendOptions) bool {
+			return opts.To == email &&
+				opts.Subject != "" &&
+				strings.Contains(opts.Body, "\n"+rates+"\n") &&
+				strings.Contains(opts.Body, preferencesLink) &&
+				strings.Contains(opts.Body, unsubscribeLink) &&
+				strings.Contains(opts.HTML, html.EscapeString(unsubscribeLink))
+		})
+	}
+	testDigest := "BTC-USD: $100.00\n"
+
+	isLastRate := func(pair entity.CurrencyPair, value float64) interface{} {
+		return mock.MatchedBy(func(rate *entity.Rate) bool {
+			return rate.Pair == pair && rate.Value == value && !rate.FetchedAt.IsZero()
+		})
+	}
+
+	isDeadLetterFor := func(email string) interface{} {
+		return mock.MatchedBy(func(letter *entity.DeadLetter) bool {
+			return letter.To == email && letter.ID != "" && letter.Error == "some err"
+		})
 	}
 
 	testCases := []struct {
@@ -131,14 +429,13 @@ func TestEmailService_SendRateInfo(t *testing.T) {
 		{
 			name: "positive: successfully send rate info to all emails",
 			mock: func(m mocksForExecution) {
-				m.emailStorage.On("List", ctx).Return(testEmails, nil)
+				m.emailStorage.On("List", ctx).Return(testSubscribers, nil)
 				m.cryptoAPI.On("GetRate", ctx, testGetRateFromCurrency, testGetRateToCurrency).Return(testRate, nil)
+				m.rateStorage.On("List", ctx).Return(nil, nil)
+				m.rateStorage.On("Save", ctx, isLastRate(entity.DefaultCurrencyPair, testRate)).Return(nil)
 
 				for _, testEmail := range testEmails {
-					sendOptions := testSendEmailOptions
-					sendOptions.To = testEmail
-
-					m.emailAPI.On("Send", ctx, &sendOptions).Return(nil)
+					m.emailAPI.On("Send", ctx, isRateEmail(testEmail, testDigest)).Return(nil)
 				}
 			},
 			expected: expected{
@@ -149,19 +446,19 @@ func TestEmailService_SendRateInfo(t *testing.T) {
 		{
 			name: "positive: successfully send rate info to some emails",
 			mock: func(m mocksForExecution) {
-				m.emailStorage.On("List", ctx).Return(testEmails, nil)
+				m.emailStorage.On("List", ctx).Return(testSubscribers, nil)
 				m.cryptoAPI.On("GetRate", ctx, testGetRateFromCurrency, testGetRateToCurrency).Return(testRate, nil)
+				m.rateStorage.On("List", ctx).Return(nil, nil)
+				m.rateStorage.On("Save", ctx, isLastRate(entity.DefaultCurrencyPair, testRate)).Return(nil)
 
 				for i, testEmail := range testEmails {
-					sendOptions := testSendEmailOptions
-					sendOptions.To = testEmail
-
 					var err error
 					if i == 0 {
 						err = errors.New("some err")
 					}
-					m.emailAPI.On("Send", ctx, &sendOptions).Return(err)
+					m.emailAPI.On("Send", ctx, isRateEmail(testEmail, testDigest)).Return(err)
 				}
+				m.deadLetterStorage.On("Save", ctx, isDeadLetterFor("email1@test.com")).Return(nil)
 			},
 			expected: expected{
 				err:          nil,
@@ -169,16 +466,158 @@ func TestEmailService_SendRateInfo(t *testing.T) {
 			},
 		},
 		{
-			name: "negative: failed to send rate info to all emails",
+			name: "positive: skip pending emails and remove expired ones",
 			mock: func(m mocksForExecution) {
-				m.emailStorage.On("List", ctx).Return(testEmails, nil)
+				m.emailStorage.On("List", ctx).Return(append([]*entity.Subscriber{
+					{
+						Email:     "pending@test.com",
+						Status:    entity.SubscriberStatusPending,
+						CreatedAt: time.Now(),
+					},
+					{
+						Email:     "expired@test.com",
+						Status:    entity.SubscriberStatusPending,
+						CreatedAt: time.Now().Add(-48 * time.Hour),
+					},
+				}, testSubscribers...), nil)
+				m.emailStorage.On("Delete", ctx, "expired@test.com").Return(nil)
 				m.cryptoAPI.On("GetRate", ctx, testGetRateFromCurrency, testGetRateToCurrency).Return(testRate, nil)
+				m.rateStorage.On("List", ctx).Return(nil, nil)
+				m.rateStorage.On("Save", ctx, isLastRate(entity.DefaultCurrencyPair, testRate)).Return(nil)
 
 				for _, testEmail := range testEmails {
-					sendOptions := testSendEmailOptions
-					sendOptions.To = testEmail
+					m.emailAPI.On("Send", ctx, isRateEmail(testEmail, testDigest)).Return(nil)
+				}
+			},
+			expected: expected{
+				err:          nil,
+				failedEmails: nil,
+			},
+		},
+		{
+			name: "positive: send digest of subscriber pairs fetching each pair once",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("List", ctx).Return([]*entity.Subscriber{
+					{
+						Email:  "email1@test.com",
+						Status: entity.SubscriberStatusConfirmed,
+						Pairs: []entity.CurrencyPair{
+							entity.DefaultCurrencyPair,
+							{Crypto: entity.CryptoCurrencyETH, Fiat: entity.FiatCurrencyUAH},
+						},
+					},
+					{
+						Email:  "email2@test.com",
+						Status: entity.SubscriberStatusConfirmed,
+						Pairs: []entity.CurrencyPair{
+							{Crypto: entity.CryptoCurrencyETH, Fiat: entity.FiatCurrencyUAH},
+						},
+					},
+				}, nil)
+				m.cryptoAPI.On("GetRate", ctx, testGetRateFromCurrency, testGetRateToCurrency).Return(testRate, nil).Once()
+				m.cryptoAPI.On("GetRate", ctx, "ETH", "UAH").Return(float64(50), nil).Once()
+				m.rateStorage.On("List", ctx).Return(nil, nil)
+				m.rateStorage.On("Save", ctx, isLastRate(entity.DefaultCurrencyPair, testRate)).Return(nil)
+				m.rateStorage.On("Save", ctx, isLastRate(entity.CurrencyPair{Crypto: entity.CryptoCurrencyETH, Fiat: entity.FiatCurrencyUAH}, 50)).Return(nil)
+
+				m.emailAPI.On("Send", ctx, isRateEmail("email1@test.com",
+					"BTC-USD: $100.00\nETH-UAH: ₴50.00\n")).Return(nil)
+				m.emailAPI.On("Send", ctx, isRateEmail("email2@test.com",
+					"ETH-UAH: ₴50.00\n")).Return(nil)
+			},
+			expected: expected{
+				err:          nil,
+				failedEmails: nil,
+			},
+		},
+		{
+			name: "positive: show change of rate since last email",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("List", ctx).Return(testSubscribers[:1], nil)
+				m.cryptoAPI.On("GetRate", ctx, testGetRateFromCurrency, testGetRateToCurrency).Return(testRate, nil)
+				m.rateStorage.On("List", ctx).Return([]*entity.Rate{
+					{
+						Pair:      entity.DefaultCurrencyPair,
+						Value:     80,
+						FetchedAt: time.Now().Add(-24 * time.Hour),
+					},
+				}, nil)
+				m.rateStorage.On("Save", ctx, isLastRate(entity.DefaultCurrencyPair, testRate)).Return(nil)
+
+				m.emailAPI.On("Send", ctx, isRateEmail("email1@test.com",
+					"BTC-USD: $100.00 (+25.00% since last email)\n")).Return(nil)
+			},
+			expected: expected{
+				err:          nil,
+				failedEmails: nil,
+			},
+		},
+		{
+			name: "positive: send email in subscriber's locale",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("List", ctx).Return([]*entity.Subscriber{
+					{
+						Email:  "email1@test.com",
+						Status: entity.SubscriberStatusConfirmed,
+						Pairs: []entity.CurrencyPair{
+							{Crypto: entity.CryptoCurrencyETH, Fiat: entity.FiatCurrencyUAH},
+						},
+						Locale: entity.LocaleUkrainian,
+					},
+				}, nil)
+				m.cryptoAPI.On("GetRate", ctx, "ETH", "UAH").Return(float64(61234.5), nil)
+				m.rateStorage.On("List", ctx).Return(nil, nil)
+				m.rateStorage.On("Save", ctx, mock.Anything).Return(nil)
 
-					m.emailAPI.On("Send", ctx, &sendOptions).Return(errors.New("some err"))
+				m.emailAPI.On("Send", ctx, mock.MatchedBy(func(opts *service.SendOptions) bool {
+					return strings.HasPrefix(opts.Subject, "Курси криптовалют на ") &&
+						strings.Contains(opts.Body, "ETH-UAH: 61\u00a0234,50\u00a0₴\n")
+				})).Return(nil)
+			},
+			expected: expected{
+				err:          nil,
+				failedEmails: nil,
+			},
+		},
+		{
+			name: "positive: skip subscribers whose pairs failed to be fetched",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("List", ctx).Return([]*entity.Subscriber{
+					{
+						Email:  "email1@test.com",
+						Status: entity.SubscriberStatusConfirmed,
+						Pairs:  []entity.CurrencyPair{entity.DefaultCurrencyPair},
+					},
+					{
+						Email:  "email2@test.com",
+						Status: entity.SubscriberStatusConfirmed,
+						Pairs: []entity.CurrencyPair{
+							{Crypto: entity.CryptoCurrencyETH, Fiat: entity.FiatCurrencyUAH},
+						},
+					},
+				}, nil)
+				m.cryptoAPI.On("GetRate", ctx, testGetRateFromCurrency, testGetRateToCurrency).Return(testRate, nil)
+				m.rateStorage.On("List", ctx).Return(nil, nil)
+				m.rateStorage.On("Save", ctx, isLastRate(entity.DefaultCurrencyPair, testRate)).Return(nil)
+				m.cryptoAPI.On("GetRate", ctx, "ETH", "UAH").Return(float64(0), errors.New("some err"))
+
+				m.emailAPI.On("Send", ctx, isRateEmail("email1@test.com", testDigest)).Return(nil)
+			},
+			expected: expected{
+				err:          nil,
+				failedEmails: []string{"email2@test.com"},
+			},
+		},
+		{
+			name: "negative: failed to send rate info to all emails",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("List", ctx).Return(testSubscribers, nil)
+				m.cryptoAPI.On("GetRate", ctx, testGetRateFromCurrency, testGetRateToCurrency).Return(testRate, nil)
+				m.rateStorage.On("List", ctx).Return(nil, nil)
+
+				for _, testEmail := range testEmails {
+					m.emailAPI.On("Send", ctx, isRateEmail(testEmail, testDigest)).Return(errors.New("some err"))
+					m.deadLetterStorage.On("Save", ctx, isDeadLetterFor(testEmail)).Return(nil)
 				}
 			},
 			expected: expected{
@@ -194,22 +633,27 @@ func TestEmailService_SendRateInfo(t *testing.T) {
 			t.Parallel()
 
 			testMocks := mocksForExecution{
-				emailStorage: mocks.NewEmailStorage(t),
-				cryptoAPI:    mocks.NewCryptoAPI(t),
-				emailAPI:     mocks.NewEmailAPI(t),
+				emailStorage:      mocks.NewEmailStorage(t),
+				deadLetterStorage: mocks.NewDeadLetterStorage(t),
+				rateStorage:       mocks.NewRateStorage(t),
+				cryptoAPI:         mocks.NewCryptoAPI(t),
+				emailAPI:          mocks.NewEmailAPI(t),
 			}
 
 			tc.mock(testMocks)
 
 			emailService := service.NewEmailService(&service.Options{
 				Storages: service.Storages{
-					Email: testMocks.emailStorage,
+					Email:      testMocks.emailStorage,
+					DeadLetter: testMocks.deadLetterStorage,
+					Rate:       testMocks.rateStorage,
 				},
 				APIs: service.APIs{
 					Email:  testMocks.emailAPI,
 					Crypto: testMocks.cryptoAPI,
 				},
 				Logger: logging.NewZapLogger("debug"),
+				Cfg:    testConfig,
 			})
 
 			output, err := emailService.SendRateInfo(ctx)
@@ -217,5 +661,471 @@ func TestEmailService_SendRateInfo(t *testing.T) {
 			assert.Equal(t, tc.expected.failedEmails, output.FailedEmails)
 		})
 	}
+}
+
+func TestEmailService_Unsubscribe(t *testing.T) {
+	t.Parallel()
+
+	type mocksForExecution struct {
+		emailStorage *mocks.EmailStorage
+	}
+
+	type args struct {
+		email string
+	}
+
+	type expected struct {
+		err error
+	}
+
+	ctx := context.Background()
+	testEmail := "email@test.com"
+
+	testCases := []struct {
+		name     string
+		mock     func(m mocksForExecution)
+		args     args
+		expected expected
+	}{
+		{
+			name: "positive: unsubscribed email",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Exist", ctx, testEmail).Return(true, nil)
+				m.emailStorage.On("Delete", ctx, testEmail).Return(nil)
+			},
+			args: args{
+				email: testEmail,
+			},
+			expected: expected{
+				err: nil,
+			},
+		},
+		{
+			name: "negative: email is not subscribed",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Exist", ctx, testEmail).Return(false, nil)
+			},
+			args: args{
+				email: testEmail,
+			},
+			expected: expected{
+				err: service.ErrUnsubscribeNotSubscribed,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			testMocks := mocksForExecution{
+				emailStorage: mocks.NewEmailStorage(t),
+			}
+
+			tc.mock(testMocks)
+
+			emailService := service.NewEmailService(&service.Options{
+				Storages: service.Storages{
+					Email: testMocks.emailStorage,
+				},
+				Logger: logging.NewZapLogger("debug"),
+				Cfg:    testConfig,
+			})
+
+			err := emailService.Unsubscribe(ctx, tc.args.email)
+			assert.Equal(t, tc.expected.err, err)
+		})
+	}
+}
+
+func TestEmailService_GetPairs(t *testing.T) {
+	t.Parallel()
+
+	type mocksForExecution struct {
+		emailStorage *mocks.EmailStorage
+	}
+
+	type args struct {
+		email string
+		token string
+	}
+
+	type expected struct {
+		pairs []entity.CurrencyPair
+		err   error
+	}
+
+	ctx := context.Background()
+	testEmail := "email@test.com"
+	testToken := token.NewHMAC(testConfig.Token.Secret).Sign("preferences", testEmail)
+	testPairs := []entity.CurrencyPair{
+		{Crypto: entity.CryptoCurrencyETH, Fiat: entity.FiatCurrencyUAH},
+	}
+
+	testCases := []struct {
+		name     string
+		mock     func(m mocksForExecution)
+		args     args
+		expected expected
+	}{
+		{
+			name: "positive: got pairs",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(&entity.Subscriber{
+					Email:  testEmail,
+					Status: entity.SubscriberStatusConfirmed,
+					Pairs:  testPairs,
+				}, nil)
+			},
+			args: args{
+				email: testEmail,
+				token: testToken,
+			},
+			expected: expected{
+				pairs: testPairs,
+			},
+		},
+		{
+			name: "negative: email is not subscribed",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(nil, nil)
+			},
+			args: args{
+				email: testEmail,
+				token: testToken,
+			},
+			expected: expected{
+				err: service.ErrUpdatePairsNotSubscribed,
+			},
+		},
+		{
+			name: "negative: invalid token",
+			mock: func(m mocksForExecution) {},
+			args: args{
+				email: testEmail,
+				token: token.NewHMAC(testConfig.Token.Secret).Sign("unsubscribe", testEmail),
+			},
+			expected: expected{
+				err: service.ErrUpdatePairsInvalidToken,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			testMocks := mocksForExecution{
+				emailStorage: mocks.NewEmailStorage(t),
+			}
+
+			tc.mock(testMocks)
+
+			emailService := service.NewEmailService(&service.Options{
+				Storages: service.Storages{
+					Email: testMocks.emailStorage,
+				},
+				Logger: logging.NewZapLogger("debug"),
+				Cfg:    testConfig,
+			})
+
+			pairs, err := emailService.GetPairs(ctx, tc.args.email, tc.args.token)
+			assert.Equal(t, tc.expected.err, err)
+			assert.Equal(t, tc.expected.pairs, pairs)
+		})
+	}
+}
+
+func TestEmailService_UpdatePairs(t *testing.T) {
+	t.Parallel()
+
+	type mocksForExecution struct {
+		emailStorage *mocks.EmailStorage
+	}
+
+	type args struct {
+		opts *service.UpdatePairsOptions
+	}
+
+	type expected struct {
+		err error
+	}
+
+	ctx := context.Background()
+	testEmail := "email@test.com"
+	testToken := token.NewHMAC(testConfig.Token.Secret).Sign("preferences", testEmail)
+	testPairs := []entity.CurrencyPair{
+		{Crypto: entity.CryptoCurrencyETH, Fiat: entity.FiatCurrencyUAH},
+	}
+
+	testCases := []struct {
+		name     string
+		mock     func(m mocksForExecution)
+		args     args
+		expected expected
+	}{
+		{
+			name: "positive: updated pairs",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(&entity.Subscriber{
+					Email:  testEmail,
+					Status: entity.SubscriberStatusConfirmed,
+					Pairs:  []entity.CurrencyPair{entity.DefaultCurrencyPair},
+				}, nil)
+				m.emailStorage.On("Update", ctx, &entity.Subscriber{
+					Email:  testEmail,
+					Status: entity.SubscriberStatusConfirmed,
+					Pairs:  testPairs,
+				}).Return(nil)
+			},
+			args: args{
+				opts: &service.UpdatePairsOptions{
+					Email: testEmail,
+					Token: testToken,
+					Pairs: testPairs,
+				},
+			},
+			expected: expected{
+				err: nil,
+			},
+		},
+		{
+			name: "negative: email is not subscribed",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(nil, nil)
+			},
+			args: args{
+				opts: &service.UpdatePairsOptions{
+					Email: testEmail,
+					Token: testToken,
+					Pairs: testPairs,
+				},
+			},
+			expected: expected{
+				err: service.ErrUpdatePairsNotSubscribed,
+			},
+		},
+		{
+			name: "negative: invalid token",
+			mock: func(m mocksForExecution) {},
+			args: args{
+				opts: &service.UpdatePairsOptions{
+					Email: testEmail,
+					Token: token.NewHMAC(testConfig.Token.Secret).Sign("unsubscribe", testEmail),
+					Pairs: testPairs,
+				},
+			},
+			expected: expected{
+				err: service.ErrUpdatePairsInvalidToken,
+			},
+		},
+		{
+			name: "negative: no pairs",
+			mock: func(m mocksForExecution) {},
+			args: args{
+				opts: &service.UpdatePairsOptions{
+					Email: testEmail,
+					Token: testToken,
+				},
+			},
+			expected: expected{
+				err: service.ErrUpdatePairsNoPairs,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			testMocks := mocksForExecution{
+				emailStorage: mocks.NewEmailStorage(t),
+			}
+
+			tc.mock(testMocks)
+
+			emailService := service.NewEmailService(&service.Options{
+				Storages: service.Storages{
+					Email: testMocks.emailStorage,
+				},
+				Logger: logging.NewZapLogger("debug"),
+				Cfg:    testConfig,
+			})
+
+			err := emailService.UpdatePairs(ctx, tc.args.opts)
+			assert.Equal(t, tc.expected.err, err)
+		})
+	}
+}
+
+func TestEmailService_VerifyUnsubscribeToken(t *testing.T) {
+	t.Parallel()
+
+	testEmail := "email@test.com"
+	testToken := token.NewHMAC(testConfig.Token.Secret).Sign("unsubscribe", testEmail)
+
+	testCases := []struct {
+		name     string
+		email    string
+		token    string
+		expected error
+	}{
+		{
+			name:     "positive: valid token",
+			email:    testEmail,
+			token:    testToken,
+			expected: nil,
+		},
+		{
+			name:     "negative: token issued for another email",
+			email:    "another@test.com",
+			token:    testToken,
+			expected: service.ErrUnsubscribeInvalidToken,
+		},
+		{
+			name:     "negative: malformed token",
+			email:    testEmail,
+			token:    "not a token",
+			expected: service.ErrUnsubscribeInvalidToken,
+		},
+	}
+
+	emailService := service.NewEmailService(&service.Options{
+		Logger: logging.NewZapLogger("debug"),
+		Cfg:    testConfig,
+	})
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := emailService.VerifyUnsubscribeToken(tc.email, tc.token)
+			assert.Equal(t, tc.expected, err)
+		})
+	}
+}
+
+func TestEmailService_SendRateInfo_Canceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	testEmails := []string{
+		"email1@test.com",
+		"email2@test.com",
+		"email3@test.com",
+	}
+
+	testSubscribers := make([]*entity.Subscriber, 0, len(testEmails))
+	for _, testEmail := range testEmails {
+		testSubscribers = append(testSubscribers, &entity.Subscriber{
+			Email:  testEmail,
+			Status: entity.SubscriberStatusConfirmed,
+			Pairs:  []entity.CurrencyPair{entity.DefaultCurrencyPair},
+		})
+	}
+
+	emailStorage := mocks.NewEmailStorage(t)
+	emailStorage.On("List", ctx).Return(testSubscribers, nil)
+
+	cryptoAPI := mocks.NewCryptoAPI(t)
+	cryptoAPI.On("GetRate", ctx, entity.CryptoCurrencyBTC.String(), entity.FiatCurrencyUSD.String()).Return(float64(100), nil)
+
+	// rates are not remembered, because interrupted sending will be resumed
+	rateStorage := mocks.NewRateStorage(t)
+	rateStorage.On("List", ctx).Return(nil, nil)
+
+	// first email cancels sending, so the rest of them are not sent
+	emailAPI := mocks.NewEmailAPI(t)
+	emailAPI.On("Send", ctx, mock.MatchedBy(func(opts *service.SendOptions) bool {
+		return opts.To == testEmails[0]
+	})).Run(func(args mock.Arguments) {
+		cancel()
+	}).Return(nil).Once()
+
+	cfg := *testConfig
+	cfg.Sender = config.Sender{
+		Concurrency: 1,
+	}
+
+	emailService := service.NewEmailService(&service.Options{
+		Storages: service.Storages{
+			Email: emailStorage,
+			Rate:  rateStorage,
+		},
+		APIs: service.APIs{
+			Email:  emailAPI,
+			Crypto: cryptoAPI,
+		},
+		Logger: logging.NewZapLogger("debug"),
+		Cfg:    &cfg,
+	})
+
+	output, err := emailService.SendRateInfo(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, testEmails[1:], output.FailedEmails)
+}
+
+func TestEmailService_SendRateInfo_Unauthorized(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testEmails := []string{
+		"email1@test.com",
+		"email2@test.com",
+		"email3@test.com",
+	}
+
+	testSubscribers := make([]*entity.Subscriber, 0, len(testEmails))
+	for _, testEmail := range testEmails {
+		testSubscribers = append(testSubscribers, &entity.Subscriber{
+			Email:  testEmail,
+			Status: entity.SubscriberStatusConfirmed,
+			Pairs:  []entity.CurrencyPair{entity.DefaultCurrencyPair},
+		})
+	}
+
+	emailStorage := mocks.NewEmailStorage(t)
+	emailStorage.On("List", ctx).Return(testSubscribers, nil)
+
+	cryptoAPI := mocks.NewCryptoAPI(t)
+	cryptoAPI.On("GetRate", ctx, entity.CryptoCurrencyBTC.String(), entity.FiatCurrencyUSD.String()).Return(float64(100), nil)
+
+	rateStorage := mocks.NewRateStorage(t)
+	rateStorage.On("List", ctx).Return(nil, nil)
+
+	// rejected credentials abort sending without dead letters, so the rest of emails are not sent
+	emailAPI := mocks.NewEmailAPI(t)
+	emailAPI.On("Send", ctx, mock.MatchedBy(func(opts *service.SendOptions) bool {
+		return opts.To == testEmails[0]
+	})).Return(fmt.Errorf("%w: bad password", service.ErrEmailUnauthorized)).Once()
+
+	cfg := *testConfig
+	cfg.Sender = config.Sender{
+		Concurrency: 1,
+	}
+
+	emailService := service.NewEmailService(&service.Options{
+		Storages: service.Storages{
+			Email:      emailStorage,
+			DeadLetter: mocks.NewDeadLetterStorage(t),
+			Rate:       rateStorage,
+		},
+		APIs: service.APIs{
+			Email:  emailAPI,
+			Crypto: cryptoAPI,
+		},
+		Logger: logging.NewZapLogger("debug"),
+		Cfg:    &cfg,
+	})
 
+	output, err := emailService.SendRateInfo(ctx)
+	assert.ErrorIs(t, err, service.ErrEmailUnauthorized)
+	assert.Equal(t, testEmails, output.FailedEmails)
 }
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+)
+
+// numberFormat describes how numbers are written in locale.
+type numberFormat struct {
+	groupSeparator   string
+	decimalSeparator string
+	// symbolAfter places currency symbol after amount separated by space, e.g. "100,00 ₴".
+	symbolAfter bool
+}
+
+var numberFormats = map[entity.Locale]numberFormat{
+	entity.LocaleEnglish: {
+		groupSeparator:   ",",
+		decimalSeparator: ".",
+	},
+	// non-breaking spaces keep number on one line
+	entity.LocaleUkrainian: {
+		groupSeparator:   "\u00a0",
+		decimalSeparator: ",",
+		symbolAfter:      true,
+	},
+}
+
+func getNumberFormat(locale entity.Locale) numberFormat {
+	if format, ok := numberFormats[locale]; ok {
+		return format
+	}
+	return numberFormats[entity.DefaultLocale]
+}
+
+// formatMoney formats amount of fiat currency with two decimals and grouped thousands,
+// e.g. "$27,345.60" in English and "27 345,60 ₴" in Ukrainian.
+func formatMoney(amount float64, currency entity.FiatCurrency, locale entity.Locale) string {
+	format := getNumberFormat(locale)
+
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	number := format.formatNumber(amount)
+	if format.symbolAfter {
+		return sign + number + "\u00a0" + currency.Symbol()
+	}
+	return sign + currency.Symbol() + number
+}
+
+// formatPercent formats change in percents with explicit sign, e.g. "+1.25%".
+func formatPercent(percent float64, locale entity.Locale) string {
+	// avoid "-0.00%" for tiny negative changes
+	if math.Abs(percent) < 0.005 {
+		percent = 0
+	}
+
+	sign := "+"
+	if percent < 0 {
+		sign = "-"
+		percent = -percent
+	}
+	return sign + getNumberFormat(locale).formatNumber(percent) + "%"
+}
+
+// formatNumber formats non-negative number with two decimals and grouped thousands.
+func (f numberFormat) formatNumber(number float64) string {
+	integer, fraction, _ := strings.Cut(fmt.Sprintf("%.2f", number), ".")
+
+	var b strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteString(f.groupSeparator)
+		}
+		b.WriteRune(digit)
+	}
+	b.WriteString(f.decimalSeparator + fraction)
+
+	return b.String()
+}
@@ -0,0 +1,98 @@
+// Code generated by mockery v2.27.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+	entity "github.com/vadimpk/gses-2023/core/internal/entity"
+)
+
+// AlertStorage is an autogenerated mock type for the AlertStorage type
+type AlertStorage struct {
+	mock.Mock
+}
+
+// Delete provides a mock function with given fields: ctx, id
+func (_m *AlertStorage) Delete(ctx context.Context, id string) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// List provides a mock function with given fields: ctx
+func (_m *AlertStorage) List(ctx context.Context) ([]*entity.Alert, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []*entity.Alert
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*entity.Alert, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []*entity.Alert); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entity.Alert)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Save provides a mock function with given fields: ctx, alert
+func (_m *AlertStorage) Save(ctx context.Context, alert *entity.Alert) error {
+	ret := _m.Called(ctx, alert)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.Alert) error); ok {
+		r0 = rf(ctx, alert)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Update provides a mock function with given fields: ctx, alert
+func (_m *AlertStorage) Update(ctx context.Context, alert *entity.Alert) error {
+	ret := _m.Called(ctx, alert)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.Alert) error); ok {
+		r0 = rf(ctx, alert)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewAlertStorage interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewAlertStorage creates a new instance of AlertStorage. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewAlertStorage(t mockConstructorTestingTNewAlertStorage) *AlertStorage {
+	mock := &AlertStorage{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,84 @@
+// Code generated by mockery v2.27.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+	entity "github.com/vadimpk/gses-2023/core/internal/entity"
+)
+
+// DeadLetterStorage is an autogenerated mock type for the DeadLetterStorage type
+type DeadLetterStorage struct {
+	mock.Mock
+}
+
+// Delete provides a mock function with given fields: ctx, id
+func (_m *DeadLetterStorage) Delete(ctx context.Context, id string) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// List provides a mock function with given fields: ctx
+func (_m *DeadLetterStorage) List(ctx context.Context) ([]*entity.DeadLetter, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []*entity.DeadLetter
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*entity.DeadLetter, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []*entity.DeadLetter); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entity.DeadLetter)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Save provides a mock function with given fields: ctx, letter
+func (_m *DeadLetterStorage) Save(ctx context.Context, letter *entity.DeadLetter) error {
+	ret := _m.Called(ctx, letter)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.DeadLetter) error); ok {
+		r0 = rf(ctx, letter)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewDeadLetterStorage interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewDeadLetterStorage creates a new instance of DeadLetterStorage. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewDeadLetterStorage(t mockConstructorTestingTNewDeadLetterStorage) *DeadLetterStorage {
+	mock := &DeadLetterStorage{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
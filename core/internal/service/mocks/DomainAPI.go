@@ -0,0 +1,53 @@
+// Code generated by mockery v2.27.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// DomainAPI is an autogenerated mock type for the DomainAPI type
+type DomainAPI struct {
+	mock.Mock
+}
+
+// AcceptsMail provides a mock function with given fields: ctx, domain
+func (_m *DomainAPI) AcceptsMail(ctx context.Context, domain string) (bool, error) {
+	ret := _m.Called(ctx, domain)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(ctx, domain)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, domain)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, domain)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewDomainAPI interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewDomainAPI creates a new instance of DomainAPI. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewDomainAPI(t mockConstructorTestingTNewDomainAPI) *DomainAPI {
+	mock := &DomainAPI{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
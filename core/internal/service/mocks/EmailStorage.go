@@ -6,6 +6,9 @@ import (
 	context "context"
 
 	mock "github.com/stretchr/testify/mock"
+	entity "github.com/vadimpk/gses-2023/core/internal/entity"
+
+	service "github.com/vadimpk/gses-2023/core/internal/service"
 )
 
 // EmailStorage is an autogenerated mock type for the EmailStorage type
@@ -13,6 +16,20 @@ type EmailStorage struct {
 	mock.Mock
 }
 
+// Delete provides a mock function with given fields: ctx, email
+func (_m *EmailStorage) Delete(ctx context.Context, email string) error {
+	ret := _m.Called(ctx, email)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Exist provides a mock function with given fields: ctx, email
 func (_m *EmailStorage) Exist(ctx context.Context, email string) (bool, error) {
 	ret := _m.Called(ctx, email)
@@ -37,20 +54,46 @@ func (_m *EmailStorage) Exist(ctx context.Context, email string) (bool, error) {
 	return r0, r1
 }
 
+// Get provides a mock function with given fields: ctx, email
+func (_m *EmailStorage) Get(ctx context.Context, email string) (*entity.Subscriber, error) {
+	ret := _m.Called(ctx, email)
+
+	var r0 *entity.Subscriber
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*entity.Subscriber, error)); ok {
+		return rf(ctx, email)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entity.Subscriber); ok {
+		r0 = rf(ctx, email)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.Subscriber)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // List provides a mock function with given fields: ctx
-func (_m *EmailStorage) List(ctx context.Context) ([]string, error) {
+func (_m *EmailStorage) List(ctx context.Context) ([]*entity.Subscriber, error) {
 	ret := _m.Called(ctx)
 
-	var r0 []string
+	var r0 []*entity.Subscriber
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context) ([]string, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*entity.Subscriber, error)); ok {
 		return rf(ctx)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) []string); ok {
+	if rf, ok := ret.Get(0).(func(context.Context) []*entity.Subscriber); ok {
 		r0 = rf(ctx)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]string)
+			r0 = ret.Get(0).([]*entity.Subscriber)
 		}
 	}
 
@@ -63,13 +106,86 @@ func (_m *EmailStorage) List(ctx context.Context) ([]string, error) {
 	return r0, r1
 }
 
-// Save provides a mock function with given fields: ctx, email
-func (_m *EmailStorage) Save(ctx context.Context, email string) error {
-	ret := _m.Called(ctx, email)
+// Save provides a mock function with given fields: ctx, subscriber
+func (_m *EmailStorage) Save(ctx context.Context, subscriber *entity.Subscriber) error {
+	ret := _m.Called(ctx, subscriber)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, email)
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.Subscriber) error); ok {
+		r0 = rf(ctx, subscriber)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveMany provides a mock function with given fields: ctx, subscribers
+func (_m *EmailStorage) SaveMany(ctx context.Context, subscribers []*entity.Subscriber) ([]string, error) {
+	ret := _m.Called(ctx, subscribers)
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*entity.Subscriber) ([]string, error)); ok {
+		return rf(ctx, subscribers)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []*entity.Subscriber) []string); ok {
+		r0 = rf(ctx, subscribers)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []*entity.Subscriber) error); ok {
+		r1 = rf(ctx, subscribers)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Search provides a mock function with given fields: ctx, filter
+func (_m *EmailStorage) Search(ctx context.Context, filter *service.SubscriberFilter) ([]*entity.Subscriber, int, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 []*entity.Subscriber
+	var r1 int
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, *service.SubscriberFilter) ([]*entity.Subscriber, int, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *service.SubscriberFilter) []*entity.Subscriber); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entity.Subscriber)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *service.SubscriberFilter) int); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, *service.SubscriberFilter) error); ok {
+		r2 = rf(ctx, filter)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Update provides a mock function with given fields: ctx, subscriber
+func (_m *EmailStorage) Update(ctx context.Context, subscriber *entity.Subscriber) error {
+	ret := _m.Called(ctx, subscriber)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.Subscriber) error); ok {
+		r0 = rf(ctx, subscriber)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -0,0 +1,70 @@
+// Code generated by mockery v2.27.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+	entity "github.com/vadimpk/gses-2023/core/internal/entity"
+)
+
+// RateStorage is an autogenerated mock type for the RateStorage type
+type RateStorage struct {
+	mock.Mock
+}
+
+// List provides a mock function with given fields: ctx
+func (_m *RateStorage) List(ctx context.Context) ([]*entity.Rate, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []*entity.Rate
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*entity.Rate, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []*entity.Rate); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entity.Rate)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Save provides a mock function with given fields: ctx, rate
+func (_m *RateStorage) Save(ctx context.Context, rate *entity.Rate) error {
+	ret := _m.Called(ctx, rate)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.Rate) error); ok {
+		r0 = rf(ctx, rate)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewRateStorage interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewRateStorage creates a new instance of RateStorage. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewRateStorage(t mockConstructorTestingTNewRateStorage) *RateStorage {
+	mock := &RateStorage{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
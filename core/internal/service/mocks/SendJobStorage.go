@@ -0,0 +1,136 @@
+// Code generated by mockery v2.27.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+	entity "github.com/vadimpk/gses-2023/core/internal/entity"
+
+	time "time"
+)
+
+// SendJobStorage is an autogenerated mock type for the SendJobStorage type
+type SendJobStorage struct {
+	mock.Mock
+}
+
+// DeleteFinished provides a mock function with given fields: ctx, before
+func (_m *SendJobStorage) DeleteFinished(ctx context.Context, before time.Time) (int, error) {
+	ret := _m.Called(ctx, before)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int, error)); ok {
+		return rf(ctx, before)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int); ok {
+		r0 = rf(ctx, before)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Get provides a mock function with given fields: ctx, id
+func (_m *SendJobStorage) Get(ctx context.Context, id string) (*entity.SendJob, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *entity.SendJob
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*entity.SendJob, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entity.SendJob); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.SendJob)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: ctx
+func (_m *SendJobStorage) List(ctx context.Context) ([]*entity.SendJob, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []*entity.SendJob
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*entity.SendJob, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []*entity.SendJob); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entity.SendJob)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Save provides a mock function with given fields: ctx, job
+func (_m *SendJobStorage) Save(ctx context.Context, job *entity.SendJob) error {
+	ret := _m.Called(ctx, job)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.SendJob) error); ok {
+		r0 = rf(ctx, job)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Update provides a mock function with given fields: ctx, job
+func (_m *SendJobStorage) Update(ctx context.Context, job *entity.SendJob) error {
+	ret := _m.Called(ctx, job)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.SendJob) error); ok {
+		r0 = rf(ctx, job)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewSendJobStorage interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewSendJobStorage creates a new instance of SendJobStorage. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewSendJobStorage(t mockConstructorTestingTNewSendJobStorage) *SendJobStorage {
+	mock := &SendJobStorage{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+)
+
+// sendJobProgressInterval is how often progress of running job is saved to storage.
+const sendJobProgressInterval = time.Second
+
+type sendJobService struct {
+	serviceContext
+	email *emailService
+
+	// ctx is canceled on Shutdown to interrupt running jobs.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewSendJobService(opts *Options, emailService *emailService) *sendJobService {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &sendJobService{
+		serviceContext: serviceContext{
+			storages: opts.Storages,
+			apis:     opts.APIs,
+			logger:   opts.Logger.Named("SendJobService"),
+			cfg:      opts.Cfg,
+		},
+		email:  emailService,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (s *sendJobService) Start(ctx context.Context) (*entity.SendJob, error) {
+	logger := s.logger.Named("Start").
+		WithContext(ctx)
+
+	s.deleteFinished(ctx)
+
+	jobs, err := s.storages.SendJob.List(ctx)
+	if err != nil {
+		logger.Error("failed to get jobs from storage", "err", err)
+		return nil, fmt.Errorf("failed to get jobs from storage: %w", err)
+	}
+	for _, job := range jobs {
+		if !job.State.IsFinished() {
+			logger.Info("another job is in progress", "id", job.ID)
+			return nil, ErrSendJobInProgress
+		}
+	}
+
+	// lock is released by job when it stops
+	if !s.email.sending.TryLock() {
+		logger.Info("rate info is already being sent")
+		return nil, ErrSendJobInProgress
+	}
+
+	id, err := newID()
+	if err != nil {
+		s.email.sending.Unlock()
+		logger.Error("failed to generate job id", "err", err)
+		return nil, fmt.Errorf("failed to generate job id: %w", err)
+	}
+	logger = logger.With("id", id)
+
+	now := time.Now()
+	job := &entity.SendJob{
+		ID:        id,
+		State:     entity.SendJobStateQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err = s.storages.SendJob.Save(ctx, job)
+	if err != nil {
+		s.email.sending.Unlock()
+		logger.Error("failed to save job", "err", err)
+		return nil, fmt.Errorf("failed to save job to storage: %w", err)
+	}
+
+	// job is modified by background goroutine, so copy is returned
+	created := *job
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.email.sending.Unlock()
+		s.process(s.ctx, job)
+	}()
+
+	logger.Info("successfully started job")
+	return &created, nil
+}
+
+func (s *sendJobService) Get(ctx context.Context, id string) (*entity.SendJob, error) {
+	logger := s.logger.Named("Get").
+		WithContext(ctx).
+		With("id", id)
+
+	job, err := s.storages.SendJob.Get(ctx, id)
+	if err != nil {
+		logger.Error("failed to get job from storage", "err", err)
+		return nil, fmt.Errorf("failed to get job from storage: %w", err)
+	}
+	if job == nil {
+		logger.Info("job doesn't exist")
+		return nil, ErrSendJobNotFound
+	}
+
+	return job, nil
+}
+
+func (s *sendJobService) Resume(ctx context.Context) error {
+	logger := s.logger.Named("Resume").
+		WithContext(ctx)
+
+	s.deleteFinished(ctx)
+
+	jobs, err := s.storages.SendJob.List(ctx)
+	if err != nil {
+		logger.Error("failed to get jobs from storage", "err", err)
+		return fmt.Errorf("failed to get jobs from storage: %w", err)
+	}
+
+	var unfinished []*entity.SendJob
+	for _, job := range jobs {
+		if !job.State.IsFinished() {
+			logger.Info("resuming job", "id", job.ID, "sent", job.Sent, "failed", job.Failed)
+			unfinished = append(unfinished, job)
+		}
+	}
+	if len(unfinished) == 0 {
+		return nil
+	}
+
+	// jobs are resumed one after another, so that subscribers don't get rate info from several jobs
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for _, job := range unfinished {
+			s.email.sending.Lock()
+			s.process(s.ctx, job)
+			s.email.sending.Unlock()
+		}
+	}()
+
+	return nil
+}
+
+func (s *sendJobService) Shutdown() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// deleteFinished removes jobs that finished before retention period, so that storage doesn't grow
+// with every job. Failure is only logged, because it doesn't affect new jobs.
+func (s *sendJobService) deleteFinished(ctx context.Context) {
+	logger := s.logger.Named("deleteFinished").
+		WithContext(ctx)
+
+	retention := time.Duration(s.cfg.Sender.JobRetention) * time.Hour
+	deleted, err := s.storages.SendJob.DeleteFinished(ctx, time.Now().Add(-retention))
+	if err != nil {
+		logger.Error("failed to delete finished jobs", "err", err)
+		return
+	}
+	if deleted > 0 {
+		logger.Info("deleted finished jobs", "count", deleted)
+	}
+}
+
+// process sends rate info skipping emails that job has already processed. Progress is saved
+// to storage periodically, so that job interrupted by shutdown or crash can be resumed.
+func (s *sendJobService) process(ctx context.Context, job *entity.SendJob) {
+	logger := s.logger.Named("process").
+		With("id", job.ID)
+
+	var mu sync.Mutex
+	lastSaved := time.Now()
+
+	// recipients are processed concurrently, so ones after the first unprocessed recipient are kept in done
+	// until cursor reaches them
+	skip := make(map[string]struct{}, len(job.Processed))
+	done := make(map[string]struct{})
+	for _, email := range job.Processed {
+		skip[email] = struct{}{}
+		done[email] = struct{}{}
+	}
+	var (
+		emails []string
+		next   int
+	)
+
+	// save must be called with mu held. Storage is called without ctx, because progress
+	// has to be saved even when job is interrupted.
+	save := func() {
+		job.Processed = nil
+		for email := range done {
+			if email > job.Cursor {
+				job.Processed = append(job.Processed, email)
+			}
+		}
+		sort.Strings(job.Processed)
+
+		job.UpdatedAt = time.Now()
+		lastSaved = job.UpdatedAt
+		if err := s.storages.SendJob.Update(context.Background(), job); err != nil {
+			logger.Error("failed to save job", "err", err)
+		}
+	}
+
+	mu.Lock()
+	job.State = entity.SendJobStateRunning
+	save()
+	mu.Unlock()
+
+	_, err := s.email.sendRateInfo(ctx, &sendRateInfoOptions{
+		after: job.Cursor,
+		skip:  skip,
+		onStart: func(recipients []string) {
+			mu.Lock()
+			defer mu.Unlock()
+			emails = recipients
+			job.Total = job.Sent + job.Failed + len(recipients)
+			save()
+		},
+		onProcessed: func(email string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			done[email] = struct{}{}
+			for next < len(emails) {
+				if _, ok := done[emails[next]]; !ok {
+					break
+				}
+				job.Cursor = emails[next]
+				delete(done, emails[next])
+				next++
+			}
+			if err != nil {
+				job.Failed++
+				job.FailedEmails = append(job.FailedEmails, email)
+			} else {
+				job.Sent++
+			}
+			if time.Since(lastSaved) >= sendJobProgressInterval {
+				save()
+			}
+		},
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	switch {
+	case ctx.Err() != nil:
+		// job stays running, so that it's resumed after restart
+		logger.Info("job interrupted")
+	case err != nil:
+		logger.Error("job failed", "err", err)
+		job.State = entity.SendJobStateFailed
+		job.Error = err.Error()
+	default:
+		logger.Info("job completed")
+		job.State = entity.SendJobStateCompleted
+	}
+	save()
+}
@@ -0,0 +1,279 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/core/internal/service/mocks"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+type sendJobTestMocks struct {
+	emailStorage      *mocks.EmailStorage
+	sendJobStorage    *mocks.SendJobStorage
+	deadLetterStorage *mocks.DeadLetterStorage
+	rateStorage       *mocks.RateStorage
+	cryptoAPI         *mocks.CryptoAPI
+	emailAPI          *mocks.EmailAPI
+}
+
+func newSendJobTestService(t *testing.T, m sendJobTestMocks) service.SendJobService {
+	opts := &service.Options{
+		Storages: service.Storages{
+			Email:      m.emailStorage,
+			SendJob:    m.sendJobStorage,
+			DeadLetter: m.deadLetterStorage,
+			Rate:       m.rateStorage,
+		},
+		APIs: service.APIs{
+			Email:  m.emailAPI,
+			Crypto: m.cryptoAPI,
+		},
+		Logger: logging.NewZapLogger("debug"),
+		Cfg:    testConfig,
+	}
+
+	return service.NewSendJobService(opts, service.NewEmailService(opts))
+}
+
+// finishedJobs returns channel that receives copy of job when it's saved in finished state.
+func finishedJobs(m *mocks.SendJobStorage) chan entity.SendJob {
+	finished := make(chan entity.SendJob, 1)
+	m.On("Update", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		job := args.Get(1).(*entity.SendJob)
+		if job.State.IsFinished() {
+			finished <- *job
+		}
+	}).Return(nil)
+	return finished
+}
+
+func waitFinishedJob(t *testing.T, finished chan entity.SendJob) entity.SendJob {
+	select {
+	case job := <-finished:
+		return job
+	case <-time.After(time.Second):
+		require.FailNow(t, "job didn't finish")
+	}
+	return entity.SendJob{}
+}
+
+func TestSendJobService_Start(t *testing.T) {
+	t.Parallel()
+
+	testMocks := sendJobTestMocks{
+		emailStorage:      mocks.NewEmailStorage(t),
+		sendJobStorage:    mocks.NewSendJobStorage(t),
+		deadLetterStorage: mocks.NewDeadLetterStorage(t),
+		rateStorage:       mocks.NewRateStorage(t),
+		cryptoAPI:         mocks.NewCryptoAPI(t),
+		emailAPI:          mocks.NewEmailAPI(t),
+	}
+
+	testMocks.emailStorage.On("List", mock.Anything).Return([]*entity.Subscriber{
+		{
+			Email:  "email1@test.com",
+			Status: entity.SubscriberStatusConfirmed,
+			Pairs:  []entity.CurrencyPair{entity.DefaultCurrencyPair},
+		},
+		{
+			Email:  "email2@test.com",
+			Status: entity.SubscriberStatusConfirmed,
+			Pairs:  []entity.CurrencyPair{entity.DefaultCurrencyPair},
+		},
+	}, nil)
+	testMocks.cryptoAPI.On("GetRate", mock.Anything, "BTC", "USD").Return(float64(100), nil)
+	testMocks.rateStorage.On("List", mock.Anything).Return(nil, nil)
+	testMocks.rateStorage.On("Save", mock.Anything, mock.Anything).Return(nil)
+	testMocks.emailAPI.On("Send", mock.Anything, mock.MatchedBy(func(opts *service.SendOptions) bool {
+		return opts.To == "email1@test.com"
+	})).Return(nil)
+	testMocks.emailAPI.On("Send", mock.Anything, mock.MatchedBy(func(opts *service.SendOptions) bool {
+		return opts.To == "email2@test.com"
+	})).Return(assert.AnError)
+	testMocks.deadLetterStorage.On("Save", mock.Anything, mock.Anything).Return(nil)
+	testMocks.sendJobStorage.On("DeleteFinished", mock.Anything, mock.Anything).Return(1, nil)
+	testMocks.sendJobStorage.On("List", mock.Anything).Return([]*entity.SendJob{
+		{
+			ID:    "completed",
+			State: entity.SendJobStateCompleted,
+		},
+	}, nil)
+	testMocks.sendJobStorage.On("Save", mock.Anything, mock.Anything).Return(nil)
+	finished := finishedJobs(testMocks.sendJobStorage)
+
+	sendJobService := newSendJobTestService(t, testMocks)
+	defer sendJobService.Shutdown()
+
+	job, err := sendJobService.Start(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, entity.SendJobStateQueued, job.State)
+	assert.NotEmpty(t, job.ID)
+
+	finishedJob := waitFinishedJob(t, finished)
+	assert.Equal(t, job.ID, finishedJob.ID)
+	assert.Equal(t, entity.SendJobStateCompleted, finishedJob.State)
+	assert.Equal(t, 2, finishedJob.Total)
+	assert.Equal(t, 1, finishedJob.Sent)
+	assert.Equal(t, 1, finishedJob.Failed)
+	assert.Equal(t, []string{"email2@test.com"}, finishedJob.FailedEmails)
+}
+
+func TestSendJobService_Start_InProgress(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unfinished job in storage", func(t *testing.T) {
+		t.Parallel()
+
+		testMocks := sendJobTestMocks{
+			sendJobStorage: mocks.NewSendJobStorage(t),
+		}
+		testMocks.sendJobStorage.On("DeleteFinished", mock.Anything, mock.Anything).Return(0, nil)
+		testMocks.sendJobStorage.On("List", mock.Anything).Return([]*entity.SendJob{
+			{
+				ID:    "running",
+				State: entity.SendJobStateRunning,
+			},
+		}, nil)
+
+		sendJobService := newSendJobTestService(t, testMocks)
+		defer sendJobService.Shutdown()
+
+		job, err := sendJobService.Start(context.Background())
+		assert.Nil(t, job)
+		assert.Equal(t, service.ErrSendJobInProgress, err)
+	})
+
+	t.Run("concurrent start", func(t *testing.T) {
+		t.Parallel()
+
+		testMocks := sendJobTestMocks{
+			emailStorage:   mocks.NewEmailStorage(t),
+			sendJobStorage: mocks.NewSendJobStorage(t),
+			rateStorage:    mocks.NewRateStorage(t),
+			cryptoAPI:      mocks.NewCryptoAPI(t),
+			emailAPI:       mocks.NewEmailAPI(t),
+		}
+
+		testMocks.emailStorage.On("List", mock.Anything).Return([]*entity.Subscriber{
+			{
+				Email:  "email1@test.com",
+				Status: entity.SubscriberStatusConfirmed,
+				Pairs:  []entity.CurrencyPair{entity.DefaultCurrencyPair},
+			},
+		}, nil)
+		testMocks.cryptoAPI.On("GetRate", mock.Anything, "BTC", "USD").Return(float64(100), nil)
+		testMocks.rateStorage.On("List", mock.Anything).Return(nil, nil)
+		testMocks.rateStorage.On("Save", mock.Anything, mock.Anything).Return(nil)
+		// sending is blocked until the second job is rejected
+		release := make(chan struct{})
+		testMocks.emailAPI.On("Send", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			<-release
+		}).Return(nil).Once()
+		// storage doesn't see the first job yet, so the second one is rejected because sending is in progress
+		testMocks.sendJobStorage.On("DeleteFinished", mock.Anything, mock.Anything).Return(0, nil)
+		testMocks.sendJobStorage.On("List", mock.Anything).Return(nil, nil)
+		testMocks.sendJobStorage.On("Save", mock.Anything, mock.Anything).Return(nil).Once()
+		finished := finishedJobs(testMocks.sendJobStorage)
+
+		sendJobService := newSendJobTestService(t, testMocks)
+		defer sendJobService.Shutdown()
+
+		_, err := sendJobService.Start(context.Background())
+		require.NoError(t, err)
+
+		job, err := sendJobService.Start(context.Background())
+		assert.Nil(t, job)
+		assert.Equal(t, service.ErrSendJobInProgress, err)
+
+		close(release)
+		finishedJob := waitFinishedJob(t, finished)
+		assert.Equal(t, 1, finishedJob.Sent)
+	})
+}
+
+func TestSendJobService_Resume(t *testing.T) {
+	t.Parallel()
+
+	testMocks := sendJobTestMocks{
+		emailStorage:   mocks.NewEmailStorage(t),
+		sendJobStorage: mocks.NewSendJobStorage(t),
+		rateStorage:    mocks.NewRateStorage(t),
+		cryptoAPI:      mocks.NewCryptoAPI(t),
+		emailAPI:       mocks.NewEmailAPI(t),
+	}
+
+	testMocks.sendJobStorage.On("DeleteFinished", mock.Anything, mock.Anything).Return(0, nil)
+	testMocks.sendJobStorage.On("List", mock.Anything).Return([]*entity.SendJob{
+		{
+			ID:    "completed",
+			State: entity.SendJobStateCompleted,
+		},
+		{
+			ID:        "interrupted",
+			State:     entity.SendJobStateRunning,
+			Total:     4,
+			Sent:      2,
+			Cursor:    "email1@test.com",
+			Processed: []string{"email3@test.com"},
+		},
+	}, nil)
+	subscribers := make([]*entity.Subscriber, 0, 4)
+	for _, email := range []string{"email4@test.com", "email3@test.com", "email2@test.com", "email1@test.com"} {
+		subscribers = append(subscribers, &entity.Subscriber{
+			Email:  email,
+			Status: entity.SubscriberStatusConfirmed,
+			Pairs:  []entity.CurrencyPair{entity.DefaultCurrencyPair},
+		})
+	}
+	testMocks.emailStorage.On("List", mock.Anything).Return(subscribers, nil)
+	testMocks.cryptoAPI.On("GetRate", mock.Anything, "BTC", "USD").Return(float64(100), nil)
+	testMocks.rateStorage.On("List", mock.Anything).Return(nil, nil)
+	testMocks.rateStorage.On("Save", mock.Anything, mock.Anything).Return(nil)
+	// email1@test.com and email3@test.com were processed before restart, so only the rest are sent to
+	for _, email := range []string{"email2@test.com", "email4@test.com"} {
+		email := email
+		testMocks.emailAPI.On("Send", mock.Anything, mock.MatchedBy(func(opts *service.SendOptions) bool {
+			return opts.To == email
+		})).Return(nil).Once()
+	}
+	finished := finishedJobs(testMocks.sendJobStorage)
+
+	sendJobService := newSendJobTestService(t, testMocks)
+	defer sendJobService.Shutdown()
+
+	err := sendJobService.Resume(context.Background())
+	require.NoError(t, err)
+
+	finishedJob := waitFinishedJob(t, finished)
+	assert.Equal(t, "interrupted", finishedJob.ID)
+	assert.Equal(t, entity.SendJobStateCompleted, finishedJob.State)
+	assert.Equal(t, 4, finishedJob.Total)
+	assert.Equal(t, 4, finishedJob.Sent)
+	assert.Equal(t, "email4@test.com", finishedJob.Cursor)
+	assert.Empty(t, finishedJob.Processed)
+}
+
+func TestSendJobService_Get(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testMocks := sendJobTestMocks{
+		sendJobStorage: mocks.NewSendJobStorage(t),
+	}
+	testMocks.sendJobStorage.On("Get", ctx, "unknown").Return(nil, nil)
+
+	sendJobService := newSendJobTestService(t, testMocks)
+	defer sendJobService.Shutdown()
+
+	job, err := sendJobService.Get(ctx, "unknown")
+	assert.Nil(t, job)
+	assert.Equal(t, service.ErrSendJobNotFound, err)
+}
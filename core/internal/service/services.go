@@ -2,14 +2,22 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"time"
 
 	"github.com/vadimpk/gses-2023/core/config"
+	"github.com/vadimpk/gses-2023/core/internal/entity"
 	"github.com/vadimpk/gses-2023/pkg/logging"
 )
 
 type Services struct {
-	Email EmailService
+	Email      EmailService
+	SendJob    SendJobService
+	DeadLetter DeadLetterService
+	Alert      AlertService
+	Subscriber SubscriberService
 }
 
 type Options struct {
@@ -17,6 +25,10 @@ type Options struct {
 	APIs     APIs
 	Logger   logging.Logger
 	Cfg      *config.Config
+	// Templates are used to render emails, embedded defaults are used if nil.
+	Templates *Templates
+	// DisposableDomains are domains of emails that can't be subscribed, no domains are blocked if nil.
+	DisposableDomains *DomainList
 }
 
 type serviceContext struct {
@@ -28,20 +40,240 @@ type serviceContext struct {
 
 // EmailService provides business logic for email service.
 type EmailService interface {
-	// Subscribe subscribes email to newsletter.
-	Subscribe(ctx context.Context, email string) error
-	// SendRateInfo sends emails to all subscribers about current rate info.
+	// Subscribe creates pending subscription and sends confirmation email. Returns subscriber, whose
+	// email is normalized form of requested one.
+	Subscribe(ctx context.Context, opts *SubscribeOptions) (*entity.Subscriber, error)
+	// ConfirmSubscription activates pending subscription if token from confirmation email is valid.
+	ConfirmSubscription(ctx context.Context, email, token string) error
+	// SendRateInfo sends emails to all confirmed subscribers about current rate info.
 	SendRateInfo(ctx context.Context) (*SendRateInfoOutput, error)
+	// Unsubscribe removes email from newsletter.
+	Unsubscribe(ctx context.Context, email string) error
+	// VerifyUnsubscribeToken checks if token from unsubscribe link was issued for email.
+	VerifyUnsubscribeToken(email, token string) error
+	// GetPairs returns currency pairs of subscriber if token from rate email is valid.
+	GetPairs(ctx context.Context, email, token string) ([]entity.CurrencyPair, error)
+	// UpdatePairs replaces currency pairs of subscriber if token from rate email is valid.
+	UpdatePairs(ctx context.Context, opts *UpdatePairsOptions) error
+}
+
+// SendJobService provides business logic for sending rate info in background.
+type SendJobService interface {
+	// Start creates job that sends rate info to all confirmed subscribers in background. Only one job
+	// runs at a time, finished jobs older than retention period are removed.
+	Start(ctx context.Context) (*entity.SendJob, error)
+	// Get returns job by id.
+	Get(ctx context.Context, id string) (*entity.SendJob, error)
+	// Resume starts jobs that were interrupted by restart.
+	Resume(ctx context.Context) error
+	// Shutdown interrupts running jobs and waits for them to save progress.
+	Shutdown()
+}
+
+// DeadLetterService provides business logic for emails that failed to be sent after all retries.
+type DeadLetterService interface {
+	// List returns emails that failed to be sent.
+	List(ctx context.Context) ([]*entity.DeadLetter, error)
+	// Redrive sends selected dead letters again. Letters that are sent successfully are removed from the list,
+	// as well as letters whose recipient is no longer a confirmed subscriber.
+	Redrive(ctx context.Context, opts *RedriveOptions) (*RedriveOutput, error)
+}
+
+type RedriveOptions struct {
+	// IDs are ids of letters to send again.
+	IDs []string
+	// All selects all letters instead of IDs.
+	All bool
+}
+
+type RedriveOutput struct {
+	// Sent are ids of letters that were sent and removed from the list.
+	Sent []string
+	// Failed are ids of letters that failed to be sent again and stay in the list.
+	Failed []string
+	// Dropped are ids of letters that were removed from the list without sending, because recipient
+	// unsubscribed or was deleted, and selected ids that are not in the list.
+	Dropped []string
+}
+
+// AlertService provides business logic for alerts about rates meeting subscriber's conditions.
+type AlertService interface {
+	// Create registers alert for subscriber if token from rate email is valid.
+	Create(ctx context.Context, opts *CreateAlertOptions) (*entity.Alert, error)
+	// List returns alerts of subscriber if token from rate email is valid.
+	List(ctx context.Context, email, token string) ([]*entity.Alert, error)
+	// Delete removes alert of subscriber if token from rate email is valid.
+	Delete(ctx context.Context, email, token, id string) error
+	// Evaluate fetches current rates of pairs that have alerts and notifies subscribers about
+	// alerts that are triggered.
+	Evaluate(ctx context.Context) error
+}
+
+// SubscriberService provides business logic for managing subscribers by administrators.
+type SubscriberService interface {
+	// List returns page of subscribers matching filter.
+	List(ctx context.Context, opts *ListSubscribersOptions) (*ListSubscribersOutput, error)
+	// Get returns subscriber by email.
+	Get(ctx context.Context, email string) (*entity.Subscriber, error)
+	// Delete removes subscriber by email.
+	Delete(ctx context.Context, email string) error
+	// Import saves subscribers that don't exist yet. Subscribers with invalid data are skipped and
+	// reported in output instead of failing the whole import.
+	Import(ctx context.Context, subscribers []*ImportSubscriberOptions) (*ImportSubscribersOutput, error)
+}
+
+type ListSubscribersOptions struct {
+	// Query is case-insensitive part of email.
+	Query  string
+	Status entity.SubscriberStatus
+	Offset int
+	// Limit is max number of subscribers on page, DefaultSubscribersPageSize is used if it's zero.
+	Limit int
+}
+
+const (
+	// DefaultSubscribersPageSize is number of subscribers on page if limit is not set.
+	DefaultSubscribersPageSize = 50
+	// MaxSubscribersPageSize is max number of subscribers on page.
+	MaxSubscribersPageSize = 1000
+)
+
+type ListSubscribersOutput struct {
+	Subscribers []*entity.Subscriber
+	// Total is number of subscribers matching filter on all pages.
+	Total int
+}
+
+type ImportSubscriberOptions struct {
+	Email string
+	// Status is entity.SubscriberStatusConfirmed if empty, as imported subscribers usually have
+	// confirmed subscription elsewhere.
+	Status entity.SubscriberStatus
+	// Pairs are entity.DefaultCurrencyPair if empty.
+	Pairs []entity.CurrencyPair
+	// Locale is entity.DefaultLocale if empty.
+	Locale entity.Locale
+	// CreatedAt is time of import if zero.
+	CreatedAt time.Time
+}
+
+// MaxImportSubscribers is max number of subscribers imported at once.
+const MaxImportSubscribers = 10000
+
+type ImportSubscribersOutput struct {
+	// Imported are emails of saved subscribers.
+	Imported []string
+	// Existing are emails of subscribers that were skipped because they already exist.
+	Existing []string
+	// Invalid are subscribers that were skipped because of invalid data.
+	Invalid []*ImportError
+}
+
+type ImportError struct {
+	Email string
+	Err   error
+}
+
+type CreateAlertOptions struct {
+	Email     string
+	Token     string
+	Pair      entity.CurrencyPair
+	Condition entity.AlertCondition
+	// Threshold is rate for entity.AlertConditionAbove and entity.AlertConditionBelow and change
+	// in percents for entity.AlertConditionChange.
+	Threshold float64
+	// Window is used for entity.AlertConditionChange, 24 hours are used if it's zero.
+	Window time.Duration
+}
+
+type SubscribeOptions struct {
+	Email string
+	// Pairs are currency pairs to receive rates for. entity.DefaultCurrencyPair is used if empty.
+	Pairs []entity.CurrencyPair
+	// Locale is language of emails. entity.DefaultLocale is used if empty.
+	Locale entity.Locale
+}
+
+type UpdatePairsOptions struct {
+	Email string
+	Token string
+	Pairs []entity.CurrencyPair
 }
 
 var (
 	// ErrSubscribeAlreadySubscribed is returned when email is already subscribed.
 	ErrSubscribeAlreadySubscribed = errors.New("already subscribed")
+	// ErrSubscribeConfirmationPending is returned when email is subscribed, but subscription is not confirmed yet.
+	ErrSubscribeConfirmationPending = errors.New("subscription is waiting for confirmation")
+	// ErrSubscribeInvalidEmail is returned when email is not a valid address.
+	ErrSubscribeInvalidEmail = errors.New("invalid email")
+	// ErrSubscribeUndeliverableEmail is returned when domain of email can't receive emails.
+	ErrSubscribeUndeliverableEmail = errors.New("email domain doesn't accept mail")
+	// ErrSubscribeDisposableEmail is returned when email belongs to disposable email service.
+	ErrSubscribeDisposableEmail = errors.New("disposable emails are not allowed")
+
+	// ErrConfirmSubscriptionInvalidToken is returned when confirmation token doesn't match email.
+	ErrConfirmSubscriptionInvalidToken = errors.New("invalid confirmation token")
+	// ErrConfirmSubscriptionTokenExpired is returned when confirmation token has expired.
+	ErrConfirmSubscriptionTokenExpired = errors.New("confirmation token expired")
+	// ErrConfirmSubscriptionNotFound is returned when there is no pending subscription for email.
+	ErrConfirmSubscriptionNotFound = errors.New("subscription not found")
 
 	// ErrSendRateInfoFailedToSendToAllEmails is returned when failed to send rate info to all emails.
 	ErrSendRateInfoFailedToSendToAllEmails = errors.New("failed to send rate info to all emails")
+	// ErrSendRateInfoInProgress is returned when rate info is already being sent by scheduler or send job.
+	ErrSendRateInfoInProgress = errors.New("rate info is already being sent")
+
+	// ErrUnsubscribeNotSubscribed is returned when email is not subscribed.
+	ErrUnsubscribeNotSubscribed = errors.New("not subscribed")
+	// ErrUnsubscribeInvalidToken is returned when unsubscribe token doesn't match email.
+	ErrUnsubscribeInvalidToken = errors.New("invalid unsubscribe token")
+
+	// ErrSendJobNotFound is returned when job with such id doesn't exist.
+	ErrSendJobNotFound = errors.New("send job not found")
+
+	// ErrRedriveNoLetters is returned when neither ids of dead letters nor all letters are selected.
+	ErrRedriveNoLetters = errors.New("ids of letters or all are required")
+	// ErrSendJobInProgress is returned when another send job is unfinished or rate info is already being sent.
+	ErrSendJobInProgress = errors.New("send job is already in progress")
+
+	// ErrUpdatePairsInvalidToken is returned when preferences token doesn't match email, also by GetPairs.
+	ErrUpdatePairsInvalidToken = errors.New("invalid preferences token")
+	// ErrUpdatePairsNotSubscribed is returned when email is not subscribed, also by GetPairs.
+	ErrUpdatePairsNotSubscribed = errors.New("not subscribed")
+	// ErrUpdatePairsNoPairs is returned when no currency pairs are provided.
+	ErrUpdatePairsNoPairs = errors.New("at least one currency pair is required")
+
+	// ErrAlertInvalidToken is returned when preferences token doesn't match email.
+	ErrAlertInvalidToken = errors.New("invalid preferences token")
+	// ErrAlertNotSubscribed is returned when email is not subscribed or subscription is not confirmed.
+	ErrAlertNotSubscribed = errors.New("not subscribed")
+	// ErrAlertNotFound is returned when subscriber doesn't have alert with such id.
+	ErrAlertNotFound = errors.New("alert not found")
+	// ErrAlertInvalidThreshold is returned when threshold is not positive.
+	ErrAlertInvalidThreshold = errors.New("threshold must be positive")
+	// ErrAlertInvalidWindow is returned when window of change alert is out of allowed range.
+	ErrAlertInvalidWindow = errors.New("window must be between 1 hour and 7 days")
+	// ErrAlertLimitExceeded is returned when subscriber already has max number of alerts.
+	ErrAlertLimitExceeded = errors.New("too many alerts")
+
+	// ErrSubscriberNotFound is returned when subscriber with such email doesn't exist.
+	ErrSubscriberNotFound = errors.New("subscriber not found")
+	// ErrImportSubscribersEmpty is returned when there are no subscribers to import.
+	ErrImportSubscribersEmpty = errors.New("no subscribers to import")
+	// ErrImportSubscribersTooMany is returned when number of imported subscribers exceeds MaxImportSubscribers.
+	ErrImportSubscribersTooMany = errors.New("too many subscribers to import")
 )
 
 type SendRateInfoOutput struct {
 	FailedEmails []string
 }
+
+// newID returns random identifier for entities that don't have natural one.
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
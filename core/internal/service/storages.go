@@ -1,19 +1,137 @@
 package service
 
-import "context"
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+)
 
 type Storages struct {
-	Email EmailStorage
+	Email      EmailStorage
+	SendJob    SendJobStorage
+	DeadLetter DeadLetterStorage
+	Rate       RateStorage
+	Alert      AlertStorage
 }
 
-// EmailStorage provides methods for storing emails that are used in EmailService.
+// ErrAlreadyExists is returned by storages when saved entity has the same key as existing one.
+var ErrAlreadyExists = errors.New("already exists")
+
+// EmailStorage provides methods for storing subscribers that are used in EmailService.
 //
 //go:generate go run github.com/vektra/mockery/v2@v2.27.1 --dir . --name EmailStorage --output ../../internal/service/mocks
 type EmailStorage interface {
-	// Save saves email to storage.
-	Save(ctx context.Context, email string) error
-	// List returns list of emails from storage.
-	List(ctx context.Context) ([]string, error)
+	// Save saves subscriber to storage. Storages that enforce unique emails return ErrAlreadyExists
+	// if subscriber with the same email exists.
+	Save(ctx context.Context, subscriber *entity.Subscriber) error
+	// Get returns subscriber by email. Returns nil if subscriber doesn't exist.
+	Get(ctx context.Context, email string) (*entity.Subscriber, error)
+	// Update replaces subscriber with the same email in storage.
+	Update(ctx context.Context, subscriber *entity.Subscriber) error
+	// List returns list of subscribers from storage.
+	List(ctx context.Context) ([]*entity.Subscriber, error)
 	// Exist checks if email exists in storage.
 	Exist(ctx context.Context, email string) (bool, error)
+	// Delete removes email from storage.
+	Delete(ctx context.Context, email string) error
+	// Search returns page of subscribers matching filter in the same order as List and total number
+	// of matching subscribers.
+	Search(ctx context.Context, filter *SubscriberFilter) ([]*entity.Subscriber, int, error)
+	// SaveMany saves subscribers that don't exist in storage yet at once. Returns emails of subscribers
+	// that were skipped because they already exist, including repeated emails of subscribers.
+	SaveMany(ctx context.Context, subscribers []*entity.Subscriber) ([]string, error)
+}
+
+// SubscriberFilter selects page of subscribers in EmailStorage.Search.
+type SubscriberFilter struct {
+	// Query is case-insensitive part of email, subscribers with any email match if it's empty.
+	Query string
+	// Status matches subscribers with such status, subscribers with any status match if it's empty.
+	Status entity.SubscriberStatus
+	// Offset is number of matching subscribers to skip.
+	Offset int
+	// Limit is max number of returned subscribers, it has to be positive.
+	Limit int
+}
+
+// Match checks if subscriber matches Query and Status of filter.
+func (f *SubscriberFilter) Match(subscriber *entity.Subscriber) bool {
+	if f.Status != "" && subscriber.Status != f.Status {
+		return false
+	}
+	return strings.Contains(strings.ToLower(subscriber.Email), strings.ToLower(f.Query))
+}
+
+// Page returns subscribers matching filter within its Offset and Limit and total number of matching
+// subscribers. It's used by storages that filter subscribers in memory.
+func (f *SubscriberFilter) Page(subscribers []*entity.Subscriber) ([]*entity.Subscriber, int) {
+	var page []*entity.Subscriber
+	total := 0
+	for _, subscriber := range subscribers {
+		if !f.Match(subscriber) {
+			continue
+		}
+		if total >= f.Offset && len(page) < f.Limit {
+			page = append(page, subscriber)
+		}
+		total++
+	}
+	return page, total
+}
+
+// SendJobStorage provides methods for storing send jobs that are used in SendJobService.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.27.1 --dir . --name SendJobStorage --output ../../internal/service/mocks
+type SendJobStorage interface {
+	// Save saves job to storage.
+	Save(ctx context.Context, job *entity.SendJob) error
+	// Get returns job by id. Returns nil if job doesn't exist.
+	Get(ctx context.Context, id string) (*entity.SendJob, error)
+	// Update replaces job with the same id in storage.
+	Update(ctx context.Context, job *entity.SendJob) error
+	// List returns list of jobs from storage.
+	List(ctx context.Context) ([]*entity.SendJob, error)
+	// DeleteFinished removes finished jobs that were last updated before given time. Returns number of
+	// removed jobs.
+	DeleteFinished(ctx context.Context, before time.Time) (int, error)
+}
+
+// DeadLetterStorage provides methods for storing emails that failed to be sent.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.27.1 --dir . --name DeadLetterStorage --output ../../internal/service/mocks
+type DeadLetterStorage interface {
+	// Save saves dead letter to storage.
+	Save(ctx context.Context, letter *entity.DeadLetter) error
+	// List returns list of dead letters from storage.
+	List(ctx context.Context) ([]*entity.DeadLetter, error)
+	// Delete removes dead letter by id from storage.
+	Delete(ctx context.Context, id string) error
+}
+
+// RateStorage provides methods for storing rates that were last sent to subscribers, so that
+// next email can show how rates changed.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.27.1 --dir . --name RateStorage --output ../../internal/service/mocks
+type RateStorage interface {
+	// Save replaces last rate of the same currency pair.
+	Save(ctx context.Context, rate *entity.Rate) error
+	// List returns last rate of each currency pair.
+	List(ctx context.Context) ([]*entity.Rate, error)
+}
+
+// AlertStorage provides methods for storing rate alerts that are used in AlertService.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.27.1 --dir . --name AlertStorage --output ../../internal/service/mocks
+type AlertStorage interface {
+	// Save saves new alert to storage.
+	Save(ctx context.Context, alert *entity.Alert) error
+	// List returns all alerts.
+	List(ctx context.Context) ([]*entity.Alert, error)
+	// Update replaces alert with the same id.
+	Update(ctx context.Context, alert *entity.Alert) error
+	// Delete removes alert by id.
+	Delete(ctx context.Context, id string) error
 }
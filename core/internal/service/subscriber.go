@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+)
+
+type subscriberService struct {
+	serviceContext
+	// disposableDomains are domains of emails that can't be imported, it may be nil.
+	disposableDomains *DomainList
+}
+
+func NewSubscriberService(opts *Options) *subscriberService {
+	return &subscriberService{
+		serviceContext: serviceContext{
+			storages: opts.Storages,
+			apis:     opts.APIs,
+			logger:   opts.Logger.Named("SubscriberService"),
+			cfg:      opts.Cfg,
+		},
+		disposableDomains: opts.DisposableDomains,
+	}
+}
+
+func (s *subscriberService) List(ctx context.Context, opts *ListSubscribersOptions) (*ListSubscribersOutput, error) {
+	logger := s.logger.Named("List").
+		WithContext(ctx).
+		With("opts", opts)
+
+	filter := &SubscriberFilter{
+		Query:  opts.Query,
+		Status: opts.Status,
+		Offset: opts.Offset,
+		Limit:  opts.Limit,
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultSubscribersPageSize
+	}
+	if filter.Limit > MaxSubscribersPageSize {
+		filter.Limit = MaxSubscribersPageSize
+	}
+
+	subscribers, total, err := s.storages.Email.Search(ctx, filter)
+	if err != nil {
+		logger.Error("failed to search subscribers in storage", "err", err)
+		return nil, fmt.Errorf("failed to search subscribers in storage: %w", err)
+	}
+
+	return &ListSubscribersOutput{
+		Subscribers: subscribers,
+		Total:       total,
+	}, nil
+}
+
+func (s *subscriberService) Get(ctx context.Context, email string) (*entity.Subscriber, error) {
+	logger := s.logger.Named("Get").
+		WithContext(ctx).
+		With("email", email)
+
+	// subscribed emails are normalized, but records stored before normalization was introduced may be in
+	// mixed case or even invalid by current rules, so email is looked up as it is if normalized one isn't found
+	emails := []string{email}
+	local, domain, err := normalizeEmail(email, s.cfg.EmailValidation)
+	if err != nil {
+		logger.Info("invalid email", "err", err)
+	} else if normalized := local + "@" + domain; normalized != email {
+		emails = []string{normalized, email}
+	}
+
+	var subscriber *entity.Subscriber
+	for _, e := range emails {
+		subscriber, err = s.storages.Email.Get(ctx, e)
+		if err != nil {
+			logger.Error("failed to get email from storage", "err", err)
+			return nil, fmt.Errorf("failed to get email from storage: %w", err)
+		}
+		if subscriber != nil {
+			break
+		}
+	}
+	if subscriber == nil {
+		logger.Info("email doesn't exist")
+		return nil, ErrSubscriberNotFound
+	}
+
+	return subscriber, nil
+}
+
+func (s *subscriberService) Delete(ctx context.Context, email string) error {
+	logger := s.logger.Named("Delete").
+		WithContext(ctx).
+		With("email", email)
+
+	subscriber, err := s.Get(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	err = s.storages.Email.Delete(ctx, subscriber.Email)
+	if err != nil {
+		logger.Error("failed to delete email from storage", "err", err)
+		return fmt.Errorf("failed to delete email from storage: %w", err)
+	}
+
+	logger.Info("successfully deleted subscriber")
+	return nil
+}
+
+func (s *subscriberService) Import(ctx context.Context, subscribers []*ImportSubscriberOptions) (*ImportSubscribersOutput, error) {
+	logger := s.logger.Named("Import").
+		WithContext(ctx).
+		With("count", len(subscribers))
+
+	if len(subscribers) == 0 {
+		logger.Info("no subscribers to import")
+		return nil, ErrImportSubscribersEmpty
+	}
+	if len(subscribers) > MaxImportSubscribers {
+		logger.Info("too many subscribers to import")
+		return nil, ErrImportSubscribersTooMany
+	}
+
+	output := &ImportSubscribersOutput{}
+	now := time.Now()
+	valid := make([]*entity.Subscriber, 0, len(subscribers))
+	for _, opts := range subscribers {
+		subscriber, err := s.newImportedSubscriber(opts, now)
+		if err != nil {
+			output.Invalid = append(output.Invalid, &ImportError{
+				Email: opts.Email,
+				Err:   err,
+			})
+			continue
+		}
+		valid = append(valid, subscriber)
+	}
+
+	if len(valid) > 0 {
+		existing, err := s.storages.Email.SaveMany(ctx, valid)
+		if err != nil {
+			logger.Error("failed to save subscribers", "err", err)
+			return nil, fmt.Errorf("failed to save subscribers to storage: %w", err)
+		}
+		output.Existing = existing
+
+		// the same email may be both imported and repeated, so only its repetitions are skipped
+		skipped := make(map[string]int, len(existing))
+		for _, email := range existing {
+			skipped[email]++
+		}
+		for _, subscriber := range valid {
+			if skipped[subscriber.Email] > 0 {
+				skipped[subscriber.Email]--
+				continue
+			}
+			output.Imported = append(output.Imported, subscriber.Email)
+		}
+	}
+
+	logger.Info("successfully imported subscribers",
+		"imported", len(output.Imported), "existing", len(output.Existing), "invalid", len(output.Invalid))
+	return output, nil
+}
+
+// newImportedSubscriber validates imported subscriber and fills in defaults.
+func (s *subscriberService) newImportedSubscriber(opts *ImportSubscriberOptions, now time.Time) (*entity.Subscriber, error) {
+	local, domain, err := normalizeEmail(opts.Email, s.cfg.EmailValidation)
+	if err != nil {
+		return nil, err
+	}
+	if s.disposableDomains != nil && s.disposableDomains.Contains(domain) {
+		return nil, ErrSubscribeDisposableEmail
+	}
+
+	subscriber := &entity.Subscriber{
+		Email:     local + "@" + domain,
+		Status:    opts.Status,
+		CreatedAt: opts.CreatedAt,
+		Pairs:     opts.Pairs,
+		Locale:    opts.Locale,
+	}
+	if subscriber.Status == "" {
+		subscriber.Status = entity.SubscriberStatusConfirmed
+	}
+	if !subscriber.Status.IsValid() {
+		return nil, fmt.Errorf("%w: %q", entity.ErrInvalidSubscriberStatus, subscriber.Status)
+	}
+	if subscriber.CreatedAt.IsZero() {
+		subscriber.CreatedAt = now
+	}
+	if len(subscriber.Pairs) == 0 {
+		subscriber.Pairs = []entity.CurrencyPair{entity.DefaultCurrencyPair}
+	}
+	if subscriber.Locale == "" {
+		subscriber.Locale = entity.DefaultLocale
+	}
+
+	return subscriber, nil
+}
@@ -0,0 +1,303 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/core/internal/service/mocks"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+func newTestSubscriberService(emailStorage *mocks.EmailStorage, disposableDomains *service.DomainList) service.SubscriberService {
+	return service.NewSubscriberService(&service.Options{
+		Storages: service.Storages{
+			Email: emailStorage,
+		},
+		Logger:            logging.NewZapLogger("debug"),
+		Cfg:               validationTestConfig,
+		DisposableDomains: disposableDomains,
+	})
+}
+
+func TestSubscriberService_List(t *testing.T) {
+	t.Parallel()
+
+	type mocksForExecution struct {
+		emailStorage *mocks.EmailStorage
+	}
+
+	type args struct {
+		opts *service.ListSubscribersOptions
+	}
+
+	type expected struct {
+		output *service.ListSubscribersOutput
+		err    error
+	}
+
+	ctx := context.Background()
+	testSubscribers := []*entity.Subscriber{{Email: "email@test.com"}}
+	testErr := errors.New("some err")
+
+	testCases := []struct {
+		name     string
+		mock     func(m mocksForExecution)
+		args     args
+		expected expected
+	}{
+		{
+			name: "positive: listed subscribers",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Search", ctx, &service.SubscriberFilter{
+					Query:  "test",
+					Status: entity.SubscriberStatusConfirmed,
+					Offset: 10,
+					Limit:  5,
+				}).Return(testSubscribers, 11, nil)
+			},
+			args: args{
+				opts: &service.ListSubscribersOptions{
+					Query:  "test",
+					Status: entity.SubscriberStatusConfirmed,
+					Offset: 10,
+					Limit:  5,
+				},
+			},
+			expected: expected{
+				output: &service.ListSubscribersOutput{
+					Subscribers: testSubscribers,
+					Total:       11,
+				},
+			},
+		},
+		{
+			name: "positive: page size is limited",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Search", ctx, &service.SubscriberFilter{
+					Limit: service.MaxSubscribersPageSize,
+				}).Return(nil, 0, nil)
+			},
+			args: args{
+				opts: &service.ListSubscribersOptions{
+					Offset: -1,
+					Limit:  service.MaxSubscribersPageSize + 1,
+				},
+			},
+			expected: expected{
+				output: &service.ListSubscribersOutput{},
+			},
+		},
+		{
+			name: "positive: default page size",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Search", ctx, &service.SubscriberFilter{
+					Limit: service.DefaultSubscribersPageSize,
+				}).Return(nil, 0, nil)
+			},
+			args: args{
+				opts: &service.ListSubscribersOptions{},
+			},
+			expected: expected{
+				output: &service.ListSubscribersOutput{},
+			},
+		},
+		{
+			name: "negative: failed to search subscribers",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Search", ctx, mock.Anything).Return(nil, 0, testErr)
+			},
+			args: args{
+				opts: &service.ListSubscribersOptions{},
+			},
+			expected: expected{
+				err: testErr,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			testMocks := mocksForExecution{
+				emailStorage: mocks.NewEmailStorage(t),
+			}
+
+			tc.mock(testMocks)
+
+			output, err := newTestSubscriberService(testMocks.emailStorage, nil).List(ctx, tc.args.opts)
+			assert.ErrorIs(t, err, tc.expected.err)
+			assert.Equal(t, tc.expected.output, output)
+		})
+	}
+}
+
+func TestSubscriberService_Delete(t *testing.T) {
+	t.Parallel()
+
+	type mocksForExecution struct {
+		emailStorage *mocks.EmailStorage
+	}
+
+	type args struct {
+		email string
+	}
+
+	type expected struct {
+		err error
+	}
+
+	ctx := context.Background()
+	testEmail := "email@test.com"
+	testErr := errors.New("some err")
+
+	testCases := []struct {
+		name     string
+		mock     func(m mocksForExecution)
+		args     args
+		expected expected
+	}{
+		{
+			name: "positive: deleted subscriber by normalized email",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(&entity.Subscriber{Email: testEmail}, nil)
+				m.emailStorage.On("Delete", ctx, testEmail).Return(nil)
+			},
+			args: args{
+				email: "Email@Test.com",
+			},
+		},
+		{
+			name: "positive: deleted legacy subscriber stored in mixed case",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(nil, nil)
+				m.emailStorage.On("Get", ctx, "Email@Test.com").Return(&entity.Subscriber{Email: "Email@Test.com"}, nil)
+				m.emailStorage.On("Delete", ctx, "Email@Test.com").Return(nil)
+			},
+			args: args{
+				email: "Email@Test.com",
+			},
+		},
+		{
+			name: "positive: deleted legacy subscriber with invalid email",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, "email").Return(&entity.Subscriber{Email: "email"}, nil)
+				m.emailStorage.On("Delete", ctx, "email").Return(nil)
+			},
+			args: args{
+				email: "email",
+			},
+		},
+		{
+			name: "negative: subscriber doesn't exist",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(nil, nil)
+			},
+			args: args{
+				email: testEmail,
+			},
+			expected: expected{
+				err: service.ErrSubscriberNotFound,
+			},
+		},
+		{
+			name: "negative: invalid email",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, "email").Return(nil, nil)
+			},
+			args: args{
+				email: "email",
+			},
+			expected: expected{
+				err: service.ErrSubscriberNotFound,
+			},
+		},
+		{
+			name: "negative: failed to delete subscriber",
+			mock: func(m mocksForExecution) {
+				m.emailStorage.On("Get", ctx, testEmail).Return(&entity.Subscriber{Email: testEmail}, nil)
+				m.emailStorage.On("Delete", ctx, testEmail).Return(testErr)
+			},
+			args: args{
+				email: testEmail,
+			},
+			expected: expected{
+				err: testErr,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			testMocks := mocksForExecution{
+				emailStorage: mocks.NewEmailStorage(t),
+			}
+
+			tc.mock(testMocks)
+
+			err := newTestSubscriberService(testMocks.emailStorage, nil).Delete(ctx, tc.args.email)
+			assert.ErrorIs(t, err, tc.expected.err)
+		})
+	}
+}
+
+func TestSubscriberService_Import(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	createdAt := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	disposableDomains, err := service.NewDomainList("mailinator.com")
+	require.NoError(t, err)
+
+	emailStorage := mocks.NewEmailStorage(t)
+	emailStorage.On("SaveMany", ctx, mock.MatchedBy(func(subscribers []*entity.Subscriber) bool {
+		return len(subscribers) == 4 &&
+			assert.ObjectsAreEqual(&entity.Subscriber{
+				Email:     "new@test.com",
+				Status:    entity.SubscriberStatusConfirmed,
+				CreatedAt: createdAt,
+				Pairs:     []entity.CurrencyPair{entity.DefaultCurrencyPair},
+				Locale:    entity.DefaultLocale,
+			}, subscribers[0]) &&
+			subscribers[1].Email == "existing@test.com" &&
+			subscribers[2].Email == "pending@test.com" && subscribers[2].Status == entity.SubscriberStatusPending &&
+			subscribers[3].Email == "new@test.com"
+	})).Return([]string{"existing@test.com", "new@test.com"}, nil)
+
+	output, err := newTestSubscriberService(emailStorage, disposableDomains).Import(ctx, []*service.ImportSubscriberOptions{
+		{Email: "New@Test.com", CreatedAt: createdAt},
+		{Email: "existing@test.com"},
+		{Email: "invalid"},
+		{Email: "pending@test.com", Status: entity.SubscriberStatusPending},
+		{Email: "user@mailinator.com"},
+		{Email: "new@test.com"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"pending@test.com", "new@test.com"}, output.Imported)
+	assert.Equal(t, []string{"existing@test.com", "new@test.com"}, output.Existing)
+	require.Len(t, output.Invalid, 2)
+	assert.Equal(t, "invalid", output.Invalid[0].Email)
+	assert.ErrorIs(t, output.Invalid[0].Err, service.ErrSubscribeInvalidEmail)
+	assert.Equal(t, "user@mailinator.com", output.Invalid[1].Email)
+	assert.ErrorIs(t, output.Invalid[1].Err, service.ErrSubscribeDisposableEmail)
+}
+
+func TestSubscriberService_Import_Empty(t *testing.T) {
+	t.Parallel()
+
+	_, err := newTestSubscriberService(mocks.NewEmailStorage(t), nil).Import(context.Background(), nil)
+	assert.ErrorIs(t, err, service.ErrImportSubscribersEmpty)
+}
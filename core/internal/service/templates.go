@@ -0,0 +1,161 @@
+package service
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/pkg/mailtemplate"
+)
+
+// defaultTemplates are used for emails that don't have templates in configured directory.
+// Templates of each locale are stored in directory named after it, e.g. "templates/uk".
+//
+//go:embed templates/*/*.tmpl
+var defaultTemplates embed.FS
+
+const rateInfoTemplate = "rate_info"
+
+// Templates are email templates grouped by locale.
+type Templates struct {
+	locales map[entity.Locale]*mailtemplate.Set
+}
+
+// LoadTemplates loads email templates from dir on top of embedded defaults, so only templates that
+// need to be changed have to be present in dir. Dir has the same structure as defaults: a
+// subdirectory per locale. Only defaults are loaded if dir is empty.
+func LoadTemplates(dir string) (*Templates, error) {
+	defaults, err := fs.Sub(defaultTemplates, "templates")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open default templates: %w", err)
+	}
+
+	bundles := []fs.FS{defaults}
+	if dir != "" {
+		if _, err = os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("failed to open templates directory: %w", err)
+		}
+		bundles = append(bundles, os.DirFS(dir))
+	}
+
+	templates := &Templates{
+		locales: make(map[entity.Locale]*mailtemplate.Set),
+	}
+	for _, bundle := range bundles {
+		entries, err := fs.ReadDir(bundle, ".")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list templates: %w", err)
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+
+			locale, err := entity.ParseLocale(entry.Name())
+			if err != nil || locale.String() != entry.Name() {
+				return nil, fmt.Errorf("templates directory %q doesn't match supported locale", entry.Name())
+			}
+			if _, ok := templates.locales[locale]; ok {
+				continue
+			}
+
+			set, err := loadLocaleTemplates(locale, bundles)
+			if err != nil {
+				return nil, err
+			}
+			templates.locales[locale] = set
+		}
+	}
+
+	return templates, nil
+}
+
+// loadLocaleTemplates parses templates of locale from each bundle that has them, later bundles
+// override templates of earlier ones.
+func loadLocaleTemplates(locale entity.Locale, bundles []fs.FS) (*mailtemplate.Set, error) {
+	var fileSystems []fs.FS
+	for _, bundle := range bundles {
+		localeFS, err := fs.Sub(bundle, locale.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s templates: %w", locale, err)
+		}
+		fileSystems = append(fileSystems, localeFS)
+	}
+
+	set, err := mailtemplate.New(map[string]interface{}{
+		"money": func(amount float64, currency entity.FiatCurrency) string {
+			return formatMoney(amount, currency, locale)
+		},
+		"percent": func(percent float64) string {
+			return formatPercent(percent, locale)
+		},
+	}, fileSystems...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s templates: %w", locale, err)
+	}
+
+	return set, nil
+}
+
+// Render renders email in locale. If locale doesn't have template for email, entity.DefaultLocale is used.
+func (t *Templates) Render(locale entity.Locale, name string, data interface{}) (*mailtemplate.Message, error) {
+	chain := []entity.Locale{locale}
+	if locale != entity.DefaultLocale {
+		chain = append(chain, entity.DefaultLocale)
+	}
+
+	for _, l := range chain {
+		set, ok := t.locales[l]
+		if !ok {
+			continue
+		}
+
+		message, err := set.Render(name, data)
+		if errors.Is(err, mailtemplate.ErrNotFound) {
+			continue
+		}
+		return message, err
+	}
+
+	return nil, fmt.Errorf("%w: %s", mailtemplate.ErrNotFound, name)
+}
+
+// rateInfoEmailData is passed to rate info templates.
+type rateInfoEmailData struct {
+	Rates []rateInfoEmailRate
+	// Timestamp is time when rates were fetched in UTC.
+	Timestamp       time.Time
+	PreferencesLink string
+	UnsubscribeLink string
+}
+
+type rateInfoEmailRate struct {
+	Pair entity.CurrencyPair
+	Rate float64
+	// Change is nil if rate of the pair wasn't sent before.
+	Change *rateChange
+}
+
+// rateChange describes how rate changed since it was sent in the last email.
+type rateChange struct {
+	Previous float64
+	Percent  float64
+	Since    time.Time
+}
+
+func newRateChange(current float64, previous *entity.Rate) *rateChange {
+	if previous == nil || previous.Value == 0 {
+		return nil
+	}
+
+	return &rateChange{
+		Previous: previous.Value,
+		Percent:  (current - previous.Value) / previous.Value * 100,
+		Since:    previous.FetchedAt,
+	}
+}
@@ -0,0 +1,98 @@
+package service_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/core/pkg/mailtemplate"
+)
+
+func TestLoadTemplates(t *testing.T) {
+	t.Parallel()
+
+	type expected struct {
+		message *mailtemplate.Message
+		err     bool
+	}
+
+	writeFiles := func(t *testing.T, files map[string]string) string {
+		dir := t.TempDir()
+		for name, content := range files {
+			path := filepath.Join(dir, name)
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		}
+		return dir
+	}
+
+	testCases := []struct {
+		name     string
+		files    map[string]string
+		locale   entity.Locale
+		template string
+		expected expected
+	}{
+		{
+			name: "positive: custom template overrides default one",
+			files: map[string]string{
+				"uk/greeting.subject.tmpl": "Привіт",
+				"uk/greeting.txt.tmpl":     "{{money 1234.5 .}}",
+			},
+			locale:   entity.LocaleUkrainian,
+			template: "greeting",
+			expected: expected{
+				message: &mailtemplate.Message{
+					Subject: "Привіт",
+					Text:    "1\u00a0234,50\u00a0₴",
+				},
+			},
+		},
+		{
+			name: "positive: fall back to default locale",
+			files: map[string]string{
+				"en/greeting.subject.tmpl": "Hello",
+				"en/greeting.txt.tmpl":     "{{money 1234.5 .}}",
+			},
+			locale:   entity.LocaleUkrainian,
+			template: "greeting",
+			expected: expected{
+				message: &mailtemplate.Message{
+					Subject: "Hello",
+					Text:    "₴1,234.50",
+				},
+			},
+		},
+		{
+			name: "negative: directory of unsupported locale",
+			files: map[string]string{
+				"fr/greeting.txt.tmpl": "Bonjour",
+			},
+			expected: expected{
+				err: true,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			templates, err := service.LoadTemplates(writeFiles(t, tc.files))
+			if tc.expected.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			message, err := templates.Render(tc.locale, tc.template, entity.FiatCurrencyUAH)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected.message, message)
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package localstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/pkg/database"
+)
+
+type alertStorage struct {
+	db       *database.FileDB
+	filename string
+}
+
+func NewAlertStorage(db *database.FileDB, filename string) *alertStorage {
+	return &alertStorage{
+		db:       db,
+		filename: filename,
+	}
+}
+
+// alertRecord is a representation of alert in file. Each record is stored as JSON on separate line.
+type alertRecord struct {
+	ID          string    `json:"id"`
+	Email       string    `json:"email"`
+	Pair        string    `json:"pair"`
+	Condition   string    `json:"condition"`
+	Threshold   float64   `json:"threshold"`
+	Window      int64     `json:"window_seconds,omitempty"`
+	Triggered   bool      `json:"triggered"`
+	TriggeredAt time.Time `json:"triggered_at,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func newAlertRecord(alert *entity.Alert) alertRecord {
+	return alertRecord{
+		ID:          alert.ID,
+		Email:       alert.Email,
+		Pair:        alert.Pair.String(),
+		Condition:   alert.Condition.String(),
+		Threshold:   alert.Threshold,
+		Window:      int64(alert.Window / time.Second),
+		Triggered:   alert.Triggered,
+		TriggeredAt: alert.TriggeredAt,
+		CreatedAt:   alert.CreatedAt,
+	}
+}
+
+func (r alertRecord) toEntity() (*entity.Alert, error) {
+	pair, err := entity.ParseCurrencyPair(r.Pair)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse alert record: %w", err)
+	}
+
+	condition, err := entity.ParseAlertCondition(r.Condition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse alert record: %w", err)
+	}
+
+	return &entity.Alert{
+		ID:          r.ID,
+		Email:       r.Email,
+		Pair:        pair,
+		Condition:   condition,
+		Threshold:   r.Threshold,
+		Window:      time.Duration(r.Window) * time.Second,
+		Triggered:   r.Triggered,
+		TriggeredAt: r.TriggeredAt,
+		CreatedAt:   r.CreatedAt,
+	}, nil
+}
+
+func (s *alertStorage) Save(ctx context.Context, alert *entity.Alert) error {
+	data, err := json.Marshal(newAlertRecord(alert))
+	if err != nil {
+		return err
+	}
+
+	return s.db.Append(ctx, s.filename, data)
+}
+
+func (s *alertStorage) List(ctx context.Context) ([]*entity.Alert, error) {
+	data, err := s.db.Read(ctx, s.filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAlerts(data)
+}
+
+func (s *alertStorage) Update(ctx context.Context, alert *entity.Alert) error {
+	return s.db.Update(ctx, s.filename, func(data []byte) ([]byte, error) {
+		alerts, err := parseAlerts(data)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, a := range alerts {
+			if a.ID == alert.ID {
+				alerts[i] = alert
+			}
+		}
+
+		var buf bytes.Buffer
+		for _, alert := range alerts {
+			data, err := json.Marshal(newAlertRecord(alert))
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(data)
+			buf.WriteByte('\n')
+		}
+
+		return buf.Bytes(), nil
+	})
+}
+
+func (s *alertStorage) Delete(ctx context.Context, id string) error {
+	_, err := s.db.Delete(ctx, s.filename, func(line []byte) bool {
+		var record alertRecord
+		return json.Unmarshal(line, &record) == nil && record.ID == id
+	})
+	return err
+}
+
+// Compact removes duplicate records of the same alert, keeping the last one, and lines that can't
+// be parsed. Returns number of removed lines.
+func (s *alertStorage) Compact(ctx context.Context) (int, error) {
+	return s.db.Compact(ctx, s.filename, func(line []byte) (string, bool) {
+		var record alertRecord
+		if err := json.Unmarshal(line, &record); err != nil || record.ID == "" {
+			return "", false
+		}
+		return record.ID, true
+	})
+}
+
+func parseAlerts(data []byte) ([]*entity.Alert, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	lines := strings.Split(string(data), "\n")
+
+	alerts := make([]*entity.Alert, 0, len(lines))
+	for _, line := range lines {
+		// Skip any empty strings that may occur due to trailing new lines
+		if line == "" {
+			continue
+		}
+
+		var record alertRecord
+		err := json.Unmarshal([]byte(line), &record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse alert record: %w", err)
+		}
+
+		alert, err := record.toEntity()
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
@@ -0,0 +1,118 @@
+package localstorage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/pkg/database"
+)
+
+type deadLetterStorage struct {
+	db       *database.FileDB
+	filename string
+}
+
+func NewDeadLetterStorage(db *database.FileDB, filename string) *deadLetterStorage {
+	return &deadLetterStorage{
+		db:       db,
+		filename: filename,
+	}
+}
+
+// deadLetterRecord is a representation of dead letter in file. Each record is stored as JSON on separate line.
+type deadLetterRecord struct {
+	ID       string    `json:"id"`
+	To       string    `json:"to"`
+	Subject  string    `json:"subject"`
+	Body     string    `json:"body"`
+	HTML     string    `json:"html,omitempty"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+func newDeadLetterRecord(letter *entity.DeadLetter) deadLetterRecord {
+	return deadLetterRecord{
+		ID:       letter.ID,
+		To:       letter.To,
+		Subject:  letter.Subject,
+		Body:     letter.Body,
+		HTML:     letter.HTML,
+		Error:    letter.Error,
+		FailedAt: letter.FailedAt,
+	}
+}
+
+func (r deadLetterRecord) toEntity() *entity.DeadLetter {
+	return &entity.DeadLetter{
+		ID:       r.ID,
+		To:       r.To,
+		Subject:  r.Subject,
+		Body:     r.Body,
+		HTML:     r.HTML,
+		Error:    r.Error,
+		FailedAt: r.FailedAt,
+	}
+}
+
+func (s *deadLetterStorage) Save(ctx context.Context, letter *entity.DeadLetter) error {
+	data, err := json.Marshal(newDeadLetterRecord(letter))
+	if err != nil {
+		return err
+	}
+
+	return s.db.Append(ctx, s.filename, data)
+}
+
+func (s *deadLetterStorage) List(ctx context.Context) ([]*entity.DeadLetter, error) {
+	data, err := s.db.Read(ctx, s.filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	lines := strings.Split(string(data), "\n")
+
+	letters := make([]*entity.DeadLetter, 0, len(lines))
+	for _, line := range lines {
+		// Skip any empty strings that may occur due to trailing new lines
+		if line == "" {
+			continue
+		}
+
+		var record deadLetterRecord
+		err := json.Unmarshal([]byte(line), &record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse dead letter record: %w", err)
+		}
+		letters = append(letters, record.toEntity())
+	}
+
+	return letters, nil
+}
+
+func (s *deadLetterStorage) Delete(ctx context.Context, id string) error {
+	_, err := s.db.Delete(ctx, s.filename, func(line []byte) bool {
+		var record deadLetterRecord
+		return json.Unmarshal(line, &record) == nil && record.ID == id
+	})
+	return err
+}
+
+// Compact removes duplicate records of the same letter, keeping the last one, and lines that can't
+// be parsed. Returns number of removed lines.
+func (s *deadLetterStorage) Compact(ctx context.Context) (int, error) {
+	return s.db.Compact(ctx, s.filename, func(line []byte) (string, bool) {
+		var record deadLetterRecord
+		if err := json.Unmarshal(line, &record); err != nil || record.ID == "" {
+			return "", false
+		}
+		return record.ID, true
+	})
+}
@@ -1,66 +1,425 @@
 package localstorage
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"os"
 	"strings"
+	"time"
 
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/internal/service"
 	"github.com/vadimpk/gses-2023/core/pkg/database"
 )
 
 type emailStorage struct {
 	db       *database.FileDB
 	filename string
+	// normalize normalizes emails of stored records on Compact, it may be nil.
+	normalize func(email string) (string, bool)
 }
 
-func NewEmailStorage(db *database.FileDB, filename string) *emailStorage {
-	return &emailStorage{
+type EmailStorageOption func(*emailStorage)
+
+// NormalizeEmails makes Compact normalize emails of records stored before normalization was introduced.
+// Normalize reports false if email can't be normalized, such records are kept as they are.
+func NormalizeEmails(normalize func(email string) (string, bool)) EmailStorageOption {
+	return func(s *emailStorage) {
+		s.normalize = normalize
+	}
+}
+
+func NewEmailStorage(db *database.FileDB, filename string, opts ...EmailStorageOption) *emailStorage {
+	s := &emailStorage{
 		db:       db,
 		filename: filename,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
-func (s *emailStorage) Save(ctx context.Context, email string) error {
-	return s.db.Append(ctx, s.filename, []byte(email))
+// subscriberRecord is a representation of subscriber in file. Each record is stored as JSON on separate line.
+type subscriberRecord struct {
+	Email     string    `json:"email"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	Pairs     []string  `json:"pairs,omitempty"`
+	Locale    string    `json:"locale,omitempty"`
 }
 
-func (s *emailStorage) List(ctx context.Context) ([]string, error) {
-	data, err := s.db.Read(ctx, s.filename)
+func newSubscriberRecord(subscriber *entity.Subscriber) subscriberRecord {
+	pairs := make([]string, 0, len(subscriber.Pairs))
+	for _, pair := range subscriber.Pairs {
+		pairs = append(pairs, pair.String())
+	}
+
+	return subscriberRecord{
+		Email:     subscriber.Email,
+		Status:    subscriber.Status.String(),
+		CreatedAt: subscriber.CreatedAt,
+		Pairs:     pairs,
+		Locale:    subscriber.Locale.String(),
+	}
+}
+
+// toEntity converts record to subscriber. Records stored before currency pairs were introduced
+// get entity.DefaultCurrencyPair, which was the only pair sent at that time. Records stored before
+// locales were introduced get entity.DefaultLocale.
+func (r subscriberRecord) toEntity() (*entity.Subscriber, error) {
+	pairs := make([]entity.CurrencyPair, 0, len(r.Pairs))
+	for _, p := range r.Pairs {
+		pair, err := entity.ParseCurrencyPair(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse subscriber record: %w", err)
+		}
+		pairs = append(pairs, pair)
+	}
+	if len(pairs) == 0 {
+		pairs = append(pairs, entity.DefaultCurrencyPair)
+	}
+
+	locale := entity.DefaultLocale
+	if r.Locale != "" {
+		var err error
+		locale, err = entity.ParseLocale(r.Locale)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse subscriber record: %w", err)
+		}
+	}
+
+	return &entity.Subscriber{
+		Email:     r.Email,
+		Status:    entity.SubscriberStatus(r.Status),
+		CreatedAt: r.CreatedAt,
+		Pairs:     pairs,
+		Locale:    locale,
+	}, nil
+}
+
+// parseSubscriberRecord parses line from file. Lines that are not JSON are treated as plain emails
+// stored before double opt-in was introduced, so they are considered confirmed.
+func parseSubscriberRecord(line string) (subscriberRecord, error) {
+	if !strings.HasPrefix(line, "{") {
+		return subscriberRecord{
+			Email:  line,
+			Status: entity.SubscriberStatusConfirmed.String(),
+		}, nil
+	}
+
+	var record subscriberRecord
+	err := json.Unmarshal([]byte(line), &record)
+	if err != nil {
+		return subscriberRecord{}, fmt.Errorf("failed to parse subscriber record: %w", err)
+	}
+
+	return record, nil
+}
+
+func (s *emailStorage) Save(ctx context.Context, subscriber *entity.Subscriber) error {
+	_, err := s.save(ctx, subscriber, nil)
+	return err
+}
+
+// save is Save that calls before, if it's set, with info of file before it's changed and returns info of file
+// after it, both are taken while file is locked.
+func (s *emailStorage) save(ctx context.Context, subscriber *entity.Subscriber, before func(info os.FileInfo)) (os.FileInfo, error) {
+	line, err := json.Marshal(newSubscriberRecord(subscriber))
 	if err != nil {
 		return nil, err
 	}
 
-	if len(data) == 0 {
-		return nil, nil
+	// existence is checked under file lock, so that concurrent saves of the same email don't create duplicates
+	return s.update(ctx, before, func(data []byte) ([]byte, error) {
+		subscribers, err := parseSubscribers(data)
+		if err != nil {
+			return nil, err
+		}
+		for _, existing := range subscribers {
+			if existing.Email == subscriber.Email {
+				return nil, service.ErrAlreadyExists
+			}
+		}
+
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			data = append(data, '\n')
+		}
+		return append(append(data, line...), '\n'), nil
+	})
+}
+
+// Get returns subscriber by email. If there are duplicate records of email, the last one is returned, which
+// is also the one kept by Compact.
+func (s *emailStorage) Get(ctx context.Context, email string) (*entity.Subscriber, error) {
+	subscribers, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(subscribers) - 1; i >= 0; i-- {
+		if subscribers[i].Email == email {
+			return subscribers[i], nil
+		}
 	}
 
-	emails := strings.Split(string(data), "\n")
+	return nil, nil
+}
+
+func (s *emailStorage) Update(ctx context.Context, subscriber *entity.Subscriber) error {
+	_, err := s.replace(ctx, subscriber, nil)
+	return err
+}
 
-	// Filter out any empty strings that may occur due to trailing new lines
-	filteredEmails := emails[:0]
-	for _, email := range emails {
-		if email != "" {
-			filteredEmails = append(filteredEmails, email)
+// replace is Update that reports versions of file like save.
+func (s *emailStorage) replace(ctx context.Context, subscriber *entity.Subscriber, before func(info os.FileInfo)) (os.FileInfo, error) {
+	return s.update(ctx, before, func(data []byte) ([]byte, error) {
+		subscribers, err := parseSubscribers(data)
+		if err != nil {
+			return nil, err
 		}
+
+		for i := range subscribers {
+			if subscribers[i].Email == subscriber.Email {
+				subscribers[i] = subscriber
+			}
+		}
+
+		return formatSubscribers(subscribers)
+	})
+}
+
+func (s *emailStorage) List(ctx context.Context) ([]*entity.Subscriber, error) {
+	data, err := s.db.Read(ctx, s.filename)
+	if err != nil {
+		return nil, err
 	}
 
-	return filteredEmails, nil
+	return parseSubscribers(data)
 }
 
 func (s *emailStorage) Exist(ctx context.Context, email string) (bool, error) {
-	emails, err := s.List(ctx)
+	subscriber, err := s.Get(ctx, email)
 	if err != nil {
 		return false, err
 	}
 
-	if len(emails) == 0 {
-		return false, nil
+	return subscriber != nil, nil
+}
+
+func (s *emailStorage) Delete(ctx context.Context, email string) error {
+	_, err := s.delete(ctx, email, nil)
+	return err
+}
+
+// delete is Delete that reports versions of file like save.
+func (s *emailStorage) delete(ctx context.Context, email string, before func(info os.FileInfo)) (os.FileInfo, error) {
+	return s.update(ctx, before, func(data []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		for _, line := range bytes.Split(data, []byte{'\n'}) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			if record, err := parseSubscriberRecord(string(line)); err == nil && record.Email == email {
+				continue
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), nil
+	})
+}
+
+func (s *emailStorage) Search(ctx context.Context, filter *service.SubscriberFilter) ([]*entity.Subscriber, int, error) {
+	subscribers, err := s.List(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, total := filter.Page(subscribers)
+	return page, total, nil
+}
+
+func (s *emailStorage) SaveMany(ctx context.Context, subscribers []*entity.Subscriber) ([]string, error) {
+	existing, _, err := s.saveMany(ctx, subscribers, nil)
+	return existing, err
+}
+
+// saveMany is SaveMany that reports versions of file like save.
+func (s *emailStorage) saveMany(ctx context.Context, subscribers []*entity.Subscriber, before func(info os.FileInfo)) ([]string, os.FileInfo, error) {
+	var existing []string
+	info, err := s.update(ctx, before, func(data []byte) ([]byte, error) {
+		existing = nil
+
+		stored, err := parseSubscribers(data)
+		if err != nil {
+			return nil, err
+		}
+		emails := make(map[string]struct{}, len(stored)+len(subscribers))
+		for _, subscriber := range stored {
+			emails[subscriber.Email] = struct{}{}
+		}
+
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			data = append(data, '\n')
+		}
+		for _, subscriber := range subscribers {
+			if _, ok := emails[subscriber.Email]; ok {
+				existing = append(existing, subscriber.Email)
+				continue
+			}
+			emails[subscriber.Email] = struct{}{}
+
+			line, err := json.Marshal(newSubscriberRecord(subscriber))
+			if err != nil {
+				return nil, err
+			}
+			data = append(append(data, line...), '\n')
+		}
+
+		return data, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return existing, info, nil
+}
+
+// update replaces content of file with result of fn. Before, if it's set, is called with info of file while
+// it's locked, and info of file after update is returned.
+func (s *emailStorage) update(ctx context.Context, before func(info os.FileInfo), fn func(data []byte) ([]byte, error)) (os.FileInfo, error) {
+	return s.db.UpdateWithInfo(ctx, s.filename, func(info os.FileInfo, data []byte) ([]byte, error) {
+		if before != nil {
+			before(info)
+		}
+		return fn(data)
+	})
+}
+
+// Compact removes duplicate records of the same email, keeping the last one, and lines that can't
+// be parsed. If emails are normalized, records whose emails become the same are merged too: confirmed
+// one is kept, then the earliest one. Returns number of removed lines.
+func (s *emailStorage) Compact(ctx context.Context) (int, error) {
+	if s.normalize == nil {
+		return s.db.Compact(ctx, s.filename, func(line []byte) (string, bool) {
+			record, err := parseSubscriberRecord(string(line))
+			if err != nil || record.Email == "" {
+				return "", false
+			}
+			return record.Email, true
+		})
 	}
 
-	for _, e := range emails {
-		if e == email {
-			return true, nil
+	removed := 0
+	err := s.db.Update(ctx, s.filename, func(data []byte) ([]byte, error) {
+		removed = 0
+		if len(data) == 0 {
+			return data, nil
+		}
+
+		type entry struct {
+			record subscriberRecord
+			line   []byte
+			// stored is email as it's stored, before normalization
+			stored string
+		}
+		var entries []entry
+		// kept is index of entry of each email in entries
+		kept := make(map[string]int)
+		for _, line := range bytes.Split(bytes.TrimSuffix(data, []byte{'\n'}), []byte{'\n'}) {
+			record, err := parseSubscriberRecord(string(line))
+			if len(bytes.TrimSpace(line)) == 0 || err != nil || record.Email == "" {
+				removed++
+				continue
+			}
+
+			stored := record.Email
+			if email, ok := s.normalize(record.Email); ok && email != record.Email {
+				record.Email = email
+				if line, err = json.Marshal(record); err != nil {
+					return nil, err
+				}
+			}
+
+			i, ok := kept[record.Email]
+			if !ok {
+				kept[record.Email] = len(entries)
+				entries = append(entries, entry{record: record, line: line, stored: stored})
+				continue
+			}
+			removed++
+			if stored == entries[i].stored || preferSubscriberRecord(record, entries[i].record) {
+				entries[i] = entry{record: record, line: line, stored: stored}
+			}
+		}
+
+		var buf bytes.Buffer
+		for _, e := range entries {
+			buf.Write(e.line)
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return removed, nil
+}
+
+// preferSubscriberRecord reports whether record is kept instead of existing record whose email is normalized
+// to the same one. Confirmed record is preferred, then the earliest one, then the last one.
+func preferSubscriberRecord(record, existing subscriberRecord) bool {
+	confirmed := entity.SubscriberStatusConfirmed.String()
+	if (record.Status == confirmed) != (existing.Status == confirmed) {
+		return record.Status == confirmed
+	}
+	return !record.CreatedAt.After(existing.CreatedAt)
+}
+
+func parseSubscribers(data []byte) ([]*entity.Subscriber, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	lines := strings.Split(string(data), "\n")
+
+	subscribers := make([]*entity.Subscriber, 0, len(lines))
+	for _, line := range lines {
+		// Skip any empty strings that may occur due to trailing new lines
+		if line == "" {
+			continue
+		}
+
+		record, err := parseSubscriberRecord(line)
+		if err != nil {
+			return nil, err
+		}
+
+		subscriber, err := record.toEntity()
+		if err != nil {
+			return nil, err
+		}
+		subscribers = append(subscribers, subscriber)
+	}
+
+	return subscribers, nil
+}
+
+func formatSubscribers(subscribers []*entity.Subscriber) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, subscriber := range subscribers {
+		data, err := json.Marshal(newSubscriberRecord(subscriber))
+		if err != nil {
+			return nil, err
 		}
+		buf.Write(data)
+		buf.WriteByte('\n')
 	}
 
-	return false, nil
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,378 @@
+package localstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/core/pkg/database"
+)
+
+// indexedEmailStorage keeps subscribers of emailStorage in memory, so that reads don't parse the
+// whole file. Writes go through to the file first. File is reloaded when it is changed by someone
+// else, e.g. by another process or manual edit.
+type indexedEmailStorage struct {
+	storage *emailStorage
+
+	mu          sync.RWMutex
+	subscribers map[string]*entity.Subscriber
+	// order is emails in order of records in file, so that List returns subscribers in the same order as emailStorage.
+	order  []string
+	loaded bool
+	// version is info of file that subscribers were loaded from or written to, nil if file doesn't exist.
+	version os.FileInfo
+}
+
+func NewIndexedEmailStorage(db *database.FileDB, filename string, opts ...EmailStorageOption) *indexedEmailStorage {
+	return &indexedEmailStorage{
+		storage: NewEmailStorage(db, filename, opts...),
+	}
+}
+
+func (s *indexedEmailStorage) Save(ctx context.Context, subscriber *entity.Subscriber) error {
+	appended, err := s.append(ctx,
+		func() ([]byte, error) {
+			if _, ok := s.subscribers[subscriber.Email]; ok {
+				return nil, service.ErrAlreadyExists
+			}
+			return json.Marshal(newSubscriberRecord(subscriber))
+		},
+		func() {
+			s.add(subscriber)
+		},
+	)
+	if err != nil || appended {
+		return err
+	}
+
+	return s.write(
+		func(before func(info os.FileInfo)) (os.FileInfo, error) {
+			return s.storage.save(ctx, subscriber, before)
+		},
+		func() {
+			s.add(subscriber)
+		},
+	)
+}
+
+func (s *indexedEmailStorage) Get(ctx context.Context, email string) (*entity.Subscriber, error) {
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subscriber, ok := s.subscribers[email]
+	if !ok {
+		return nil, nil
+	}
+	return cloneSubscriber(subscriber), nil
+}
+
+func (s *indexedEmailStorage) Update(ctx context.Context, subscriber *entity.Subscriber) error {
+	return s.write(
+		func(before func(info os.FileInfo)) (os.FileInfo, error) {
+			return s.storage.replace(ctx, subscriber, before)
+		},
+		func() {
+			if _, ok := s.subscribers[subscriber.Email]; ok {
+				s.subscribers[subscriber.Email] = cloneSubscriber(subscriber)
+			}
+		},
+	)
+}
+
+func (s *indexedEmailStorage) List(ctx context.Context) ([]*entity.Subscriber, error) {
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subscribers := make([]*entity.Subscriber, 0, len(s.order))
+	for _, email := range s.order {
+		subscribers = append(subscribers, cloneSubscriber(s.subscribers[email]))
+	}
+
+	return subscribers, nil
+}
+
+func (s *indexedEmailStorage) Exist(ctx context.Context, email string) (bool, error) {
+	if err := s.refresh(ctx); err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.subscribers[email]
+	return ok, nil
+}
+
+func (s *indexedEmailStorage) Delete(ctx context.Context, email string) error {
+	return s.write(
+		func(before func(info os.FileInfo)) (os.FileInfo, error) {
+			return s.storage.delete(ctx, email, before)
+		},
+		func() {
+			if _, ok := s.subscribers[email]; !ok {
+				return
+			}
+			delete(s.subscribers, email)
+			for i := range s.order {
+				if s.order[i] == email {
+					s.order = append(s.order[:i], s.order[i+1:]...)
+					break
+				}
+			}
+		},
+	)
+}
+
+func (s *indexedEmailStorage) Search(ctx context.Context, filter *service.SubscriberFilter) ([]*entity.Subscriber, int, error) {
+	if err := s.refresh(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// subscribers are matched in order of file without copying all of them
+	var page []*entity.Subscriber
+	total := 0
+	for _, email := range s.order {
+		subscriber := s.subscribers[email]
+		if !filter.Match(subscriber) {
+			continue
+		}
+		if total >= filter.Offset && len(page) < filter.Limit {
+			page = append(page, cloneSubscriber(subscriber))
+		}
+		total++
+	}
+
+	return page, total, nil
+}
+
+func (s *indexedEmailStorage) SaveMany(ctx context.Context, subscribers []*entity.Subscriber) ([]string, error) {
+	var existing []string
+	apply := func() {
+		for _, subscriber := range subscribers {
+			if _, ok := s.subscribers[subscriber.Email]; !ok {
+				s.add(subscriber)
+			}
+		}
+	}
+
+	appended, err := s.append(ctx,
+		func() ([]byte, error) {
+			existing = nil
+
+			var lines [][]byte
+			added := make(map[string]struct{}, len(subscribers))
+			for _, subscriber := range subscribers {
+				_, stored := s.subscribers[subscriber.Email]
+				_, duplicate := added[subscriber.Email]
+				if stored || duplicate {
+					existing = append(existing, subscriber.Email)
+					continue
+				}
+				added[subscriber.Email] = struct{}{}
+
+				line, err := json.Marshal(newSubscriberRecord(subscriber))
+				if err != nil {
+					return nil, err
+				}
+				lines = append(lines, line)
+			}
+			return bytes.Join(lines, []byte{'\n'}), nil
+		},
+		apply,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if appended {
+		return existing, nil
+	}
+
+	err = s.write(
+		func(before func(info os.FileInfo)) (os.FileInfo, error) {
+			var (
+				info os.FileInfo
+				err  error
+			)
+			existing, info, err = s.storage.saveMany(ctx, subscribers, before)
+			return info, err
+		},
+		apply,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+// Compact compacts file and makes subscribers to be reloaded. Returns number of removed lines.
+func (s *indexedEmailStorage) Compact(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.loaded = false
+	return s.storage.Compact(ctx)
+}
+
+// refresh loads subscribers from file if they weren't loaded yet or file has changed since then.
+func (s *indexedEmailStorage) refresh(ctx context.Context) error {
+	info, err := s.stat(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	fresh := s.loaded && sameFileVersion(s.version, info)
+	s.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// file is stat before it's read, so that change made in between leads to another reload
+	// instead of being missed
+	info, err = s.stat(ctx)
+	if err != nil {
+		return err
+	}
+	if s.loaded && sameFileVersion(s.version, info) {
+		return nil
+	}
+
+	subscribers, err := s.storage.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the last of duplicate records is kept, like by emailStorage.Get and Compact
+	s.subscribers = make(map[string]*entity.Subscriber, len(subscribers))
+	s.order = make([]string, 0, len(subscribers))
+	for _, subscriber := range subscribers {
+		if _, ok := s.subscribers[subscriber.Email]; !ok {
+			s.order = append(s.order, subscriber.Email)
+		}
+		s.subscribers[subscriber.Email] = subscriber
+	}
+	s.version = info
+	s.loaded = true
+
+	return nil
+}
+
+// errFileChanged reports that file was changed since subscribers were loaded.
+var errFileChanged = errors.New("file was changed")
+
+// append appends lines returned by prepare to file without reading it, prepare checks duplicates against
+// subscribers in memory. Then apply makes the same change in memory. Reports false without changing file
+// if subscribers aren't fresh, so that caller has to write through emailStorage, which reads file instead.
+func (s *indexedEmailStorage) append(ctx context.Context, prepare func() ([]byte, error), apply func()) (bool, error) {
+	if err := s.refresh(ctx); err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before, err := s.stat(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !s.loaded || !sameFileVersion(s.version, before) {
+		return false, nil
+	}
+
+	data, err := prepare()
+	if err != nil {
+		return false, err
+	}
+	if len(data) == 0 {
+		return true, nil
+	}
+
+	after, err := s.storage.db.AppendIf(ctx, s.storage.filename, data, func(info os.FileInfo) error {
+		// another process may change file before it's locked
+		if !sameFileVersion(s.version, info) {
+			return errFileChanged
+		}
+		return nil
+	})
+	if errors.Is(err, errFileChanged) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	apply()
+	s.version = after
+	return true, nil
+}
+
+// add adds subscriber to the end of subscribers in memory.
+func (s *indexedEmailStorage) add(subscriber *entity.Subscriber) {
+	s.subscribers[subscriber.Email] = cloneSubscriber(subscriber)
+	s.order = append(s.order, subscriber.Email)
+}
+
+// write calls write, which changes file and returns its info after change, and then apply, which makes
+// the same change in memory. Write calls before with info of file before change. Both infos are taken
+// while file is locked, so if file was changed by someone else before the write, it's detected and
+// subscribers are reloaded on next read instead.
+func (s *indexedEmailStorage) write(write func(before func(info os.FileInfo)) (os.FileInfo, error), apply func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fresh := s.loaded
+	after, err := write(func(info os.FileInfo) {
+		fresh = fresh && sameFileVersion(s.version, info)
+	})
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		s.loaded = false
+		return nil
+	}
+
+	apply()
+	s.version = after
+	return nil
+}
+
+func (s *indexedEmailStorage) stat(ctx context.Context) (os.FileInfo, error) {
+	return s.storage.db.Stat(ctx, s.storage.filename)
+}
+
+// sameFileVersion checks if file wasn't changed between two stats. Rewrites replace file, so they
+// are detected even if modification time and size are the same.
+func sameFileVersion(a, b os.FileInfo) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return os.SameFile(a, b) && a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size()
+}
+
+// cloneSubscriber copies subscriber, so that callers can't change subscribers in memory.
+func cloneSubscriber(subscriber *entity.Subscriber) *entity.Subscriber {
+	clone := *subscriber
+	clone.Pairs = append([]entity.CurrencyPair(nil), subscriber.Pairs...)
+	return &clone
+}
@@ -0,0 +1,303 @@
+package localstorage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/core/pkg/database"
+)
+
+func newTestSubscriber(email string, createdAt time.Time) *entity.Subscriber {
+	return &entity.Subscriber{
+		Email:     email,
+		Status:    entity.SubscriberStatusConfirmed,
+		CreatedAt: createdAt,
+		Pairs:     []entity.CurrencyPair{entity.DefaultCurrencyPair},
+		Locale:    entity.DefaultLocale,
+	}
+}
+
+func TestIndexedEmailStorage_WriteThrough(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := database.NewFileDB(t.TempDir(), database.SyncWrites(false))
+	indexed := NewIndexedEmailStorage(db, "emails.txt")
+	file := NewEmailStorage(db, "emails.txt")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, indexed.Save(ctx, newTestSubscriber("a@test.com", now)))
+	require.NoError(t, indexed.Save(ctx, newTestSubscriber("b@test.com", now.Add(time.Second))))
+	assert.ErrorIs(t, indexed.Save(ctx, newTestSubscriber("a@test.com", now)), service.ErrAlreadyExists)
+
+	updated := newTestSubscriber("a@test.com", now)
+	updated.Status = entity.SubscriberStatusPending
+	require.NoError(t, indexed.Update(ctx, updated))
+	require.NoError(t, indexed.Delete(ctx, "b@test.com"))
+
+	// file has the same content as memory
+	fromFile, err := file.List(ctx)
+	require.NoError(t, err)
+	fromMemory, err := indexed.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, fromFile, fromMemory)
+	require.Len(t, fromMemory, 1)
+	assert.Equal(t, entity.SubscriberStatusPending, fromMemory[0].Status)
+}
+
+func TestIndexedEmailStorage_ReloadsOnExternalChange(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := database.NewFileDB(t.TempDir(), database.SyncWrites(false))
+	indexed := NewIndexedEmailStorage(db, "emails.txt")
+	// another process sharing the same file
+	external := NewEmailStorage(db, "emails.txt")
+
+	exists, err := indexed.Exist(ctx, "a@test.com")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	require.NoError(t, external.Save(ctx, newTestSubscriber("a@test.com", time.Now())))
+
+	exists, err = indexed.Exist(ctx, "a@test.com")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	require.NoError(t, external.Delete(ctx, "a@test.com"))
+
+	subscriber, err := indexed.Get(ctx, "a@test.com")
+	require.NoError(t, err)
+	assert.Nil(t, subscriber)
+}
+
+func TestIndexedEmailStorage_ExternalChangeDuringWrite(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := database.NewFileDB(t.TempDir(), database.SyncWrites(false))
+	indexed := NewIndexedEmailStorage(db, "emails.txt")
+	external := NewEmailStorage(db, "emails.txt")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, indexed.Save(ctx, newTestSubscriber("a@test.com", now)))
+
+	// another process writes just before file is locked by update, its change is merged into file
+	updated := newTestSubscriber("a@test.com", now)
+	updated.Status = entity.SubscriberStatusPending
+	err := indexed.write(
+		func(before func(info os.FileInfo)) (os.FileInfo, error) {
+			require.NoError(t, external.Save(ctx, newTestSubscriber("b@test.com", now)))
+			return indexed.storage.replace(ctx, updated, before)
+		},
+		func() {
+			indexed.subscribers[updated.Email] = cloneSubscriber(updated)
+		},
+	)
+	require.NoError(t, err)
+
+	// and it isn't hidden by memory that has only our change
+	subscriber, err := indexed.Get(ctx, "b@test.com")
+	require.NoError(t, err)
+	assert.NotNil(t, subscriber)
+	subscriber, err = indexed.Get(ctx, "a@test.com")
+	require.NoError(t, err)
+	assert.Equal(t, entity.SubscriberStatusPending, subscriber.Status)
+}
+
+func TestIndexedEmailStorage_Duplicates(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := database.NewFileDB(t.TempDir(), database.SyncWrites(false))
+	indexed := NewIndexedEmailStorage(db, "emails.txt")
+	file := NewEmailStorage(db, "emails.txt")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	first := newTestSubscriber("a@test.com", now)
+	last := newTestSubscriber("a@test.com", now.Add(time.Second))
+	last.Status = entity.SubscriberStatusPending
+	data, err := formatSubscribers([]*entity.Subscriber{first, last})
+	require.NoError(t, err)
+	require.NoError(t, db.Rewrite(ctx, "emails.txt", data))
+
+	// both storages return the last record, which is also kept by Compact
+	fromFile, err := file.Get(ctx, "a@test.com")
+	require.NoError(t, err)
+	fromMemory, err := indexed.Get(ctx, "a@test.com")
+	require.NoError(t, err)
+	assert.Equal(t, last, fromFile)
+	assert.Equal(t, last, fromMemory)
+
+	_, err = indexed.Compact(ctx)
+	require.NoError(t, err)
+	fromMemory, err = indexed.Get(ctx, "a@test.com")
+	require.NoError(t, err)
+	assert.Equal(t, last, fromMemory)
+}
+
+func TestIndexedEmailStorage_SaveAppends(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := database.NewFileDB(t.TempDir(), database.SyncWrites(false))
+	indexed := NewIndexedEmailStorage(db, "emails.txt")
+	external := NewEmailStorage(db, "emails.txt")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, indexed.Save(ctx, newTestSubscriber("a@test.com", now)))
+	before, err := db.Stat(ctx, "emails.txt")
+	require.NoError(t, err)
+
+	// fresh index is enough to check duplicates, so file is appended instead of being rewritten
+	require.NoError(t, indexed.Save(ctx, newTestSubscriber("b@test.com", now)))
+	existing, err := indexed.SaveMany(ctx, []*entity.Subscriber{
+		newTestSubscriber("a@test.com", now),
+		newTestSubscriber("c@test.com", now),
+		newTestSubscriber("c@test.com", now),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a@test.com", "c@test.com"}, existing)
+	assert.ErrorIs(t, indexed.Save(ctx, newTestSubscriber("b@test.com", now)), service.ErrAlreadyExists)
+
+	after, err := db.Stat(ctx, "emails.txt")
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(before, after), "file was rewritten")
+
+	// external change makes duplicates to be checked against file
+	require.NoError(t, external.Save(ctx, newTestSubscriber("d@test.com", now)))
+	assert.ErrorIs(t, indexed.Save(ctx, newTestSubscriber("d@test.com", now)), service.ErrAlreadyExists)
+	require.NoError(t, indexed.Save(ctx, newTestSubscriber("e@test.com", now)))
+
+	fromFile, err := external.List(ctx)
+	require.NoError(t, err)
+	fromMemory, err := indexed.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, fromFile, fromMemory)
+	emails := make([]string, 0, len(fromFile))
+	for _, subscriber := range fromFile {
+		emails = append(emails, subscriber.Email)
+	}
+	assert.Equal(t, []string{"a@test.com", "b@test.com", "c@test.com", "d@test.com", "e@test.com"}, emails)
+}
+
+func TestIndexedEmailStorage_ReturnsCopies(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := database.NewFileDB(t.TempDir(), database.SyncWrites(false))
+	indexed := NewIndexedEmailStorage(db, "emails.txt")
+
+	require.NoError(t, indexed.Save(ctx, newTestSubscriber("a@test.com", time.Now())))
+
+	subscriber, err := indexed.Get(ctx, "a@test.com")
+	require.NoError(t, err)
+	subscriber.Status = entity.SubscriberStatusPending
+	subscriber.Pairs[0] = entity.CurrencyPair{Crypto: entity.CryptoCurrencyETH, Fiat: entity.FiatCurrencyUAH}
+
+	subscriber, err = indexed.Get(ctx, "a@test.com")
+	require.NoError(t, err)
+	assert.Equal(t, entity.SubscriberStatusConfirmed, subscriber.Status)
+	assert.Equal(t, entity.DefaultCurrencyPair, subscriber.Pairs[0])
+}
+
+// newBenchmarkEmailDB returns file database with emails.txt of n confirmed subscribers.
+func newBenchmarkEmailDB(b *testing.B, n int) *database.FileDB {
+	db := database.NewFileDB(b.TempDir(), database.SyncWrites(false))
+
+	subscribers := make([]*entity.Subscriber, 0, n)
+	now := time.Now()
+	for i := 0; i < n; i++ {
+		subscribers = append(subscribers, newTestSubscriber(fmt.Sprintf("email-%d@test.com", i), now))
+	}
+	data, err := formatSubscribers(subscribers)
+	require.NoError(b, err)
+	require.NoError(b, db.Rewrite(context.Background(), "emails.txt", data))
+
+	return db
+}
+
+const benchmarkSubscribers = 100_000
+
+func benchmarkExist(b *testing.B, storage service.EmailStorage) {
+	ctx := context.Background()
+
+	// warm up, so that initial load isn't measured
+	_, err := storage.Exist(ctx, "missing@test.com")
+	require.NoError(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		exists, err := storage.Exist(ctx, fmt.Sprintf("email-%d@test.com", i%benchmarkSubscribers))
+		if err != nil || !exists {
+			b.Fatal("subscriber not found", err)
+		}
+	}
+}
+
+func BenchmarkEmailStorage_Exist_100k(b *testing.B) {
+	db := newBenchmarkEmailDB(b, benchmarkSubscribers)
+	benchmarkExist(b, NewEmailStorage(db, "emails.txt"))
+}
+
+func BenchmarkIndexedEmailStorage_Exist_100k(b *testing.B) {
+	db := newBenchmarkEmailDB(b, benchmarkSubscribers)
+	benchmarkExist(b, NewIndexedEmailStorage(db, "emails.txt"))
+}
+
+func BenchmarkEmailStorage_List_100k(b *testing.B) {
+	db := newBenchmarkEmailDB(b, benchmarkSubscribers)
+	storage := NewEmailStorage(db, "emails.txt")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := storage.List(context.Background()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIndexedEmailStorage_List_100k(b *testing.B) {
+	db := newBenchmarkEmailDB(b, benchmarkSubscribers)
+	storage := NewIndexedEmailStorage(db, "emails.txt")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := storage.List(context.Background()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkSave(b *testing.B, storage service.EmailStorage) {
+	ctx := context.Background()
+
+	// warm up, so that initial load isn't measured
+	_, err := storage.Exist(ctx, "missing@test.com")
+	require.NoError(b, err)
+
+	now := time.Now()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := storage.Save(ctx, newTestSubscriber(fmt.Sprintf("new-%d@test.com", i), now)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEmailStorage_Save_100k(b *testing.B) {
+	db := newBenchmarkEmailDB(b, benchmarkSubscribers)
+	benchmarkSave(b, NewEmailStorage(db, "emails.txt"))
+}
+
+func BenchmarkIndexedEmailStorage_Save_100k(b *testing.B) {
+	db := newBenchmarkEmailDB(b, benchmarkSubscribers)
+	benchmarkSave(b, NewIndexedEmailStorage(db, "emails.txt"))
+}
@@ -0,0 +1,123 @@
+package localstorage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/core/pkg/database"
+)
+
+func TestEmailStorage_SearchAndSaveMany(t *testing.T) {
+	t.Parallel()
+
+	storages := map[string]func(db *database.FileDB) service.EmailStorage{
+		"file": func(db *database.FileDB) service.EmailStorage {
+			return NewEmailStorage(db, "emails.txt")
+		},
+		"indexed": func(db *database.FileDB) service.EmailStorage {
+			return NewIndexedEmailStorage(db, "emails.txt")
+		},
+	}
+
+	for name, newStorage := range storages {
+		newStorage := newStorage
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			storage := newStorage(database.NewFileDB(t.TempDir(), database.SyncWrites(false)))
+
+			now := time.Now().UTC().Truncate(time.Second)
+			require.NoError(t, storage.Save(ctx, newTestSubscriber("existing@test.com", now)))
+
+			pending := newTestSubscriber("Pending@Test.com", now)
+			pending.Status = entity.SubscriberStatusPending
+			existing, err := storage.SaveMany(ctx, []*entity.Subscriber{
+				newTestSubscriber("a@test.com", now),
+				newTestSubscriber("existing@test.com", now),
+				pending,
+				newTestSubscriber("b@other.com", now),
+				newTestSubscriber("a@test.com", now),
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []string{"existing@test.com", "a@test.com"}, existing)
+
+			all, err := storage.List(ctx)
+			require.NoError(t, err)
+			assert.Len(t, all, 4)
+
+			page, total, err := storage.Search(ctx, &service.SubscriberFilter{Query: "TEST.COM", Offset: 1, Limit: 1})
+			require.NoError(t, err)
+			assert.Equal(t, 3, total)
+			require.Len(t, page, 1)
+			assert.Equal(t, "a@test.com", page[0].Email)
+
+			page, total, err = storage.Search(ctx, &service.SubscriberFilter{
+				Status: entity.SubscriberStatusPending,
+				Limit:  10,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, 1, total)
+			require.Len(t, page, 1)
+			assert.Equal(t, "Pending@Test.com", page[0].Email)
+		})
+	}
+}
+
+func TestEmailStorage_Compact_NormalizeEmails(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := database.NewFileDB(t.TempDir(), database.SyncWrites(false))
+	storage := NewIndexedEmailStorage(db, "emails.txt", NormalizeEmails(func(email string) (string, bool) {
+		if !strings.Contains(email, "@") {
+			return "", false
+		}
+		return strings.ToLower(strings.TrimSpace(email)), true
+	}))
+
+	now := time.Now().UTC().Truncate(time.Second)
+	pending := newTestSubscriber("Pending@Test.com", now.Add(-time.Hour))
+	pending.Status = entity.SubscriberStatusPending
+	data, err := formatSubscribers([]*entity.Subscriber{
+		newTestSubscriber("User@Test.com", now),
+		newTestSubscriber("user@test.com", now.Add(-time.Hour)),
+		pending,
+		newTestSubscriber("pending@test.com", now),
+		newTestSubscriber("invalid", now),
+	})
+	require.NoError(t, err)
+	// plain emails were stored before double opt-in was introduced
+	data = append([]byte(" Legacy@Test.com\n"), data...)
+	require.NoError(t, db.Rewrite(ctx, "emails.txt", data))
+
+	removed, err := storage.Compact(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	subscribers, err := storage.List(ctx)
+	require.NoError(t, err)
+	got := make(map[string]*entity.Subscriber, len(subscribers))
+	emails := make([]string, 0, len(subscribers))
+	for _, subscriber := range subscribers {
+		got[subscriber.Email] = subscriber
+		emails = append(emails, subscriber.Email)
+	}
+	assert.Equal(t, []string{"legacy@test.com", "user@test.com", "pending@test.com", "invalid"}, emails)
+	// the earliest subscriber is kept
+	assert.Equal(t, now.Add(-time.Hour), got["user@test.com"].CreatedAt)
+	// confirmed subscriber is kept even if it's later
+	assert.Equal(t, entity.SubscriberStatusConfirmed, got["pending@test.com"].Status)
+	assert.Equal(t, entity.SubscriberStatusConfirmed, got["legacy@test.com"].Status)
+
+	// compaction is idempotent
+	removed, err = storage.Compact(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+}
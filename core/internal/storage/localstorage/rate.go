@@ -0,0 +1,117 @@
+package localstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/pkg/database"
+)
+
+type rateStorage struct {
+	db       *database.FileDB
+	filename string
+}
+
+func NewRateStorage(db *database.FileDB, filename string) *rateStorage {
+	return &rateStorage{
+		db:       db,
+		filename: filename,
+	}
+}
+
+// rateRecord is a representation of rate in file. Each record is stored as JSON on separate line.
+type rateRecord struct {
+	Pair      string    `json:"pair"`
+	Value     float64   `json:"value"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+func (s *rateStorage) Save(ctx context.Context, rate *entity.Rate) error {
+	return s.db.Update(ctx, s.filename, func(data []byte) ([]byte, error) {
+		rates, err := parseRates(data)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		for _, r := range append(rates, rate) {
+			if r.Pair == rate.Pair && r != rate {
+				continue
+			}
+
+			data, err := json.Marshal(rateRecord{
+				Pair:      r.Pair.String(),
+				Value:     r.Value,
+				FetchedAt: r.FetchedAt,
+			})
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(data)
+			buf.WriteByte('\n')
+		}
+
+		return buf.Bytes(), nil
+	})
+}
+
+func (s *rateStorage) List(ctx context.Context) ([]*entity.Rate, error) {
+	data, err := s.db.Read(ctx, s.filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRates(data)
+}
+
+// Compact removes duplicate records of the same pair, keeping the last one, and lines that can't
+// be parsed. Returns number of removed lines.
+func (s *rateStorage) Compact(ctx context.Context) (int, error) {
+	return s.db.Compact(ctx, s.filename, func(line []byte) (string, bool) {
+		var record rateRecord
+		if err := json.Unmarshal(line, &record); err != nil || record.Pair == "" {
+			return "", false
+		}
+		return record.Pair, true
+	})
+}
+
+func parseRates(data []byte) ([]*entity.Rate, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	lines := strings.Split(string(data), "\n")
+
+	rates := make([]*entity.Rate, 0, len(lines))
+	for _, line := range lines {
+		// Skip any empty strings that may occur due to trailing new lines
+		if line == "" {
+			continue
+		}
+
+		var record rateRecord
+		err := json.Unmarshal([]byte(line), &record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse rate record: %w", err)
+		}
+
+		pair, err := entity.ParseCurrencyPair(record.Pair)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse rate record: %w", err)
+		}
+
+		rates = append(rates, &entity.Rate{
+			Pair:      pair,
+			Value:     record.Value,
+			FetchedAt: record.FetchedAt,
+		})
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,38 @@
+package localstorage
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/pkg/database"
+)
+
+type schedulerStorage struct {
+	db       *database.FileDB
+	filename string
+}
+
+func NewSchedulerStorage(db *database.FileDB, filename string) *schedulerStorage {
+	return &schedulerStorage{
+		db:       db,
+		filename: filename,
+	}
+}
+
+func (s *schedulerStorage) GetLastRun(ctx context.Context) (time.Time, error) {
+	data, err := s.db.Read(ctx, s.filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if len(data) == 0 {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+}
+
+func (s *schedulerStorage) SaveLastRun(ctx context.Context, t time.Time) error {
+	return s.db.Rewrite(ctx, s.filename, []byte(t.Format(time.RFC3339)+"\n"))
+}
@@ -0,0 +1,199 @@
+package localstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/pkg/database"
+)
+
+type sendJobStorage struct {
+	db       *database.FileDB
+	filename string
+}
+
+func NewSendJobStorage(db *database.FileDB, filename string) *sendJobStorage {
+	return &sendJobStorage{
+		db:       db,
+		filename: filename,
+	}
+}
+
+// sendJobRecord is a representation of send job in file. Each record is stored as JSON on separate line.
+type sendJobRecord struct {
+	ID           string    `json:"id"`
+	State        string    `json:"state"`
+	Total        int       `json:"total"`
+	Sent         int       `json:"sent"`
+	Failed       int       `json:"failed"`
+	Cursor       string    `json:"cursor,omitempty"`
+	Processed    []string  `json:"processed,omitempty"`
+	FailedEmails []string  `json:"failed_emails,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func newSendJobRecord(job *entity.SendJob) sendJobRecord {
+	return sendJobRecord{
+		ID:           job.ID,
+		State:        job.State.String(),
+		Total:        job.Total,
+		Sent:         job.Sent,
+		Failed:       job.Failed,
+		Cursor:       job.Cursor,
+		Processed:    job.Processed,
+		FailedEmails: job.FailedEmails,
+		Error:        job.Error,
+		CreatedAt:    job.CreatedAt,
+		UpdatedAt:    job.UpdatedAt,
+	}
+}
+
+func (r sendJobRecord) toEntity() *entity.SendJob {
+	return &entity.SendJob{
+		ID:           r.ID,
+		State:        entity.SendJobState(r.State),
+		Total:        r.Total,
+		Sent:         r.Sent,
+		Failed:       r.Failed,
+		Cursor:       r.Cursor,
+		Processed:    r.Processed,
+		FailedEmails: r.FailedEmails,
+		Error:        r.Error,
+		CreatedAt:    r.CreatedAt,
+		UpdatedAt:    r.UpdatedAt,
+	}
+}
+
+func (s *sendJobStorage) Save(ctx context.Context, job *entity.SendJob) error {
+	data, err := json.Marshal(newSendJobRecord(job))
+	if err != nil {
+		return err
+	}
+
+	return s.db.Append(ctx, s.filename, data)
+}
+
+func (s *sendJobStorage) Get(ctx context.Context, id string) (*entity.SendJob, error) {
+	jobs, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, job := range jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *sendJobStorage) Update(ctx context.Context, job *entity.SendJob) error {
+	return s.db.Update(ctx, s.filename, func(data []byte) ([]byte, error) {
+		jobs, err := parseSendJobs(data)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range jobs {
+			if jobs[i].ID == job.ID {
+				jobs[i] = job
+			}
+		}
+
+		return formatSendJobs(jobs)
+	})
+}
+
+func (s *sendJobStorage) DeleteFinished(ctx context.Context, before time.Time) (int, error) {
+	var deleted int
+	err := s.db.Update(ctx, s.filename, func(data []byte) ([]byte, error) {
+		jobs, err := parseSendJobs(data)
+		if err != nil {
+			return nil, err
+		}
+
+		kept := jobs[:0]
+		for _, job := range jobs {
+			if job.State.IsFinished() && job.UpdatedAt.Before(before) {
+				deleted++
+				continue
+			}
+			kept = append(kept, job)
+		}
+
+		return formatSendJobs(kept)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+func (s *sendJobStorage) List(ctx context.Context) ([]*entity.SendJob, error) {
+	data, err := s.db.Read(ctx, s.filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSendJobs(data)
+}
+
+// Compact removes duplicate records of the same job, keeping the last one, and lines that can't
+// be parsed. Returns number of removed lines.
+func (s *sendJobStorage) Compact(ctx context.Context) (int, error) {
+	return s.db.Compact(ctx, s.filename, func(line []byte) (string, bool) {
+		var record sendJobRecord
+		if err := json.Unmarshal(line, &record); err != nil || record.ID == "" {
+			return "", false
+		}
+		return record.ID, true
+	})
+}
+
+func parseSendJobs(data []byte) ([]*entity.SendJob, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	lines := strings.Split(string(data), "\n")
+
+	jobs := make([]*entity.SendJob, 0, len(lines))
+	for _, line := range lines {
+		// Skip any empty strings that may occur due to trailing new lines
+		if line == "" {
+			continue
+		}
+
+		var record sendJobRecord
+		err := json.Unmarshal([]byte(line), &record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse send job record: %w", err)
+		}
+		jobs = append(jobs, record.toEntity())
+	}
+
+	return jobs, nil
+}
+
+func formatSendJobs(jobs []*entity.SendJob) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, job := range jobs {
+		data, err := json.Marshal(newSendJobRecord(job))
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,41 @@
+package localstorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/pkg/database"
+)
+
+func TestSendJobStorage_DeleteFinished(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storage := NewSendJobStorage(database.NewFileDB(t.TempDir(), database.SyncWrites(false)), "send_jobs.txt")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	old := now.Add(-48 * time.Hour)
+	for _, job := range []*entity.SendJob{
+		{ID: "old-completed", State: entity.SendJobStateCompleted, UpdatedAt: old},
+		{ID: "old-failed", State: entity.SendJobStateFailed, UpdatedAt: old},
+		{ID: "old-running", State: entity.SendJobStateRunning, UpdatedAt: old, Cursor: "a@test.com"},
+		{ID: "new-completed", State: entity.SendJobStateCompleted, UpdatedAt: now},
+	} {
+		require.NoError(t, storage.Save(ctx, job))
+	}
+
+	deleted, err := storage.DeleteFinished(ctx, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	jobs, err := storage.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "old-running", jobs[0].ID)
+	assert.Equal(t, "a@test.com", jobs[0].Cursor)
+	assert.Equal(t, "new-completed", jobs[1].ID)
+}
@@ -0,0 +1,335 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/core/pkg/database"
+)
+
+type emailStorage struct {
+	db *database.SQLDB
+}
+
+// NewEmailStorage returns storage of subscribers in "subscribers" table, Migrate has to be
+// called before using it.
+func NewEmailStorage(db *database.SQLDB) *emailStorage {
+	return &emailStorage{
+		db: db,
+	}
+}
+
+const subscriberColumns = "email, status, created_at, pairs, locale"
+
+func (s *emailStorage) Save(ctx context.Context, subscriber *entity.Subscriber) error {
+	_, err := s.db.ExecContext(ctx,
+		s.db.Rebind("INSERT INTO subscribers ("+subscriberColumns+") VALUES (?, ?, ?, ?, ?)"),
+		subscriber.Email, subscriber.Status.String(), subscriber.CreatedAt.UTC(),
+		formatPairs(subscriber.Pairs), subscriber.Locale.String(),
+	)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return fmt.Errorf("%w: %v", service.ErrAlreadyExists, err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *emailStorage) Get(ctx context.Context, email string) (*entity.Subscriber, error) {
+	row := s.db.QueryRowContext(ctx,
+		s.db.Rebind("SELECT "+subscriberColumns+" FROM subscribers WHERE email = ?"), email)
+
+	subscriber, err := scanSubscriber(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return subscriber, err
+}
+
+func (s *emailStorage) Update(ctx context.Context, subscriber *entity.Subscriber) error {
+	_, err := s.db.ExecContext(ctx,
+		s.db.Rebind("UPDATE subscribers SET status = ?, created_at = ?, pairs = ?, locale = ? WHERE email = ?"),
+		subscriber.Status.String(), subscriber.CreatedAt.UTC(), formatPairs(subscriber.Pairs),
+		subscriber.Locale.String(), subscriber.Email,
+	)
+	return err
+}
+
+func (s *emailStorage) List(ctx context.Context) ([]*entity.Subscriber, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+subscriberColumns+" FROM subscribers ORDER BY created_at, email")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscribers []*entity.Subscriber
+	for rows.Next() {
+		subscriber, err := scanSubscriber(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscribers = append(subscribers, subscriber)
+	}
+
+	return subscribers, rows.Err()
+}
+
+func (s *emailStorage) Exist(ctx context.Context, email string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx,
+		s.db.Rebind("SELECT EXISTS (SELECT 1 FROM subscribers WHERE email = ?)"), email).Scan(&exists)
+	return exists, err
+}
+
+func (s *emailStorage) Delete(ctx context.Context, email string) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind("DELETE FROM subscribers WHERE email = ?"), email)
+	return err
+}
+
+func (s *emailStorage) Search(ctx context.Context, filter *service.SubscriberFilter) ([]*entity.Subscriber, int, error) {
+	where, args := subscriberFilterCondition(filter)
+
+	var total int
+	err := s.db.QueryRowContext(ctx, s.db.Rebind("SELECT COUNT(*) FROM subscribers"+where), args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		s.db.Rebind("SELECT "+subscriberColumns+" FROM subscribers"+where+" ORDER BY created_at, email LIMIT ? OFFSET ?"),
+		append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var subscribers []*entity.Subscriber
+	for rows.Next() {
+		subscriber, err := scanSubscriber(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		subscribers = append(subscribers, subscriber)
+	}
+
+	return subscribers, total, rows.Err()
+}
+
+// subscriberFilterCondition returns WHERE clause matching Query and Status of filter and its arguments.
+func subscriberFilterCondition(filter *service.SubscriberFilter) (string, []interface{}) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	if filter.Query != "" {
+		// query is matched literally, so wildcards in it are escaped
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(strings.ToLower(filter.Query))
+		conditions = append(conditions, `LOWER(email) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escaped+"%")
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status.String())
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (s *emailStorage) SaveMany(ctx context.Context, subscribers []*entity.Subscriber) ([]string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// conflicting rows are skipped instead of failing transaction, so existing emails are reported
+	// by number of inserted rows
+	insert, err := tx.PrepareContext(ctx, s.db.Rebind(
+		"INSERT INTO subscribers ("+subscriberColumns+") VALUES (?, ?, ?, ?, ?) ON CONFLICT (email) DO NOTHING"))
+	if err != nil {
+		return nil, err
+	}
+	defer insert.Close()
+
+	var existing []string
+	for _, subscriber := range subscribers {
+		res, err := insert.ExecContext(ctx,
+			subscriber.Email, subscriber.Status.String(), subscriber.CreatedAt.UTC(),
+			formatPairs(subscriber.Pairs), subscriber.Locale.String(),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if inserted == 0 {
+			existing = append(existing, subscriber.Email)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+// NormalizeEmails changes emails of subscribers stored before emails were normalized to results of normalize,
+// which reports false for emails that can't be normalized, they are left as they are. Subscribers whose
+// emails become the same are merged: confirmed one is kept, then the earliest one. Returns number of changed
+// and removed subscribers.
+func (s *emailStorage) NormalizeEmails(ctx context.Context, normalize func(email string) (string, bool)) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rows, err := tx.QueryContext(ctx, "SELECT "+subscriberColumns+" FROM subscribers ORDER BY created_at, email")
+	if err != nil {
+		return 0, err
+	}
+	// kept is subscriber kept for each normalized email
+	kept := make(map[string]*entity.Subscriber)
+	var removed []string
+	for rows.Next() {
+		subscriber, err := scanSubscriber(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		email, ok := normalize(subscriber.Email)
+		if !ok {
+			email = subscriber.Email
+		}
+		existing, ok := kept[email]
+		if !ok {
+			kept[email] = subscriber
+			continue
+		}
+		if preferSubscriber(subscriber, existing) {
+			kept[email], subscriber = subscriber, existing
+		}
+		removed = append(removed, subscriber.Email)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// merged subscribers are removed first, so that kept ones can take their emails
+	for _, email := range removed {
+		if _, err = tx.ExecContext(ctx, s.db.Rebind("DELETE FROM subscribers WHERE email = ?"), email); err != nil {
+			return 0, err
+		}
+	}
+	changed := len(removed)
+	for email, subscriber := range kept {
+		if email == subscriber.Email {
+			continue
+		}
+		_, err = tx.ExecContext(ctx, s.db.Rebind("UPDATE subscribers SET email = ? WHERE email = ?"), email, subscriber.Email)
+		if err != nil {
+			return 0, err
+		}
+		changed++
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return changed, nil
+}
+
+// preferSubscriber reports whether subscriber is kept instead of existing one whose email is normalized to
+// the same one. Confirmed subscriber is preferred, then the earliest one.
+func preferSubscriber(subscriber, existing *entity.Subscriber) bool {
+	confirmed := entity.SubscriberStatusConfirmed
+	if (subscriber.Status == confirmed) != (existing.Status == confirmed) {
+		return subscriber.Status == confirmed
+	}
+	return subscriber.CreatedAt.Before(existing.CreatedAt)
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSubscriber reads row selected with subscriberColumns. Rows without pairs or locale get
+// entity.DefaultCurrencyPair and entity.DefaultLocale.
+func scanSubscriber(row scanner) (*entity.Subscriber, error) {
+	var (
+		email, status, pairs, locale string
+		createdAt                    time.Time
+	)
+	err := row.Scan(&email, &status, &createdAt, &pairs, &locale)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriber := &entity.Subscriber{
+		Email:     email,
+		Status:    entity.SubscriberStatus(status),
+		CreatedAt: createdAt,
+		Pairs:     []entity.CurrencyPair{entity.DefaultCurrencyPair},
+		Locale:    entity.DefaultLocale,
+	}
+
+	if pairs != "" {
+		subscriber.Pairs, err = parsePairs(pairs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse subscriber row: %w", err)
+		}
+	}
+
+	if locale != "" {
+		subscriber.Locale, err = entity.ParseLocale(locale)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse subscriber row: %w", err)
+		}
+	}
+
+	return subscriber, nil
+}
+
+// formatPairs joins pairs to comma separated list, e.g. "BTC-USD,ETH-UAH".
+func formatPairs(pairs []entity.CurrencyPair) string {
+	formatted := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		formatted = append(formatted, pair.String())
+	}
+	return strings.Join(formatted, ",")
+}
+
+func parsePairs(s string) ([]entity.CurrencyPair, error) {
+	parts := strings.Split(s, ",")
+	pairs := make([]entity.CurrencyPair, 0, len(parts))
+	for _, part := range parts {
+		pair, err := entity.ParseCurrencyPair(part)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
@@ -0,0 +1,82 @@
+//go:build integration
+// +build integration
+
+package sqlstorage
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/pkg/database"
+)
+
+// newTestPostgresDB connects to database from GSES_TEST_POSTGRES_DSN, which has to be empty.
+func newTestPostgresDB(t *testing.T) *database.SQLDB {
+	dsn := os.Getenv("GSES_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("GSES_TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := database.NewSQLDB(database.SQLDriverPostgres, dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = db.Exec("DROP TABLE IF EXISTS subscribers, rate_limits, used_nonces, schema_migrations")
+		_ = db.Close()
+	})
+
+	require.NoError(t, Migrate(context.Background(), db))
+	require.NoError(t, MigrateRateLimits(context.Background(), db))
+	return db
+}
+
+func TestMigrate_Postgres_Concurrent(t *testing.T) {
+	db := newTestPostgresDB(t)
+	_, err := db.Exec("DROP TABLE subscribers, rate_limits, used_nonces, schema_migrations")
+	require.NoError(t, err)
+
+	// replicas starting at the same time migrate the same database
+	const replicas = 5
+	errs := make(chan error, 2*replicas)
+	var wg sync.WaitGroup
+	for i := 0; i < replicas; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- Migrate(context.Background(), db)
+			errs <- MigrateRateLimits(context.Background(), db)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+func TestEmailStorage_Postgres(t *testing.T) {
+	db := newTestPostgresDB(t)
+
+	testEmailStorage(t, db)
+
+	_, err := db.Exec("DELETE FROM subscribers")
+	require.NoError(t, err)
+
+	testEmailStorageConcurrentSave(t, db)
+
+	_, err = db.Exec("DELETE FROM subscribers")
+	require.NoError(t, err)
+
+	testEmailStorageSearchAndSaveMany(t, db)
+}
+
+func TestRateLimitStore_Postgres(t *testing.T) {
+	testRateLimitStore(t, newTestPostgresDB(t))
+}
+
+func TestNonceStore_Postgres(t *testing.T) {
+	testNonceStore(t, newTestPostgresDB(t))
+}
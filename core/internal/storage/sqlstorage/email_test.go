@@ -0,0 +1,318 @@
+package sqlstorage
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/internal/entity"
+	"github.com/vadimpk/gses-2023/core/internal/service"
+	"github.com/vadimpk/gses-2023/core/pkg/database"
+)
+
+func newTestSQLiteDB(t *testing.T) *database.SQLDB {
+	db, err := database.NewSQLDB(database.SQLDriverSQLite, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	// sets of migrations may share database
+	require.NoError(t, Migrate(context.Background(), db))
+	require.NoError(t, MigrateRateLimits(context.Background(), db))
+	return db
+}
+
+func TestEmailStorage_SQLite(t *testing.T) {
+	t.Parallel()
+
+	testEmailStorage(t, newTestSQLiteDB(t))
+}
+
+func TestEmailStorage_SQLite_ConcurrentSave(t *testing.T) {
+	t.Parallel()
+
+	testEmailStorageConcurrentSave(t, newTestSQLiteDB(t))
+}
+
+func TestEmailStorage_SQLite_SearchAndSaveMany(t *testing.T) {
+	t.Parallel()
+
+	testEmailStorageSearchAndSaveMany(t, newTestSQLiteDB(t))
+}
+
+func TestMigrate_Idempotent(t *testing.T) {
+	t.Parallel()
+
+	db := newTestSQLiteDB(t)
+	assert.NoError(t, Migrate(context.Background(), db))
+	assert.NoError(t, MigrateRateLimits(context.Background(), db))
+}
+
+func TestMigrateRateLimits_OnlyRateLimitTables(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, err := database.NewSQLDB(database.SQLDriverSQLite, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	require.NoError(t, MigrateRateLimits(ctx, db))
+
+	rows, err := db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name")
+	require.NoError(t, err)
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var table string
+		require.NoError(t, rows.Scan(&table))
+		tables = append(tables, table)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"rate_limits", "schema_migrations", "used_nonces"}, tables)
+}
+
+func TestEmailStorage_NormalizeEmails(t *testing.T) {
+	t.Parallel()
+
+	lowercase := func(email string) (string, bool) {
+		if !strings.Contains(email, "@") {
+			return "", false
+		}
+		return strings.ToLower(strings.TrimSpace(email)), true
+	}
+	// e.g. local parts aren't lowercased by configuration
+	lowercaseDomain := func(email string) (string, bool) {
+		local, domain, ok := strings.Cut(strings.TrimSpace(email), "@")
+		return local + "@" + strings.ToLower(domain), ok
+	}
+
+	now := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name            string
+		normalize       func(email string) (string, bool)
+		expectedChanged int
+		expected        []*entity.Subscriber
+	}{
+		{
+			name:            "positive: merged subscribers",
+			normalize:       lowercase,
+			expectedChanged: 3,
+			expected: []*entity.Subscriber{
+				// the earliest subscriber is kept
+				{Email: "user@test.com", Status: entity.SubscriberStatusConfirmed, CreatedAt: now.Add(-time.Hour)},
+				{Email: "invalid", Status: entity.SubscriberStatusPending, CreatedAt: now},
+				{Email: "other@test.com", Status: entity.SubscriberStatusPending, CreatedAt: now},
+				// confirmed subscriber is kept even if it's later
+				{Email: "pending@test.com", Status: entity.SubscriberStatusConfirmed, CreatedAt: now},
+			},
+		},
+		{
+			name:            "positive: distinct local parts are kept",
+			normalize:       lowercaseDomain,
+			expectedChanged: 3,
+			expected: []*entity.Subscriber{
+				{Email: "Pending@test.com", Status: entity.SubscriberStatusPending, CreatedAt: now.Add(-time.Hour)},
+				{Email: "user@test.com", Status: entity.SubscriberStatusConfirmed, CreatedAt: now.Add(-time.Hour)},
+				{Email: "Other@test.com", Status: entity.SubscriberStatusPending, CreatedAt: now},
+				{Email: "User@test.com", Status: entity.SubscriberStatusConfirmed, CreatedAt: now},
+				{Email: "invalid", Status: entity.SubscriberStatusPending, CreatedAt: now},
+				{Email: "pending@test.com", Status: entity.SubscriberStatusConfirmed, CreatedAt: now},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			storage := NewEmailStorage(newTestSQLiteDB(t))
+			// subscribers were stored before normalization was introduced
+			for _, subscriber := range []*entity.Subscriber{
+				{Email: "User@Test.com", Status: entity.SubscriberStatusConfirmed, CreatedAt: now},
+				{Email: "user@test.com", Status: entity.SubscriberStatusConfirmed, CreatedAt: now.Add(-time.Hour)},
+				{Email: "Pending@Test.com", Status: entity.SubscriberStatusPending, CreatedAt: now.Add(-time.Hour)},
+				{Email: "pending@test.com", Status: entity.SubscriberStatusConfirmed, CreatedAt: now},
+				{Email: " Other@Test.com ", Status: entity.SubscriberStatusPending, CreatedAt: now},
+				{Email: "invalid", Status: entity.SubscriberStatusPending, CreatedAt: now},
+			} {
+				require.NoError(t, storage.Save(ctx, subscriber))
+			}
+
+			changed, err := storage.NormalizeEmails(ctx, tc.normalize)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedChanged, changed)
+
+			subscribers, err := storage.List(ctx)
+			require.NoError(t, err)
+			require.Len(t, subscribers, len(tc.expected))
+			for i, expected := range tc.expected {
+				assert.Equal(t, expected.Email, subscribers[i].Email)
+				assert.Equal(t, expected.Status, subscribers[i].Status)
+				assert.True(t, expected.CreatedAt.Equal(subscribers[i].CreatedAt), expected.Email)
+			}
+
+			// emails are normalized once
+			changed, err = storage.NormalizeEmails(ctx, tc.normalize)
+			require.NoError(t, err)
+			assert.Zero(t, changed)
+		})
+	}
+}
+
+// testEmailStorage checks behavior every SQL driver has to provide, db has to be empty and migrated.
+func testEmailStorage(t *testing.T, db *database.SQLDB) {
+	ctx := context.Background()
+	storage := NewEmailStorage(db)
+
+	subscriber := &entity.Subscriber{
+		Email:     "email@test.com",
+		Status:    entity.SubscriberStatusPending,
+		CreatedAt: time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC),
+		Pairs: []entity.CurrencyPair{
+			{Crypto: entity.CryptoCurrencyBTC, Fiat: entity.FiatCurrencyUSD},
+			{Crypto: entity.CryptoCurrencyETH, Fiat: entity.FiatCurrencyUAH},
+		},
+		Locale: entity.LocaleUkrainian,
+	}
+
+	got, err := storage.Get(ctx, subscriber.Email)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	require.NoError(t, storage.Save(ctx, subscriber))
+
+	err = storage.Save(ctx, subscriber)
+	assert.ErrorIs(t, err, service.ErrAlreadyExists)
+
+	got, err = storage.Get(ctx, subscriber.Email)
+	require.NoError(t, err)
+	assert.Equal(t, subscriber.Email, got.Email)
+	assert.Equal(t, subscriber.Status, got.Status)
+	assert.True(t, subscriber.CreatedAt.Equal(got.CreatedAt))
+	assert.Equal(t, subscriber.Pairs, got.Pairs)
+	assert.Equal(t, subscriber.Locale, got.Locale)
+
+	exists, err := storage.Exist(ctx, subscriber.Email)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	subscriber.Status = entity.SubscriberStatusConfirmed
+	subscriber.Pairs = nil
+	require.NoError(t, storage.Update(ctx, subscriber))
+
+	got, err = storage.Get(ctx, subscriber.Email)
+	require.NoError(t, err)
+	assert.Equal(t, entity.SubscriberStatusConfirmed, got.Status)
+	assert.Equal(t, []entity.CurrencyPair{entity.DefaultCurrencyPair}, got.Pairs)
+
+	other := &entity.Subscriber{
+		Email:     "other@test.com",
+		Status:    entity.SubscriberStatusConfirmed,
+		CreatedAt: subscriber.CreatedAt.Add(time.Hour),
+		Locale:    entity.LocaleEnglish,
+	}
+	require.NoError(t, storage.Save(ctx, other))
+
+	list, err := storage.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, subscriber.Email, list[0].Email)
+	assert.Equal(t, other.Email, list[1].Email)
+
+	require.NoError(t, storage.Delete(ctx, subscriber.Email))
+
+	exists, err = storage.Exist(ctx, subscriber.Email)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+// testEmailStorageConcurrentSave checks that only one of concurrent saves of the same email succeeds.
+func testEmailStorageConcurrentSave(t *testing.T, db *database.SQLDB) {
+	ctx := context.Background()
+	storage := NewEmailStorage(db)
+
+	const workers = 10
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- storage.Save(ctx, &entity.Subscriber{
+				Email:     "email@test.com",
+				Status:    entity.SubscriberStatusPending,
+				CreatedAt: time.Now(),
+				Locale:    entity.LocaleEnglish,
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	saved := 0
+	for err := range errs {
+		if err == nil {
+			saved++
+			continue
+		}
+		assert.ErrorIs(t, err, service.ErrAlreadyExists)
+	}
+	assert.Equal(t, 1, saved)
+}
+
+// testEmailStorageSearchAndSaveMany checks bulk saving and searching, db has to be empty and migrated.
+func testEmailStorageSearchAndSaveMany(t *testing.T, db *database.SQLDB) {
+	ctx := context.Background()
+	storage := NewEmailStorage(db)
+
+	createdAt := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	newSubscriber := func(email string, status entity.SubscriberStatus) *entity.Subscriber {
+		createdAt = createdAt.Add(time.Minute)
+		return &entity.Subscriber{
+			Email:     email,
+			Status:    status,
+			CreatedAt: createdAt,
+			Locale:    entity.LocaleEnglish,
+		}
+	}
+
+	require.NoError(t, storage.Save(ctx, newSubscriber("existing@test.com", entity.SubscriberStatusConfirmed)))
+
+	existing, err := storage.SaveMany(ctx, []*entity.Subscriber{
+		newSubscriber("a@test.com", entity.SubscriberStatusConfirmed),
+		newSubscriber("existing@test.com", entity.SubscriberStatusConfirmed),
+		newSubscriber("Pending@Test.com", entity.SubscriberStatusPending),
+		newSubscriber("b_c@other.com", entity.SubscriberStatusConfirmed),
+		newSubscriber("a@test.com", entity.SubscriberStatusConfirmed),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"existing@test.com", "a@test.com"}, existing)
+
+	page, total, err := storage.Search(ctx, &service.SubscriberFilter{Query: "TEST.COM", Offset: 1, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	require.Len(t, page, 1)
+	assert.Equal(t, "a@test.com", page[0].Email)
+
+	page, total, err = storage.Search(ctx, &service.SubscriberFilter{Status: entity.SubscriberStatusPending, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, page, 1)
+	assert.Equal(t, "Pending@Test.com", page[0].Email)
+
+	// wildcards are matched literally
+	page, total, err = storage.Search(ctx, &service.SubscriberFilter{Query: "_", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, page, 1)
+	assert.Equal(t, "b_c@other.com", page[0].Email)
+}
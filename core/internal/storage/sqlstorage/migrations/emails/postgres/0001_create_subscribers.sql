@@ -0,0 +1,7 @@
+CREATE TABLE subscribers (
+    email      VARCHAR(320) PRIMARY KEY,
+    status     VARCHAR(32)  NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL,
+    pairs      TEXT         NOT NULL DEFAULT '',
+    locale     VARCHAR(16)  NOT NULL DEFAULT ''
+);
@@ -0,0 +1,7 @@
+CREATE TABLE subscribers (
+    email      TEXT PRIMARY KEY,
+    status     TEXT     NOT NULL,
+    created_at DATETIME NOT NULL,
+    pairs      TEXT     NOT NULL DEFAULT '',
+    locale     TEXT     NOT NULL DEFAULT ''
+);
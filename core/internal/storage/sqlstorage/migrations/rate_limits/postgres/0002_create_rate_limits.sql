@@ -0,0 +1,6 @@
+CREATE TABLE rate_limits (
+    bucket_key TEXT             PRIMARY KEY,
+    tokens     DOUBLE PRECISION NOT NULL,
+    -- updated_at is unix time in nanoseconds
+    updated_at BIGINT           NOT NULL
+);
@@ -0,0 +1,5 @@
+CREATE TABLE used_nonces (
+    nonce      TEXT    PRIMARY KEY,
+    -- expires_at is unix time in nanoseconds
+    expires_at INTEGER NOT NULL
+);
@@ -0,0 +1,62 @@
+package sqlstorage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/pkg/database"
+)
+
+// nonceStore keeps nonces of solved challenges in "used_nonces" table, so that solution can be used once by
+// all replicas sharing database.
+type nonceStore struct {
+	db  *database.SQLDB
+	now func() time.Time
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewNonceStore returns store of used nonces in "used_nonces" table, MigrateRateLimits has to be called
+// before using it.
+func NewNonceStore(db *database.SQLDB) *nonceStore {
+	return &nonceStore{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+func (s *nonceStore) Use(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	if err := s.sweep(ctx, s.now()); err != nil {
+		return false, err
+	}
+
+	// only one of concurrent inserts of the same nonce adds row
+	res, err := s.db.ExecContext(ctx, s.db.Rebind(
+		"INSERT INTO used_nonces (nonce, expires_at) VALUES (?, ?) ON CONFLICT (nonce) DO NOTHING"),
+		nonce, expiresAt.UnixNano())
+	if err != nil {
+		return false, err
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return inserted == 1, nil
+}
+
+// sweep removes nonces of expired tokens at most once per minute.
+func (s *nonceStore) sweep(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < time.Minute {
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	_, err := s.db.ExecContext(ctx, s.db.Rebind("DELETE FROM used_nonces WHERE expires_at < ?"), now.UnixNano())
+	return err
+}
@@ -0,0 +1,61 @@
+package sqlstorage
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/pkg/database"
+)
+
+func TestNonceStore_SQLite(t *testing.T) {
+	t.Parallel()
+
+	testNonceStore(t, newTestSQLiteDB(t))
+}
+
+// testNonceStore checks that nonce is used once by concurrent requests and replicas, db has to be empty
+// and migrated.
+func testNonceStore(t *testing.T, db *database.SQLDB) {
+	ctx := context.Background()
+	store := NewNonceStore(db)
+	expiresAt := time.Now().Add(time.Minute)
+
+	const workers = 20
+	var (
+		used int32
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := store.Use(ctx, "nonce", expiresAt)
+			if assert.NoError(t, err) && ok {
+				atomic.AddInt32(&used, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 1, used)
+
+	// another store sharing database sees the same nonce
+	ok, err := NewNonceStore(db).Use(ctx, "nonce", expiresAt)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// nonces of expired tokens are removed
+	later := NewNonceStore(db)
+	later.now = func() time.Time { return expiresAt.Add(time.Second) }
+	ok, err = later.Use(ctx, "other", expiresAt.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	var count int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM used_nonces").Scan(&count))
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,97 @@
+package sqlstorage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/pkg/database"
+	"github.com/vadimpk/gses-2023/core/pkg/ratelimit"
+)
+
+// rateLimitStore keeps token buckets in "rate_limits" table, so that replicas sharing database share limits.
+type rateLimitStore struct {
+	db  *database.SQLDB
+	now func() time.Time
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewRateLimitStore returns store of token buckets in "rate_limits" table, MigrateRateLimits has to be
+// called before using it.
+func NewRateLimitStore(db *database.SQLDB) *rateLimitStore {
+	return &rateLimitStore{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+func (s *rateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	now := s.now()
+	if err := s.sweep(ctx, now); err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// row has to exist to be locked, new bucket is full
+	_, err = tx.ExecContext(ctx, s.db.Rebind(
+		"INSERT INTO rate_limits (bucket_key, tokens, updated_at) VALUES (?, ?, ?) ON CONFLICT (bucket_key) DO NOTHING"),
+		key, float64(limit.Requests), now.UnixNano())
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	// sqlite has single writer, so only postgres needs row to be locked until update
+	query := "SELECT tokens, updated_at FROM rate_limits WHERE bucket_key = ?"
+	if s.db.Driver() == database.SQLDriverPostgres {
+		query += " FOR UPDATE"
+	}
+	var (
+		tokens    float64
+		updatedAt int64
+	)
+	err = tx.QueryRowContext(ctx, s.db.Rebind(query), key).Scan(&tokens, &updatedAt)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	bucket, result := limit.Take(&ratelimit.Bucket{
+		Tokens:    tokens,
+		UpdatedAt: time.Unix(0, updatedAt),
+	}, now)
+
+	_, err = tx.ExecContext(ctx, s.db.Rebind("UPDATE rate_limits SET tokens = ?, updated_at = ? WHERE bucket_key = ?"),
+		bucket.Tokens, bucket.UpdatedAt.UnixNano(), key)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	return result, nil
+}
+
+// sweep removes idle buckets at most once per minute.
+func (s *rateLimitStore) sweep(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < time.Minute {
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	_, err := s.db.ExecContext(ctx, s.db.Rebind("DELETE FROM rate_limits WHERE updated_at < ?"),
+		now.Add(-ratelimit.IdleTTL).UnixNano())
+	return err
+}
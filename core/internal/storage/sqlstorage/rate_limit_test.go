@@ -0,0 +1,56 @@
+package sqlstorage
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/pkg/database"
+	"github.com/vadimpk/gses-2023/core/pkg/ratelimit"
+)
+
+func TestRateLimitStore_SQLite(t *testing.T) {
+	t.Parallel()
+
+	testRateLimitStore(t, newTestSQLiteDB(t))
+}
+
+// testRateLimitStore checks that concurrent requests share bucket, db has to be empty and migrated.
+func testRateLimitStore(t *testing.T, db *database.SQLDB) {
+	ctx := context.Background()
+	store := NewRateLimitStore(db)
+	limit := ratelimit.Limit{Requests: 5, Period: time.Hour}
+
+	const workers = 20
+	var (
+		allowed int32
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := store.Take(ctx, "ip:127.0.0.1", limit)
+			if assert.NoError(t, err) && result.Allowed {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, limit.Requests, allowed)
+
+	// another store sharing database sees the same bucket
+	result, err := NewRateLimitStore(db).Take(ctx, "ip:127.0.0.1", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+
+	result, err = store.Take(ctx, "ip:127.0.0.2", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, limit.Requests-1, result.Remaining)
+}
@@ -0,0 +1,37 @@
+// Package sqlstorage implements storages backed by SQL databases supported by database.SQLDB.
+package sqlstorage
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+
+	"github.com/vadimpk/gses-2023/core/pkg/database"
+)
+
+// migrations are grouped by database they are applied to. Sets may share database, so names of migrations
+// are unique across them.
+//
+//go:embed migrations
+var migrations embed.FS
+
+// Migrate creates or updates tables used by email storage.
+func Migrate(ctx context.Context, db *database.SQLDB) error {
+	return migrate(ctx, db, "emails")
+}
+
+// MigrateRateLimits creates or updates tables used by rate limit and nonce stores, so that database of rate
+// limits doesn't get tables of subscribers.
+func MigrateRateLimits(ctx context.Context, db *database.SQLDB) error {
+	return migrate(ctx, db, "rate_limits")
+}
+
+func migrate(ctx context.Context, db *database.SQLDB, set string) error {
+	driverMigrations, err := fs.Sub(migrations, "migrations/"+set+"/"+string(db.Driver()))
+	if err != nil {
+		return fmt.Errorf("failed to get migrations of driver: %w", err)
+	}
+
+	return db.Migrate(ctx, driverMigrations)
+}
@@ -0,0 +1,42 @@
+// Package challenge implements challenges that clients solve to prove that request is not made by bot,
+// so that automated requests become expensive.
+package challenge
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrMissingSolution is returned when client hasn't sent solution.
+	ErrMissingSolution = errors.New("challenge solution is missing")
+	// ErrInvalidSolution is returned when solution is wrong, expired or was already used.
+	ErrInvalidSolution = errors.New("challenge solution is invalid")
+)
+
+const (
+	TypeProofOfWork = "proof-of-work"
+	TypeCaptcha     = "captcha"
+)
+
+// Challenge describes what client has to solve before its request is accepted.
+type Challenge struct {
+	// Type is TypeProofOfWork or TypeCaptcha.
+	Type string `json:"type"`
+	// Token and Difficulty are set for proof-of-work. Client has to find counter such that SHA-256 of
+	// "<token>:<counter>" starts with Difficulty zero bits and send "<token>:<counter>" as solution.
+	Token      string `json:"token,omitempty"`
+	Difficulty int    `json:"difficulty,omitempty"`
+	// Provider and SiteKey are set for captcha, they configure widget that produces solution.
+	Provider string `json:"provider,omitempty"`
+	SiteKey  string `json:"site_key,omitempty"`
+}
+
+// Verifier issues challenges and verifies their solutions.
+type Verifier interface {
+	// Challenge returns new challenge for client.
+	Challenge(ctx context.Context) (*Challenge, error)
+	// Verify checks solution sent by client from remoteIP. ErrMissingSolution or ErrInvalidSolution
+	// is returned if client failed challenge, other errors mean that solution couldn't be checked.
+	Verify(ctx context.Context, solution, remoteIP string) error
+}
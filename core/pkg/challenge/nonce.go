@@ -0,0 +1,57 @@
+package challenge
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NonceStore remembers nonces of solved challenges until their tokens expire, so that solution can't be
+// replayed. Stores shared between replicas make each solution usable once across all of them.
+type NonceStore interface {
+	// Use records nonce as used until expiresAt atomically and returns false if it was used already.
+	Use(ctx context.Context, nonce string, expiresAt time.Time) (bool, error)
+}
+
+// MemoryNonceStore keeps nonces in memory of single process, so solution can be used once per process.
+type MemoryNonceStore struct {
+	mu        sync.Mutex
+	used      map[string]time.Time
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		used: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+func (s *MemoryNonceStore) Use(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if _, ok := s.used[nonce]; ok {
+		return false, nil
+	}
+	s.used[nonce] = expiresAt
+	return true, nil
+}
+
+// sweep removes nonces of expired tokens at most once per minute.
+func (s *MemoryNonceStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for nonce, expiresAt := range s.used {
+		if now.After(expiresAt) {
+			delete(s.used, nonce)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package challenge
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vadimpk/gses-2023/core/pkg/token"
+)
+
+const (
+	proofOfWorkPurpose = "proof-of-work"
+	// maxSolutionLength bounds work done for solutions sent by clients.
+	maxSolutionLength = 256
+)
+
+// ProofOfWork is hashcash-style challenge: client has to compute about 2^difficulty hashes to solve it, while
+// verifying takes one hash. Tokens are signed, so nothing is stored until challenge is solved.
+type ProofOfWork struct {
+	signer     *token.HMAC
+	difficulty int
+	ttl        time.Duration
+	now        func() time.Time
+	nonces     NonceStore
+}
+
+type ProofOfWorkOptions struct {
+	Secret string
+	// Difficulty is number of leading zero bits of solution hash.
+	Difficulty int
+	// TTL is time client has to solve challenge.
+	TTL time.Duration
+	// Nonces keeps nonces of solved challenges, MemoryNonceStore is used if it's nil.
+	Nonces NonceStore
+}
+
+func NewProofOfWork(opts *ProofOfWorkOptions) *ProofOfWork {
+	nonces := opts.Nonces
+	if nonces == nil {
+		nonces = NewMemoryNonceStore()
+	}
+
+	return &ProofOfWork{
+		signer:     token.NewHMAC(opts.Secret),
+		difficulty: opts.Difficulty,
+		ttl:        opts.TTL,
+		now:        time.Now,
+		nonces:     nonces,
+	}
+}
+
+func (p *ProofOfWork) Challenge(ctx context.Context) (*Challenge, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	difficulty := strconv.Itoa(p.difficulty)
+	n := hex.EncodeToString(nonce)
+	signed := p.signer.SignExpiring(p.now().Add(p.ttl), proofOfWorkPurpose, difficulty, n)
+
+	return &Challenge{
+		Type:       TypeProofOfWork,
+		Token:      difficulty + "." + n + "." + signed,
+		Difficulty: p.difficulty,
+	}, nil
+}
+
+func (p *ProofOfWork) Verify(ctx context.Context, solution, remoteIP string) error {
+	if solution == "" {
+		return ErrMissingSolution
+	}
+	if len(solution) > maxSolutionLength {
+		return fmt.Errorf("%w: solution is too long", ErrInvalidSolution)
+	}
+
+	tok, _, ok := strings.Cut(solution, ":")
+	if !ok {
+		return fmt.Errorf("%w: counter is missing", ErrInvalidSolution)
+	}
+	parts := strings.SplitN(tok, ".", 3)
+	if len(parts) != 3 {
+		return fmt.Errorf("%w: malformed token", ErrInvalidSolution)
+	}
+	difficulty, nonce, signed := parts[0], parts[1], parts[2]
+
+	now := p.now()
+	expiresAt, err := p.signer.VerifyExpiration(signed, now, proofOfWorkPurpose, difficulty, nonce)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSolution, err)
+	}
+	// tokens issued before difficulty was raised are not accepted
+	d, err := strconv.Atoi(difficulty)
+	if err != nil || d < p.difficulty {
+		return fmt.Errorf("%w: difficulty is too low", ErrInvalidSolution)
+	}
+
+	hash := sha256.Sum256([]byte(solution))
+	if leadingZeroBits(hash[:]) < d {
+		return fmt.Errorf("%w: hash doesn't have %d leading zero bits", ErrInvalidSolution, d)
+	}
+
+	ok, err = p.nonces.Use(ctx, nonce, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to mark challenge as solved: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: challenge was already solved", ErrInvalidSolution)
+	}
+
+	return nil
+}
+
+func leadingZeroBits(b []byte) int {
+	n := 0
+	for _, x := range b {
+		if x != 0 {
+			return n + bits.LeadingZeros8(x)
+		}
+		n += 8
+	}
+	return n
+}
+
+// Solve finds solution of proof-of-work challenge, it's meant for clients and tests.
+func Solve(c *Challenge) string {
+	for counter := 0; ; counter++ {
+		solution := c.Token + ":" + strconv.Itoa(counter)
+		hash := sha256.Sum256([]byte(solution))
+		if leadingZeroBits(hash[:]) >= c.Difficulty {
+			return solution
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package challenge
+
+import (
+	"context"
+	"crypto/sha256"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProofOfWork(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	// replicas share store of nonces
+	nonces := NewMemoryNonceStore()
+	newProofOfWork := func(difficulty int) *ProofOfWork {
+		p := NewProofOfWork(&ProofOfWorkOptions{
+			Secret:     "secret",
+			Difficulty: difficulty,
+			TTL:        time.Minute,
+			Nonces:     nonces,
+		})
+		p.now = func() time.Time { return now }
+		return p
+	}
+	p := newProofOfWork(8)
+
+	c, err := p.Challenge(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, TypeProofOfWork, c.Type)
+	assert.Equal(t, 8, c.Difficulty)
+
+	solution := Solve(c)
+	require.NoError(t, p.Verify(ctx, solution, ""))
+	// solution can be used once, also by another replica
+	assert.ErrorIs(t, p.Verify(ctx, solution, ""), ErrInvalidSolution)
+	assert.ErrorIs(t, newProofOfWork(8).Verify(ctx, solution, ""), ErrInvalidSolution)
+
+	assert.ErrorIs(t, p.Verify(ctx, "", ""), ErrMissingSolution)
+
+	c, err = p.Challenge(ctx)
+	require.NoError(t, err)
+	solution = Solve(c)
+
+	for name, s := range map[string]string{
+		"without counter": c.Token,
+		"wrong counter":   wrongCounter(c),
+		"tampered":        "9" + strings.TrimPrefix(solution, "8"),
+		"too long":        solution + strings.Repeat("0", maxSolutionLength),
+	} {
+		assert.ErrorIs(t, p.Verify(ctx, s, ""), ErrInvalidSolution, name)
+	}
+
+	// tokens issued with lower difficulty are rejected after it's raised
+	assert.ErrorIs(t, newProofOfWork(12).Verify(ctx, solution, ""), ErrInvalidSolution)
+
+	now = now.Add(2 * time.Minute)
+	assert.ErrorIs(t, p.Verify(ctx, solution, ""), ErrInvalidSolution)
+}
+
+// wrongCounter returns solution of c with counter that doesn't produce enough zero bits.
+func wrongCounter(c *Challenge) string {
+	for counter := 0; ; counter++ {
+		s := c.Token + ":x" + strings.Repeat("0", counter)
+		if hash := sha256.Sum256([]byte(s)); leadingZeroBits(hash[:]) < c.Difficulty {
+			return s
+		}
+	}
+}
@@ -1,26 +1,54 @@
 package database
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
 )
 
+// FileDB stores data in files of base directory. Files are locked while they are accessed, so that
+// concurrent requests and processes sharing base directory don't corrupt them, and are replaced
+// atomically, so that crash in the middle of rewrite leaves either old or new content.
 type FileDB struct {
 	baseFilePath string
+	// sync makes writes wait until data reaches disk.
+	sync bool
+
+	mu    sync.Mutex
+	locks map[string]*sync.RWMutex
+}
+
+type FileDBOption func(*FileDB)
+
+// SyncWrites - configures whether writes are flushed to disk before returning. It's enabled by default,
+// disabling it makes writes faster, but data written just before power loss may be lost.
+func SyncWrites(enabled bool) FileDBOption {
+	return func(f *FileDB) {
+		f.sync = enabled
+	}
 }
 
-func NewFileDB(baseFilePath string) *FileDB {
+func NewFileDB(baseFilePath string, opts ...FileDBOption) *FileDB {
 	err := os.MkdirAll(baseFilePath, os.ModePerm)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	return &FileDB{
+	f := &FileDB{
 		baseFilePath: baseFilePath,
+		sync:         true,
+		locks:        make(map[string]*sync.RWMutex),
+	}
+	for _, opt := range opts {
+		opt(f)
 	}
+
+	return f
 }
 
 func (f *FileDB) Close() error {
@@ -42,32 +70,332 @@ func (f *FileDB) Destroy(ctx context.Context) error {
 
 var writePermissionCode = 0600
 
+// Append adds data to the end of file as a new line.
 func (f *FileDB) Append(ctx context.Context, file string, data []byte) error {
+	_, err := f.AppendIf(ctx, file, data, nil)
+	return err
+}
+
+// AppendIf adds data to the end of file as a new line if check, which is called with info of file while
+// it's locked, returns nil. Otherwise file is left intact and the error is returned. Info is nil if file
+// doesn't exist. Returns info of file after append, taken before file is unlocked. It lets callers that keep
+// content of file in memory append to it without reading it, as long as file hasn't changed since it was read.
+func (f *FileDB) AppendIf(ctx context.Context, file string, data []byte, check func(info os.FileInfo) error) (os.FileInfo, error) {
+	unlock, err := f.lock(file, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if check != nil {
+		info, err := f.Stat(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+		if err = check(info); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = f.append(file, data); err != nil {
+		return nil, err
+	}
+
+	return f.Stat(ctx, file)
+}
+
+// append adds data to the end of file as a new line. Must be called with file locked.
+func (f *FileDB) append(file string, data []byte) error {
 	fullPath := filepath.Join(f.baseFilePath, file)
 
 	// Open the file in append mode
-	fh, err := os.OpenFile(fullPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, os.FileMode(writePermissionCode))
+	fh, err := os.OpenFile(fullPath, os.O_CREATE|os.O_APPEND|os.O_RDWR, os.FileMode(writePermissionCode))
 	if err != nil {
 		return err
 	}
 	defer fh.Close()
 
-	// Append new data onto the next line
-	_, err = fh.Write(append(data, '\n'))
+	line := append(data, '\n')
 
-	return err
+	// previous append may have been interrupted in the middle of line, new line shouldn't be glued to it
+	torn, err := endsWithTornLine(fh)
+	if err != nil {
+		return err
+	}
+	if torn {
+		line = append([]byte{'\n'}, line...)
+	}
+
+	if _, err = fh.Write(line); err != nil {
+		return err
+	}
+
+	if f.sync {
+		return fh.Sync()
+	}
+	return nil
 }
 
+func endsWithTornLine(fh *os.File) (bool, error) {
+	info, err := fh.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size() == 0 {
+		return false, nil
+	}
+
+	last := make([]byte, 1)
+	if _, err = fh.ReadAt(last, info.Size()-1); err != nil {
+		return false, err
+	}
+	return last[0] != '\n', nil
+}
+
+// Read returns content of file. Returns nil if file doesn't exist.
 func (f *FileDB) Read(ctx context.Context, file string) ([]byte, error) {
-	fullPath := filepath.Join(f.baseFilePath, file)
+	unlock, err := f.lock(file, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return f.read(file)
+}
 
-	// Check if file exists, if not return an error
-	_, err := os.Stat(fullPath)
+func (f *FileDB) read(file string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(f.baseFilePath, file))
 	if os.IsNotExist(err) {
 		return nil, nil
-	} else if err != nil {
+	}
+	return data, err
+}
+
+// Stat returns info of file, which changes whenever file is written. Returns nil if file doesn't exist.
+func (f *FileDB) Stat(ctx context.Context, file string) (os.FileInfo, error) {
+	info, err := os.Stat(filepath.Join(f.baseFilePath, file))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return info, err
+}
+
+// Rewrite atomically replaces content of file with data.
+func (f *FileDB) Rewrite(ctx context.Context, file string, data []byte) error {
+	unlock, err := f.lock(file, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return f.replace(file, data)
+}
+
+// Update atomically replaces content of file with result of fn, which is called with current content.
+// File stays locked while fn is running, so that concurrent updates are not lost. If fn returns error,
+// file is left intact and the error is returned.
+func (f *FileDB) Update(ctx context.Context, file string, fn func(data []byte) ([]byte, error)) error {
+	_, err := f.UpdateWithInfo(ctx, file, func(info os.FileInfo, data []byte) ([]byte, error) {
+		return fn(data)
+	})
+	return err
+}
+
+// UpdateWithInfo is Update, whose fn also gets info of file before update, and which returns info of file
+// after it. Both are taken while file is locked, so that callers that keep content of file in memory know
+// if anyone else has changed it in between. Info is nil if file doesn't exist.
+func (f *FileDB) UpdateWithInfo(ctx context.Context, file string, fn func(info os.FileInfo, data []byte) ([]byte, error)) (os.FileInfo, error) {
+	unlock, err := f.lock(file, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	info, err := f.Stat(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	data, err := f.read(file)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := fn(info, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = f.replace(file, updated); err != nil {
 		return nil, err
 	}
 
-	return os.ReadFile(fullPath)
+	return f.Stat(ctx, file)
+}
+
+// Delete atomically removes lines of file for which match returns true and returns number of removed lines.
+func (f *FileDB) Delete(ctx context.Context, file string, match func(line []byte) bool) (int, error) {
+	removed := 0
+	err := f.Update(ctx, file, func(data []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		for _, line := range splitLines(data) {
+			if match(line) {
+				removed++
+				continue
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return removed, nil
+}
+
+// Compact removes empty lines, lines key reports as malformed, and all but the last line of lines
+// with the same key, then atomically rewrites file. It's used to clean up files after interrupted
+// writes and duplicates of records. Returns number of removed lines.
+func (f *FileDB) Compact(ctx context.Context, file string, key func(line []byte) (string, bool)) (int, error) {
+	removed := 0
+	err := f.Update(ctx, file, func(data []byte) ([]byte, error) {
+		removed = 0
+		if len(data) == 0 {
+			return data, nil
+		}
+
+		lines := bytes.Split(bytes.TrimSuffix(data, []byte{'\n'}), []byte{'\n'})
+		keys := make([]string, len(lines))
+		valid := make([]bool, len(lines))
+		last := make(map[string]int, len(lines))
+		for i, line := range lines {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			k, ok := key(line)
+			if !ok {
+				continue
+			}
+			keys[i], valid[i] = k, true
+			last[k] = i
+		}
+
+		var buf bytes.Buffer
+		for i, line := range lines {
+			if !valid[i] || last[keys[i]] != i {
+				removed++
+				continue
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return removed, nil
+}
+
+// splitLines returns non-empty lines of data.
+func splitLines(data []byte) [][]byte {
+	var lines [][]byte
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// replace writes data to temporary file and renames it to file, so that readers see either old or
+// new content. Must be called with file locked.
+func (f *FileDB) replace(file string, data []byte) (err error) {
+	fullPath := filepath.Join(f.baseFilePath, file)
+	dir := filepath.Dir(fullPath)
+
+	// temporary file is created next to file, so that rename doesn't cross directories
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(file)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err = tmp.Chmod(os.FileMode(writePermissionCode)); err != nil {
+		return fmt.Errorf("failed to set permissions of temporary file: %w", err)
+	}
+	if f.sync {
+		if err = tmp.Sync(); err != nil {
+			return fmt.Errorf("failed to sync temporary file: %w", err)
+		}
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), fullPath); err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+
+	if f.sync {
+		// rename is durable only after directory entry is flushed
+		return syncDir(dir)
+	}
+	return nil
+}
+
+// lock locks file for reading or writing within process and across processes sharing base directory.
+// Returned function releases the lock.
+func (f *FileDB) lock(file string, exclusive bool) (func(), error) {
+	f.mu.Lock()
+	mu, ok := f.locks[file]
+	if !ok {
+		mu = &sync.RWMutex{}
+		f.locks[file] = mu
+	}
+	f.mu.Unlock()
+
+	if exclusive {
+		mu.Lock()
+	} else {
+		mu.RLock()
+	}
+	unlockProcess := func() {
+		if exclusive {
+			mu.Unlock()
+		} else {
+			mu.RUnlock()
+		}
+	}
+
+	// file itself is replaced on rewrite, so separate lock file is locked instead
+	lockPath := filepath.Join(f.baseFilePath, filepath.Dir(file), "."+filepath.Base(file)+".lock")
+	lockFile, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, os.FileMode(writePermissionCode))
+	if err != nil {
+		unlockProcess()
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err = lockFileHandle(lockFile, exclusive); err != nil {
+		_ = lockFile.Close()
+		unlockProcess()
+		return nil, fmt.Errorf("failed to lock file: %w", err)
+	}
+
+	return func() {
+		_ = unlockFileHandle(lockFile)
+		_ = lockFile.Close()
+		unlockProcess()
+	}, nil
 }
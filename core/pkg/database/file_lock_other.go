@@ -0,0 +1,20 @@
+//go:build !unix
+
+package database
+
+import "os"
+
+// Files are locked only within process on platforms without flock.
+
+func lockFileHandle(f *os.File, exclusive bool) error {
+	return nil
+}
+
+func unlockFileHandle(f *os.File) error {
+	return nil
+}
+
+// syncDir is no-op, directories can't be synced on these platforms.
+func syncDir(path string) error {
+	return nil
+}
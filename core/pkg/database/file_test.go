@@ -0,0 +1,265 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFileDB(t *testing.T) *FileDB {
+	return NewFileDB(t.TempDir(), SyncWrites(false))
+}
+
+func TestFileDB_Append_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := newTestFileDB(t)
+
+	const workers, lines = 8, 50
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				assert.NoError(t, db.Append(ctx, "test.txt", []byte(fmt.Sprintf("worker-%d-line-%d", w, i))))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	data, err := db.Read(ctx, "test.txt")
+	require.NoError(t, err)
+
+	got := splitLines(data)
+	assert.Len(t, got, workers*lines)
+	for _, line := range got {
+		assert.True(t, bytes.HasPrefix(line, []byte("worker-")), "line is corrupted: %q", line)
+	}
+}
+
+func TestFileDB_Append_AfterTornLine(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := newTestFileDB(t)
+
+	// interrupted append leaves line without newline
+	require.NoError(t, os.WriteFile(filepath.Join(db.baseFilePath, "test.txt"), []byte("first\n{\"tor"), 0600))
+	require.NoError(t, db.Append(ctx, "test.txt", []byte("second")))
+
+	data, err := db.Read(ctx, "test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "first\n{\"tor\nsecond\n", string(data))
+}
+
+func TestFileDB_AppendIf(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := newTestFileDB(t)
+
+	info, err := db.AppendIf(ctx, "test.txt", []byte("first"), func(info os.FileInfo) error {
+		assert.Nil(t, info)
+		return nil
+	})
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.EqualValues(t, len("first\n"), info.Size())
+
+	errChanged := errors.New("file was changed")
+	_, err = db.AppendIf(ctx, "test.txt", []byte("second"), func(info os.FileInfo) error {
+		require.NotNil(t, info)
+		assert.EqualValues(t, len("first\n"), info.Size())
+		return errChanged
+	})
+	assert.ErrorIs(t, err, errChanged)
+
+	data, err := db.Read(ctx, "test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "first\n", string(data))
+}
+
+func TestFileDB_UpdateWithInfo(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := newTestFileDB(t)
+
+	info, err := db.UpdateWithInfo(ctx, "test.txt", func(info os.FileInfo, data []byte) ([]byte, error) {
+		assert.Nil(t, info)
+		return []byte("first\n"), nil
+	})
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.EqualValues(t, len("first\n"), info.Size())
+
+	after, err := db.UpdateWithInfo(ctx, "test.txt", func(before os.FileInfo, data []byte) ([]byte, error) {
+		require.NotNil(t, before)
+		assert.True(t, os.SameFile(info, before))
+		return append(data, "second\n"...), nil
+	})
+	require.NoError(t, err)
+	// file is replaced by update
+	assert.False(t, os.SameFile(info, after))
+	assert.EqualValues(t, len("first\nsecond\n"), after.Size())
+}
+
+func TestFileDB_Read_NotExist(t *testing.T) {
+	t.Parallel()
+
+	data, err := newTestFileDB(t).Read(context.Background(), "missing.txt")
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+}
+
+func TestFileDB_Update_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := newTestFileDB(t)
+
+	// every update increments counter, lost updates would leave it lower
+	const updates = 100
+	var wg sync.WaitGroup
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := db.Update(ctx, "counter.txt", func(data []byte) ([]byte, error) {
+				n := 0
+				if len(data) > 0 {
+					var err error
+					n, err = strconv.Atoi(strings.TrimSpace(string(data)))
+					if err != nil {
+						return nil, err
+					}
+				}
+				return []byte(strconv.Itoa(n+1) + "\n"), nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	data, err := db.Read(ctx, "counter.txt")
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(updates)+"\n", string(data))
+}
+
+func TestFileDB_Update_Error(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := newTestFileDB(t)
+	require.NoError(t, db.Rewrite(ctx, "test.txt", []byte("content\n")))
+
+	testErr := fmt.Errorf("some err")
+	err := db.Update(ctx, "test.txt", func(data []byte) ([]byte, error) {
+		return nil, testErr
+	})
+	assert.ErrorIs(t, err, testErr)
+
+	data, err := db.Read(ctx, "test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "content\n", string(data))
+}
+
+func TestFileDB_Rewrite_LeavesNoTemporaryFiles(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := NewFileDB(t.TempDir())
+
+	require.NoError(t, db.Rewrite(ctx, "test.txt", []byte("old\n")))
+	require.NoError(t, db.Rewrite(ctx, "test.txt", []byte("new\n")))
+
+	data, err := db.Read(ctx, "test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "new\n", string(data))
+
+	entries, err := os.ReadDir(db.baseFilePath)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.NotContains(t, entry.Name(), ".tmp-")
+	}
+
+	info, err := os.Stat(filepath.Join(db.baseFilePath, "test.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestFileDB_NestedFile(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := newTestFileDB(t)
+	require.NoError(t, os.Mkdir(filepath.Join(db.baseFilePath, "sub"), 0700))
+	file := filepath.Join("sub", "test.txt")
+
+	require.NoError(t, db.Append(ctx, file, []byte("a")))
+	require.NoError(t, db.Rewrite(ctx, file, []byte("b\n")))
+	require.NoError(t, db.Update(ctx, file, func(data []byte) ([]byte, error) {
+		return append(data, "c\n"...), nil
+	}))
+
+	data, err := db.Read(ctx, file)
+	require.NoError(t, err)
+	assert.Equal(t, "b\nc\n", string(data))
+
+	// lock and temporary files are kept next to file
+	_, err = os.Stat(filepath.Join(db.baseFilePath, "sub", ".test.txt.lock"))
+	assert.NoError(t, err)
+	entries, err := os.ReadDir(db.baseFilePath)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "sub", entries[0].Name())
+}
+
+func TestFileDB_Delete(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := newTestFileDB(t)
+	require.NoError(t, db.Rewrite(ctx, "test.txt", []byte("a\nb\n\na\nc\n")))
+
+	removed, err := db.Delete(ctx, "test.txt", func(line []byte) bool {
+		return string(line) == "a"
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	data, err := db.Read(ctx, "test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "b\nc\n", string(data))
+}
+
+func TestFileDB_Compact(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := newTestFileDB(t)
+	require.NoError(t, db.Rewrite(ctx, "test.txt", []byte("a=1\nb=1\n\na=2\nbroken\nc=1\nb=2")))
+
+	removed, err := db.Compact(ctx, "test.txt", func(line []byte) (string, bool) {
+		key, _, ok := strings.Cut(string(line), "=")
+		return key, ok
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 4, removed)
+
+	data, err := db.Read(ctx, "test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "a=2\nc=1\nb=2\n", string(data))
+}
@@ -0,0 +1,229 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib" // registers "pgx" driver
+	"modernc.org/sqlite"               // registers "sqlite" driver
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLDriver is a kind of SQL database.
+type SQLDriver string
+
+const (
+	SQLDriverSQLite   SQLDriver = "sqlite"
+	SQLDriverPostgres SQLDriver = "postgres"
+)
+
+// SQLDB wraps sql.DB and hides differences between supported drivers.
+type SQLDB struct {
+	*sql.DB
+	driver SQLDriver
+}
+
+// NewSQLDB opens connection pool to database. DSN is a file path or URI for SQLite and
+// connection string for PostgreSQL.
+func NewSQLDB(driver SQLDriver, dsn string) (*SQLDB, error) {
+	var driverName string
+	switch driver {
+	case SQLDriverSQLite:
+		driverName = "sqlite"
+	case SQLDriverPostgres:
+		driverName = "pgx"
+	default:
+		return nil, fmt.Errorf("unknown sql driver: %q", driver)
+	}
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if driver == SQLDriverSQLite {
+		// SQLite allows only one writer at a time, single connection makes writers wait for each
+		// other instead of failing with SQLITE_BUSY
+		db.SetMaxOpenConns(1)
+	}
+
+	return &SQLDB{
+		DB:     db,
+		driver: driver,
+	}, nil
+}
+
+func (d *SQLDB) Ping(ctx context.Context) error {
+	return d.PingContext(ctx)
+}
+
+func (d *SQLDB) Driver() SQLDriver {
+	return d.driver
+}
+
+// Rebind replaces "?" placeholders in query with ones of database driver.
+func (d *SQLDB) Rebind(query string) string {
+	if d.driver != SQLDriverPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// migrationsLockKey is key of PostgreSQL advisory lock held while migrations are applied.
+const migrationsLockKey int64 = 0x67736573 // "gses"
+
+// Migrate applies migrations that were not applied yet. Migrations are .sql files in the root of
+// migrations and are applied in order of their names, e.g. "0001_create_subscribers.sql". Each
+// migration is applied in its own transaction. On PostgreSQL processes migrating the same database,
+// e.g. replicas starting at the same time, wait for each other, so that migrations are applied once.
+func (d *SQLDB) Migrate(ctx context.Context, migrations fs.FS) error {
+	if d.driver == SQLDriverPostgres {
+		unlock, err := d.lockMigrations(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to lock migrations: %w", err)
+		}
+		defer unlock()
+	}
+
+	_, err := d.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version VARCHAR(255) PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	applied, err := d.appliedMigrations(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	names, err := fs.Glob(migrations, "*.sql")
+	if err != nil {
+		return fmt.Errorf("failed to list migrations: %w", err)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		version := strings.TrimSuffix(path.Base(name), ".sql")
+		if _, ok := applied[version]; ok {
+			continue
+		}
+
+		query, err := fs.ReadFile(migrations, name)
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", version, err)
+		}
+
+		err = d.applyMigration(ctx, version, string(query))
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", version, err)
+		}
+	}
+
+	return nil
+}
+
+// lockMigrations takes advisory lock in session of dedicated connection, the lock is released with
+// returned function.
+func (d *SQLDB) lockMigrations(ctx context.Context) (func(), error) {
+	conn, err := d.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationsLockKey)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return func() {
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationsLockKey)
+		if err != nil {
+			// session holding the lock mustn't return to pool, closing connection releases the lock
+			_ = conn.Raw(func(interface{}) error {
+				return driver.ErrBadConn
+			})
+		}
+		_ = conn.Close()
+	}, nil
+}
+
+func (d *SQLDB) appliedMigrations(ctx context.Context) (map[string]struct{}, error) {
+	rows, err := d.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[string]struct{})
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = struct{}{}
+	}
+
+	return applied, rows.Err()
+}
+
+func (d *SQLDB) applyMigration(ctx context.Context, version, query string) error {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, d.Rebind("INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)"),
+		version, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// IsUniqueViolation checks if err is caused by violation of unique or primary key constraint.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// unique_violation
+		return pgErr.Code == "23505"
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE ||
+			sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+
+	return false
+}
@@ -0,0 +1,120 @@
+// Package mailtemplate renders emails consisting of subject, plain text and HTML parts.
+//
+// Email named "name" is described by files "name.subject.tmpl", "name.txt.tmpl" and optional
+// "name.html.tmpl". Other "*.html.tmpl" files are parsed with HTML parts and other "*.tmpl" files
+// with subject and text parts, so they can be used as partials.
+package mailtemplate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	texttemplate "text/template"
+)
+
+const (
+	extension        = ".tmpl"
+	subjectExtension = ".subject" + extension
+	textExtension    = ".txt" + extension
+	htmlExtension    = ".html" + extension
+)
+
+// ErrNotFound is returned when there is no template for email.
+var ErrNotFound = errors.New("template not found")
+
+// Message is rendered email.
+type Message struct {
+	Subject string
+	Text    string
+	// HTML is empty if email doesn't have HTML template.
+	HTML string
+}
+
+// Set is a collection of parsed email templates.
+type Set struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// New parses "*.tmpl" files from root of each file system. Files from later file systems override
+// files with the same name from earlier ones, so embedded defaults can be partially customized.
+func New(funcs map[string]interface{}, fileSystems ...fs.FS) (*Set, error) {
+	files := make(map[string][]byte)
+	for _, fsys := range fileSystems {
+		names, err := fs.Glob(fsys, "*"+extension)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list templates: %w", err)
+		}
+
+		for _, name := range names {
+			content, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read template %s: %w", name, err)
+			}
+			files[name] = content
+		}
+	}
+
+	set := &Set{
+		text: texttemplate.New("").Funcs(funcs),
+		html: htmltemplate.New("").Funcs(funcs),
+	}
+	for name, content := range files {
+		var err error
+		if strings.HasSuffix(name, htmlExtension) {
+			_, err = set.html.New(name).Parse(string(content))
+		} else {
+			_, err = set.text.New(name).Parse(string(content))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+	}
+
+	return set, nil
+}
+
+// Render executes templates of email with given name.
+func (s *Set) Render(name string, data interface{}) (*Message, error) {
+	subject, err := s.executeText(name+subjectExtension, data)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := s.executeText(name+textExtension, data)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &Message{
+		// subject can't span multiple lines, but it's convenient to end template file with newline
+		Subject: strings.Join(strings.Fields(subject), " "),
+		Text:    text,
+	}
+
+	if tmpl := s.html.Lookup(name + htmlExtension); tmpl != nil {
+		var buf bytes.Buffer
+		if err = tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to execute template %s: %w", tmpl.Name(), err)
+		}
+		message.HTML = buf.String()
+	}
+
+	return message, nil
+}
+
+func (s *Set) executeText(name string, data interface{}) (string, error) {
+	tmpl := s.text.Lookup(name)
+	if tmpl == nil {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute template %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
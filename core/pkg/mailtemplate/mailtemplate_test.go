@@ -0,0 +1,99 @@
+package mailtemplate_test
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/core/pkg/mailtemplate"
+)
+
+func TestSet_Render(t *testing.T) {
+	t.Parallel()
+
+	defaults := fstest.MapFS{
+		"greeting.subject.tmpl": {Data: []byte("Hello, {{.Name}}\n")},
+		"greeting.txt.tmpl":     {Data: []byte("Hi {{upper .Name}}")},
+		"greeting.html.tmpl":    {Data: []byte(`{{template "layout.html.tmpl" .}}`)},
+		"layout.html.tmpl":      {Data: []byte(`<p>Hi {{.Name}}</p>`)},
+		"plain.subject.tmpl":    {Data: []byte("Plain")},
+		"plain.txt.tmpl":        {Data: []byte("Plain text")},
+	}
+	overrides := fstest.MapFS{
+		"greeting.txt.tmpl": {Data: []byte("Custom hi {{upper .Name}}")},
+	}
+	funcs := map[string]interface{}{
+		"upper": strings.ToUpper,
+	}
+	data := map[string]string{"Name": "<Bob>"}
+
+	testCases := []struct {
+		name        string
+		fileSystems []fs.FS
+		template    string
+		expected    *mailtemplate.Message
+		expectedErr error
+	}{
+		{
+			name:        "positive: render text and html parts",
+			fileSystems: []fs.FS{defaults},
+			template:    "greeting",
+			expected: &mailtemplate.Message{
+				Subject: "Hello, <Bob>",
+				Text:    "Hi <BOB>",
+				HTML:    "<p>Hi &lt;Bob&gt;</p>",
+			},
+		},
+		{
+			name:        "positive: override default template",
+			fileSystems: []fs.FS{defaults, overrides},
+			template:    "greeting",
+			expected: &mailtemplate.Message{
+				Subject: "Hello, <Bob>",
+				Text:    "Custom hi <BOB>",
+				HTML:    "<p>Hi &lt;Bob&gt;</p>",
+			},
+		},
+		{
+			name:        "positive: render email without html part",
+			fileSystems: []fs.FS{defaults},
+			template:    "plain",
+			expected: &mailtemplate.Message{
+				Subject: "Plain",
+				Text:    "Plain text",
+			},
+		},
+		{
+			name:        "negative: template not found",
+			fileSystems: []fs.FS{defaults},
+			template:    "unknown",
+			expectedErr: mailtemplate.ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			set, err := mailtemplate.New(funcs, tc.fileSystems...)
+			require.NoError(t, err)
+
+			message, err := set.Render(tc.template, data)
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expected, message)
+		})
+	}
+}
+
+func TestNew_InvalidTemplate(t *testing.T) {
+	t.Parallel()
+
+	_, err := mailtemplate.New(nil, fstest.MapFS{
+		"broken.txt.tmpl": {Data: []byte("{{.Name")},
+	})
+	assert.Error(t, err)
+}
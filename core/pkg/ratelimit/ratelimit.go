@@ -0,0 +1,120 @@
+// Package ratelimit implements token bucket rate limiting with pluggable storage of buckets.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests requests per Period. Bucket holds up to Requests tokens and is refilled
+// continuously, so that full bucket allows burst of Requests requests.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+var ErrInvalidLimit = errors.New("invalid rate limit")
+
+var limitPeriods = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+// ParseLimit parses limit in "<requests>/<period>" format, where period is "s", "m" or "h", e.g. "5/m".
+func ParseLimit(s string) (Limit, error) {
+	requests, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, s)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, s)
+	}
+	d, ok := limitPeriods[period]
+	if !ok {
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, s)
+	}
+
+	return Limit{
+		Requests: n,
+		Period:   d,
+	}, nil
+}
+
+func (l Limit) String() string {
+	for name, period := range limitPeriods {
+		if period == l.Period {
+			return fmt.Sprintf("%d/%s", l.Requests, name)
+		}
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// Bucket is state of client's token bucket kept by Store.
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// Result is outcome of taking token from bucket.
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining is number of requests allowed right away.
+	Remaining int
+	// Reset is time until bucket is full again.
+	Reset time.Duration
+	// RetryAfter is time until next request is allowed, it's zero if request is allowed.
+	RetryAfter time.Duration
+}
+
+// Take refills bucket for time passed since its update and takes token from it if there is one.
+// Nil bucket is treated as full. Returns updated bucket and result.
+func (l Limit) Take(bucket *Bucket, now time.Time) (*Bucket, Result) {
+	capacity := float64(l.Requests)
+	perToken := l.Period / time.Duration(l.Requests)
+
+	tokens := capacity
+	if bucket != nil {
+		elapsed := now.Sub(bucket.UpdatedAt)
+		if elapsed < 0 {
+			// clocks of replicas sharing bucket may differ slightly
+			elapsed = 0
+		}
+		tokens = math.Min(capacity, bucket.Tokens+float64(elapsed)/float64(perToken))
+	}
+
+	result := Result{
+		Limit: l.Requests,
+	}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - tokens) * float64(perToken))
+	}
+	result.Remaining = int(tokens)
+	result.Reset = time.Duration((capacity - tokens) * float64(perToken))
+
+	return &Bucket{
+		Tokens:    tokens,
+		UpdatedAt: now,
+	}, result
+}
+
+// Store keeps buckets of clients. Stores shared between replicas make limits apply to all of them together.
+type Store interface {
+	// Take takes token from bucket of key atomically.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// IdleTTL is time after which unused bucket may be removed from store. Buckets of supported limits are
+// full long before it, so removing them doesn't change results.
+const IdleTTL = 24 * time.Hour
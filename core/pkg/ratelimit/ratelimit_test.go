@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	t.Parallel()
+
+	limit, err := ParseLimit(" 5/m ")
+	require.NoError(t, err)
+	assert.Equal(t, Limit{Requests: 5, Period: time.Minute}, limit)
+	assert.Equal(t, "5/m", limit.String())
+
+	for _, s := range []string{"", "5", "0/m", "-1/s", "5/d", "x/m"} {
+		_, err = ParseLimit(s)
+		assert.ErrorIs(t, err, ErrInvalidLimit, s)
+	}
+}
+
+func TestLimit_Take(t *testing.T) {
+	t.Parallel()
+
+	limit := Limit{Requests: 2, Period: time.Minute}
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	bucket, result := limit.Take(nil, now)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 30 * time.Second}, result)
+
+	bucket, result = limit.Take(bucket, now)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: time.Minute}, result)
+
+	bucket, result = limit.Take(bucket, now.Add(10*time.Second))
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 20*time.Second, result.RetryAfter)
+
+	// a token is refilled every 30 seconds
+	_, result = limit.Take(bucket, now.Add(30*time.Second))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	limit := Limit{Requests: 1, Period: time.Hour}
+	result, err := store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// buckets of different keys are independent
+	result, err = store.Take(ctx, "b", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	now = now.Add(IdleTTL + time.Minute)
+	_, err = store.Take(ctx, "c", limit)
+	require.NoError(t, err)
+	assert.Len(t, store.buckets, 1)
+}
@@ -0,0 +1,223 @@
+package token
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHMAC_Sign(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		secret   string
+		payload  []string
+		expected string
+	}{
+		{
+			name:     "positive: payload parts",
+			secret:   "secret",
+			payload:  []string{"unsubscribe", "user@example.com"},
+			expected: "0i20y9vMghY0AEvAl8b--OTX7y0dEhkik9_LdRZTsvo",
+		},
+		{
+			name:     "positive: empty payload",
+			secret:   "secret",
+			expected: "-eZuF5tnR65UEI-C-K3os8Jddv0wr95sOVgixTAZYWk",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, NewHMAC(tc.secret).Sign(tc.payload...))
+		})
+	}
+}
+
+func TestHMAC_Verify(t *testing.T) {
+	t.Parallel()
+
+	h := NewHMAC("secret")
+	token := h.Sign("unsubscribe", "user@example.com")
+
+	testCases := []struct {
+		name     string
+		hmac     *HMAC
+		token    string
+		payload  []string
+		expected bool
+	}{
+		{
+			name:     "positive: same payload",
+			hmac:     h,
+			token:    token,
+			payload:  []string{"unsubscribe", "user@example.com"},
+			expected: true,
+		},
+		{
+			name:    "negative: another payload",
+			hmac:    h,
+			token:   token,
+			payload: []string{"unsubscribe", "other@example.com"},
+		},
+		{
+			name:    "negative: payload parts are joined differently",
+			hmac:    h,
+			token:   h.Sign("a", "bc"),
+			payload: []string{"ab", "c"},
+		},
+		{
+			name:    "negative: another secret",
+			hmac:    NewHMAC("other-secret"),
+			token:   token,
+			payload: []string{"unsubscribe", "user@example.com"},
+		},
+		{
+			name:    "negative: tampered token",
+			hmac:    h,
+			token:   "A" + token[1:],
+			payload: []string{"unsubscribe", "user@example.com"},
+		},
+		{
+			name:    "negative: malformed token",
+			hmac:    h,
+			token:   "not base64!",
+			payload: []string{"unsubscribe", "user@example.com"},
+		},
+		{
+			name:    "negative: empty token",
+			hmac:    h,
+			payload: []string{"unsubscribe", "user@example.com"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, tc.hmac.Verify(tc.token, tc.payload...))
+		})
+	}
+}
+
+func TestHMAC_SignExpiring(t *testing.T) {
+	t.Parallel()
+
+	h := NewHMAC("secret")
+	now := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(time.Hour)
+	token := h.SignExpiring(expiresAt, "confirm", "user@example.com")
+
+	// expiration time can't be extended without breaking signature
+	exp, sig, ok := strings.Cut(token, ".")
+	require.True(t, ok)
+	extended := strconv.FormatInt(expiresAt.Add(time.Hour).Unix(), 10) + "." + sig
+	require.NotEqual(t, exp+"."+sig, extended)
+
+	testCases := []struct {
+		name        string
+		token       string
+		now         time.Time
+		payload     []string
+		expectedErr error
+	}{
+		{
+			name:    "positive: before expiration",
+			token:   token,
+			now:     now,
+			payload: []string{"confirm", "user@example.com"},
+		},
+		{
+			name:    "positive: at expiration",
+			token:   token,
+			now:     expiresAt,
+			payload: []string{"confirm", "user@example.com"},
+		},
+		{
+			name:        "negative: expired",
+			token:       token,
+			now:         expiresAt.Add(time.Second),
+			payload:     []string{"confirm", "user@example.com"},
+			expectedErr: ErrExpired,
+		},
+		{
+			name:        "negative: another payload",
+			token:       token,
+			now:         now,
+			payload:     []string{"confirm", "other@example.com"},
+			expectedErr: ErrInvalid,
+		},
+		{
+			name:        "negative: extended expiration",
+			token:       extended,
+			now:         now,
+			payload:     []string{"confirm", "user@example.com"},
+			expectedErr: ErrInvalid,
+		},
+		{
+			name:        "negative: token of Sign",
+			token:       h.Sign("confirm", "user@example.com"),
+			now:         now,
+			payload:     []string{"confirm", "user@example.com"},
+			expectedErr: ErrInvalid,
+		},
+		{
+			name:        "negative: expiration isn't number",
+			token:       "soon." + h.Sign("confirm", "user@example.com", "soon"),
+			now:         now,
+			payload:     []string{"confirm", "user@example.com"},
+			expectedErr: ErrInvalid,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := h.VerifyExpiring(tc.token, tc.now, tc.payload...)
+			if tc.expectedErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			}
+
+			expiration, err := h.VerifyExpiration(tc.token, tc.now, tc.payload...)
+			if tc.expectedErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, expiresAt.Unix(), expiration.Unix())
+			} else {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.True(t, expiration.IsZero())
+			}
+		})
+	}
+}
+
+func TestHMAC_SignExpiring_KeepsPayload(t *testing.T) {
+	t.Parallel()
+
+	h := NewHMAC("secret")
+	now := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	// spare capacity must not be used for expiration time
+	payload := make([]string, 2, 3)
+	payload[0], payload[1] = "confirm", "user@example.com"
+	backing := payload[:3]
+
+	token := h.SignExpiring(now.Add(time.Hour), payload...)
+	assert.Empty(t, backing[2])
+
+	_, err := h.VerifyExpiration(token, now, payload...)
+	require.NoError(t, err)
+	assert.Empty(t, backing[2])
+}
@@ -12,8 +12,10 @@ type (
 	// Config - represent top level application configuration object.
 	Config struct {
 		App
+		API
 		Log
 		CoinAPI
+		RateCache
 	}
 
 	App struct {
@@ -21,6 +23,15 @@ type (
 		HTTPReadTimeout     int    `env:"GSES_READ_TIMEOUT" env-default:"60"`
 		HTTPWriteTimeout    int    `env:"GSES_WRITE_TIMEOUT" env-default:"60"`
 		HTTPShutdownTimeout int    `env:"GSES_SHUTDOWN_TIMEOUT" env-default:"60"`
+		// MetricsAddr is address of separate listener serving expvar variables at "/debug/vars", e.g.
+		// "127.0.0.1:9091". They are not served if it's empty, because they expose internals of process.
+		MetricsAddr string `env:"GSES_METRICS_ADDR"`
+	}
+
+	// API - represents deprecation of unversioned "/api" alias of "/api/v1", see apiversion.ParseDeprecation.
+	API struct {
+		UnversionedDeprecatedSince string `env:"GSES_API_UNVERSIONED_DEPRECATED_SINCE"`
+		UnversionedSunset          string `env:"GSES_API_UNVERSIONED_SUNSET"`
 	}
 
 	// Log - represents logger configuration.
@@ -28,6 +39,19 @@ type (
 		Level string `env:"GSES_LOG_LEVEL" env-default:"debug"`
 	}
 
+	// RateCache - represents configuration of cache of rates fetched from providers.
+	RateCache struct {
+		// TTL is number of seconds during which cached rate is returned without requesting providers.
+		TTL int `env:"GSES_RATE_CACHE_TTL" env-default:"60"`
+		// MaxStaleness is number of seconds since rate was fetched during which it's returned as stale if
+		// providers fail.
+		MaxStaleness int `env:"GSES_RATE_CACHE_MAX_STALENESS" env-default:"600"`
+		// FetchTimeout is number of seconds request of rates to providers may take. Request is shared by
+		// concurrent misses, so it doesn't end when client that started it disconnects. Waiting clients get
+		// error after timeout, even if provider doesn't respond.
+		FetchTimeout int `env:"GSES_RATE_CACHE_FETCH_TIMEOUT" env-default:"10"`
+	}
+
 	// CoinAPI - represents configuration for account at https://coinapi.io.
 	CoinAPI struct {
 		Key string `env:"GSES_COIN_API_KEY" env-default:"F9326003-515F-4655-A9A8-2ACF5D8E900F"`
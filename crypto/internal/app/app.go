@@ -1,6 +1,8 @@
 package app
 
 import (
+	"expvar"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -13,6 +15,7 @@ import (
 	"github.com/vadimpk/gses-2023/crypto/internal/crypto_provider/coinapi"
 	"github.com/vadimpk/gses-2023/crypto/internal/crypto_provider/coinbase"
 	"github.com/vadimpk/gses-2023/crypto/internal/crypto_provider/coingecko"
+	"github.com/vadimpk/gses-2023/pkg/apiversion"
 	"github.com/vadimpk/gses-2023/pkg/httpserver"
 	"github.com/vadimpk/gses-2023/pkg/logging"
 )
@@ -39,10 +42,22 @@ func Run(cfg *config.Config) {
 		Config:    cfg,
 	})
 
+	// counters of rate cache are served by metrics server, if it's enabled
+	expvar.Publish("rateCache", expvar.Func(func() interface{} {
+		return cryptoService.CacheStats()
+	}))
+
+	unversionedDeprecation, err := apiversion.ParseDeprecation(cfg.API.UnversionedDeprecatedSince,
+		cfg.API.UnversionedSunset, "/api/v1")
+	if err != nil {
+		logger.Fatal("failed to parse deprecation of unversioned api", "err", err)
+	}
+
 	handler := httpcontroller.New(httpcontroller.Options{
-		CryptoService: cryptoService,
-		Config:        cfg,
-		Logger:        logger,
+		CryptoService:          cryptoService,
+		Config:                 cfg,
+		Logger:                 logger,
+		UnversionedDeprecation: unversionedDeprecation,
 	})
 
 	// init and run http server
@@ -54,6 +69,20 @@ func Run(cfg *config.Config) {
 		httpserver.ShutdownTimeout(time.Second*time.Duration(cfg.App.HTTPShutdownTimeout)),
 	)
 
+	// metrics are served on separate listener, so that they aren't exposed with public API
+	var metricsNotify <-chan error
+	var metricsServer *httpserver.Server
+	if cfg.App.MetricsAddr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/debug/vars", expvar.Handler())
+		metricsServer = httpserver.New(
+			metricsMux,
+			httpserver.Addr(cfg.App.MetricsAddr),
+			httpserver.ShutdownTimeout(time.Second*time.Duration(cfg.App.HTTPShutdownTimeout)),
+		)
+		metricsNotify = metricsServer.Notify()
+	}
+
 	// waiting signal
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
@@ -64,11 +93,20 @@ func Run(cfg *config.Config) {
 
 	case err := <-httpServer.Notify():
 		logger.Error("app - Run - httpServer.Notify", "err", err)
+
+	case err := <-metricsNotify:
+		logger.Error("app - Run - metricsServer.Notify", "err", err)
 	}
 
 	// shutdown http server
-	err := httpServer.Shutdown()
+	err = httpServer.Shutdown()
 	if err != nil {
 		logger.Error("app - Run - httpServer.Shutdown", "err", err)
 	}
+	if metricsServer != nil {
+		err = metricsServer.Shutdown()
+		if err != nil {
+			logger.Error("app - Run - metricsServer.Shutdown", "err", err)
+		}
+	}
 }
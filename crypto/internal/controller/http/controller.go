@@ -5,12 +5,18 @@ import (
 	"fmt"
 	"net/http"
 	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/DataDog/gostackparse"
 	"github.com/gin-gonic/gin"
 	"github.com/vadimpk/gses-2023/crypto/config"
 	"github.com/vadimpk/gses-2023/crypto/internal/crypto"
 	"github.com/vadimpk/gses-2023/crypto/internal/entity"
+	"github.com/vadimpk/gses-2023/pkg/apiversion"
+	"github.com/vadimpk/gses-2023/pkg/httpbind"
+	"github.com/vadimpk/gses-2023/pkg/httprender"
 	"github.com/vadimpk/gses-2023/pkg/logging"
 )
 
@@ -18,12 +24,44 @@ type Options struct {
 	CryptoService crypto.Service
 	Config        *config.Config
 	Logger        logging.Logger
+	// UnversionedDeprecation marks routes under unversioned "/api" alias as deprecated, if it's set.
+	UnversionedDeprecation *apiversion.Deprecation
 }
 
+const (
+	// apiBasePath is unversioned alias of v1 kept for clients that don't use versions.
+	apiBasePath = "/api"
+	apiV1Path   = "/api/v1"
+	// apiV2Path serves the same routes as v1, except that "/rate" responds with object instead of naked number.
+	apiV2Path = "/api/v2"
+)
+
 func New(opts Options) *gin.Engine {
 	r := gin.Default()
 
-	setupCryptoRoutes(&opts, r.Group("/api"))
+	doc := newOpenAPIDocument()
+	// routes are served under "/api/v2", "/api/v1" and "/api", which is alias of v1 kept for clients that
+	// don't use versions
+	for _, version := range []struct {
+		basePath    string
+		deprecation *apiversion.Deprecation
+		// nakedRate makes "/rate" respond with naked number, so that existing clients can still decode it.
+		nakedRate bool
+	}{
+		{basePath: apiV2Path},
+		{basePath: apiV1Path, nakedRate: true},
+		{basePath: apiBasePath, deprecation: opts.UnversionedDeprecation, nakedRate: true},
+	} {
+		router := r.Group(version.basePath)
+		if version.deprecation != nil {
+			router.Use(version.deprecation.Middleware(opts.Logger, router))
+		}
+
+		setupCryptoRoutes(&opts, router, version.nakedRate)
+		if err := setupDocsRoutes(router, doc); err != nil {
+			opts.Logger.Error("failed to setup docs routes", "err", err)
+		}
+	}
 
 	return r
 }
@@ -34,32 +72,74 @@ type cryptoRoutes struct {
 	logger        logging.Logger
 }
 
-func setupCryptoRoutes(opts *Options, router *gin.RouterGroup) {
+func setupCryptoRoutes(opts *Options, router *gin.RouterGroup, nakedRate bool) {
 	cryptoRoutes := cryptoRoutes{
 		cryptoService: opts.CryptoService,
 		config:        opts.Config,
 		logger:        opts.Logger.Named("Crypto"),
 	}
 
-	router.GET("/rate", wrapHandler(opts.Logger, cryptoRoutes.getRate))
+	getRate := cryptoRoutes.getRate
+	if nakedRate {
+		getRate = cryptoRoutes.getNakedRate
+	}
+	router.GET("/rate", wrapHandler(opts.Logger, getRate))
+	router.GET("/rates", wrapHandler(opts.Logger, cryptoRoutes.getRates))
+	router.POST("/rates", wrapHandler(opts.Logger, cryptoRoutes.getRates))
 }
 
 type getRateRequestQuery struct {
-	CryptoCurrency string `form:"crypto_currency" binding:"required"`
-	FiatCurrency   string `form:"fiat_currency" binding:"required"`
+	CryptoCurrency string `form:"crypto_currency" json:"crypto_currency" binding:"required"`
+	FiatCurrency   string `form:"fiat_currency" json:"fiat_currency" binding:"required"`
+}
+
+type getRateResponseBody struct {
+	// Pair is currency pair of rate, e.g. "BTC-UAH".
+	Pair string `json:"pair"`
+	// Rate is price of one unit of crypto currency in fiat currency as decimal string, so that precision isn't
+	// lost by clients that parse numbers as floats.
+	Rate      string    `json:"rate"`
+	Provider  string    `json:"provider"`
+	FetchedAt time.Time `json:"fetched_at"`
+	// CacheAge is number of seconds since rate was fetched from provider.
+	CacheAge int64 `json:"cache_age"`
+	// Stale is true if rate couldn't be refreshed and previously fetched rate is returned.
+	Stale bool `json:"stale"`
+}
+
+func newGetRateResponseBody(rate *entity.Rate) getRateResponseBody {
+	return getRateResponseBody{
+		Pair:      rate.Pair.String(),
+		Rate:      strconv.FormatFloat(rate.Value, 'f', -1, 64),
+		Provider:  rate.Provider,
+		FetchedAt: rate.FetchedAt.UTC(),
+		CacheAge:  int64(time.Since(rate.FetchedAt) / time.Second),
+		Stale:     rate.Stale,
+	}
 }
 
 func (r *cryptoRoutes) getRate(c *gin.Context) (interface{}, *httpResponseError) {
-	logger := r.logger.Named("getRate")
+	rate, errResp := r.rate(c, r.logger.Named("getRate"))
+	if errResp != nil {
+		return nil, errResp
+	}
+	return newGetRateResponseBody(rate), nil
+}
 
+// getNakedRate responds with rate as naked number, e.g. 1234.5, which is response of "/rate" before v2.
+func (r *cryptoRoutes) getNakedRate(c *gin.Context) (interface{}, *httpResponseError) {
+	rate, errResp := r.rate(c, r.logger.Named("getNakedRate"))
+	if errResp != nil {
+		return nil, errResp
+	}
+	return rate.Value, nil
+}
+
+func (r *cryptoRoutes) rate(c *gin.Context, logger logging.Logger) (*entity.Rate, *httpResponseError) {
 	var query getRateRequestQuery
-	if err := c.ShouldBindQuery(&query); err != nil {
-		logger.Info("failed to bind query", "err", err)
-		return nil, &httpResponseError{
-			Type:    ErrorTypeClient,
-			Message: "failed to bind query",
-			Details: err.Error(),
-		}
+	if errResp := bindRequest(c, &query); errResp != nil {
+		logger.Info("failed to bind request", "err", errResp)
+		return nil, errResp
 	}
 	logger = logger.With("query", query)
 
@@ -82,6 +162,94 @@ func (r *cryptoRoutes) getRate(c *gin.Context) (interface{}, *httpResponseError)
 	return rate, nil
 }
 
+type getRatesRequestQuery struct {
+	// Pairs is comma separated list of currency pairs, e.g. "BTC-UAH,ETH-USD".
+	Pairs string `form:"pairs" json:"pairs" binding:"required"`
+}
+
+type getRatesResponseBody struct {
+	// Rates are results of pairs in requested order, duplicate pairs are returned once.
+	Rates []getRatesResponseItem `json:"rates"`
+}
+
+type getRatesResponseItem struct {
+	Pair string               `json:"pair"`
+	Rate *getRateResponseBody `json:"rate,omitempty"`
+	// Error is set instead of Rate if rate of pair couldn't be got.
+	Error string `json:"error,omitempty"`
+}
+
+func (r *cryptoRoutes) getRates(c *gin.Context) (interface{}, *httpResponseError) {
+	logger := r.logger.Named("getRates")
+
+	var query getRatesRequestQuery
+	if errResp := bindRequest(c, &query); errResp != nil {
+		logger.Info("failed to bind request", "err", errResp)
+		return nil, errResp
+	}
+	logger = logger.With("query", query)
+
+	var (
+		items []getRatesResponseItem
+		pairs []entity.CurrencyPair
+		// index of item of each valid pair
+		indexes = make(map[entity.CurrencyPair]int)
+	)
+	for _, s := range strings.Split(query.Pairs, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		pair, err := entity.ParseCurrencyPair(s)
+		if err != nil {
+			items = append(items, getRatesResponseItem{Pair: s, Error: entity.ErrInvalidCurrencyPair.Error()})
+			continue
+		}
+		if _, ok := indexes[pair]; ok {
+			continue
+		}
+		indexes[pair] = len(items)
+		items = append(items, getRatesResponseItem{Pair: pair.String()})
+		pairs = append(pairs, pair)
+	}
+	if len(items) > crypto.MaxRatesPairs {
+		logger.Info("too many pairs", "count", len(items))
+		return nil, &httpResponseError{
+			Type:    ErrorTypeClient,
+			Message: fmt.Sprintf("too many pairs, at most %d are allowed", crypto.MaxRatesPairs),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	if len(pairs) > 0 {
+		results, err := r.cryptoService.GetRates(c.Request.Context(), &crypto.GetRatesOptions{
+			Pairs: pairs,
+		})
+		if err != nil {
+			logger.Error("failed to get rates", "err", err)
+			return nil, &httpResponseError{
+				Type:    ErrorTypeServer,
+				Message: "failed to get rates",
+				Details: err.Error(),
+			}
+		}
+
+		for _, result := range results {
+			item := &items[indexes[result.Pair]]
+			if result.Err != nil {
+				item.Error = "failed to get rate"
+				continue
+			}
+			rate := newGetRateResponseBody(result.Rate)
+			item.Rate = &rate
+		}
+	}
+
+	logger.Info("successfully got rates")
+	return getRatesResponseBody{Rates: items}, nil
+}
+
 type httpResponseError struct {
 	Type    httpErrType `json:"-"`
 	Message string      `json:"message"`
@@ -102,6 +270,19 @@ func (e httpResponseError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Type, e.Message)
 }
 
+// bindRequest binds parameters of request to obj and validates them, see httpbind.Bind.
+func bindRequest(c *gin.Context, obj interface{}) *httpResponseError {
+	if err := httpbind.Bind(c, obj); err != nil {
+		return &httpResponseError{
+			Code:    err.Code,
+			Type:    ErrorTypeClient,
+			Message: err.Message,
+			Details: err.Details,
+		}
+	}
+	return nil
+}
+
 func wrapHandler(logger logging.Logger, handler func(c *gin.Context) (interface{}, *httpResponseError)) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		logger := logger.Named("wrapHandler")
@@ -138,15 +319,17 @@ func wrapHandler(logger logging.Logger, handler func(c *gin.Context) (interface{
 		if err != nil {
 			if err.Type == ErrorTypeServer {
 				logger.Error("internal server error")
-				c.AbortWithStatusJSON(http.StatusInternalServerError, err)
+				c.Abort()
+				httprender.Render(c, http.StatusInternalServerError, err)
 			} else {
 				logger.Info("client error")
-				c.AbortWithStatusJSON(err.Code, err)
+				c.Abort()
+				httprender.Render(c, err.Code, err)
 			}
 			return
 		}
 
 		logger.Info("request handled")
-		c.JSON(http.StatusOK, body)
+		httprender.Render(c, http.StatusOK, body)
 	}
 }
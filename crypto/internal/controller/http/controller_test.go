@@ -0,0 +1,205 @@
+package httpcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/crypto/config"
+	"github.com/vadimpk/gses-2023/crypto/internal/crypto"
+	"github.com/vadimpk/gses-2023/crypto/internal/entity"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+type fakeCryptoService struct {
+	crypto.Service
+	getRate  func(ctx context.Context, opts *crypto.GetRateOptions) (*entity.Rate, error)
+	getRates func(ctx context.Context, opts *crypto.GetRatesOptions) ([]*crypto.RateResult, error)
+}
+
+func (s *fakeCryptoService) GetRate(ctx context.Context, opts *crypto.GetRateOptions) (*entity.Rate, error) {
+	return s.getRate(ctx, opts)
+}
+
+func (s *fakeCryptoService) GetRates(ctx context.Context, opts *crypto.GetRatesOptions) ([]*crypto.RateResult, error) {
+	return s.getRates(ctx, opts)
+}
+
+func TestGetRate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fetchedAt := time.Now().Add(-90 * time.Second)
+
+	r := New(Options{
+		Config: &config.Config{},
+		Logger: logging.New("debug"),
+		CryptoService: &fakeCryptoService{
+			getRate: func(ctx context.Context, opts *crypto.GetRateOptions) (*entity.Rate, error) {
+				if opts.Fiat == entity.FiatCurrencyUSD {
+					return nil, errors.New("provider is unavailable")
+				}
+				return &entity.Rate{
+					Pair:      entity.CurrencyPair{Crypto: opts.Crypto, Fiat: opts.Fiat},
+					Value:     1234567.891,
+					Provider:  "coinbase",
+					FetchedAt: fetchedAt,
+					Stale:     true,
+				}, nil
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/rate?crypto_currency=BTC&fiat_currency=UAH", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body getRateResponseBody
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "BTC-UAH", body.Pair)
+	assert.Equal(t, "1234567.891", body.Rate)
+	assert.Equal(t, "coinbase", body.Provider)
+	assert.True(t, fetchedAt.Equal(body.FetchedAt))
+	assert.InDelta(t, 90, body.CacheAge, 1)
+	assert.True(t, body.Stale)
+
+	// v1 and unversioned alias respond with naked number as before v2
+	for _, basePath := range []string{apiV1Path, apiBasePath} {
+		req = httptest.NewRequest(http.MethodGet, basePath+"/rate?crypto_currency=BTC&fiat_currency=UAH", nil)
+		rec = httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, basePath)
+
+		var rate float64
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rate), basePath)
+		assert.Equal(t, 1234567.891, rate, basePath)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v2/rate?crypto_currency=BTC&fiat_currency=USD", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v2/rate?crypto_currency=BTC", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGetRates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var requested []entity.CurrencyPair
+	r := New(Options{
+		Config: &config.Config{},
+		Logger: logging.New("debug"),
+		CryptoService: &fakeCryptoService{
+			getRates: func(ctx context.Context, opts *crypto.GetRatesOptions) ([]*crypto.RateResult, error) {
+				requested = opts.Pairs
+
+				results := make([]*crypto.RateResult, len(opts.Pairs))
+				for i, pair := range opts.Pairs {
+					results[i] = &crypto.RateResult{Pair: pair}
+					if pair.Fiat == entity.FiatCurrencyUSD {
+						results[i].Err = errors.New("provider is unavailable")
+						continue
+					}
+					results[i].Rate = &entity.Rate{Pair: pair, Value: 100, Provider: "coingecko", FetchedAt: time.Now()}
+				}
+				return results, nil
+			},
+		},
+	})
+
+	testCases := []struct {
+		name          string
+		method        string
+		query         string
+		contentType   string
+		body          string
+		expectedCode  int
+		expectedPairs []entity.CurrencyPair
+		expected      []getRatesResponseItem
+	}{
+		{
+			name:         "positive: query with failed, invalid and duplicate pairs",
+			method:       http.MethodGet,
+			query:        "pairs=btc-uah,%20ETH-USD,BTC-EUR,BTC-UAH",
+			expectedCode: http.StatusOK,
+			expectedPairs: []entity.CurrencyPair{
+				{Crypto: entity.CryptoCurrencyBTC, Fiat: entity.FiatCurrencyUAH},
+				{Crypto: entity.CryptoCurrencyETH, Fiat: entity.FiatCurrencyUSD},
+			},
+			expected: []getRatesResponseItem{
+				{Pair: "BTC-UAH", Rate: &getRateResponseBody{Pair: "BTC-UAH", Rate: "100", Provider: "coingecko"}},
+				{Pair: "ETH-USD", Error: "failed to get rate"},
+				{Pair: "BTC-EUR", Error: "invalid currency pair"},
+			},
+		},
+		{
+			name:         "positive: json body",
+			method:       http.MethodPost,
+			contentType:  "application/json",
+			body:         `{"pairs": "ETH-UAH"}`,
+			expectedCode: http.StatusOK,
+			expectedPairs: []entity.CurrencyPair{
+				{Crypto: entity.CryptoCurrencyETH, Fiat: entity.FiatCurrencyUAH},
+			},
+			expected: []getRatesResponseItem{
+				{Pair: "ETH-UAH", Rate: &getRateResponseBody{Pair: "ETH-UAH", Rate: "100", Provider: "coingecko"}},
+			},
+		},
+		{
+			name:         "positive: only invalid pairs",
+			method:       http.MethodGet,
+			query:        "pairs=BTC",
+			expectedCode: http.StatusOK,
+			expected: []getRatesResponseItem{
+				{Pair: "BTC", Error: "invalid currency pair"},
+			},
+		},
+		{
+			name:         "negative: missing pairs",
+			method:       http.MethodGet,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "negative: too many pairs",
+			method:       http.MethodGet,
+			query:        "pairs=" + strings.Repeat("BTC,", crypto.MaxRatesPairs) + "ETH",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		requested = nil
+
+		req := httptest.NewRequest(tc.method, "/api/v1/rates?"+tc.query, strings.NewReader(tc.body))
+		if tc.contentType != "" {
+			req.Header.Set("Content-Type", tc.contentType)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, tc.expectedCode, rec.Code, tc.name)
+		if tc.expectedCode != http.StatusOK {
+			continue
+		}
+
+		var body getRatesResponseBody
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body), tc.name)
+		for _, item := range body.Rates {
+			if item.Rate != nil {
+				item.Rate.FetchedAt = time.Time{}
+			}
+		}
+		assert.Equal(t, tc.expectedPairs, requested, tc.name)
+		assert.Equal(t, tc.expected, body.Rates, tc.name)
+	}
+}
@@ -0,0 +1,86 @@
+package httpcontroller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vadimpk/gses-2023/pkg/openapi"
+)
+
+const (
+	openAPIPath   = "/openapi.json"
+	swaggerUIPath = "/docs"
+	// swaggerUIAssetsPath is where styles and scripts of Swagger UI are served, so that it works offline.
+	swaggerUIAssetsPath = swaggerUIPath + "/assets"
+)
+
+// newOpenAPIDocument describes every route of router. Routes are described here rather than next to
+// handlers, so that TestOpenAPIDocument catches routes that were added without description.
+func newOpenAPIDocument() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "gses-2023 crypto API",
+		Description: "Rates of crypto currencies from configured providers. Routes are also served under unversioned \"/api\" prefix, which is alias of \"/api/v1\".",
+		Version:     "2.0.0",
+	}, httpResponseError{})
+
+	doc.Add(openapi.Route{
+		Method:   http.MethodGet,
+		Path:     "/api/v2/rate",
+		Summary:  "Get rate of crypto currency in fiat currency",
+		Tags:     []string{"rates"},
+		Request:  getRateRequestQuery{},
+		Response: getRateResponseBody{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnsupportedMediaType, http.StatusInternalServerError},
+	})
+	doc.Add(openapi.Route{
+		Method:      http.MethodGet,
+		Path:        "/api/v1/rate",
+		Summary:     "Get rate of crypto currency in fiat currency as number",
+		Description: "Responds with naked number, e.g. 1234.5. Use \"/api/v2/rate\" to also get provider and time when rate was fetched.",
+		Tags:        []string{"rates"},
+		Request:     getRateRequestQuery{},
+		Response:    float64(0),
+		Errors:      []int{http.StatusBadRequest, http.StatusUnsupportedMediaType, http.StatusInternalServerError},
+	})
+
+	for _, basePath := range []string{apiV1Path, apiV2Path} {
+		for _, method := range []string{http.MethodGet, http.MethodPost} {
+			doc.Add(openapi.Route{
+				Method:      method,
+				Path:        basePath + "/rates",
+				Summary:     "Get rates of many currency pairs",
+				Description: "Returns rates of up to 50 pairs in requested order. Pairs that are invalid or whose rate couldn't be got have error instead of rate, so that they don't fail the whole request.",
+				Tags:        []string{"rates"},
+				Request:     getRatesRequestQuery{},
+				Response:    getRatesResponseBody{},
+				Errors:      []int{http.StatusBadRequest, http.StatusUnsupportedMediaType, http.StatusInternalServerError},
+			})
+		}
+	}
+
+	return doc
+}
+
+// setupDocsRoutes serves OpenAPI document of API and Swagger UI that shows it.
+func setupDocsRoutes(router *gin.RouterGroup, doc *openapi.Document) error {
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal openapi document: %w", err)
+	}
+	page, err := openapi.SwaggerUI(doc.Info.Title, router.BasePath()+openAPIPath, router.BasePath()+swaggerUIAssetsPath)
+	if err != nil {
+		return fmt.Errorf("failed to render swagger ui: %w", err)
+	}
+
+	router.GET(openAPIPath, func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+	})
+	router.GET(swaggerUIPath, func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+	})
+	router.StaticFS(swaggerUIAssetsPath, http.FS(openapi.SwaggerUIAssets))
+
+	return nil
+}
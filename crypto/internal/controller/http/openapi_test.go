@@ -0,0 +1,48 @@
+package httpcontroller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/vadimpk/gses-2023/crypto/config"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := New(Options{
+		Config: &config.Config{},
+		Logger: logging.New("debug"),
+	})
+	doc := newOpenAPIDocument()
+
+	routes := make(map[string]bool)
+	for _, route := range r.Routes() {
+		// unversioned routes are aliases of v1 routes
+		path := route.Path
+		if !strings.HasPrefix(path, apiV1Path+"/") && !strings.HasPrefix(path, apiV2Path+"/") {
+			path = apiV1Path + strings.TrimPrefix(path, apiBasePath)
+		}
+		_, rel, _ := strings.Cut(strings.TrimPrefix(path, "/api/"), "/")
+		if "/"+rel == openAPIPath || "/"+rel == swaggerUIPath || strings.HasPrefix("/"+rel, swaggerUIAssetsPath+"/") {
+			continue
+		}
+
+		routes[route.Method+" "+path] = true
+		assert.True(t, doc.Has(route.Method, path), "route %s %s is not described in openapi document", route.Method, route.Path)
+	}
+
+	// document doesn't describe removed routes either
+	assert.Len(t, doc.Operations(), len(routes))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"/api/v1/rate"`)
+	assert.Contains(t, rec.Body.String(), `"/api/v2/rate"`)
+}
@@ -0,0 +1,41 @@
+package httpcontroller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/vadimpk/gses-2023/crypto/config"
+	"github.com/vadimpk/gses-2023/pkg/apiversion"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+func TestVersionedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := New(Options{
+		Config: &config.Config{},
+		Logger: logging.New("debug"),
+		UnversionedDeprecation: &apiversion.Deprecation{
+			Since:             time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
+			Sunset:            time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			SuccessorBasePath: apiV1Path,
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+
+	req = httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "@1688169600", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Mon, 01 Jan 2024 00:00:00 GMT", rec.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v1/openapi.json>; rel="successor-version"`, rec.Header().Get("Link"))
+}
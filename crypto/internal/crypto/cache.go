@@ -0,0 +1,71 @@
+package crypto
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vadimpk/gses-2023/crypto/internal/entity"
+)
+
+// CacheStats are counters of rate cache since start of service.
+type CacheStats struct {
+	// Hits is number of rates that were returned from cache without requesting providers.
+	Hits int64 `json:"hits"`
+	// Misses is number of rates that were requested from providers.
+	Misses int64 `json:"misses"`
+	// StaleHits is number of misses that failed and were answered with stale rate from cache.
+	StaleHits int64 `json:"stale_hits"`
+	// Coalesced is number of misses that joined request to providers started by concurrent miss of the same
+	// pair instead of making their own.
+	Coalesced int64 `json:"coalesced"`
+}
+
+// rateCache keeps last rate of each pair that was fetched from providers.
+type rateCache struct {
+	mu    sync.RWMutex
+	rates map[entity.CurrencyPair]entity.Rate
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	staleHits atomic.Int64
+	coalesced atomic.Int64
+}
+
+func newRateCache() *rateCache {
+	return &rateCache{
+		rates: make(map[entity.CurrencyPair]entity.Rate),
+	}
+}
+
+// get returns rate of pair if it was fetched not earlier than maxAge before now.
+func (c *rateCache) get(pair entity.CurrencyPair, now time.Time, maxAge time.Duration) (*entity.Rate, bool) {
+	c.mu.RLock()
+	rate, ok := c.rates[pair]
+	c.mu.RUnlock()
+
+	if !ok || now.Sub(rate.FetchedAt) > maxAge {
+		return nil, false
+	}
+	return &rate, true
+}
+
+func (c *rateCache) set(rate *entity.Rate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// rate fetched by slower request mustn't replace newer one
+	if cached, ok := c.rates[rate.Pair]; ok && cached.FetchedAt.After(rate.FetchedAt) {
+		return
+	}
+	c.rates[rate.Pair] = *rate
+}
+
+func (c *rateCache) stats() CacheStats {
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		StaleHits: c.staleHits.Load(),
+		Coalesced: c.coalesced.Load(),
+	}
+}
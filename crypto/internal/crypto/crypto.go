@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/vadimpk/gses-2023/crypto/config"
 	"github.com/vadimpk/gses-2023/crypto/internal/crypto_provider"
@@ -13,7 +15,10 @@ import (
 
 type Service interface {
 	// GetRate returns current rate for crypto currency.
-	GetRate(ctx context.Context, opts *GetRateOptions) (float64, error)
+	GetRate(ctx context.Context, opts *GetRateOptions) (*entity.Rate, error)
+	// GetRates returns current rates of many pairs in order of opts.Pairs. Failure to get rate of one pair
+	// is reported in its result and doesn't fail others.
+	GetRates(ctx context.Context, opts *GetRatesOptions) ([]*RateResult, error)
 }
 
 var (
@@ -38,6 +43,48 @@ func (o *GetRateOptions) Validate() error {
 	return nil
 }
 
+// MaxRatesPairs is maximum number of pairs in one GetRates call.
+const MaxRatesPairs = 50
+
+var (
+	ErrGetRatesNoPairs       = errors.New("no pairs")
+	ErrGetRatesTooManyPairs  = fmt.Errorf("more than %d pairs", MaxRatesPairs)
+	ErrGetRatesDuplicatePair = errors.New("duplicate pair")
+)
+
+type GetRatesOptions struct {
+	Pairs []entity.CurrencyPair
+}
+
+func (o *GetRatesOptions) Validate() error {
+	if len(o.Pairs) == 0 {
+		return ErrGetRatesNoPairs
+	}
+	if len(o.Pairs) > MaxRatesPairs {
+		return ErrGetRatesTooManyPairs
+	}
+
+	seen := make(map[entity.CurrencyPair]bool, len(o.Pairs))
+	for _, pair := range o.Pairs {
+		if !pair.IsValid() {
+			return fmt.Errorf("%w: %q", entity.ErrInvalidCurrencyPair, pair.String())
+		}
+		if seen[pair] {
+			return fmt.Errorf("%w: %q", ErrGetRatesDuplicatePair, pair.String())
+		}
+		seen[pair] = true
+	}
+
+	return nil
+}
+
+// RateResult is rate of pair or error that occurred while getting it.
+type RateResult struct {
+	Pair entity.CurrencyPair
+	Rate *entity.Rate
+	Err  error
+}
+
 type Options struct {
 	Providers crypto_provider.CryptoAPIProviders
 	Logger    logging.Logger
@@ -48,38 +95,212 @@ type cryptoService struct {
 	logger    logging.Logger
 	cfg       *config.Config
 	providers crypto_provider.CryptoAPIProviders
+
+	cache *rateCache
+	// flights are requests to providers in progress, concurrent misses of the same pair wait for them
+	// instead of making their own requests
+	flightsMu    sync.Mutex
+	flights      map[entity.CurrencyPair]*flight
+	cacheTTL     time.Duration
+	maxStaleness time.Duration
+	fetchTimeout time.Duration
+	now          func() time.Time
 }
 
+// defaultFetchTimeout is used if fetch timeout isn't configured, so that requests to providers can't hang forever.
+const defaultFetchTimeout = 10 * time.Second
+
 func NewCryptoService(opts Options) *cryptoService {
+	fetchTimeout := time.Duration(opts.Config.RateCache.FetchTimeout) * time.Second
+	if fetchTimeout <= 0 {
+		fetchTimeout = defaultFetchTimeout
+	}
+
 	return &cryptoService{
-		providers: opts.Providers,
-		logger:    opts.Logger.Named("Crypto"),
-		cfg:       opts.Config,
+		providers:    opts.Providers,
+		logger:       opts.Logger.Named("Crypto"),
+		cfg:          opts.Config,
+		cache:        newRateCache(),
+		flights:      make(map[entity.CurrencyPair]*flight),
+		cacheTTL:     time.Duration(opts.Config.RateCache.TTL) * time.Second,
+		maxStaleness: time.Duration(opts.Config.RateCache.MaxStaleness) * time.Second,
+		fetchTimeout: fetchTimeout,
+		now:          time.Now,
 	}
 }
 
-func (s *cryptoService) GetRate(ctx context.Context, opts *GetRateOptions) (float64, error) {
+// CacheStats returns counters of rate cache.
+func (s *cryptoService) CacheStats() CacheStats {
+	return s.cache.stats()
+}
+
+func (s *cryptoService) GetRate(ctx context.Context, opts *GetRateOptions) (*entity.Rate, error) {
 	logger := s.logger.Named("GetRate").
 		WithContext(ctx).
 		With("opts", opts)
 
 	if err := opts.Validate(); err != nil {
 		logger.Info(err.Error())
-		return 0, err
+		return nil, err
+	}
+
+	pair := entity.CurrencyPair{Crypto: opts.Crypto, Fiat: opts.Fiat}
+	if rate, ok := s.cache.get(pair, s.now(), s.cacheTTL); ok {
+		s.cache.hits.Add(1)
+		logger.With("rate", rate).Info("successfully got rate from cache")
+		return rate, nil
+	}
+	s.cache.misses.Add(1)
+
+	result, err := s.fetch([]entity.CurrencyPair{pair})[pair].wait(ctx)
+	if err != nil {
+		if rate, ok := s.staleRate(pair); ok {
+			logger.Warn("failed to get rate, stale rate is returned", "err", err, "rate", rate)
+			return rate, nil
+		}
+		logger.Error("failed to get rate", "err", err)
+		return nil, err
+	}
+
+	// result is shared with concurrent callers, so that each of them gets its own copy
+	rate := *result
+	logger.With("provider", rate.Provider).Info("successfully got rate")
+	return &rate, nil
+}
+
+func (s *cryptoService) GetRates(ctx context.Context, opts *GetRatesOptions) ([]*RateResult, error) {
+	logger := s.logger.Named("GetRates").
+		WithContext(ctx).
+		With("opts", opts)
+
+	if err := opts.Validate(); err != nil {
+		logger.Info(err.Error())
+		return nil, err
 	}
 
+	now := s.now()
+	results := make([]*RateResult, len(opts.Pairs))
+	var missing []entity.CurrencyPair
+	for i, pair := range opts.Pairs {
+		results[i] = &RateResult{Pair: pair}
+		if rate, ok := s.cache.get(pair, now, s.cacheTTL); ok {
+			s.cache.hits.Add(1)
+			results[i].Rate = rate
+			continue
+		}
+		s.cache.misses.Add(1)
+		missing = append(missing, pair)
+	}
+	if len(missing) == 0 {
+		logger.Info("successfully got rates from cache")
+		return results, nil
+	}
+
+	flights := s.fetch(missing)
+	failed := 0
+	for _, result := range results {
+		if result.Rate != nil {
+			continue
+		}
+
+		rate, err := flights[result.Pair].wait(ctx)
+		if err == nil {
+			// result is shared with concurrent callers, so that each of them gets its own copy
+			rateCopy := *rate
+			result.Rate = &rateCopy
+			continue
+		}
+		result.Err = err
+
+		if rate, ok := s.staleRate(result.Pair); ok {
+			logger.Warn("failed to get rate, stale rate is returned", "pair", result.Pair.String(), "err", result.Err)
+			result.Rate, result.Err = rate, nil
+			continue
+		}
+		failed++
+		logger.Error("failed to get rate", "pair", result.Pair.String(), "err", result.Err)
+	}
+
+	logger.With("missed", len(missing)).With("failed", failed).Info("successfully got rates")
+	return results, nil
+}
+
+// staleRate returns cached rate of pair marked as stale, if it's not older than max staleness.
+func (s *cryptoService) staleRate(pair entity.CurrencyPair) (*entity.Rate, bool) {
+	rate, ok := s.cache.get(pair, s.now(), s.maxStaleness)
+	if !ok {
+		return nil, false
+	}
+
+	s.cache.staleHits.Add(1)
+	rate.Stale = true
+	return rate, true
+}
+
+// fetchRate gets rate of pair from providers and caches it.
+func (s *cryptoService) fetchRate(ctx context.Context, pair entity.CurrencyPair) (*entity.Rate, error) {
 	chain, err := crypto_provider.NewCryptoAPIChain(s.providers)
 	if err != nil {
-		logger.Error("failed to create crypto api chain", "err", err)
-		return 0, fmt.Errorf("failed to create crypto api chain: %w", err)
+		return nil, fmt.Errorf("failed to create crypto api chain: %w", err)
 	}
 
-	rate, err := chain.GetRate(ctx, opts.Crypto.String(), opts.Fiat.String())
+	value, provider, err := chain.GetRate(ctx, pair.Crypto.String(), pair.Fiat.String())
 	if err != nil {
-		logger.Error("failed to get rate", "err", err)
-		return 0, fmt.Errorf("failed to get rate from api: %w", err)
+		return nil, fmt.Errorf("failed to get rate from api: %w", err)
+	}
+
+	rate := &entity.Rate{
+		Pair:      pair,
+		Value:     value,
+		Provider:  string(provider),
+		FetchedAt: s.now(),
 	}
+	s.cache.set(rate)
 
-	logger.Info("successfully got rate")
 	return rate, nil
 }
+
+// fetchRates gets rates of pairs from providers at once and caches them.
+func (s *cryptoService) fetchRates(ctx context.Context, pairs []entity.CurrencyPair) map[entity.CurrencyPair]*RateResult {
+	results := make(map[entity.CurrencyPair]*RateResult, len(pairs))
+
+	chain, err := crypto_provider.NewCryptoAPIChain(s.providers)
+	if err != nil {
+		for _, pair := range pairs {
+			results[pair] = &RateResult{Pair: pair, Err: fmt.Errorf("failed to create crypto api chain: %w", err)}
+		}
+		return results
+	}
+
+	providerPairs := make([]crypto_provider.Pair, len(pairs))
+	for i, pair := range pairs {
+		providerPairs[i] = crypto_provider.Pair{
+			FromCurrency: pair.Crypto.String(),
+			ToCurrency:   pair.Fiat.String(),
+		}
+	}
+
+	rates := chain.GetRates(ctx, providerPairs)
+	fetchedAt := s.now()
+
+	for i, pair := range pairs {
+		rate := rates[providerPairs[i]]
+		if rate.Err != nil {
+			results[pair] = &RateResult{Pair: pair, Err: fmt.Errorf("failed to get rate from api: %w", rate.Err)}
+			continue
+		}
+
+		results[pair] = &RateResult{
+			Pair: pair,
+			Rate: &entity.Rate{
+				Pair:      pair,
+				Value:     rate.Rate,
+				Provider:  string(rate.Provider),
+				FetchedAt: fetchedAt,
+			},
+		}
+		s.cache.set(results[pair].Rate)
+	}
+
+	return results
+}
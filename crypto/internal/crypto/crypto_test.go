@@ -0,0 +1,318 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/crypto/config"
+	"github.com/vadimpk/gses-2023/crypto/internal/crypto_provider"
+	"github.com/vadimpk/gses-2023/crypto/internal/entity"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+type fakeProvider struct {
+	calls   atomic.Int32
+	rate    atomic.Int64
+	failing atomic.Bool
+	// release blocks requests until it's closed, if it's set
+	release chan struct{}
+}
+
+func (p *fakeProvider) GetRate(ctx context.Context, fromCurrency, toCurrency string) (float64, error) {
+	p.calls.Add(1)
+	if p.release != nil {
+		<-p.release
+	}
+	if p.failing.Load() {
+		return 0, errors.New("provider is unavailable")
+	}
+	return float64(p.rate.Load()), nil
+}
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newTestService returns service that gets rates from provider only, other providers of chain always fail.
+func newTestService(provider *fakeProvider) (*cryptoService, *fakeClock) {
+	failing := &fakeProvider{}
+	failing.failing.Store(true)
+
+	cfg := &config.Config{}
+	cfg.RateCache.TTL = 60
+	cfg.RateCache.MaxStaleness = 600
+
+	s := NewCryptoService(Options{
+		Providers: crypto_provider.CryptoAPIProviders{
+			crypto_provider.CryptoAPIProviderCoinAPI:   provider,
+			crypto_provider.CryptoAPIProviderCoinGecko: failing,
+			crypto_provider.CryptoAPIProviderCoinbase:  failing,
+		},
+		Logger: logging.New("debug"),
+		Config: cfg,
+	})
+	clock := &fakeClock{now: time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)}
+	s.now = clock.Now
+
+	return s, clock
+}
+
+func TestCryptoService_GetRate_Cache(t *testing.T) {
+	t.Parallel()
+
+	provider := &fakeProvider{}
+	provider.rate.Store(100)
+	s, clock := newTestService(provider)
+
+	ctx := context.Background()
+	opts := &GetRateOptions{Crypto: entity.CryptoCurrencyBTC, Fiat: entity.FiatCurrencyUAH}
+	fetchedAt := clock.Now()
+
+	rate, err := s.GetRate(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, &entity.Rate{
+		Pair:      entity.CurrencyPair{Crypto: entity.CryptoCurrencyBTC, Fiat: entity.FiatCurrencyUAH},
+		Value:     100,
+		Provider:  string(crypto_provider.CryptoAPIProviderCoinAPI),
+		FetchedAt: fetchedAt,
+	}, rate)
+
+	// cached rate is returned within ttl
+	provider.rate.Store(200)
+	clock.Add(time.Minute)
+	rate, err = s.GetRate(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, rate.Value)
+	assert.EqualValues(t, 1, provider.calls.Load())
+
+	// rate is refreshed after ttl
+	clock.Add(time.Second)
+	rate, err = s.GetRate(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 200.0, rate.Value)
+	assert.False(t, rate.Stale)
+	assert.EqualValues(t, 2, provider.calls.Load())
+
+	// stale rate is returned if providers fail within max staleness
+	provider.failing.Store(true)
+	clock.Add(10 * time.Minute)
+	rate, err = s.GetRate(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 200.0, rate.Value)
+	assert.True(t, rate.Stale)
+
+	// error is returned after max staleness
+	clock.Add(time.Second)
+	_, err = s.GetRate(ctx, opts)
+	assert.Error(t, err)
+
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 4, StaleHits: 1}, s.CacheStats())
+}
+
+func TestCryptoService_GetRate_Coalescing(t *testing.T) {
+	t.Parallel()
+
+	provider := &fakeProvider{release: make(chan struct{})}
+	provider.rate.Store(100)
+	s, _ := newTestService(provider)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			rate, err := s.GetRate(context.Background(), &GetRateOptions{
+				Crypto: entity.CryptoCurrencyETH,
+				Fiat:   entity.FiatCurrencyUSD,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, 100.0, rate.Value)
+		}()
+	}
+
+	// let every caller miss cache and join request before provider responds
+	require.Eventually(t, func() bool {
+		return s.CacheStats().Coalesced == callers-1
+	}, time.Second, time.Millisecond)
+	close(provider.release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, provider.calls.Load())
+	assert.EqualValues(t, callers, s.CacheStats().Misses)
+}
+
+func TestCryptoService_GetRate_CoalescingCanceledCaller(t *testing.T) {
+	t.Parallel()
+
+	provider := &fakeProvider{release: make(chan struct{})}
+	provider.rate.Store(100)
+	s, _ := newTestService(provider)
+	opts := &GetRateOptions{Crypto: entity.CryptoCurrencyETH, Fiat: entity.FiatCurrencyUSD}
+
+	// the first caller starts request to providers and goes away before it's done
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := s.GetRate(ctx, opts)
+		first <- err
+	}()
+	require.Eventually(t, func() bool {
+		return provider.calls.Load() == 1
+	}, time.Second, time.Millisecond)
+
+	second := make(chan *entity.Rate, 1)
+	go func() {
+		rate, err := s.GetRate(context.Background(), opts)
+		assert.NoError(t, err)
+		second <- rate
+	}()
+	require.Eventually(t, func() bool {
+		return s.CacheStats().Coalesced == 1
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-first, context.Canceled)
+
+	// request isn't canceled with the first caller, so the second one gets rate
+	close(provider.release)
+	rate := <-second
+	require.NotNil(t, rate)
+	assert.Equal(t, 100.0, rate.Value)
+	assert.EqualValues(t, 1, provider.calls.Load())
+}
+
+func TestCryptoService_GetRates_Cache(t *testing.T) {
+	t.Parallel()
+
+	provider := &fakeProvider{}
+	provider.rate.Store(100)
+	s, clock := newTestService(provider)
+
+	ctx := context.Background()
+	btcUAH := entity.CurrencyPair{Crypto: entity.CryptoCurrencyBTC, Fiat: entity.FiatCurrencyUAH}
+	ethUSD := entity.CurrencyPair{Crypto: entity.CryptoCurrencyETH, Fiat: entity.FiatCurrencyUSD}
+
+	_, err := s.GetRate(ctx, &GetRateOptions{Crypto: btcUAH.Crypto, Fiat: btcUAH.Fiat})
+	require.NoError(t, err)
+
+	// only missing pair is requested from providers
+	provider.rate.Store(200)
+	clock.Add(time.Minute)
+	results, err := s.GetRates(ctx, &GetRatesOptions{Pairs: []entity.CurrencyPair{btcUAH, ethUSD}})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, 100.0, results[0].Rate.Value)
+	assert.Equal(t, 200.0, results[1].Rate.Value)
+	assert.EqualValues(t, 2, provider.calls.Load())
+
+	// failed pairs are returned as stale while cached rate isn't too old, and as error afterwards
+	provider.failing.Store(true)
+	clock.Add(2 * time.Minute)
+	results, err = s.GetRates(ctx, &GetRatesOptions{Pairs: []entity.CurrencyPair{btcUAH, ethUSD}})
+	require.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.True(t, results[0].Rate.Stale)
+	assert.NoError(t, results[1].Err)
+	assert.True(t, results[1].Rate.Stale)
+
+	clock.Add(8 * time.Minute)
+	results, err = s.GetRates(ctx, &GetRatesOptions{Pairs: []entity.CurrencyPair{btcUAH, ethUSD}})
+	require.NoError(t, err)
+	assert.Error(t, results[0].Err)
+	assert.Nil(t, results[0].Rate)
+	assert.True(t, results[1].Rate.Stale)
+}
+
+func TestCryptoService_GetRates_Coalescing(t *testing.T) {
+	t.Parallel()
+
+	provider := &fakeProvider{release: make(chan struct{})}
+	provider.rate.Store(100)
+	s, _ := newTestService(provider)
+
+	btcUAH := entity.CurrencyPair{Crypto: entity.CryptoCurrencyBTC, Fiat: entity.FiatCurrencyUAH}
+	ethUSD := entity.CurrencyPair{Crypto: entity.CryptoCurrencyETH, Fiat: entity.FiatCurrencyUSD}
+
+	const callers = 5
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			results, err := s.GetRates(context.Background(), &GetRatesOptions{
+				Pairs: []entity.CurrencyPair{btcUAH, ethUSD},
+			})
+			assert.NoError(t, err)
+			for _, result := range results {
+				assert.NoError(t, result.Err)
+				assert.Equal(t, 100.0, result.Rate.Value)
+			}
+		}()
+	}
+
+	// batches and single rates of the same pairs share requests too
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		rate, err := s.GetRate(context.Background(), &GetRateOptions{Crypto: ethUSD.Crypto, Fiat: ethUSD.Fiat})
+		assert.NoError(t, err)
+		assert.Equal(t, 100.0, rate.Value)
+	}()
+
+	require.Eventually(t, func() bool {
+		return s.CacheStats().Coalesced == 2*callers-1
+	}, time.Second, time.Millisecond)
+	close(provider.release)
+	wg.Wait()
+
+	// provider isn't batch one, so each pair is requested once
+	assert.EqualValues(t, 2, provider.calls.Load())
+}
+
+func TestCryptoService_GetRate_FetchTimeout(t *testing.T) {
+	t.Parallel()
+
+	// provider ignores context and hangs until it's released
+	provider := &fakeProvider{release: make(chan struct{})}
+	defer close(provider.release)
+	provider.rate.Store(100)
+	s, _ := newTestService(provider)
+	s.fetchTimeout = 50 * time.Millisecond
+	opts := &GetRateOptions{Crypto: entity.CryptoCurrencyBTC, Fiat: entity.FiatCurrencyUSD}
+
+	_, err := s.GetRate(context.Background(), opts)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// flight is released after timeout, so that the next miss makes its own request instead of joining it
+	s.flightsMu.Lock()
+	assert.Empty(t, s.flights)
+	s.flightsMu.Unlock()
+
+	_, err = s.GetRate(context.Background(), opts)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.EqualValues(t, 2, provider.calls.Load())
+	assert.Zero(t, s.CacheStats().Coalesced)
+}
@@ -0,0 +1,91 @@
+package crypto
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vadimpk/gses-2023/crypto/internal/entity"
+)
+
+// flight is request of rate of pair to providers, that concurrent misses of the pair wait for.
+type flight struct {
+	// done is closed when rate or err is set.
+	done chan struct{}
+	rate *entity.Rate
+	err  error
+}
+
+// wait returns result of flight, or error of ctx if it's done earlier.
+func (f *flight) wait(ctx context.Context) (*entity.Rate, error) {
+	select {
+	case <-f.done:
+		return f.rate, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetch returns flights of pairs. Pairs that are already requested join flights in progress, the rest are
+// requested from providers at once. Request isn't bound to context of any caller, so that caller that
+// goes away doesn't fail others waiting for the same flight, it's canceled after fetch timeout instead.
+func (s *cryptoService) fetch(pairs []entity.CurrencyPair) map[entity.CurrencyPair]*flight {
+	flights := make(map[entity.CurrencyPair]*flight, len(pairs))
+	var started []entity.CurrencyPair
+
+	s.flightsMu.Lock()
+	for _, pair := range pairs {
+		f, ok := s.flights[pair]
+		if ok {
+			s.cache.coalesced.Add(1)
+		} else {
+			f = &flight{done: make(chan struct{})}
+			s.flights[pair] = f
+			started = append(started, pair)
+		}
+		flights[pair] = f
+	}
+	s.flightsMu.Unlock()
+
+	if len(started) > 0 {
+		go s.land(started, flights)
+	}
+
+	return flights
+}
+
+// land requests pairs of started flights from providers and completes them. Flights are completed with error
+// once fetch timeout passes, even if provider doesn't return, so that later misses don't wait for it forever.
+func (s *cryptoService) land(pairs []entity.CurrencyPair, flights map[entity.CurrencyPair]*flight) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.fetchTimeout)
+	defer cancel()
+
+	// fetched is buffered, so that request that outlives timeout doesn't block after flights are completed
+	fetched := make(chan map[entity.CurrencyPair]*RateResult, 1)
+	go func() {
+		if len(pairs) == 1 {
+			rate, err := s.fetchRate(ctx, pairs[0])
+			fetched <- map[entity.CurrencyPair]*RateResult{pairs[0]: {Pair: pairs[0], Rate: rate, Err: err}}
+			return
+		}
+		fetched <- s.fetchRates(ctx, pairs)
+	}()
+
+	var results map[entity.CurrencyPair]*RateResult
+	select {
+	case results = <-fetched:
+	case <-ctx.Done():
+		results = make(map[entity.CurrencyPair]*RateResult, len(pairs))
+		for _, pair := range pairs {
+			results[pair] = &RateResult{Pair: pair, Err: fmt.Errorf("failed to get rate from api: %w", ctx.Err())}
+		}
+	}
+
+	s.flightsMu.Lock()
+	defer s.flightsMu.Unlock()
+	for _, pair := range pairs {
+		f := flights[pair]
+		f.rate, f.err = results[pair].Rate, results[pair].Err
+		delete(s.flights, pair)
+		close(f.done)
+	}
+}
@@ -22,6 +22,7 @@ func (c *coinAPI) GetRate(ctx context.Context, fromCurrency, toCurrency string)
 
 	var respBody getRateResponseBody
 	resp, err := c.client.R().
+		SetContext(ctx).
 		SetResult(&respBody).
 		Get(url)
 	logger = logger.With("responseBody", resp.String()).With("statusCode", resp.StatusCode())
@@ -24,6 +24,7 @@ func (c *coinbaseAPI) GetRate(ctx context.Context, fromCurrency, toCurrency stri
 
 	var respBody getRateResponseBody
 	resp, err := c.client.R().
+		SetContext(ctx).
 		SetQueryParams(map[string]string{
 			"currency": strings.ToUpper(fromCurrency),
 		}).
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+
+	"github.com/vadimpk/gses-2023/crypto/internal/crypto_provider"
 )
 
 type getRateResponseBody map[string]map[string]float64
@@ -21,6 +23,7 @@ func (c *coinGeckoAPI) GetRate(ctx context.Context, fromCurrency, toCurrency str
 
 	var respBody getRateResponseBody
 	resp, err := c.client.R().
+		SetContext(ctx).
 		SetQueryParams(map[string]string{
 			"ids":           strings.ToUpper(fromCurrency),
 			"vs_currencies": strings.ToUpper(toCurrency),
@@ -49,6 +52,66 @@ func (c *coinGeckoAPI) GetRate(ctx context.Context, fromCurrency, toCurrency str
 	return respBody[fromCurrency][toCurrency], nil
 }
 
+// GetRates returns rates of all pairs with one request, since /simple/price accepts many ids and vs_currencies.
+// Rates of all combinations of requested currencies are returned by API, but only requested pairs are kept.
+func (c *coinGeckoAPI) GetRates(ctx context.Context, pairs []crypto_provider.Pair) (map[crypto_provider.Pair]float64, error) {
+	logger := c.logger.
+		Named("GetRates").
+		WithContext(ctx).
+		With("pairs", pairs)
+
+	var ids, vsCurrencies []string
+	seen := make(map[string]bool)
+	for _, pair := range pairs {
+		id, vsCurrency := parseCurrency(pair.FromCurrency), parseCurrency(pair.ToCurrency)
+		if id == "" || vsCurrency == "" {
+			continue
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+		if !seen[vsCurrency] {
+			seen[vsCurrency] = true
+			vsCurrencies = append(vsCurrencies, vsCurrency)
+		}
+	}
+	if len(ids) == 0 || len(vsCurrencies) == 0 {
+		logger.Info("no supported pairs")
+		return map[crypto_provider.Pair]float64{}, nil
+	}
+
+	var respBody getRateResponseBody
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetQueryParams(map[string]string{
+			"ids":           strings.Join(ids, ","),
+			"vs_currencies": strings.Join(vsCurrencies, ","),
+		}).
+		SetResult(&respBody).
+		Get("/simple/price")
+	if err != nil {
+		logger.Error("failed to get rates", "err", err)
+		return nil, fmt.Errorf("failed to get rates: %w", err)
+	}
+	logger = logger.With("responseBody", resp.String()).With("statusCode", resp.StatusCode())
+
+	if resp.StatusCode() != http.StatusOK {
+		logger.Error("failed to get rates")
+		return nil, fmt.Errorf("failed to get rates: status %s", resp.Status())
+	}
+
+	rates := make(map[crypto_provider.Pair]float64, len(pairs))
+	for _, pair := range pairs {
+		if rate, ok := respBody[parseCurrency(pair.FromCurrency)][parseCurrency(pair.ToCurrency)]; ok {
+			rates[pair] = rate
+		}
+	}
+
+	logger.With("rates", len(rates)).Info("successfully got rates")
+	return rates, nil
+}
+
 func parseCurrency(c string) string {
 	switch c {
 	case "BTC":
@@ -0,0 +1,40 @@
+package coingecko
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/crypto/internal/crypto_provider"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+func TestCoinGeckoAPI_GetRates(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/simple/price", r.URL.Path)
+		assert.Equal(t, "bitcoin,ethereum", r.URL.Query().Get("ids"))
+		assert.Equal(t, "uah,usd", r.URL.Query().Get("vs_currencies"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"bitcoin": {"uah": 1000000, "usd": 30000}, "ethereum": {"uah": 70000}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	api := New(&Options{Logger: logging.New("debug")})
+	api.client.SetBaseURL(server.URL)
+
+	btcUAH := crypto_provider.Pair{FromCurrency: "BTC", ToCurrency: "UAH"}
+	ethUSD := crypto_provider.Pair{FromCurrency: "ETH", ToCurrency: "USD"}
+	ethUAH := crypto_provider.Pair{FromCurrency: "ETH", ToCurrency: "UAH"}
+	unknown := crypto_provider.Pair{FromCurrency: "DOGE", ToCurrency: "UAH"}
+
+	rates, err := api.GetRates(context.Background(), []crypto_provider.Pair{btcUAH, ethUSD, ethUAH, unknown})
+	require.NoError(t, err)
+	assert.Equal(t, map[crypto_provider.Pair]float64{btcUAH: 1000000, ethUAH: 70000}, rates)
+	assert.Equal(t, 1, requests)
+}
@@ -3,6 +3,7 @@ package crypto_provider
 import (
 	"context"
 	"fmt"
+	"sync"
 )
 
 // CryptoProvider provides methods for getting crypto rates that are used in CryptoService and
@@ -11,6 +12,18 @@ type CryptoProvider interface {
 	GetRate(ctx context.Context, fromCurrency, toCurrency string) (float64, error)
 }
 
+// BatchCryptoProvider is implemented by providers that can return rates of many pairs in one request.
+// Pairs that are missing in returned map are considered failed.
+type BatchCryptoProvider interface {
+	GetRates(ctx context.Context, pairs []Pair) (map[Pair]float64, error)
+}
+
+// Pair is pair of currencies, rate of FromCurrency in ToCurrency is requested.
+type Pair struct {
+	FromCurrency string
+	ToCurrency   string
+}
+
 // CryptoAPIProviderType represents type of 3rd party provider of crypto API.
 type CryptoAPIProviderType string
 
@@ -25,8 +38,9 @@ type CryptoAPIProviders map[CryptoAPIProviderType]CryptoProvider
 
 // CryptoAPIChain is used to create chain of responsibility for crypto APIs.
 type CryptoAPIChain struct {
-	api  CryptoProvider
-	next *CryptoAPIChain
+	provider CryptoAPIProviderType
+	api      CryptoProvider
+	next     *CryptoAPIChain
 }
 
 // NewCryptoAPIChain creates chain of responsibility for crypto APIs.
@@ -54,8 +68,9 @@ func (chain *CryptoAPIChain) build(providers CryptoAPIProviders, order ...Crypto
 			return nil, fmt.Errorf("unknown provider: %s", provider)
 		}
 		lastProvider = &CryptoAPIChain{
-			api:  api,
-			next: lastProvider,
+			provider: provider,
+			api:      api,
+			next:     lastProvider,
 		}
 	}
 
@@ -66,10 +81,82 @@ func (chain *CryptoAPIChain) build(providers CryptoAPIProviders, order ...Crypto
 	return lastProvider, nil
 }
 
-func (chain *CryptoAPIChain) GetRate(ctx context.Context, fromCurrency, toCurrency string) (float64, error) {
+// GetRate returns rate from first provider of chain that succeeded and type of that provider.
+func (chain *CryptoAPIChain) GetRate(ctx context.Context, fromCurrency, toCurrency string) (float64, CryptoAPIProviderType, error) {
 	rate, err := chain.api.GetRate(ctx, fromCurrency, toCurrency)
 	if err != nil && chain.next != nil {
 		return chain.next.GetRate(ctx, fromCurrency, toCurrency)
 	}
-	return rate, err
+	return rate, chain.provider, err
+}
+
+// PairRate is rate of pair returned by chain or error of last provider that failed to return it.
+type PairRate struct {
+	Rate     float64
+	Provider CryptoAPIProviderType
+	Err      error
+}
+
+// GetRates returns rates of pairs. Each provider gets pairs that previous providers failed to return, batch
+// providers get them in one request and other providers get each pair concurrently.
+func (chain *CryptoAPIChain) GetRates(ctx context.Context, pairs []Pair) map[Pair]*PairRate {
+	rates := chain.getRates(ctx, pairs)
+
+	var failed []Pair
+	for _, pair := range pairs {
+		if rates[pair].Err != nil {
+			failed = append(failed, pair)
+		}
+	}
+	if len(failed) > 0 && chain.next != nil {
+		for pair, rate := range chain.next.GetRates(ctx, failed) {
+			rates[pair] = rate
+		}
+	}
+
+	return rates
+}
+
+func (chain *CryptoAPIChain) getRates(ctx context.Context, pairs []Pair) map[Pair]*PairRate {
+	rates := make(map[Pair]*PairRate, len(pairs))
+
+	if batch, ok := chain.api.(BatchCryptoProvider); ok {
+		values, err := batch.GetRates(ctx, pairs)
+		for _, pair := range pairs {
+			rate := &PairRate{Provider: chain.provider}
+			if value, ok := values[pair]; err == nil && ok {
+				rate.Rate = value
+			} else if err != nil {
+				rate.Err = err
+			} else {
+				rate.Err = fmt.Errorf("rate of %s in %s not found", pair.FromCurrency, pair.ToCurrency)
+			}
+			rates[pair] = rate
+		}
+		return rates
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, pair := range pairs {
+		wg.Add(1)
+		go func(pair Pair) {
+			defer wg.Done()
+
+			value, err := chain.api.GetRate(ctx, pair.FromCurrency, pair.ToCurrency)
+
+			mu.Lock()
+			defer mu.Unlock()
+			rates[pair] = &PairRate{
+				Rate:     value,
+				Provider: chain.provider,
+				Err:      err,
+			}
+		}(pair)
+	}
+	wg.Wait()
+
+	return rates
 }
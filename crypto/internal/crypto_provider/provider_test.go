@@ -0,0 +1,95 @@
+package crypto_provider
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProvider struct {
+	rates map[Pair]float64
+	calls int32
+}
+
+func (p *fakeProvider) GetRate(ctx context.Context, fromCurrency, toCurrency string) (float64, error) {
+	atomic.AddInt32(&p.calls, 1)
+	rate, ok := p.rates[Pair{FromCurrency: fromCurrency, ToCurrency: toCurrency}]
+	if !ok {
+		return 0, errors.New("rate not found")
+	}
+	return rate, nil
+}
+
+type fakeBatchProvider struct {
+	fakeProvider
+	err error
+}
+
+func (p *fakeBatchProvider) GetRates(ctx context.Context, pairs []Pair) (map[Pair]float64, error) {
+	atomic.AddInt32(&p.calls, 1)
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	rates := make(map[Pair]float64)
+	for _, pair := range pairs {
+		if rate, ok := p.rates[pair]; ok {
+			rates[pair] = rate
+		}
+	}
+	return rates, nil
+}
+
+func TestCryptoAPIChain_GetRates(t *testing.T) {
+	t.Parallel()
+
+	btcUAH := Pair{FromCurrency: "BTC", ToCurrency: "UAH"}
+	btcUSD := Pair{FromCurrency: "BTC", ToCurrency: "USD"}
+	ethUAH := Pair{FromCurrency: "ETH", ToCurrency: "UAH"}
+	ethUSD := Pair{FromCurrency: "ETH", ToCurrency: "USD"}
+
+	coinAPI := &fakeProvider{rates: map[Pair]float64{btcUAH: 1, btcUSD: 2}}
+	coinGecko := &fakeBatchProvider{fakeProvider: fakeProvider{rates: map[Pair]float64{btcUSD: 3, ethUAH: 4}}}
+	coinbase := &fakeProvider{rates: map[Pair]float64{btcUAH: 5}}
+
+	chain, err := NewCryptoAPIChain(CryptoAPIProviders{
+		CryptoAPIProviderCoinAPI:   coinAPI,
+		CryptoAPIProviderCoinGecko: coinGecko,
+		CryptoAPIProviderCoinbase:  coinbase,
+	})
+	require.NoError(t, err)
+
+	rates := chain.GetRates(context.Background(), []Pair{btcUAH, btcUSD, ethUAH, ethUSD})
+	require.Len(t, rates, 4)
+	assert.Equal(t, &PairRate{Rate: 1, Provider: CryptoAPIProviderCoinAPI}, rates[btcUAH])
+	assert.Equal(t, &PairRate{Rate: 2, Provider: CryptoAPIProviderCoinAPI}, rates[btcUSD])
+	assert.Equal(t, &PairRate{Rate: 4, Provider: CryptoAPIProviderCoinGecko}, rates[ethUAH])
+	assert.Error(t, rates[ethUSD].Err)
+	assert.Equal(t, CryptoAPIProviderCoinbase, rates[ethUSD].Provider)
+
+	// each pair is requested from regular provider, failed pairs are requested from batch provider at once
+	assert.EqualValues(t, 4, coinAPI.calls)
+	assert.EqualValues(t, 1, coinGecko.calls)
+	assert.EqualValues(t, 1, coinbase.calls)
+}
+
+func TestCryptoAPIChain_GetRates_BatchError(t *testing.T) {
+	t.Parallel()
+
+	pair := Pair{FromCurrency: "BTC", ToCurrency: "UAH"}
+	coinGecko := &fakeBatchProvider{err: errors.New("too many requests")}
+	coinbase := &fakeProvider{rates: map[Pair]float64{pair: 5}}
+
+	chain, err := NewCryptoAPIChain(CryptoAPIProviders{
+		CryptoAPIProviderCoinGecko: coinGecko,
+		CryptoAPIProviderCoinbase:  coinbase,
+	}, CryptoAPIProviderCoinGecko, CryptoAPIProviderCoinbase)
+	require.NoError(t, err)
+
+	rates := chain.GetRates(context.Background(), []Pair{pair})
+	assert.Equal(t, &PairRate{Rate: 5, Provider: CryptoAPIProviderCoinbase}, rates[pair])
+}
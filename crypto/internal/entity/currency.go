@@ -1,5 +1,11 @@
 package entity
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
 type CryptoCurrency string
 
 const (
@@ -39,3 +45,40 @@ func (f FiatCurrency) IsValid() bool {
 	_, ok := fiatCurrencies[f]
 	return ok
 }
+
+// CurrencyPair is a pair of crypto and fiat currencies.
+type CurrencyPair struct {
+	Crypto CryptoCurrency
+	Fiat   FiatCurrency
+}
+
+const currencyPairSeparator = "-"
+
+// String returns pair in format "BTC-USD", that is accepted by ParseCurrencyPair.
+func (p CurrencyPair) String() string {
+	return p.Crypto.String() + currencyPairSeparator + p.Fiat.String()
+}
+
+func (p CurrencyPair) IsValid() bool {
+	return p.Crypto.IsValid() && p.Fiat.IsValid()
+}
+
+var ErrInvalidCurrencyPair = errors.New("invalid currency pair")
+
+// ParseCurrencyPair parses pair in format "BTC-USD". Currency codes are case-insensitive.
+func ParseCurrencyPair(s string) (CurrencyPair, error) {
+	crypto, fiat, ok := strings.Cut(strings.TrimSpace(s), currencyPairSeparator)
+	if !ok {
+		return CurrencyPair{}, fmt.Errorf("%w: %q", ErrInvalidCurrencyPair, s)
+	}
+
+	pair := CurrencyPair{
+		Crypto: CryptoCurrency(strings.ToUpper(crypto)),
+		Fiat:   FiatCurrency(strings.ToUpper(fiat)),
+	}
+	if !pair.IsValid() {
+		return CurrencyPair{}, fmt.Errorf("%w: %q", ErrInvalidCurrencyPair, s)
+	}
+
+	return pair, nil
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// Rate is rate of crypto currency in fiat currency.
+type Rate struct {
+	Pair  CurrencyPair
+	Value float64
+	// Provider is name of provider that served rate.
+	Provider string
+	// FetchedAt is when rate was fetched from provider.
+	FetchedAt time.Time
+	// Stale is true if rate couldn't be refreshed and previously fetched rate is returned.
+	Stale bool
+}
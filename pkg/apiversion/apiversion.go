@@ -0,0 +1,75 @@
+// Package apiversion announces deprecation of API routes that are replaced by a newer version.
+package apiversion
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+// Deprecation marks routes that are going to be removed.
+type Deprecation struct {
+	// Since is when routes were deprecated.
+	Since time.Time
+	// Sunset is when routes are going to be removed, it's not announced if it's zero.
+	Sunset time.Time
+	// SuccessorBasePath is base path under which routes are served by their successor, e.g. "/api/v1".
+	SuccessorBasePath string
+}
+
+// ParseDeprecation parses dates of deprecation in RFC 3339 format, e.g. "2023-07-01T00:00:00Z". Nil is
+// returned if since is empty, which means that routes are not deprecated.
+func ParseDeprecation(since, sunset, successorBasePath string) (*Deprecation, error) {
+	if since == "" {
+		return nil, nil
+	}
+
+	deprecation := &Deprecation{
+		SuccessorBasePath: successorBasePath,
+	}
+
+	var err error
+	deprecation.Since, err = time.Parse(time.RFC3339, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse deprecation date: %w", err)
+	}
+	if sunset != "" {
+		deprecation.Sunset, err = time.Parse(time.RFC3339, sunset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse sunset date: %w", err)
+		}
+		if deprecation.Sunset.Before(deprecation.Since) {
+			return nil, fmt.Errorf("sunset date %s is before deprecation date %s", sunset, since)
+		}
+	}
+
+	return deprecation, nil
+}
+
+// Middleware announces deprecation of routes of router with Deprecation (RFC 9745), Sunset (RFC 8594) and
+// Link headers, and logs their usage, so that remaining clients can be found before removal.
+func (d *Deprecation) Middleware(logger logging.Logger, router *gin.RouterGroup) gin.HandlerFunc {
+	logger = logger.Named("deprecationMiddleware")
+
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+		if !d.Sunset.IsZero() {
+			c.Header("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if d.SuccessorBasePath != "" {
+			successor := d.SuccessorBasePath + strings.TrimPrefix(c.Request.URL.Path, router.BasePath())
+			c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		}
+
+		logger.WithContext(c.Request.Context()).
+			With("route", c.Request.Method+" "+c.FullPath()).
+			With("clientIP", c.ClientIP()).
+			With("userAgent", c.Request.UserAgent()).
+			Warn("deprecated route is used")
+	}
+}
@@ -0,0 +1,81 @@
+package apiversion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimpk/gses-2023/pkg/logging"
+)
+
+func TestParseDeprecation(t *testing.T) {
+	t.Parallel()
+
+	deprecation, err := ParseDeprecation("", "", "/api/v1")
+	assert.NoError(t, err)
+	assert.Nil(t, deprecation)
+
+	deprecation, err = ParseDeprecation("2023-07-01T00:00:00Z", "", "/api/v1")
+	require.NoError(t, err)
+	assert.Equal(t, &Deprecation{Since: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC), SuccessorBasePath: "/api/v1"}, deprecation)
+
+	for _, dates := range [][2]string{{"2023-07-01", ""}, {"2023-07-01T00:00:00Z", "soon"}, {"2023-07-01T00:00:00Z", "2023-06-01T00:00:00Z"}} {
+		_, err = ParseDeprecation(dates[0], dates[1], "/api/v1")
+		assert.Error(t, err, dates)
+	}
+}
+
+func TestDeprecation_Middleware(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		deprecation    *Deprecation
+		expectedSunset string
+		expectedLink   string
+	}{
+		{
+			name: "sunset and successor",
+			deprecation: &Deprecation{
+				Since:             time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
+				Sunset:            time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				SuccessorBasePath: "/api/v1",
+			},
+			expectedSunset: "Mon, 01 Jan 2024 00:00:00 GMT",
+			expectedLink:   `</api/v1/rate>; rel="successor-version"`,
+		},
+		{
+			name: "deprecation only",
+			deprecation: &Deprecation{
+				Since: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			router := r.Group("/api")
+			router.Use(tc.deprecation.Middleware(logging.NewZapLogger("debug"), router))
+			router.GET("/rate", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/rate", nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "@1688169600", rec.Header().Get("Deprecation"))
+			assert.Equal(t, tc.expectedSunset, rec.Header().Get("Sunset"))
+			assert.Equal(t, tc.expectedLink, rec.Header().Get("Link"))
+		})
+	}
+}
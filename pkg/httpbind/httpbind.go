@@ -0,0 +1,216 @@
+// Package httpbind binds parameters of gin requests to structs and validates them, so that services report
+// invalid requests the same way. Errors are returned as Error, which services put into their own error
+// responses.
+package httpbind
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// maxBodySize is size of request body that is read, larger bodies are rejected with 413, so that public
+	// endpoints can't be made to read unbounded bodies.
+	maxBodySize = 1 << 20
+	// maxMultipartMemory is size of multipart form kept in memory, rest of it is stored in temporary files.
+	maxMultipartMemory = 1 << 20
+)
+
+// Error describes why request can't be bound.
+type Error struct {
+	// Code is HTTP status of response, e.g. 400 or 415.
+	Code    int
+	Message string
+	// Details is either a string or FieldErrors of invalid fields.
+	Details interface{}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v", e.Message, e.Details)
+}
+
+// FieldError describes why value of request field is invalid.
+type FieldError struct {
+	// Field is path of parameter as client sends it, e.g. "email" or "items[0].email".
+	Field string `json:"field"`
+	// Rule is validation rule that failed, e.g. "required" or "email".
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+var registerTagNameOnce sync.Once
+
+// Bind binds parameters of request to obj and validates them with its binding tags. Parameters are read
+// from query and from JSON, form-urlencoded or multipart body depending on Content-Type, body takes
+// precedence over query. Fields are named by their json tag, or by form tag if they don't have one.
+func Bind(c *gin.Context, obj interface{}) *Error {
+	registerTagNameOnce.Do(registerTagName)
+
+	if err := binding.MapFormWithTag(obj, c.Request.URL.Query(), "form"); err != nil {
+		return &Error{
+			Code:    http.StatusBadRequest,
+			Message: "failed to bind query",
+			Details: err.Error(),
+		}
+	}
+
+	if err := bindBody(c, obj); err != nil {
+		return err
+	}
+
+	if err := binding.Validator.ValidateStruct(obj); err != nil {
+		return newValidationError(err)
+	}
+
+	return nil
+}
+
+func bindBody(c *gin.Context, obj interface{}) *Error {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody || c.Request.ContentLength == 0 {
+		return nil
+	}
+
+	if c.Request.ContentLength > maxBodySize {
+		return newBodyTooLargeError()
+	}
+	// size of chunked body isn't known in advance, so it's limited while it's read
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBodySize)
+
+	var err error
+	switch c.ContentType() {
+	case "":
+		return nil
+	case binding.MIMEJSON:
+		err = json.NewDecoder(c.Request.Body).Decode(obj)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return &Error{
+				Code:    http.StatusBadRequest,
+				Message: "invalid request",
+				Details: []FieldError{{
+					Field:   typeErr.Field,
+					Rule:    "type",
+					Param:   typeErr.Type.String(),
+					Message: fmt.Sprintf("must be %s, got %s", typeErr.Type, typeErr.Value),
+				}},
+			}
+		}
+	case binding.MIMEPOSTForm:
+		if err = c.Request.ParseForm(); err == nil {
+			err = binding.MapFormWithTag(obj, c.Request.PostForm, "form")
+		}
+	case binding.MIMEMultipartPOSTForm:
+		if err = c.Request.ParseMultipartForm(maxMultipartMemory); err == nil {
+			err = binding.MapFormWithTag(obj, c.Request.MultipartForm.Value, "form")
+		}
+	default:
+		return &Error{
+			Code:    http.StatusUnsupportedMediaType,
+			Message: "unsupported content type",
+			Details: fmt.Sprintf("%s is not supported, use %s or %s", c.ContentType(), binding.MIMEJSON, binding.MIMEPOSTForm),
+		}
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return newBodyTooLargeError()
+	}
+	if err != nil {
+		return &Error{
+			Code:    http.StatusBadRequest,
+			Message: "failed to bind body",
+			Details: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+func newBodyTooLargeError() *Error {
+	return &Error{
+		Code:    http.StatusRequestEntityTooLarge,
+		Message: "request body is too large",
+		Details: fmt.Sprintf("body must be at most %d bytes", maxBodySize),
+	}
+}
+
+// newValidationError converts validation errors to Error with error of each field in details.
+func newValidationError(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return &Error{
+			Code:    http.StatusBadRequest,
+			Message: "invalid request",
+			Details: err.Error(),
+		}
+	}
+
+	details := make([]FieldError, 0, len(validationErrs))
+	for _, e := range validationErrs {
+		// namespace starts with name of bound struct, which isn't part of request
+		_, field, _ := strings.Cut(e.Namespace(), ".")
+		details = append(details, FieldError{
+			Field:   field,
+			Rule:    e.Tag(),
+			Param:   e.Param(),
+			Message: validationMessage(e),
+		})
+	}
+
+	return &Error{
+		Code:    http.StatusBadRequest,
+		Message: "invalid request",
+		Details: details,
+	}
+}
+
+func validationMessage(e validator.FieldError) string {
+	switch e.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be valid email"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(e.Param(), " ", ", ")
+	case "min":
+		return "must be at least " + e.Param()
+	case "max":
+		return "must be at most " + e.Param()
+	default:
+		return fmt.Sprintf("failed %q validation", e.Tag())
+	}
+}
+
+// registerTagName makes validator name fields the same way clients send them.
+func registerTagName() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
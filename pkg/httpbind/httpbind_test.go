@@ -0,0 +1,189 @@
+package httpbind
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testBindingRequest struct {
+	Email  string `form:"email" json:"email" binding:"required,email"`
+	Pairs  string `form:"pairs" json:"pairs"`
+	Status string `form:"status" json:"status" binding:"omitempty,oneof=pending confirmed"`
+	Limit  int    `form:"limit" json:"limit" binding:"omitempty,max=10"`
+}
+
+type testBindingNested struct {
+	Items []testBindingItem `json:"items" binding:"dive"`
+}
+
+type testBindingItem struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+func newTestBindingRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	// handler responds with bound request, or with error in the way services put it into their responses
+	handler := func(newRequest func() interface{}) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			request := newRequest()
+			if err := Bind(c, request); err != nil {
+				c.JSON(err.Code, gin.H{"message": err.Message, "details": err.Details})
+				return
+			}
+			c.JSON(http.StatusOK, request)
+		}
+	}
+	r.POST("/bind", handler(func() interface{} { return &testBindingRequest{} }))
+	r.POST("/bind/nested", handler(func() interface{} { return &testBindingNested{} }))
+	return r
+}
+
+func TestBind(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		path        string
+		query       string
+		contentType string
+		body        string
+		// chunked hides size of body, so that it's limited while it's read
+		chunked       bool
+		expectedCode  int
+		expected      testBindingRequest
+		expectedError string
+	}{
+		{
+			name:         "positive: query",
+			query:        "email=user@example.com&pairs=BTC-USD&limit=5",
+			expectedCode: http.StatusOK,
+			expected:     testBindingRequest{Email: "user@example.com", Pairs: "BTC-USD", Limit: 5},
+		},
+		{
+			name:         "positive: json body takes precedence over query",
+			query:        "email=query@example.com&pairs=BTC-USD",
+			contentType:  "application/json; charset=utf-8",
+			body:         `{"email": "user@example.com", "status": "pending"}`,
+			expectedCode: http.StatusOK,
+			expected:     testBindingRequest{Email: "user@example.com", Pairs: "BTC-USD", Status: "pending"},
+		},
+		{
+			name:         "positive: form body",
+			contentType:  "application/x-www-form-urlencoded",
+			body:         "email=user%40example.com&limit=10",
+			expectedCode: http.StatusOK,
+			expected:     testBindingRequest{Email: "user@example.com", Limit: 10},
+		},
+		{
+			name:          "negative: validation errors of each field",
+			query:         "email=user&status=deleted&limit=11",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `{"message":"invalid request","details":[{"field":"email","rule":"email","message":"must be valid email"},{"field":"status","rule":"oneof","param":"pending confirmed","message":"must be one of: pending, confirmed"},{"field":"limit","rule":"max","param":"10","message":"must be at most 10"}]}`,
+		},
+		{
+			name:          "negative: missing required field",
+			contentType:   "application/json",
+			body:          `{"pairs": "BTC-USD"}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `{"message":"invalid request","details":[{"field":"email","rule":"required","message":"is required"}]}`,
+		},
+		{
+			name:          "negative: json field of wrong type",
+			contentType:   "application/json",
+			body:          `{"email": "user@example.com", "limit": "5"}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `{"message":"invalid request","details":[{"field":"limit","rule":"type","param":"int","message":"must be int, got string"}]}`,
+		},
+		{
+			name:          "negative: fields of nested items are named by path",
+			path:          "/bind/nested",
+			contentType:   "application/json",
+			body:          `{"items": [{"email": "user@example.com"}, {"email": "user"}]}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `{"message":"invalid request","details":[{"field":"items[1].email","rule":"email","message":"must be valid email"}]}`,
+		},
+		{
+			name:         "negative: malformed json",
+			contentType:  "application/json",
+			body:         `{"email": `,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "negative: query value of wrong type",
+			query:        "email=user@example.com&limit=five",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "negative: unsupported content type",
+			contentType:  "text/plain",
+			body:         "user@example.com",
+			expectedCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:         "negative: too large json body",
+			contentType:  "application/json",
+			body:         `{"email": "` + strings.Repeat("a", maxBodySize) + `@example.com"}`,
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "negative: too large chunked json body",
+			contentType:  "application/json",
+			body:         `{"email": "` + strings.Repeat("a", maxBodySize) + `@example.com"}`,
+			chunked:      true,
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "negative: too large chunked form body",
+			contentType:  "application/x-www-form-urlencoded",
+			body:         "email=" + strings.Repeat("a", maxBodySize) + "%40example.com",
+			chunked:      true,
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "negative: too large chunked multipart body",
+			contentType: "multipart/form-data; boundary=b",
+			body: "--b\r\nContent-Disposition: form-data; name=\"email\"\r\n\r\n" +
+				strings.Repeat("a", maxBodySize) + "@example.com\r\n--b--\r\n",
+			chunked:      true,
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := "/bind"
+			if tc.path != "" {
+				path = tc.path
+			}
+			req := httptest.NewRequest(http.MethodPost, path+"?"+tc.query, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			if tc.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			newTestBindingRouter().ServeHTTP(rec, req)
+
+			require.Equal(t, tc.expectedCode, rec.Code, rec.Body.String())
+			if tc.expectedCode == http.StatusOK {
+				var request testBindingRequest
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &request))
+				assert.Equal(t, tc.expected, request)
+			} else if tc.expectedError != "" {
+				assert.JSONEq(t, tc.expectedError, rec.Body.String())
+			}
+		})
+	}
+}
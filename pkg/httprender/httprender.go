@@ -0,0 +1,136 @@
+// Package httprender writes responses of gin handlers in format negotiated with client.
+package httprender
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Render writes obj as JSON or XML, whichever is preferred by Accept header of request.
+func Render(c *gin.Context, code int, obj interface{}) {
+	if negotiateFormat(c.GetHeader("Accept")) == binding.MIMEXML {
+		c.Render(code, xmlRender{data: obj})
+		return
+	}
+	c.JSON(code, obj)
+}
+
+// negotiateFormat returns binding.MIMEXML if accept header gives XML higher quality than JSON, and
+// binding.MIMEJSON otherwise, so that clients that accept neither still get JSON.
+func negotiateFormat(accept string) string {
+	var jsonQuality, xmlQuality float64
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case binding.MIMEJSON:
+			jsonQuality = maxFloat(jsonQuality, quality)
+		case binding.MIMEXML, binding.MIMEXML2:
+			xmlQuality = maxFloat(xmlQuality, quality)
+		case "application/*", "*/*":
+			jsonQuality = maxFloat(jsonQuality, quality)
+			xmlQuality = maxFloat(xmlQuality, quality)
+		}
+	}
+
+	if xmlQuality > jsonQuality {
+		return binding.MIMEXML
+	}
+	return binding.MIMEJSON
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// xmlRender renders data as XML document with "response" root element. Data is converted from its JSON
+// form, so that elements are named by json tags and both formats have the same structure. Array items
+// are "item" elements and null values are omitted.
+type xmlRender struct {
+	data interface{}
+}
+
+func (r xmlRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+
+	data, err := json.Marshal(r.data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal json: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	encoder := xml.NewEncoder(w)
+	if err := encodeXML(decoder, encoder, "response"); err != nil {
+		return fmt.Errorf("failed to encode xml: %w", err)
+	}
+	return encoder.Flush()
+}
+
+func (r xmlRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", binding.MIMEXML+"; charset=utf-8")
+}
+
+// encodeXML encodes next JSON value of decoder as element with given name.
+func encodeXML(decoder *json.Decoder, encoder *xml.Encoder, name string) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		for decoder.More() {
+			childName := "item"
+			if t == '{' {
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				childName = key.(string)
+			}
+			if err := encodeXML(decoder, encoder, childName); err != nil {
+				return err
+			}
+		}
+		// closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+	default:
+		if err := encoder.EncodeToken(xml.CharData(fmt.Sprint(t))); err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
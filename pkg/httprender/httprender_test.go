@@ -0,0 +1,88 @@
+package httprender
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		"":                                 "application/json",
+		"application/json":                 "application/json",
+		"application/xml":                  "application/xml",
+		"text/xml":                         "application/xml",
+		"text/html":                        "application/json",
+		"*/*":                              "application/json",
+		"application/json;q=0.5, text/xml": "application/xml",
+		"application/xml;q=0.5, */*":       "application/json",
+		"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8": "application/xml",
+	}
+
+	for accept, expected := range testCases {
+		assert.Equal(t, expected, negotiateFormat(accept), accept)
+	}
+}
+
+func TestRender_XML(t *testing.T) {
+	t.Parallel()
+
+	type errorBody struct {
+		Message string      `json:"message"`
+		Details interface{} `json:"details,omitempty"`
+		Code    int         `json:"code"`
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ok", func(c *gin.Context) {
+		Render(c, http.StatusOK, struct {
+			Email  string   `json:"email"`
+			Pairs  []string `json:"pairs"`
+			Locale *string  `json:"locale"`
+		}{
+			Email: "user@example.com",
+			Pairs: []string{"BTC-USD", "ETH-UAH"},
+		})
+	})
+	r.GET("/error", func(c *gin.Context) {
+		Render(c, http.StatusBadRequest, errorBody{
+			Message: "invalid request",
+			Details: []map[string]string{{"field": "email", "rule": "required"}},
+			Code:    http.StatusBadRequest,
+		})
+	})
+
+	testCases := []struct {
+		path         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			path:         "/ok",
+			expectedCode: http.StatusOK,
+			expectedBody: `<response><email>user@example.com</email><pairs><item>BTC-USD</item><item>ETH-UAH</item></pairs></response>`,
+		},
+		{
+			path:         "/error",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `<response><message>invalid request</message><details><item><field>email</field><rule>required</rule></item></details><code>400</code></response>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("Accept", "application/xml")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		assert.Equal(t, tc.expectedCode, rec.Code)
+		assert.Equal(t, "application/xml; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, tc.expectedBody, rec.Body.String())
+	}
+}
@@ -34,6 +34,13 @@ func Port(port string) Option {
 	}
 }
 
+// Addr - configures http server address, e.g. "127.0.0.1:9091", it overrides Port.
+func Addr(addr string) Option {
+	return func(s *Server) {
+		s.server.Addr = addr
+	}
+}
+
 // ReadTimeout - configures http server read timeout.
 func ReadTimeout(timeout time.Duration) Option {
 	return func(s *Server) {
@@ -0,0 +1,259 @@
+// Package openapi builds OpenAPI 3.0 documents from request and response types of HTTP handlers.
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	mimeJSON = "application/json"
+	mimeXML  = "application/xml"
+	mimeForm = "application/x-www-form-urlencoded"
+)
+
+// Document is OpenAPI 3.0 document.
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+
+	// errorSchema is reference to schema of error responses.
+	errorSchema *Schema
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	// Type is "apiKey" or "http".
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	// Name and In are set for "apiKey" type, e.g. "X-API-Key" and "header".
+	Name string `json:"name,omitempty"`
+	In   string `json:"in,omitempty"`
+	// Scheme and BearerFormat are set for "http" type, e.g. "bearer" and "JWT".
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type Operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// New returns document without operations. Error responses of operations are described by "Error" schema
+// of errorBody struct.
+func New(info Info, errorBody interface{}) *Document {
+	d := &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   make(map[string]map[string]*Operation),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: make(map[string]*SecurityScheme),
+		},
+	}
+	d.Components.Schemas["Error"] = d.inlineSchemaOf(errorBody)
+	d.errorSchema = &Schema{Ref: "#/components/schemas/Error"}
+	return d
+}
+
+// Route describes operation of HTTP handler.
+type Route struct {
+	Method string
+	// Path is path as it's registered in gin router, e.g. "/api/alerts/:id".
+	Path        string
+	Summary     string
+	Description string
+	Tags        []string
+	// Request is struct whose fields are parameters of request named by json or form tags. Validation
+	// rules of binding tags are added to schema. Parameters are in query for GET, HEAD and DELETE requests
+	// and in body for others.
+	Request interface{}
+	// Response is returned with Status, there is no response body if it's nil.
+	Response interface{}
+	// Status is status of successful response, 200 is used if it's zero.
+	Status int
+	// Errors are statuses of error responses.
+	Errors []int
+	// Security are names of security schemes, any of them authorizes request.
+	Security []string
+	// Headers are headers of all responses, e.g. rate limit headers.
+	Headers map[string]*Header
+	// Deprecated marks operation as deprecated.
+	Deprecated bool
+}
+
+var pathParamRegexp = regexp.MustCompile(`[:*](\w+)`)
+
+// Add adds operation of route to document.
+func (d *Document) Add(route Route) {
+	op := &Operation{
+		Summary:     route.Summary,
+		Description: route.Description,
+		OperationID: operationID(route.Method, route.Path),
+		Tags:        route.Tags,
+		Responses:   make(map[string]*Response),
+		Deprecated:  route.Deprecated,
+	}
+
+	for _, match := range pathParamRegexp.FindAllStringSubmatch(route.Path, -1) {
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+
+	if route.Request != nil {
+		switch route.Method {
+		case http.MethodGet, http.MethodHead, http.MethodDelete:
+			op.Parameters = append(op.Parameters, d.queryParameters(route.Request)...)
+		default:
+			schema := d.schemaOf(route.Request)
+			op.RequestBody = &RequestBody{
+				Content: map[string]*MediaType{
+					mimeJSON: {Schema: schema},
+					mimeForm: {Schema: schema},
+				},
+			}
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	op.Responses[strconv.Itoa(status)] = &Response{
+		Description: http.StatusText(status),
+		Headers:     route.Headers,
+		Content:     d.content(route.Response),
+	}
+	for _, code := range route.Errors {
+		op.Responses[strconv.Itoa(code)] = &Response{
+			Description: http.StatusText(code),
+			Headers:     route.Headers,
+			Content:     d.content(d.errorSchema),
+		}
+	}
+
+	for _, name := range route.Security {
+		op.Security = append(op.Security, map[string][]string{name: {}})
+	}
+
+	path := pathParamRegexp.ReplaceAllString(route.Path, "{$1}")
+	if d.Paths[path] == nil {
+		d.Paths[path] = make(map[string]*Operation)
+	}
+	d.Paths[path][strings.ToLower(route.Method)] = op
+}
+
+// Has reports whether document has operation for method and path as it's registered in gin router.
+func (d *Document) Has(method, path string) bool {
+	_, ok := d.Paths[pathParamRegexp.ReplaceAllString(path, "{$1}")][strings.ToLower(method)]
+	return ok
+}
+
+// Operations returns "<METHOD> <path>" of all operations in document sorted by path.
+func (d *Document) Operations() []string {
+	var operations []string
+	for path, methods := range d.Paths {
+		for method := range methods {
+			operations = append(operations, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(operations)
+	return operations
+}
+
+// content returns JSON and XML content of body, which is either value or its schema.
+func (d *Document) content(body interface{}) map[string]*MediaType {
+	if body == nil {
+		return nil
+	}
+	schema, ok := body.(*Schema)
+	if !ok {
+		schema = d.schemaOf(body)
+	}
+	return map[string]*MediaType{
+		mimeJSON: {Schema: schema},
+		mimeXML:  {Schema: schema},
+	}
+}
+
+func (d *Document) queryParameters(request interface{}) []*Parameter {
+	schema := d.inlineSchemaOf(request)
+
+	required := make(map[string]bool, len(schema.Required))
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+
+	parameters := make([]*Parameter, 0, len(schema.Properties))
+	for _, name := range schema.propertyOrder {
+		parameters = append(parameters, &Parameter{
+			Name:     name,
+			In:       "query",
+			Required: required[name],
+			Schema:   schema.Properties[name],
+		})
+	}
+	return parameters
+}
+
+// operationID returns id like "getApiAlertsId" for "GET /api/alerts/:id".
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == ':' || r == '*' }) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
@@ -0,0 +1,76 @@
+package openapi
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testError struct {
+	Message string `json:"message"`
+}
+
+type testListRequest struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending confirmed"`
+	Limit  int    `form:"limit" binding:"required,max=100"`
+}
+
+type testCreateRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type testItem struct {
+	ID string `json:"id"`
+}
+
+func TestDocument_Add(t *testing.T) {
+	t.Parallel()
+
+	d := New(Info{Title: "test", Version: "1"}, testError{})
+	d.Add(Route{
+		Method:   http.MethodGet,
+		Path:     "/api/lists/:list/items/*item",
+		Request:  testListRequest{},
+		Response: []testItem{},
+		Errors:   []int{http.StatusNotFound},
+		Security: []string{"apiKey", "bearer"},
+	})
+	d.Add(Route{
+		Method:     http.MethodPost,
+		Path:       "/api/items",
+		Request:    testCreateRequest{},
+		Status:     http.StatusCreated,
+		Deprecated: true,
+	})
+
+	assert.Equal(t, []string{"GET /api/lists/{list}/items/{item}", "POST /api/items"}, d.Operations())
+	assert.True(t, d.Has(http.MethodGet, "/api/lists/:list/items/*item"))
+	assert.False(t, d.Has(http.MethodPost, "/api/lists/:list/items/*item"))
+	assert.Equal(t, []string{"message"}, d.Components.Schemas["Error"].propertyOrder)
+
+	list := d.Paths["/api/lists/{list}/items/{item}"]["get"]
+	require.NotNil(t, list)
+	assert.Equal(t, "getApiListsListItemsItem", list.OperationID)
+	max := 100.0
+	// path parameters come first, query parameters keep order of fields
+	assert.Equal(t, []*Parameter{
+		{Name: "list", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+		{Name: "item", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+		{Name: "status", In: "query", Schema: &Schema{Type: "string", Enum: []string{"pending", "confirmed"}}},
+		{Name: "limit", In: "query", Required: true, Schema: &Schema{Type: "integer", Format: "int32", Maximum: &max}},
+	}, list.Parameters)
+	assert.Nil(t, list.RequestBody)
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Ref: "#/components/schemas/TestItem"}}, list.Responses["200"].Content[mimeJSON].Schema)
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/Error"}, list.Responses["404"].Content[mimeXML].Schema)
+	assert.Equal(t, []map[string][]string{{"apiKey": {}}, {"bearer": {}}}, list.Security)
+
+	create := d.Paths["/api/items"]["post"]
+	require.NotNil(t, create)
+	assert.Empty(t, create.Parameters)
+	require.NotNil(t, create.RequestBody)
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/TestCreateRequest"}, create.RequestBody.Content[mimeForm].Schema)
+	assert.Nil(t, create.Responses["201"].Content)
+	assert.True(t, create.Deprecated)
+}
@@ -0,0 +1,170 @@
+package openapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testAddress struct {
+	City string `json:"city" binding:"required"`
+}
+
+type testEmbedded struct {
+	CreatedAt time.Time `json:"created_at" binding:"required"`
+}
+
+type testTree struct {
+	Name     string      `json:"name"`
+	Children []*testTree `json:"children"`
+}
+
+type testRequest struct {
+	testEmbedded
+	Email    string            `json:"email" binding:"required,email"`
+	Status   string            `form:"status" binding:"omitempty,oneof=pending confirmed"`
+	Limit    int               `json:"limit" form:"limit" binding:"min=1,max=1000"`
+	Score    float64           `json:"score" binding:"gte=0.5,lte=10"`
+	Total    int64             `json:"total"`
+	Ratio    float32           `json:"ratio"`
+	Enabled  bool              `json:"enabled,omitempty"`
+	Note     *string           `json:"note"`
+	Tags     []string          `json:"tags" binding:"max=5"`
+	Matrix   [][]int           `json:"matrix"`
+	Labels   map[string]string `json:"labels"`
+	Address  *testAddress      `json:"address" binding:"required,min=1"`
+	Previous []testAddress     `json:"previous"`
+	Tree     testTree          `json:"tree"`
+	Extra    interface{}       `json:"extra"`
+	Untagged string
+	Skipped  string `json:"-"`
+	hidden   string
+}
+
+func TestDocument_SchemaOf(t *testing.T) {
+	t.Parallel()
+
+	d := New(Info{Title: "test", Version: "1"}, struct{}{})
+	schema := d.schemaOf(testRequest{hidden: "unexported fields are skipped"})
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/TestRequest"}, schema)
+
+	request := d.Components.Schemas["TestRequest"]
+	require.NotNil(t, request)
+	assert.Equal(t, "object", request.Type)
+	assert.Equal(t, []string{"created_at", "email", "address"}, request.Required)
+	assert.Equal(t, []string{
+		"created_at", "email", "status", "limit", "score", "total", "ratio", "enabled", "note", "tags", "matrix",
+		"labels", "address", "previous", "tree", "extra", "Untagged",
+	}, request.propertyOrder)
+
+	one, five, half, ten, thousand := 1.0, 5.0, 0.5, 10.0, 1000.0
+	tests := []struct {
+		property string
+		want     *Schema
+	}{
+		{property: "created_at", want: &Schema{Type: "string", Format: "date-time"}},
+		{property: "email", want: &Schema{Type: "string", Format: "email"}},
+		{property: "status", want: &Schema{Type: "string", Enum: []string{"pending", "confirmed"}}},
+		{property: "limit", want: &Schema{Type: "integer", Format: "int32", Minimum: &one, Maximum: &thousand}},
+		{property: "score", want: &Schema{Type: "number", Format: "double", Minimum: &half, Maximum: &ten}},
+		{property: "total", want: &Schema{Type: "integer", Format: "int64"}},
+		{property: "ratio", want: &Schema{Type: "number", Format: "float"}},
+		{property: "enabled", want: &Schema{Type: "boolean"}},
+		{property: "note", want: &Schema{Type: "string", Nullable: true}},
+		// limits of slices are lengths rather than values, but they are documented the same way
+		{property: "tags", want: &Schema{Type: "array", Items: &Schema{Type: "string"}, Maximum: &five}},
+		{property: "matrix", want: &Schema{Type: "array", Items: &Schema{Type: "array", Items: &Schema{Type: "integer", Format: "int32"}}}},
+		{property: "labels", want: &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}}},
+		// references can't have siblings, so pointers to structs aren't nullable and have no limits
+		{property: "address", want: &Schema{Ref: "#/components/schemas/TestAddress"}},
+		{property: "previous", want: &Schema{Type: "array", Items: &Schema{Ref: "#/components/schemas/TestAddress"}}},
+		{property: "tree", want: &Schema{Ref: "#/components/schemas/TestTree"}},
+		{property: "extra", want: &Schema{}},
+		{property: "Untagged", want: &Schema{Type: "string"}},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.property, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, request.Properties[tc.property])
+		})
+	}
+
+	assert.Equal(t, []string{"city"}, d.Components.Schemas["TestAddress"].Required)
+	// self-referencing types are referenced instead of being expanded endlessly
+	tree := d.Components.Schemas["TestTree"]
+	require.NotNil(t, tree)
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/TestTree"}, tree.Properties["children"].Items)
+}
+
+func TestDocument_SchemaOf_Anonymous(t *testing.T) {
+	t.Parallel()
+
+	d := New(Info{Title: "test", Version: "1"}, struct{}{})
+	schema := d.schemaOf(&struct {
+		ID string `json:"id" binding:"required"`
+	}{})
+
+	// anonymous structs are inlined, pointers to them are nullable
+	assert.Equal(t, "object", schema.Type)
+	assert.True(t, schema.Nullable)
+	assert.Equal(t, []string{"id"}, schema.Required)
+	assert.Equal(t, &Schema{Type: "string"}, schema.Properties["id"])
+	assert.Len(t, d.Components.Schemas, 1)
+}
+
+func TestApplyBindingRules(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		schema       *Schema
+		tag          string
+		wantSchema   *Schema
+		wantRequired bool
+	}{
+		{
+			name:       "empty",
+			schema:     &Schema{Type: "string"},
+			tag:        "",
+			wantSchema: &Schema{Type: "string"},
+		},
+		{
+			name:         "required",
+			schema:       &Schema{Type: "string"},
+			tag:          "required",
+			wantSchema:   &Schema{Type: "string"},
+			wantRequired: true,
+		},
+		{
+			name:       "oneof",
+			schema:     &Schema{Type: "string"},
+			tag:        "oneof=en uk",
+			wantSchema: &Schema{Type: "string", Enum: []string{"en", "uk"}},
+		},
+		{
+			name:       "invalid limit",
+			schema:     &Schema{Type: "integer"},
+			tag:        "min=one",
+			wantSchema: &Schema{Type: "integer"},
+		},
+		{
+			name:       "unknown rules",
+			schema:     &Schema{Type: "string"},
+			tag:        "omitempty,dive,len=3",
+			wantSchema: &Schema{Type: "string"},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.wantRequired, applyBindingRules(tc.schema, tc.tag))
+			assert.Equal(t, tc.wantSchema, tc.schema)
+		})
+	}
+}
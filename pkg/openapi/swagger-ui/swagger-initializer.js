@@ -0,0 +1,9 @@
+// Spec URL is passed in data attribute instead of inline script, so that page works with Content-Security-Policy
+// that allows scripts of the same origin only.
+window.onload = function () {
+  var root = document.getElementById("swagger-ui");
+  window.ui = SwaggerUIBundle({
+    url: root.dataset.specUrl,
+    dom_id: "#swagger-ui",
+  });
+};
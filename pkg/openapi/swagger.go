@@ -0,0 +1,38 @@
+package openapi
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+)
+
+//go:embed swagger-ui/*.css swagger-ui/*.js swagger-ui/*.png swagger-ui/index.html
+var swaggerFiles embed.FS
+
+var swaggerTemplate = template.Must(template.ParseFS(swaggerFiles, "swagger-ui/index.html"))
+
+// SwaggerUIAssets are styles, scripts and icons of Swagger UI page, they are served under assetsURL of SwaggerUI.
+var SwaggerUIAssets = func() fs.FS {
+	assets, err := fs.Sub(swaggerFiles, "swagger-ui")
+	if err != nil {
+		panic(err)
+	}
+	return assets
+}()
+
+// SwaggerUI returns page of Swagger UI that shows document at specURL. The page loads SwaggerUIAssets from
+// assetsURL and has no inline scripts, so it works offline and with strict Content-Security-Policy.
+func SwaggerUI(title, specURL, assetsURL string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := swaggerTemplate.Execute(&buf, map[string]string{
+		"Title":     title,
+		"SpecURL":   specURL,
+		"AssetsURL": assetsURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,28 @@
+package openapi
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwaggerUI(t *testing.T) {
+	t.Parallel()
+
+	page, err := SwaggerUI(`API "v1"`, "/api/openapi.json", "/api/docs/assets")
+	require.NoError(t, err)
+	assert.Contains(t, string(page), "<title>API &#34;v1&#34;</title>")
+	assert.Contains(t, string(page), `data-spec-url="/api/openapi.json"`)
+	assert.NotContains(t, string(page), "https://")
+
+	// every asset referenced by page is embedded
+	for _, asset := range []string{"swagger-ui.css", "favicon-32x32.png", "favicon-16x16.png", "swagger-ui-bundle.js", "swagger-initializer.js"} {
+		assert.Contains(t, string(page), `"/api/docs/assets/`+asset+`"`)
+		_, err := fs.Stat(SwaggerUIAssets, asset)
+		assert.NoError(t, err, asset)
+	}
+	_, err = fs.Stat(SwaggerUIAssets, "index.html")
+	assert.NoError(t, err)
+}